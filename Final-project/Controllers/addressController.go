@@ -0,0 +1,233 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// GetCustomerAddresses handles the GET /customers/{id}/addresses request
+func GetCustomerAddresses(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+	customer, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer.Addresses)
+}
+
+// CreateCustomerAddress handles the POST /customers/{id}/addresses request. The address is normalized, and
+// becomes the default shipping or billing address if it asks to or if the customer has none.
+func CreateCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetCustomerStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+
+	// Decode and normalize the address
+	var address StructureData.CustomerAddress
+	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	if errResp := normalizeCustomerAddress(&address); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Add the address to the customer
+	createdAddress, errResp := store.AddAddress(id, address, auditContext(r))
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistCustomersToFile(store); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created address
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdAddress)
+}
+
+// UpdateCustomerAddress handles the PUT /customers/{id}/addresses/{address_id} request
+func UpdateCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetCustomerStoreInstance()
+
+	// Extract the customer and address IDs from the URL
+	id, addressID, errResp := customerChildIDs(r, "address")
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Decode and normalize the address
+	var address StructureData.CustomerAddress
+	if err := json.NewDecoder(r.Body).Decode(&address); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	if errResp := normalizeCustomerAddress(&address); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Replace the address
+	updatedAddress, errResp := store.UpdateAddress(id, addressID, address, auditContext(r))
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistCustomersToFile(store); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated address
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedAddress)
+}
+
+// DeleteCustomerAddress handles the DELETE /customers/{id}/addresses/{address_id} request. Orders keep the
+// addresses they were placed with.
+func DeleteCustomerAddress(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetCustomerStoreInstance()
+
+	// Extract the customer and address IDs from the URL
+	id, addressID, errResp := customerChildIDs(r, "address")
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Remove the address
+	if errResp := store.DeleteAddress(id, addressID, auditContext(r)); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistCustomersToFile(store); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// normalizeCustomerAddresses normalizes the address book of a customer, or its single address when it is
+// given without an address book
+func normalizeCustomerAddresses(customer *StructureData.Customer) *StructureData.ErrorResponse {
+	for i := range customer.Addresses {
+		if errResp := normalizeCustomerAddress(&customer.Addresses[i]); errResp != nil {
+			return errResp
+		}
+	}
+	if customer.Addresses != nil || customer.Address.IsEmpty() {
+		return nil
+	}
+	address, errResp := utils.NormalizeAddress(customer.Address)
+	if errResp != nil {
+		return errResp
+	}
+	customer.Address = address
+	return nil
+}
+
+// normalizeCustomerAddress checks the use of an address book entry, trims its label and normalizes its address
+func normalizeCustomerAddress(address *StructureData.CustomerAddress) *StructureData.ErrorResponse {
+	if address.Use == "" {
+		address.Use = StructureData.AddressUseAny
+	}
+	if !StructureData.IsValidAddressUse(address.Use) {
+		return StructureData.NewValidationError("use must be any, shipping or billing")
+	}
+	if address.Use == StructureData.AddressUseShipping && address.DefaultBilling ||
+		address.Use == StructureData.AddressUseBilling && address.DefaultShipping {
+		return StructureData.NewValidationError(fmt.Sprintf("A %s address cannot be the default for the other use", address.Use))
+	}
+	address.Label = strings.TrimSpace(address.Label)
+	normalized, errResp := utils.NormalizeAddress(address.Address)
+	if errResp != nil {
+		return errResp
+	}
+	address.Address = normalized
+	return nil
+}
+
+// resolveOrderAddresses snapshots the ship-to and bill-to addresses of an order, each being the entry of the
+// customer's address book chosen by address_id, a one-off address given in full, or the customer's default.
+// An order is billed to its ship-to address when the customer has no billing address. The ship-to address is
+// also the address of the customer snapshot.
+func resolveOrderAddresses(customer StructureData.Customer, order *StructureData.Order) *StructureData.ErrorResponse {
+	shipTo, errResp := resolveOrderAddress(customer, order.ShipTo, StructureData.AddressUseShipping)
+	if errResp != nil {
+		return errResp
+	}
+	billTo, errResp := resolveOrderAddress(customer, order.BillTo, StructureData.AddressUseBilling)
+	if errResp != nil {
+		return errResp
+	}
+	if billTo == nil {
+		billTo = shipTo
+	}
+
+	order.ShipTo, order.BillTo = shipTo, billTo
+	if shipTo != nil {
+		order.CustomerSnapshot.Address = shipTo.Address
+	}
+	return nil
+}
+
+// resolveOrderAddress returns the address requested for a use of an order, or the customer's default
+// address for it. It returns nil if the customer has none.
+func resolveOrderAddress(customer StructureData.Customer, requested *StructureData.OrderAddress, use StructureData.AddressUse) (*StructureData.OrderAddress, *StructureData.ErrorResponse) {
+	switch {
+	case requested != nil && requested.AddressID != 0:
+		address, ok := customer.FindAddress(requested.AddressID)
+		if !ok {
+			return nil, StructureData.NewValidationError(fmt.Sprintf("Address %d is not in the customer's address book", requested.AddressID))
+		}
+		if use == StructureData.AddressUseShipping && !address.CanShip() || use == StructureData.AddressUseBilling && !address.CanBill() {
+			return nil, StructureData.NewValidationError(fmt.Sprintf("Address %d cannot be used for %s", requested.AddressID, use))
+		}
+		return &StructureData.OrderAddress{AddressID: address.ID, Label: address.Label, Address: address.Address}, nil
+	case requested != nil && !requested.Address.IsEmpty():
+		address, errResp := utils.NormalizeAddress(requested.Address)
+		if errResp != nil {
+			return nil, errResp
+		}
+		return &StructureData.OrderAddress{Label: strings.TrimSpace(requested.Label), Address: address}, nil
+	}
+
+	address, ok := customer.DefaultAddress(use)
+	if !ok {
+		return nil, nil
+	}
+	return &StructureData.OrderAddress{AddressID: address.ID, Label: address.Label, Address: address.Address}, nil
+}
@@ -0,0 +1,370 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// maxAnalyticsBuckets bounds the length of a time series
+const maxAnalyticsBuckets = 1000
+
+// revenueAnalyticsQuery holds the parsed parameters of GET /analytics/revenue
+type revenueAnalyticsQuery struct {
+	interval StructureData.AnalyticsInterval
+	from     time.Time
+	to       time.Time
+	location *time.Location
+	splitBy  StructureData.ReportDimension
+	window   int
+	limit    int
+}
+
+// GetRevenueAnalytics handles the GET /analytics/revenue request. It returns the revenue, units and orders
+// of every day, week or month of the range, including those without sales, with their moving average and
+// the change since the previous bucket and since the same bucket a year earlier.
+func GetRevenueAnalytics(w http.ResponseWriter, r *http.Request) {
+	// Parse the query parameters
+	query, errResp := parseRevenueAnalyticsQuery(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Compute the time series
+	analytics, errResp := computeRevenueAnalytics(r.Context(), query)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analytics)
+}
+
+// parseRevenueAnalyticsQuery reads the interval (day by default), the from and to bounds as RFC 3339 times
+// or dates, the time zone, the split_by dimension, the moving_average window and the limit of split series.
+// Without from, the range covers the last 30 days, 12 weeks or 12 months.
+func parseRevenueAnalyticsQuery(r *http.Request) (revenueAnalyticsQuery, *StructureData.ErrorResponse) {
+	params := r.URL.Query()
+	query := revenueAnalyticsQuery{interval: StructureData.IntervalDay, location: time.UTC}
+
+	if value := params.Get("interval"); value != "" {
+		query.interval = StructureData.AnalyticsInterval(value)
+	}
+	switch query.interval {
+	case StructureData.IntervalDay:
+		query.window = 7
+	case StructureData.IntervalWeek:
+		query.window = 4
+	case StructureData.IntervalMonth:
+		query.window = 3
+	default:
+		return query, StructureData.NewValidationError("interval must be day, week or month")
+	}
+
+	if value := params.Get("time_zone"); value != "" {
+		location, err := time.LoadLocation(value)
+		if err != nil {
+			return query, StructureData.NewValidationError(fmt.Sprintf("Unknown time zone %q", value))
+		}
+		query.location = location
+	}
+
+	// The range, to being exclusive; a date as to includes that day
+	query.to = time.Now()
+	if value := params.Get("to"); value != "" {
+		to, dateOnly, err := parseAnalyticsTime(value, query.location)
+		if err != nil {
+			return query, StructureData.NewValidationError("Invalid to, use an RFC 3339 time or YYYY-MM-DD")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		query.to = to
+	}
+	if value := params.Get("from"); value != "" {
+		from, _, err := parseAnalyticsTime(value, query.location)
+		if err != nil {
+			return query, StructureData.NewValidationError("Invalid from, use an RFC 3339 time or YYYY-MM-DD")
+		}
+		query.from = from
+	} else {
+		switch query.interval {
+		case StructureData.IntervalDay:
+			query.from = query.to.AddDate(0, 0, -30)
+		case StructureData.IntervalWeek:
+			query.from = query.to.AddDate(0, 0, -12*7)
+		case StructureData.IntervalMonth:
+			query.from = query.to.AddDate(0, -12, 0)
+		}
+	}
+	if !query.from.Before(query.to) {
+		return query, StructureData.NewValidationError("from must be before to")
+	}
+	buckets := 0
+	for start := intervalStart(query.interval, query.from.In(query.location)); start.Before(query.to); start = nextIntervalStart(query.interval, start) {
+		if buckets++; buckets > maxAnalyticsBuckets {
+			return query, StructureData.NewValidationError(fmt.Sprintf("The range cannot have more than %d buckets", maxAnalyticsBuckets))
+		}
+	}
+
+	if value := params.Get("split_by"); value != "" {
+		query.splitBy = StructureData.ReportDimension(value)
+		if query.splitBy != StructureData.ReportByGenre && query.splitBy != StructureData.ReportByAuthor {
+			return query, StructureData.NewValidationError("split_by must be genre or author")
+		}
+	}
+	if value := params.Get("moving_average"); value != "" {
+		window, err := strconv.Atoi(value)
+		if err != nil || window < 1 || window > 100 {
+			return query, StructureData.NewValidationError("moving_average must be a number of buckets between 1 and 100")
+		}
+		query.window = window
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return query, StructureData.NewValidationError("limit must be a positive number")
+		}
+		query.limit = limit
+	}
+	return query, nil
+}
+
+// parseAnalyticsTime parses an RFC 3339 time, or a date at midnight in the location
+func parseAnalyticsTime(value string, location *time.Location) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, location)
+	return t, true, err
+}
+
+// computeRevenueAnalytics buckets the order lines from before the range, as far back as the moving average
+// and the year-over-year comparison need, then builds the series of the buckets of the range. Split series
+// are sorted by revenue, highest first.
+func computeRevenueAnalytics(ctx context.Context, query revenueAnalyticsQuery) (StructureData.RevenueAnalytics, *StructureData.ErrorResponse) {
+	firstBucket := intervalStart(query.interval, query.from.In(query.location))
+
+	// Find how far back the figures are needed
+	historyStart := firstBucket
+	for i := 0; i < query.window; i++ {
+		historyStart = previousIntervalStart(query.interval, historyStart) // Also covers the previous period
+	}
+	if yearEarlier := yearEarlierStart(query.interval, firstBucket); yearEarlier.Before(historyStart) {
+		historyStart = yearEarlier
+	}
+	orders, err := inmemoryStores.GetOrderStoreInstance().GetOrdersInTimeRange(historyStart.Add(-time.Nanosecond), query.to)
+	if err != nil {
+		return StructureData.RevenueAnalytics{}, StructureData.NewInternalError("Error fetching orders", err)
+	}
+
+	// Add every order line to the bucket of its series
+	figures := map[string]map[int64]*salesBucket{}
+	splits := map[string][]StructureData.ReportGroupKey{}
+	authorNames := map[int]string{}
+	for _, order := range orders {
+		select {
+		case <-ctx.Done(): // Check for cancellation
+			return StructureData.RevenueAnalytics{}, StructureData.NewCanceledError("Analytics were canceled", ctx.Err())
+		default:
+		}
+
+		bucketStart := intervalStart(query.interval, order.CreatedAt.In(query.location)).Unix()
+		for _, item := range order.Items {
+			groups := [][]StructureData.ReportGroupKey{{}}
+			if query.splitBy != "" {
+				groups = reportGroups([]StructureData.ReportDimension{query.splitBy}, order, item, query.location, authorNames)
+			}
+			for _, group := range groups {
+				seriesID := reportGroupID(group)
+				if figures[seriesID] == nil {
+					figures[seriesID] = map[int64]*salesBucket{}
+					splits[seriesID] = group
+				}
+				bucket := figures[seriesID][bucketStart]
+				if bucket == nil {
+					bucket = &salesBucket{orders: map[int]bool{}}
+					figures[seriesID][bucketStart] = bucket
+				}
+				bucket.revenue += item.UnitPrice * float64(item.Quantity)
+				bucket.units += item.Quantity
+				bucket.orders[order.ID] = true
+			}
+		}
+	}
+
+	analytics := StructureData.RevenueAnalytics{
+		Interval:            query.interval,
+		From:                firstBucket,
+		To:                  query.to,
+		TimeZone:            query.location.String(),
+		SplitBy:             query.splitBy,
+		MovingAverageWindow: query.window,
+		Series:              []StructureData.RevenueSeries{},
+	}
+	if query.splitBy == "" {
+		analytics.Series = append(analytics.Series, revenueSeries(query, firstBucket, figures[""], nil))
+		return analytics, nil
+	}
+
+	// One series per genre or author sold in the range
+	for seriesID, buckets := range figures {
+		series := revenueSeries(query, firstBucket, buckets, splits[seriesID])
+		if series.Totals.Units == 0 {
+			continue
+		}
+		analytics.Series = append(analytics.Series, series)
+	}
+	sort.Slice(analytics.Series, func(i, j int) bool {
+		a, b := analytics.Series[i], analytics.Series[j]
+		if a.Totals.Revenue != b.Totals.Revenue {
+			return a.Totals.Revenue > b.Totals.Revenue
+		}
+		return lessReportGroup([]StructureData.ReportGroupKey{*a.Split}, []StructureData.ReportGroupKey{*b.Split})
+	})
+	if query.limit > 0 && len(analytics.Series) > query.limit {
+		analytics.Series = analytics.Series[:query.limit]
+	}
+	return analytics, nil
+}
+
+// revenueSeries builds the points of the buckets of the range, zero-filling those without sales
+func revenueSeries(query revenueAnalyticsQuery, firstBucket time.Time, buckets map[int64]*salesBucket, split []StructureData.ReportGroupKey) StructureData.RevenueSeries {
+	series := StructureData.RevenueSeries{Points: []StructureData.RevenuePoint{}}
+	if len(split) > 0 {
+		key := split[0]
+		series.Split = &key
+	}
+
+	for start := firstBucket; start.Before(query.to); start = nextIntervalStart(query.interval, start) {
+		current := bucketFigures(buckets, start)
+		point := StructureData.RevenuePoint{
+			Period:         intervalPeriod(query.interval, start),
+			Start:          start,
+			RevenueFigures: current,
+		}
+
+		// Moving average over the bucket and the ones before it
+		var sum StructureData.RevenueFigures
+		windowStart := start
+		for i := 0; i < query.window; i++ {
+			figures := bucketFigures(buckets, windowStart)
+			sum.Revenue += figures.Revenue
+			sum.Units += figures.Units
+			sum.Orders += figures.Orders
+			windowStart = previousIntervalStart(query.interval, windowStart)
+		}
+		window := float64(query.window)
+		point.MovingAverage = StructureData.RevenueAverages{
+			Revenue: roundCents(sum.Revenue / window),
+			Units:   roundCents(float64(sum.Units) / window),
+			Orders:  roundCents(float64(sum.Orders) / window),
+		}
+
+		point.PreviousPeriod = compareRevenue(query.interval, current, buckets, previousIntervalStart(query.interval, start))
+		point.YearOverYear = compareRevenue(query.interval, current, buckets, yearEarlierStart(query.interval, start))
+
+		series.Totals.Revenue += current.Revenue
+		series.Totals.Units += current.Units
+		series.Totals.Orders += current.Orders
+		series.Points = append(series.Points, point)
+	}
+	series.Totals.Revenue = roundCents(series.Totals.Revenue)
+	return series
+}
+
+// bucketFigures returns the figures of the bucket starting at the given time, zero if nothing was sold
+func bucketFigures(buckets map[int64]*salesBucket, start time.Time) StructureData.RevenueFigures {
+	bucket, exists := buckets[start.Unix()]
+	if !exists {
+		return StructureData.RevenueFigures{}
+	}
+	return StructureData.RevenueFigures{
+		Revenue: roundCents(bucket.revenue),
+		Units:   bucket.units,
+		Orders:  len(bucket.orders),
+	}
+}
+
+// compareRevenue compares the figures of a bucket with those of the earlier bucket starting at the given time
+func compareRevenue(interval StructureData.AnalyticsInterval, current StructureData.RevenueFigures, buckets map[int64]*salesBucket, earlierStart time.Time) StructureData.RevenueComparison {
+	earlier := bucketFigures(buckets, earlierStart)
+	return StructureData.RevenueComparison{
+		Period:  intervalPeriod(interval, earlierStart),
+		Revenue: metricDelta(current.Revenue, earlier.Revenue),
+		Units:   metricDelta(float64(current.Units), float64(earlier.Units)),
+		Orders:  metricDelta(float64(current.Orders), float64(earlier.Orders)),
+	}
+}
+
+// metricDelta computes the change of a figure and its percentage, which is undefined from zero
+func metricDelta(current, previous float64) StructureData.MetricDelta {
+	delta := StructureData.MetricDelta{Previous: previous, Change: roundCents(current - previous)}
+	if previous != 0 {
+		percent := roundCents((current - previous) / previous * 100)
+		delta.Percent = &percent
+	}
+	return delta
+}
+
+// intervalStart returns the start of the day, ISO week or month of a time, in its location
+func intervalStart(interval StructureData.AnalyticsInterval, t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch interval {
+	case StructureData.IntervalWeek:
+		midnight := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+		return midnight.AddDate(0, 0, -(int(midnight.Weekday())+6)%7) // Back to Monday
+	case StructureData.IntervalMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// nextIntervalStart returns the start of the bucket after the one starting at the given time
+func nextIntervalStart(interval StructureData.AnalyticsInterval, start time.Time) time.Time {
+	switch interval {
+	case StructureData.IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case StructureData.IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// previousIntervalStart returns the start of the bucket before the one starting at the given time
+func previousIntervalStart(interval StructureData.AnalyticsInterval, start time.Time) time.Time {
+	switch interval {
+	case StructureData.IntervalWeek:
+		return start.AddDate(0, 0, -7)
+	case StructureData.IntervalMonth:
+		return start.AddDate(0, -1, 0)
+	default:
+		return start.AddDate(0, 0, -1)
+	}
+}
+
+// yearEarlierStart returns the start of the bucket a year before: the same date or month, or for weeks the
+// week 52 weeks earlier, which keeps weeks aligned on Mondays
+func yearEarlierStart(interval StructureData.AnalyticsInterval, start time.Time) time.Time {
+	if interval == StructureData.IntervalWeek {
+		return start.AddDate(0, 0, -52*7)
+	}
+	return intervalStart(interval, start.AddDate(-1, 0, 0))
+}
+
+// intervalPeriod labels a bucket like the day, week and month dimensions of the reporting engine
+func intervalPeriod(interval StructureData.AnalyticsInterval, start time.Time) string {
+	return reportPeriod(StructureData.ReportDimension(interval), start)
+}
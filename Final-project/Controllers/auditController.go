@@ -0,0 +1,198 @@
+package Controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file path for audit log persistence
+var auditFile = "audit.json"
+
+var auditMu sync.Mutex // Serializes writes of the audit file from concurrent requests and background jobs
+
+// Actors recorded when a change is not made by a named caller
+const (
+	anonymousActor = "anonymous"
+	systemActor    = "system"
+)
+
+// requestIDKey is the context key holding the ID of the current request
+type requestIDKey struct{}
+
+// InitializeAuditFile loads the audit log from the JSON file into the in-memory store, and saves the
+// file again whenever a change is recorded
+func InitializeAuditFile() {
+	store := inmemoryStores.GetAuditStoreInstance()
+	store.SetOnRecord(func() {
+		if err := persistAuditToFile(); err != nil {
+			log.Printf("Failed to save audit log: %v", err)
+		}
+	})
+
+	if _, err := os.Stat(auditFile); os.IsNotExist(err) {
+		// If the file doesn't exist, create an empty one
+		file, _ := os.Create(auditFile)
+		file.Write([]byte("[]"))
+		file.Close()
+		return
+	}
+
+	file, err := os.Open(auditFile)
+	if err != nil {
+		panic("Failed to open audit file")
+	}
+	defer file.Close()
+
+	var events []StructureData.AuditEvent
+	if err := json.NewDecoder(file).Decode(&events); err != nil {
+		panic("Failed to decode audit file")
+	}
+
+	for _, event := range events {
+		store.AddAuditEventDirectly(event)
+	}
+	log.Printf("%d audit events loaded into store", len(events))
+}
+
+// WithRequestID tags every request with an ID, taken from the X-Request-ID header or generated,
+// and echoes it back so callers can find the audit events of their request
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := strings.TrimSpace(r.Header.Get("X-Request-ID"))
+		if requestID == "" {
+			buf := make([]byte, 8)
+			rand.Read(buf)
+			requestID = hex.EncodeToString(buf)
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+	})
+}
+
+// auditContext returns who is making the request, taken from the X-Actor header, and the request ID
+func auditContext(r *http.Request) StructureData.AuditContext {
+	actor := strings.TrimSpace(r.Header.Get("X-Actor"))
+	if actor == "" {
+		actor = anonymousActor
+	}
+	requestID, _ := r.Context().Value(requestIDKey{}).(string)
+	return StructureData.AuditContext{Actor: actor, RequestID: requestID}
+}
+
+// recordAudit records a change made by a request and publishes the matching domain event
+func recordAudit(r *http.Request, resource string, id int, action StructureData.AuditAction, before, after interface{}) {
+	store := inmemoryStores.GetAuditStoreInstance()
+	change := store.Record(resource, id, action, auditContext(r), before, after)
+	inmemoryStores.GetEventBusInstance().PublishChange(change, after)
+}
+
+// GetAuditEvents handles the GET /audit request. Every filter is optional and may be repeated:
+// resource, resource_id, action, actor, request_id and field, plus from and to as RFC 3339 timestamps.
+func GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetAuditStoreInstance()
+	query := r.URL.Query()
+
+	// Build the search criteria from the query parameters
+	criteria := StructureData.AuditSearchCriteria{
+		Resources:  query["resource"],
+		Actors:     query["actor"],
+		RequestIDs: query["request_id"],
+		Fields:     query["field"],
+	}
+	for _, action := range query["action"] {
+		criteria.Actions = append(criteria.Actions, StructureData.AuditAction(action))
+	}
+	for _, idStr := range query["resource_id"] {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			writeError(w, r, StructureData.NewValidationError("Invalid resource_id"))
+			return
+		}
+		criteria.ResourceIDs = append(criteria.ResourceIDs, id)
+	}
+	for param, bound := range map[string]*time.Time{"from": &criteria.MinTimestamp, "to": &criteria.MaxTimestamp} {
+		if value := query.Get(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeError(w, r, StructureData.NewValidationError("Invalid "+param+" format. Use RFC 3339."))
+				return
+			}
+			*bound = parsed
+		}
+	}
+
+	// Perform the search
+	events, errResp := store.SearchAuditEvents(criteria)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return the search results as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetHistory handles the GET /{resource}/{id}/history request, listing the changes made to a record
+func GetHistory(w http.ResponseWriter, r *http.Request, resource string) {
+	store := inmemoryStores.GetAuditStoreInstance()
+
+	// Extract ID from the URL
+	idStr := r.URL.Path[len("/"+resource+"/"):]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid ID"))
+		return
+	}
+
+	// Retrieve the history, which outlives purged records
+	history := store.GetHistory(resource, id)
+	if len(history) == 0 {
+		writeError(w, r, StructureData.NewNotFoundError("No history for "+resource+" "+idStr))
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// persistAuditToFile saves the audit log to a temporary file in a pretty JSON format and renames it over
+// the JSON file, so an interrupted write never loses the log
+func persistAuditToFile() error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	tempPath := auditFile + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	if err := encoder.Encode(inmemoryStores.GetAuditStoreInstance().GetAllAuditEvents()); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return os.Rename(tempPath, auditFile)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 
 	inmemoryStores "finalProject/InmemoryStores"
@@ -33,10 +34,10 @@ func InitializeAuthorFile() {
 			panic("Failed to decode author file")
 		}
 
-		// Populate the in-memory store
+		// Populate the in-memory store, keeping IDs so books keep pointing at the right author
 		store := inmemoryStores.GetAuthorStoreInstance()
 		for _, author := range authors {
-			store.CreateAuthor(author)
+			store.AddAuthorDirectly(author)
 		}
 	}
 }
@@ -61,16 +62,14 @@ func GetAuthorByID(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Path[len("/authors/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid author ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid author ID"))
 		return
 	}
 
 	// Retrieve the author by ID
 	author, errResponse := store.GetAuthor(id)
 	if errResponse != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResponse)
+		writeError(w, r, errResponse)
 		return
 	}
 
@@ -86,23 +85,20 @@ func CreateAuthor(w http.ResponseWriter, r *http.Request) {
 	// Decode the request body
 	var author StructureData.Author
 	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
 		return
 	}
 
 	// Create the author in the store
-	createdAuthor, errResponse := store.CreateAuthor(author)
+	createdAuthor, errResponse := store.CreateAuthor(author, auditContext(r))
 	if errResponse != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResponse)
+		writeError(w, r, errResponse)
 		return
 	}
 
 	// Persist to JSON file
 	if err := persistAuthorsToFile(store); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving data"})
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
 		return
 	}
 
@@ -119,31 +115,27 @@ func UpdateAuthor(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Path[len("/authors/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid author ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid author ID"))
 		return
 	}
 
 	// Decode the request body
 	var author StructureData.Author
 	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
 		return
 	}
 
 	// Update the author in the store
-	updatedAuthor, errResponse := store.UpdateAuthor(id, author)
+	updatedAuthor, errResponse := store.UpdateAuthor(id, author, auditContext(r))
 	if errResponse != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResponse)
+		writeError(w, r, errResponse)
 		return
 	}
 
 	// Persist to JSON file
 	if err := persistAuthorsToFile(store); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving data"})
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
 		return
 	}
 
@@ -154,69 +146,26 @@ func UpdateAuthor(w http.ResponseWriter, r *http.Request) {
 
 // DeleteAuthor handles the DELETE /authors/{id} request
 func DeleteAuthor(w http.ResponseWriter, r *http.Request) {
-	authorStore := inmemoryStores.GetAuthorStoreInstance()
-	bookStore := inmemoryStores.GetBookStoreInstance()
-	orderStore := inmemoryStores.GetOrderStoreInstance()
+	enforcer := inmemoryStores.GetIntegrityEnforcerInstance()
 
 	// Extract ID from the URL
 	idStr := r.URL.Path[len("/authors/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid author ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid author ID"))
 		return
 	}
 
-	// Delete the author from the store
-	errResponse := authorStore.DeleteAuthor(id)
-	if errResponse != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResponse)
+	// Delete the author and apply the relation policies to the records referencing it
+	affected, errResp := enforcer.Delete(StructureData.ResourceAuthors, id, auditContext(r))
+	if errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
-	// Delete all books associated with the author if not in an order
-	books := bookStore.GetAllBooks()
-	for _, book := range books {
-		if book.Author.ID == id {
-			// Check if the book exists in any order
-			orders := orderStore.GetAllOrders()
-			bookInOrder := false
-			for _, order := range orders {
-				for _, item := range order.Items {
-					if item.Book.ID == book.ID {
-						bookInOrder = true
-						break
-					}
-				}
-				if bookInOrder {
-					break
-				}
-			}
-
-			// If the book is not in any order, delete it
-			if !bookInOrder {
-				errResp := bookStore.DeleteBook(book.ID)
-				if errResp != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					json.NewEncoder(w).Encode(errResp)
-					return
-				}
-			}
-		}
-	}
-
-	// Persist updated books to file
-	if err := persistBooksToFile(bookStore.GetAllBooks()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving books data"})
-		return
-	}
-
-	// Persist updated authors to file
-	if err := persistAuthorsToFile(authorStore); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(err)
+	// Persist every resource touched by the delete
+	if errResp := persistResources(affected); errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
@@ -231,16 +180,14 @@ func SearchAuthors(w http.ResponseWriter, r *http.Request) {
 	// Decode the search criteria
 	var criteria StructureData.AuthorSearchCriteria
 	if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid search criteria"})
+		writeError(w, r, StructureData.NewValidationError("Invalid search criteria"))
 		return
 	}
 
 	// Search authors
 	authors, errResponse := store.SearchAuthors(criteria)
 	if errResponse != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResponse)
+		writeError(w, r, errResponse)
 		return
 	}
 
@@ -249,16 +196,14 @@ func SearchAuthors(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(authors)
 }
 
-// persistAuthorsToFile saves all authors to the JSON file in a pretty JSON format
+// persistAuthorsToFile saves all authors, including the trashed ones, to the JSON file in a pretty JSON format
 func persistAuthorsToFile(store interfaces.AuthorStore) *StructureData.ErrorResponse {
-	authors, errResponse := store.SearchAuthors(StructureData.AuthorSearchCriteria{})
-	if errResponse != nil {
-		return &StructureData.ErrorResponse{Message: errResponse.Error()}
-	}
+	authors := append(store.GetAllAuthors(), store.GetDeletedAuthors()...)
+	sort.Slice(authors, func(i, j int) bool { return authors[i].ID < authors[j].ID })
 
 	file, err := os.Create(authorFile)
 	if err != nil {
-		return &StructureData.ErrorResponse{Message: "Failed to create author file: " + err.Error()}
+		return StructureData.NewInternalError("Failed to create author file", err)
 	}
 	defer file.Close()
 
@@ -267,7 +212,7 @@ func persistAuthorsToFile(store interfaces.AuthorStore) *StructureData.ErrorResp
 	encoder.SetIndent("", "  ") // Add indentation for better readability
 
 	if err := encoder.Encode(authors); err != nil {
-		return &StructureData.ErrorResponse{Message: "Failed to encode authors to file: " + err.Error()}
+		return StructureData.NewInternalError("Failed to encode authors to file", err)
 	}
 
 	return nil
@@ -5,9 +5,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 
 	inmemoryStores "finalProject/InmemoryStores"
+	interfaces "finalProject/Interfaces"
 	"finalProject/StructureData"
 )
 
@@ -46,12 +48,19 @@ func InitializeBookFile() {
 func GetAllBooks(w http.ResponseWriter, r *http.Request) {
 	store := inmemoryStores.GetBookStoreInstance()
 
+	// Parse the relations to inline
+	expand, errResp := parseExpand(r, bookExpansions)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
 	// Retrieve all books
 	books := store.GetAllBooks()
 
 	// Return JSON response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(books)
+	json.NewEncoder(w).Encode(expandBooks(books, expand))
 }
 
 // GetBookByID handles the GET /books/{id} request
@@ -62,87 +71,119 @@ func GetBookByID(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Path[len("/books/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid book ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
+		return
+	}
+
+	// Parse the relations to inline
+	expand, errResp := parseExpand(r, bookExpansions)
+	if errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Retrieve the book by ID
 	book, errResp := store.GetBook(id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Return JSON response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(book)
+	json.NewEncoder(w).Encode(expandBook(book, expand))
 }
 
 // CreateBook handles the POST /books request
 func CreateBook(w http.ResponseWriter, r *http.Request) {
 	bookStore := inmemoryStores.GetBookStoreInstance()
 	authorStore := inmemoryStores.GetAuthorStoreInstance()
+	warehouseStore := inmemoryStores.GetWarehouseStoreInstance()
 
 	// Decode the request body
 	var book StructureData.Book
 	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
 		return
 	}
 
 	// Validate stock
 	if book.Stock < 1 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Stock must be at least 1"})
+		writeError(w, r, StructureData.NewValidationError("Stock must be at least 1"))
+		return
+	}
+	if errResp := validateStockLevels(book); errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
-	// Check if the author exists
-	authors := authorStore.GetAllAuthors()
-	authorExists := false
-	for _, existingAuthor := range authors {
-		if existingAuthor.FirstName == book.Author.FirstName &&
-			existingAuthor.LastName == book.Author.LastName &&
-			existingAuthor.Bio == book.Author.Bio {
-			book.Author = existingAuthor // Link existing author
-			authorExists = true
-			break
-		}
+	// The opening stock goes to the default warehouse, resolved before anything is created
+	defaultWarehouse, errResp := warehouseStore.DefaultWarehouse()
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
 	}
 
-	// If author doesn't exist, create the author
-	if !authorExists {
-		createdAuthor, errResp := authorStore.CreateAuthor(book.Author)
-		if errResp != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(errResp)
+	if book.AuthorID != 0 {
+		// Link the author by ID
+		if errResp := inmemoryStores.GetIntegrityEnforcerInstance().ValidateBookReferences(book); errResp != nil {
+			writeError(w, r, errResp)
 			return
 		}
-		book.Author = createdAuthor
+	} else if book.Author != nil {
+		// Check if the author exists
+		authors := authorStore.GetAllAuthors()
+		authorExists := false
+		for _, existingAuthor := range authors {
+			if existingAuthor.FirstName == book.Author.FirstName &&
+				existingAuthor.LastName == book.Author.LastName &&
+				existingAuthor.Bio == book.Author.Bio {
+				book.AuthorID = existingAuthor.ID // Link existing author
+				authorExists = true
+				break
+			}
+		}
 
-		// Persist the new author to the JSON file
-		if err := persistAuthorsToFile(authorStore); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving author data"})
-			return
+		// If author doesn't exist, create the author
+		if !authorExists {
+			createdAuthor, errResp := authorStore.CreateAuthor(*book.Author, auditContext(r))
+			if errResp != nil {
+				writeError(w, r, errResp)
+				return
+			}
+			book.AuthorID = createdAuthor.ID
+
+			// Persist the new author to the JSON file
+			if err := persistAuthorsToFile(authorStore); err != nil {
+				writeError(w, r, StructureData.NewInternalError("Error saving author data", err))
+				return
+			}
 		}
 	}
 
 	// Create the book in the store
-	createdBook, errResp := bookStore.CreateBook(book)
+	createdBook, errResp := bookStore.CreateBook(book, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
+	// Place the opening stock at the default warehouse
+	if _, errResp := warehouseStore.AdjustStock(createdBook.ID, defaultWarehouse.ID, createdBook.Stock); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordStockMovements(r, StructureData.StockMovement{
+		Type:       StructureData.StockMovementAdjustment,
+		SourceType: StructureData.ResourceBooks,
+		SourceID:   createdBook.ID,
+		Note:       "Opening balance",
+	}, StructureData.Book{ID: createdBook.ID}, []StructureData.StockAllocation{{WarehouseID: defaultWarehouse.ID, Quantity: createdBook.Stock}})
+	persistStockLedger()
+
 	// Persist to JSON file
-	if err := persistBooksToFile(bookStore.GetAllBooks()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving book data"})
+	if err := persistBooksToFile(bookStore); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving book data", err))
 		return
 	}
 
@@ -151,46 +192,98 @@ func CreateBook(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(createdBook)
 }
 
+// bookUpdate is the body of a PUT /books/{id} request. The reorder threshold and target stock are
+// pointers so that leaving them out keeps the book's current values.
+type bookUpdate struct {
+	StructureData.Book
+	ReorderThreshold *int `json:"reorder_threshold"`
+	TargetStock      *int `json:"target_stock"`
+}
+
 // UpdateBook handles the PUT /books/{id} request
 func UpdateBook(w http.ResponseWriter, r *http.Request) {
 	store := inmemoryStores.GetBookStoreInstance()
+	warehouseStore := inmemoryStores.GetWarehouseStoreInstance()
 
 	// Extract ID from the URL
 	idStr := r.URL.Path[len("/books/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid book ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
 		return
 	}
 
 	// Decode the request body
-	var book StructureData.Book
-	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+	var update bookUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	book := update.Book
+
+	// Retrieve the existing book, kept for the audit log
+	previous, errResp := store.GetBook(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
+	// Keep the stock levels the request leaves out
+	book.ReorderThreshold, book.TargetStock = previous.ReorderThreshold, previous.TargetStock
+	if update.ReorderThreshold != nil {
+		book.ReorderThreshold = *update.ReorderThreshold
+	}
+	if update.TargetStock != nil {
+		book.TargetStock = *update.TargetStock
+	}
+
 	// Validate stock
 	if book.Stock < 1 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Stock must be at least 1"})
+		writeError(w, r, StructureData.NewValidationError("Stock must be at least 1"))
+		return
+	}
+	if errResp := validateStockLevels(book); errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
-	// Update the book in the store
-	updatedBook, errResp := store.UpdateBook(id, book)
+	// Ensure the referenced author exists
+	if errResp := inmemoryStores.GetIntegrityEnforcerInstance().ValidateBookReferences(book); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// A manual stock change is made at the default warehouse and recorded in the stock ledger
+	change := newStockChange(StructureData.StockMovement{
+		Type:       StructureData.StockMovementAdjustment,
+		SourceType: StructureData.ResourceBooks,
+		SourceID:   id,
+		Note:       "Stock edited",
+	})
+	if book.Stock != previous.Stock {
+		defaultWarehouse, errResp := warehouseStore.DefaultWarehouse()
+		if errResp != nil {
+			writeError(w, r, errResp)
+			return
+		}
+		if errResp := change.adjust(id, defaultWarehouse.ID, book.Stock-previous.Stock); errResp != nil {
+			writeError(w, r, StructureData.NewValidationError(errResp.Message+"; transfer stock to it first"))
+			return
+		}
+	}
+
+	// Update the book in the store, which keeps the stock just set
+	updatedBook, errResp := store.UpdateBook(id, book, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		change.undo()
+		writeError(w, r, errResp)
 		return
 	}
+	change.commit(r)
 
 	// Persist to JSON file
-	if err := persistBooksToFile(store.GetAllBooks()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving data"})
+	if err := persistBooksToFile(store); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
 		return
 	}
 
@@ -201,42 +294,26 @@ func UpdateBook(w http.ResponseWriter, r *http.Request) {
 
 // DeleteBook handles the DELETE /books/{id} request
 func DeleteBook(w http.ResponseWriter, r *http.Request) {
-	store := inmemoryStores.GetBookStoreInstance()
-	orderStore := inmemoryStores.GetOrderStoreInstance()
+	enforcer := inmemoryStores.GetIntegrityEnforcerInstance()
 
 	// Extract ID from the URL
 	idStr := r.URL.Path[len("/books/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid book ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
 		return
 	}
 
-	// Check if the book is linked to any orders
-	orders := orderStore.GetAllOrders()
-	for _, order := range orders {
-		for _, item := range order.Items {
-			if item.Book.ID == id {
-				w.WriteHeader(http.StatusConflict)
-				json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Book cannot be deleted as it is linked to existing orders"})
-				return
-			}
-		}
-	}
-
-	// Delete the book from the store
-	errResp := store.DeleteBook(id)
+	// Delete the book and apply the relation policies to the records referencing it
+	affected, errResp := enforcer.Delete(StructureData.ResourceBooks, id, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
-	// Persist to JSON file
-	if err := persistBooksToFile(store.GetAllBooks()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving data"})
+	// Persist every resource touched by the delete
+	if errResp := persistResources(affected); errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
@@ -248,29 +325,37 @@ func DeleteBook(w http.ResponseWriter, r *http.Request) {
 func SearchBooks(w http.ResponseWriter, r *http.Request) {
 	store := inmemoryStores.GetBookStoreInstance()
 
+	// Parse the relations to inline
+	expand, errResp := parseExpand(r, bookExpansions)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
 	// Decode the search criteria from the request body
 	var criteria StructureData.BookSearchCriteria
 	if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid search criteria"})
+		writeError(w, r, StructureData.NewValidationError("Invalid search criteria"))
 		return
 	}
 
 	// Perform the search
 	searchResults, errResp := store.SearchBooks(criteria)
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Return the search results as JSON
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(searchResults)
+	json.NewEncoder(w).Encode(expandBooks(searchResults, expand))
 }
 
-// persistBooksToFile saves all books to the JSON file in a pretty JSON format
-func persistBooksToFile(books []StructureData.Book) error {
+// persistBooksToFile saves all books, including the trashed ones, to the JSON file in a pretty JSON format
+func persistBooksToFile(store interfaces.BookStore) error {
+	books := append(store.GetAllBooks(), store.GetDeletedBooks()...)
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+
 	file, err := os.Create(bookFile)
 	if err != nil {
 		return err
@@ -0,0 +1,376 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// Bounds of the customer analytics
+const (
+	favouriteGenres    = 3
+	maxCohorts         = 120
+	defaultCohortCount = 12
+)
+
+// GetCustomerStats handles the GET /customers/{id}/stats request
+func GetCustomerStats(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	idStr := r.URL.Path[len("/customers/"):]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+
+	customer, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	orders, errResp := inmemoryStores.GetOrderStoreInstance().SearchOrders(StructureData.OrderSearchCriteria{CustomerIDs: []int{id}})
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customerStats(customer, orders, time.Now()))
+}
+
+// customerStats sums up the orders of a customer as of now
+func customerStats(customer StructureData.Customer, orders []StructureData.Order, now time.Time) StructureData.CustomerStats {
+	stats := StructureData.CustomerStats{
+		CustomerID:      customer.ID,
+		Name:            customer.Name,
+		CustomerSince:   customer.CreatedAt,
+		OrderCount:      len(orders),
+		FavouriteGenres: []StructureData.GenreSpend{},
+	}
+
+	genres := map[string]*StructureData.GenreSpend{}
+	for _, order := range orders {
+		stats.LifetimeSpend += order.TotalPrice
+		if stats.FirstOrderAt == nil || order.CreatedAt.Before(*stats.FirstOrderAt) {
+			first := order.CreatedAt
+			stats.FirstOrderAt = &first
+		}
+		if stats.LastOrderAt == nil || order.CreatedAt.After(*stats.LastOrderAt) {
+			last := order.CreatedAt
+			stats.LastOrderAt = &last
+		}
+
+		for _, item := range order.Items {
+			stats.UnitsBought += item.Quantity
+			for _, genre := range item.Snapshot.Genres {
+				if genres[genre] == nil {
+					genres[genre] = &StructureData.GenreSpend{Genre: genre}
+				}
+				genres[genre].Units += item.Quantity
+				genres[genre].Spend += item.UnitPrice * float64(item.Quantity)
+			}
+		}
+	}
+
+	stats.LifetimeSpend = roundCents(stats.LifetimeSpend)
+	if len(orders) > 0 {
+		stats.AverageBasket = roundCents(stats.LifetimeSpend / float64(len(orders)))
+		days := int(now.Sub(*stats.LastOrderAt).Hours() / 24)
+		stats.DaysSinceLastOrder = &days
+	}
+
+	for _, genre := range genres {
+		genre.Spend = roundCents(genre.Spend)
+		stats.FavouriteGenres = append(stats.FavouriteGenres, *genre)
+	}
+	sort.Slice(stats.FavouriteGenres, func(i, j int) bool {
+		a, b := stats.FavouriteGenres[i], stats.FavouriteGenres[j]
+		if a.Spend != b.Spend {
+			return a.Spend > b.Spend
+		}
+		return a.Genre < b.Genre
+	})
+	if len(stats.FavouriteGenres) > favouriteGenres {
+		stats.FavouriteGenres = stats.FavouriteGenres[:favouriteGenres]
+	}
+	return stats
+}
+
+// GetCustomerSegments handles the GET /analytics/customers/segments request. It scores every customer with
+// orders on recency, frequency and monetary value and puts them in a segment; ?segment= keeps the
+// customers of one segment.
+func GetCustomerSegments(w http.ResponseWriter, r *http.Request) {
+	segment := StructureData.CustomerSegment(r.URL.Query().Get("segment"))
+	if segment != "" && !isCustomerSegment(segment) {
+		writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Unknown segment %q", segment)))
+		return
+	}
+
+	// Score the customers
+	segmentation := segmentCustomers(inmemoryStores.GetCustomerStoreInstance().GetAllCustomers(), inmemoryStores.GetOrderStoreInstance().GetAllOrders(), time.Now())
+	if segment != "" {
+		customers := []StructureData.CustomerRFM{}
+		for _, customer := range segmentation.Customers {
+			if customer.Segment == segment {
+				customers = append(customers, customer)
+			}
+		}
+		segmentation.Customers = customers
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(segmentation)
+}
+
+// segmentCustomers scores the customers with orders as of now. Each score is the quintile of the customer's
+// rank, ties sharing the rank in the middle of theirs, so a lone customer scores 3.
+func segmentCustomers(customers []StructureData.Customer, orders []StructureData.Order, now time.Time) StructureData.CustomerSegmentation {
+	names := map[int]string{}
+	for _, customer := range customers {
+		names[customer.ID] = customer.Name
+	}
+
+	byCustomer := map[int]*StructureData.CustomerRFM{}
+	lastOrders := map[int]time.Time{}
+	for _, order := range orders {
+		if _, known := names[order.CustomerID]; !known {
+			continue // Deleted customers are not segmented
+		}
+		rfm := byCustomer[order.CustomerID]
+		if rfm == nil {
+			rfm = &StructureData.CustomerRFM{CustomerID: order.CustomerID, Name: names[order.CustomerID]}
+			byCustomer[order.CustomerID] = rfm
+		}
+		rfm.Frequency++
+		rfm.Monetary += order.TotalPrice
+		if order.CreatedAt.After(lastOrders[order.CustomerID]) {
+			lastOrders[order.CustomerID] = order.CreatedAt
+		}
+	}
+
+	var recencies, frequencies, monetaries []float64
+	for id, rfm := range byCustomer {
+		rfm.Monetary = roundCents(rfm.Monetary)
+		rfm.RecencyDays = int(now.Sub(lastOrders[id]).Hours() / 24)
+		recencies = append(recencies, -float64(rfm.RecencyDays)) // Fewer days score higher
+		frequencies = append(frequencies, float64(rfm.Frequency))
+		monetaries = append(monetaries, rfm.Monetary)
+	}
+
+	segmentation := StructureData.CustomerSegmentation{GeneratedAt: now, Segments: []StructureData.SegmentSummary{}, Customers: []StructureData.CustomerRFM{}}
+	summaries := map[StructureData.CustomerSegment]*StructureData.SegmentSummary{}
+	for _, segment := range StructureData.CustomerSegments {
+		summaries[segment] = &StructureData.SegmentSummary{Segment: segment}
+	}
+	for _, rfm := range byCustomer {
+		rfm.RecencyScore = quintileScore(-float64(rfm.RecencyDays), recencies)
+		rfm.FrequencyScore = quintileScore(float64(rfm.Frequency), frequencies)
+		rfm.MonetaryScore = quintileScore(rfm.Monetary, monetaries)
+		rfm.RFMScore = fmt.Sprintf("%d%d%d", rfm.RecencyScore, rfm.FrequencyScore, rfm.MonetaryScore)
+		rfm.Segment = customerSegment(rfm.RecencyScore, rfm.FrequencyScore)
+		segmentation.Customers = append(segmentation.Customers, *rfm)
+
+		summary := summaries[rfm.Segment]
+		summary.Customers++
+		summary.Revenue += rfm.Monetary
+	}
+
+	for _, segment := range StructureData.CustomerSegments {
+		summary := summaries[segment]
+		summary.Revenue = roundCents(summary.Revenue)
+		if len(byCustomer) > 0 {
+			summary.Share = roundCents(100 * float64(summary.Customers) / float64(len(byCustomer)))
+		}
+		segmentation.Segments = append(segmentation.Segments, *summary)
+	}
+
+	sort.Slice(segmentation.Customers, func(i, j int) bool {
+		a, b := segmentation.Customers[i], segmentation.Customers[j]
+		scoreA := a.RecencyScore + a.FrequencyScore + a.MonetaryScore
+		scoreB := b.RecencyScore + b.FrequencyScore + b.MonetaryScore
+		if scoreA != scoreB {
+			return scoreA > scoreB
+		}
+		if a.Monetary != b.Monetary {
+			return a.Monetary > b.Monetary
+		}
+		return a.CustomerID < b.CustomerID
+	})
+	return segmentation
+}
+
+// quintileScore scores a value from 1 to 5 by its percentile rank among all values
+func quintileScore(value float64, values []float64) int {
+	var below, equal int
+	for _, other := range values {
+		if other < value {
+			below++
+		} else if other == value {
+			equal++
+		}
+	}
+	percentile := (float64(below) + float64(equal)/2) / float64(len(values))
+	return min(1+int(math.Floor(5*percentile)), 5)
+}
+
+// customerSegment maps the recency and frequency scores to a segment
+func customerSegment(recency, frequency int) StructureData.CustomerSegment {
+	switch {
+	case recency >= 4 && frequency >= 4:
+		return StructureData.SegmentChampions
+	case recency >= 3 && frequency >= 3:
+		return StructureData.SegmentLoyal
+	case recency >= 4 && frequency == 1:
+		return StructureData.SegmentNew
+	case recency >= 3:
+		return StructureData.SegmentPotentialLoyalist
+	case recency == 1 && frequency >= 4:
+		return StructureData.SegmentCantLose
+	case frequency >= 3:
+		return StructureData.SegmentAtRisk
+	default:
+		return StructureData.SegmentHibernating
+	}
+}
+
+// isCustomerSegment reports whether a segment is one of the known segments
+func isCustomerSegment(segment StructureData.CustomerSegment) bool {
+	for _, known := range StructureData.CustomerSegments {
+		if segment == known {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCohortRetention handles the GET /analytics/customers/cohorts request. It groups the customers by the
+// month they signed up in, from to to as YYYY-MM (the last 12 months by default), and counts for every month
+// since how many of them placed an order. Months are taken in ?time_zone=, UTC by default.
+func GetCohortRetention(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	now := time.Now()
+
+	// Parse the query parameters
+	location := time.UTC
+	if value := params.Get("time_zone"); value != "" {
+		loaded, err := time.LoadLocation(value)
+		if err != nil {
+			writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Unknown time zone %q", value)))
+			return
+		}
+		location = loaded
+	}
+	currentMonth := intervalStart(StructureData.IntervalMonth, now.In(location))
+	to := currentMonth
+	if value := params.Get("to"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01", value, location)
+		if err != nil {
+			writeError(w, r, StructureData.NewValidationError("Invalid to, use YYYY-MM"))
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 1-defaultCohortCount, 0)
+	if value := params.Get("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01", value, location)
+		if err != nil {
+			writeError(w, r, StructureData.NewValidationError("Invalid from, use YYYY-MM"))
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		writeError(w, r, StructureData.NewValidationError("from cannot be after to"))
+		return
+	}
+	if from.AddDate(0, maxCohorts, 0).Before(to) {
+		writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("The range cannot have more than %d cohorts", maxCohorts)))
+		return
+	}
+
+	// Build the matrix
+	retention := cohortRetention(inmemoryStores.GetCustomerStoreInstance().GetAllCustomers(), inmemoryStores.GetOrderStoreInstance().GetAllOrders(), from, to, currentMonth)
+	retention.GeneratedAt = now
+	retention.TimeZone = location.String()
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(retention)
+}
+
+// cohortRetention builds the cohorts of the signup months from from to to, each with a period per month from
+// its signup month to the current one. Customers without a signup time are left out.
+func cohortRetention(customers []StructureData.Customer, orders []StructureData.Order, from, to, currentMonth time.Time) StructureData.CohortRetention {
+	location := from.Location()
+	retention := StructureData.CohortRetention{
+		From:    intervalPeriod(StructureData.IntervalMonth, from),
+		To:      intervalPeriod(StructureData.IntervalMonth, to),
+		Cohorts: []StructureData.Cohort{},
+	}
+
+	// Signup month of every customer of the range
+	signups := map[int]time.Time{}
+	cohortSizes := map[int64]int{}
+	for _, customer := range customers {
+		if customer.CreatedAt.IsZero() {
+			continue
+		}
+		month := intervalStart(StructureData.IntervalMonth, customer.CreatedAt.In(location))
+		if month.Before(from) || month.After(to) {
+			continue
+		}
+		signups[customer.ID] = month
+		cohortSizes[month.Unix()]++
+	}
+
+	// Customers of every cohort active in every month
+	active := map[int64]map[int64]map[int]bool{}
+	for _, order := range orders {
+		signup, inRange := signups[order.CustomerID]
+		if !inRange {
+			continue
+		}
+		month := intervalStart(StructureData.IntervalMonth, order.CreatedAt.In(location))
+		if month.Before(signup) {
+			continue // Ordered before signing up, as with imported customers
+		}
+		if active[signup.Unix()] == nil {
+			active[signup.Unix()] = map[int64]map[int]bool{}
+		}
+		if active[signup.Unix()][month.Unix()] == nil {
+			active[signup.Unix()][month.Unix()] = map[int]bool{}
+		}
+		active[signup.Unix()][month.Unix()][order.CustomerID] = true
+	}
+
+	for start := from; !start.After(to); start = start.AddDate(0, 1, 0) {
+		cohort := StructureData.Cohort{
+			Cohort:    intervalPeriod(StructureData.IntervalMonth, start),
+			Start:     start,
+			Customers: cohortSizes[start.Unix()],
+			Periods:   []StructureData.CohortPeriod{},
+		}
+		for offset, month := 0, start; !month.After(currentMonth); offset, month = offset+1, month.AddDate(0, 1, 0) {
+			period := StructureData.CohortPeriod{
+				Offset:    offset,
+				Period:    intervalPeriod(StructureData.IntervalMonth, month),
+				Customers: len(active[start.Unix()][month.Unix()]),
+			}
+			if cohort.Customers > 0 {
+				period.Rate = roundCents(100 * float64(period.Customers) / float64(cohort.Customers))
+			}
+			cohort.Periods = append(cohort.Periods, period)
+		}
+		retention.Cohorts = append(retention.Cohorts, cohort)
+	}
+	return retention
+}
@@ -5,9 +5,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 
 	inmemoryStores "finalProject/InmemoryStores"
+	interfaces "finalProject/Interfaces"
 	"finalProject/StructureData"
 )
 
@@ -35,10 +37,10 @@ func InitializeCustomerFile() {
 			customers = []StructureData.Customer{}
 		}
 
-		// Populate the in-memory store
+		// Populate the in-memory store, keeping IDs so orders keep pointing at the right customer
 		store := inmemoryStores.GetCustomerStoreInstance()
 		for _, customer := range customers {
-			store.CreateCustomer(customer)
+			store.AddCustomerDirectly(customer)
 		}
 	}
 }
@@ -63,16 +65,14 @@ func GetCustomerByID(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Path[len("/customers/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid customer ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
 		return
 	}
 
 	// Retrieve the customer by ID
 	customer, errResp := store.GetCustomer(id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
@@ -83,40 +83,26 @@ func GetCustomerByID(w http.ResponseWriter, r *http.Request) {
 
 // DeleteCustomer handles the DELETE /customers/{id} request
 func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
-	store := inmemoryStores.GetCustomerStoreInstance()
-	orderStore := inmemoryStores.GetOrderStoreInstance()
+	enforcer := inmemoryStores.GetIntegrityEnforcerInstance()
 
 	// Extract ID from the URL
 	idStr := r.URL.Path[len("/customers/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid customer ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
 		return
 	}
 
-	// Check if the customer is linked to any orders
-	orders := orderStore.GetAllOrders()
-	for _, order := range orders {
-		if order.Customer.ID == id {
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer cannot be deleted as it is linked to existing orders"})
-			return
-		}
-	}
-
-	// Delete the customer from the store
-	errResp := store.DeleteCustomer(id)
+	// Delete the customer and apply the relation policies to the records referencing it
+	affected, errResp := enforcer.Delete(StructureData.ResourceCustomers, id, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
-	// Persist to JSON file
-	if err := persistCustomersToFile(store.GetAllCustomers()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving data"})
+	// Persist every resource touched by the delete
+	if errResp := persistResources(affected); errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
@@ -133,39 +119,38 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	// Decode the request body
 	var customer StructureData.Customer
 	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
 		return
 	}
 
 	// Validate input
 	if customer.Name == "" || customer.Email == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Name and Email are required"})
+		writeError(w, r, StructureData.NewValidationError("Name and Email are required"))
+		return
+	}
+	if errResp := normalizeCustomerAddresses(&customer); errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Check for duplicate email
 	for _, existingCustomer := range store.GetAllCustomers() {
 		if existingCustomer.Email == customer.Email {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer with this email already exists"})
+			writeError(w, r, StructureData.NewConflictError("Customer with this email already exists"))
 			return
 		}
 	}
 
 	// Add the customer to the in-memory store
-	createdCustomer, errResp := store.CreateCustomer(customer)
+	createdCustomer, errResp := store.CreateCustomer(customer, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Persist to JSON file
-	if err := persistCustomersToFile(store.GetAllCustomers()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving data"})
+	if err := persistCustomersToFile(store); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
 		return
 	}
 
@@ -182,47 +167,45 @@ func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Path[len("/customers/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid customer ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
 		return
 	}
 
 	// Decode the request body
 	var customer StructureData.Customer
 	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
 		return
 	}
 
 	// Validate input
 	if customer.Name == "" || customer.Email == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Name and Email are required"})
+		writeError(w, r, StructureData.NewValidationError("Name and Email are required"))
+		return
+	}
+	if errResp := normalizeCustomerAddresses(&customer); errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Check for duplicate email (excluding the current customer)
 	for _, existingCustomer := range store.GetAllCustomers() {
 		if existingCustomer.Email == customer.Email && existingCustomer.ID != id {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer with this email already exists"})
+			writeError(w, r, StructureData.NewConflictError("Customer with this email already exists"))
 			return
 		}
 	}
 
 	// Update the customer in the store
-	updatedCustomer, errResp := store.UpdateCustomer(id, customer)
+	updatedCustomer, errResp := store.UpdateCustomer(id, customer, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Persist to JSON file
-	if err := persistCustomersToFile(store.GetAllCustomers()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving data"})
+	if err := persistCustomersToFile(store); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
 		return
 	}
 
@@ -238,16 +221,14 @@ func SearchCustomers(w http.ResponseWriter, r *http.Request) {
 	// Decode the search criteria from the request body
 	var criteria StructureData.CustomerSearchCriteria
 	if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid search criteria"})
+		writeError(w, r, StructureData.NewValidationError("Invalid search criteria"))
 		return
 	}
 
 	// Perform the search
 	searchResults, errResp := store.SearchCustomers(criteria)
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
@@ -256,8 +237,11 @@ func SearchCustomers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(searchResults)
 }
 
-// persistCustomersToFile saves all customers to the JSON file in a pretty JSON format
-func persistCustomersToFile(customers []StructureData.Customer) error {
+// persistCustomersToFile saves all customers, including the trashed ones, to the JSON file in a pretty JSON format
+func persistCustomersToFile(store interfaces.CustomerStore) error {
+	customers := append(store.GetAllCustomers(), store.GetDeletedCustomers()...)
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID < customers[j].ID })
+
 	file, err := os.Create(customerFile)
 	if err != nil {
 		return err
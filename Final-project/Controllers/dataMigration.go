@@ -0,0 +1,177 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// MigrateDataFiles rewrites JSON files written before books referenced authors by ID, orders
+// referenced customers by ID and customers had an address book. Embedded copies are turned into IDs
+// and purchase-time snapshots, authors only known through a book are added back to the author file,
+// and the single address of a customer becomes the first entry of its address book, normalized.
+// Every rewritten file is first copied to a .bak file. Running it on migrated files does nothing.
+func MigrateDataFiles() error {
+	var authors []StructureData.Author
+	if err := readJSONFile(authorFile, &authors); err != nil {
+		return err
+	}
+	knownAuthors := map[int]bool{}
+	for _, author := range authors {
+		knownAuthors[author.ID] = true
+	}
+
+	var books []StructureData.Book
+	if err := readJSONFile(bookFile, &books); err != nil {
+		return err
+	}
+	authorsChanged, booksChanged := false, false
+	for i, book := range books {
+		if book.Author == nil {
+			continue
+		}
+		if book.AuthorID == 0 {
+			books[i].AuthorID = book.Author.ID
+		}
+		// Recreate authors that only survived as an embedded copy
+		if book.Author.ID != 0 && !knownAuthors[book.Author.ID] {
+			authors = append(authors, *book.Author)
+			knownAuthors[book.Author.ID] = true
+			authorsChanged = true
+			log.Printf("Migration: restored author ID %d from book ID %d", book.Author.ID, book.ID)
+		}
+		books[i].Author = nil
+		booksChanged = true
+	}
+
+	var orders []StructureData.Order
+	if err := readJSONFile(orderFile, &orders); err != nil {
+		return err
+	}
+	ordersChanged := false
+	for i, order := range orders {
+		if order.Customer != nil {
+			if order.CustomerID == 0 {
+				orders[i].CustomerID = order.Customer.ID
+			}
+			orders[i].CustomerSnapshot = snapshotCustomer(*order.Customer)
+			orders[i].Customer = nil
+			ordersChanged = true
+		}
+		for j, item := range order.Items {
+			if item.Book == nil {
+				continue
+			}
+			if item.BookID == 0 {
+				orders[i].Items[j].BookID = item.Book.ID
+			}
+			authorID := item.Book.AuthorID
+			if authorID == 0 && item.Book.Author != nil {
+				authorID = item.Book.Author.ID
+			}
+			orders[i].Items[j].UnitPrice = item.Book.Price
+			orders[i].Items[j].Snapshot = StructureData.BookSnapshot{
+				Title:    item.Book.Title,
+				AuthorID: authorID,
+				Genres:   item.Book.Genres,
+			}
+			orders[i].Items[j].Book = nil
+			ordersChanged = true
+		}
+	}
+
+	var customers []StructureData.Customer
+	if err := readJSONFile(customerFile, &customers); err != nil {
+		return err
+	}
+	customersChanged := false
+	for i, customer := range customers {
+		if customer.Addresses != nil {
+			continue
+		}
+		customers[i].Addresses = []StructureData.CustomerAddress{}
+		customersChanged = true
+		if customer.Address.IsEmpty() {
+			continue
+		}
+		address, errResp := utils.NormalizeAddress(customer.Address)
+		if errResp != nil {
+			// Keep the address as it was rather than losing it
+			log.Printf("Migration: cannot normalize the address of customer ID %d: %s", customer.ID, errResp.Message)
+			address = customer.Address
+		}
+		customers[i].Address = address
+		customers[i].Addresses = append(customers[i].Addresses, StructureData.CustomerAddress{
+			ID:              1,
+			Use:             StructureData.AddressUseAny,
+			Address:         address,
+			DefaultShipping: true,
+			DefaultBilling:  true,
+		})
+	}
+
+	if authorsChanged {
+		if err := rewriteJSONFile(authorFile, authors); err != nil {
+			return err
+		}
+	}
+	if booksChanged {
+		if err := rewriteJSONFile(bookFile, books); err != nil {
+			return err
+		}
+	}
+	if ordersChanged {
+		if err := rewriteJSONFile(orderFile, orders); err != nil {
+			return err
+		}
+	}
+	if customersChanged {
+		if err := rewriteJSONFile(customerFile, customers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readJSONFile decodes a JSON file into v, leaving v untouched if the file does not exist
+func readJSONFile(path string, v interface{}) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewDecoder(file).Decode(v)
+}
+
+// rewriteJSONFile backs up a JSON file to path.bak and replaces it with v in a pretty JSON format
+func rewriteJSONFile(path string, v interface{}) error {
+	if original, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", original, 0644); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+
+	log.Printf("Migration: rewrote %s (backup in %s.bak)", path, path)
+	return nil
+}
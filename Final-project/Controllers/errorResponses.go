@@ -0,0 +1,64 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"finalProject/StructureData"
+)
+
+// statusByErrorCode is the single mapping from store error codes to HTTP statuses
+var statusByErrorCode = map[StructureData.ErrorCode]int{
+	StructureData.ErrorCodeNotFound:          http.StatusNotFound,
+	StructureData.ErrorCodeConflict:          http.StatusConflict,
+	StructureData.ErrorCodeValidation:        http.StatusBadRequest,
+	StructureData.ErrorCodeInsufficientStock: http.StatusUnprocessableEntity,
+	StructureData.ErrorCodeNotAcceptable:     http.StatusNotAcceptable,
+	StructureData.ErrorCodeCanceled:          http.StatusRequestTimeout,
+	StructureData.ErrorCodeInternal:          http.StatusInternalServerError,
+}
+
+// HTTPStatusForError returns the HTTP status matching the code of err
+func HTTPStatusForError(err error) int {
+	var errResp *StructureData.ErrorResponse
+	if !errors.As(err, &errResp) {
+		return http.StatusInternalServerError
+	}
+	if status, ok := statusByErrorCode[errResp.ErrorCode()]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError writes err as an RFC 7807 problem+json response. The detail is the error message without
+// its cause; internal errors are logged with their cause, and only their generic message is sent.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	code := StructureData.ErrorCodeInternal
+	detail := ""
+	var errResp *StructureData.ErrorResponse
+	if errors.As(err, &errResp) {
+		code = errResp.ErrorCode()
+		detail = errResp.Message
+	}
+	status := HTTPStatusForError(err)
+
+	problem := StructureData.ProblemDetails{
+		Type:   "/problems/" + string(code),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+	if r != nil {
+		problem.Instance = r.URL.Path
+	}
+	if code == StructureData.ErrorCodeInternal {
+		log.Printf("Internal error on %s: %v", problem.Instance, err)
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,94 @@
+package Controllers
+
+import (
+	"net/http"
+	"strings"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// Relations that can be inlined with ?expand=
+var (
+	bookExpansions  = []string{"author", "rating"}
+	orderExpansions = []string{"customer", "items.book", "items.book.author"}
+)
+
+// parseExpand reads the comma separated ?expand= query parameter, rejecting unknown relations
+func parseExpand(r *http.Request, allowed []string) (map[string]bool, *StructureData.ErrorResponse) {
+	expand := map[string]bool{}
+	for _, value := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !utils.ContainsString(allowed, value) {
+			return nil, StructureData.NewValidationError("Cannot expand " + value + "; expected one of " + strings.Join(allowed, ", "))
+		}
+		expand[value] = true
+	}
+	return expand, nil
+}
+
+// expandBook inlines the author and the rating summary of a book when requested
+func expandBook(book StructureData.Book, expand map[string]bool) StructureData.Book {
+	if expand["author"] && book.AuthorID != 0 {
+		if author, errResp := inmemoryStores.GetAuthorStoreInstance().GetAuthor(book.AuthorID); errResp == nil {
+			book.Author = &author
+		}
+	}
+	if expand["rating"] {
+		rating := inmemoryStores.GetReviewStoreInstance().GetRatingSummary(book.ID)
+		book.Rating = &rating
+	}
+	return book
+}
+
+func expandBooks(books []StructureData.Book, expand map[string]bool) []StructureData.Book {
+	if len(expand) == 0 {
+		return books
+	}
+	expanded := make([]StructureData.Book, len(books))
+	for i, book := range books {
+		expanded[i] = expandBook(book, expand)
+	}
+	return expanded
+}
+
+// expandOrder inlines the customer and the current books of an order when requested
+func expandOrder(order StructureData.Order, expand map[string]bool) StructureData.Order {
+	if expand["customer"] && order.CustomerID != 0 {
+		if customer, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(order.CustomerID); errResp == nil {
+			order.Customer = &customer
+		}
+	}
+
+	if expand["items.book"] || expand["items.book.author"] {
+		bookStore := inmemoryStores.GetBookStoreInstance()
+		bookExpand := map[string]bool{"author": expand["items.book.author"]}
+
+		// Copy the items so the stored order is left untouched
+		items := make([]StructureData.OrderItem, len(order.Items))
+		for i, item := range order.Items {
+			if book, errResp := bookStore.GetBook(item.BookID); errResp == nil && item.BookID != 0 {
+				book = expandBook(book, bookExpand)
+				item.Book = &book
+			}
+			items[i] = item
+		}
+		order.Items = items
+	}
+	return order
+}
+
+func expandOrders(orders []StructureData.Order, expand map[string]bool) []StructureData.Order {
+	if len(expand) == 0 {
+		return orders
+	}
+	expanded := make([]StructureData.Order, len(orders))
+	for i, order := range orders {
+		expanded[i] = expandOrder(order, expand)
+	}
+	return expanded
+}
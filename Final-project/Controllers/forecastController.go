@@ -0,0 +1,320 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// Defaults and bounds of the forecast query parameters
+const (
+	defaultForecastWeeks        = 12
+	maxForecastWeeks            = 104
+	defaultForecastHistoryWeeks = 104
+	maxForecastHistoryWeeks     = 520
+	defaultForecastWindow       = 4
+	defaultForecastSeasonWeeks  = 52
+	defaultForecastConfidence   = 0.95
+	minSmoothingHistoryWeeks    = 8 // Shorter histories are forecast with a moving average by the auto method
+)
+
+// forecastQuery holds the parsed parameters of the forecast requests
+type forecastQuery struct {
+	weeks        int
+	historyWeeks int
+	method       StructureData.ForecastMethod
+	window       int
+	seasonWeeks  int
+	confidence   float64
+	withHistory  bool
+	limit        int
+}
+
+// defaultForecastQuery returns the parameters used when none are given, also by the replenishment report
+func defaultForecastQuery() forecastQuery {
+	return forecastQuery{
+		weeks:        defaultForecastWeeks,
+		historyWeeks: defaultForecastHistoryWeeks,
+		method:       StructureData.ForecastAuto,
+		window:       defaultForecastWindow,
+		seasonWeeks:  defaultForecastSeasonWeeks,
+		confidence:   defaultForecastConfidence,
+	}
+}
+
+// GetDemandForecast handles the GET /analytics/forecast request. It returns the forecast of every book sold
+// in the history range, highest forecast demand first.
+func GetDemandForecast(w http.ResponseWriter, r *http.Request) {
+	// Parse the query parameters
+	query, errResp := parseForecastQuery(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Forecast the demand of every book
+	now := time.Now()
+	forecasts, errResp := computeDemandForecasts(r.Context(), now, query)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	books := []StructureData.BookForecast{}
+	for _, forecast := range forecasts {
+		if forecast.UnitsSold > 0 {
+			books = append(books, forecast)
+		}
+	}
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].ForecastUnits != books[j].ForecastUnits {
+			return books[i].ForecastUnits > books[j].ForecastUnits
+		}
+		return books[i].BookID < books[j].BookID
+	})
+	if query.limit > 0 && len(books) > query.limit {
+		books = books[:query.limit]
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StructureData.DemandForecast{
+		GeneratedAt:  now,
+		Weeks:        query.weeks,
+		HistoryWeeks: query.historyWeeks,
+		Method:       query.method,
+		Confidence:   query.confidence,
+		Books:        books,
+	})
+}
+
+// GetBookForecast handles the GET /analytics/forecast/{id} request. It returns the forecast of one book with
+// its weekly history, unless history=false.
+func GetBookForecast(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	idStr := r.URL.Path[len("/analytics/forecast/"):]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
+		return
+	}
+
+	// Parse the query parameters
+	query, errResp := parseForecastQuery(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	query.withHistory = r.URL.Query().Get("history") != "false"
+
+	if _, errResp := inmemoryStores.GetBookStoreInstance().GetBook(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Forecast the demand of the book
+	forecasts, errResp := computeDemandForecasts(r.Context(), time.Now(), query, id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecasts[id])
+}
+
+// parseForecastQuery reads the weeks to forecast, the history_weeks used, the method, the moving average
+// window, the season length in weeks, the confidence level of the bands and the limit of books
+func parseForecastQuery(r *http.Request) (forecastQuery, *StructureData.ErrorResponse) {
+	params := r.URL.Query()
+	query := defaultForecastQuery()
+
+	var errResp *StructureData.ErrorResponse
+	if query.weeks, errResp = positiveQueryInt(r, "weeks", query.weeks); errResp != nil {
+		return query, errResp
+	}
+	if query.weeks > maxForecastWeeks {
+		return query, StructureData.NewValidationError(fmt.Sprintf("weeks cannot be more than %d", maxForecastWeeks))
+	}
+	if query.historyWeeks, errResp = positiveQueryInt(r, "history_weeks", query.historyWeeks); errResp != nil {
+		return query, errResp
+	}
+	if query.historyWeeks > maxForecastHistoryWeeks {
+		return query, StructureData.NewValidationError(fmt.Sprintf("history_weeks cannot be more than %d", maxForecastHistoryWeeks))
+	}
+	if query.window, errResp = positiveQueryInt(r, "window", query.window); errResp != nil {
+		return query, errResp
+	}
+	if query.seasonWeeks, errResp = positiveQueryInt(r, "season_weeks", query.seasonWeeks); errResp != nil {
+		return query, errResp
+	}
+	if query.limit, errResp = positiveQueryInt(r, "limit", 0); errResp != nil {
+		return query, errResp
+	}
+
+	if value := params.Get("method"); value != "" {
+		query.method = StructureData.ForecastMethod(value)
+	}
+	switch query.method {
+	case StructureData.ForecastAuto, StructureData.ForecastMovingAverage, StructureData.ForecastExponentialSmoothing:
+	default:
+		return query, StructureData.NewValidationError("method must be auto, moving_average or exponential_smoothing")
+	}
+
+	if value := params.Get("confidence"); value != "" {
+		confidence, err := strconv.ParseFloat(value, 64)
+		if err != nil || confidence < 0.5 || confidence >= 1 {
+			return query, StructureData.NewValidationError("confidence must be a level between 0.5 and 1, like 0.95")
+		}
+		query.confidence = confidence
+	}
+	return query, nil
+}
+
+// computeDemandForecasts counts the weekly units sold of every book, or of the given books, over the complete
+// weeks of the history range and forecasts the weeks from the current one on. Weeks are ISO weeks in UTC.
+// Books without sales get a forecast of zero.
+func computeDemandForecasts(ctx context.Context, now time.Time, query forecastQuery, bookIDs ...int) (map[int]StructureData.BookForecast, *StructureData.ErrorResponse) {
+	currentWeek := intervalStart(StructureData.IntervalWeek, now.UTC())
+	historyStart := currentWeek.AddDate(0, 0, -7*query.historyWeeks)
+	orders, err := inmemoryStores.GetOrderStoreInstance().GetOrdersInTimeRange(historyStart.Add(-time.Nanosecond), currentWeek)
+	if err != nil {
+		return nil, StructureData.NewInternalError("Error fetching orders", err)
+	}
+
+	wanted := map[int]bool{}
+	for _, id := range bookIDs {
+		wanted[id] = true
+	}
+
+	// Units sold per book and week since the start of the history
+	weekly := map[int]map[int]int{}
+	for _, order := range orders {
+		select {
+		case <-ctx.Done(): // Check for cancellation
+			return nil, StructureData.NewCanceledError("Forecast was canceled", ctx.Err())
+		default:
+		}
+
+		if !order.CreatedAt.Before(currentWeek) {
+			continue
+		}
+		week := int(intervalStart(StructureData.IntervalWeek, order.CreatedAt.UTC()).Sub(historyStart).Hours()) / (7 * 24)
+		for _, item := range order.Items {
+			if len(wanted) > 0 && !wanted[item.BookID] {
+				continue
+			}
+			if weekly[item.BookID] == nil {
+				weekly[item.BookID] = map[int]int{}
+			}
+			weekly[item.BookID][week] += item.Quantity
+		}
+	}
+
+	forecasts := map[int]StructureData.BookForecast{}
+	for _, book := range inmemoryStores.GetBookStoreInstance().GetAllBooks() {
+		if len(wanted) > 0 && !wanted[book.ID] {
+			continue
+		}
+		forecasts[book.ID] = forecastBook(book, weekly[book.ID], historyStart, currentWeek, query)
+	}
+	return forecasts, nil
+}
+
+// forecastBook forecasts one book from its units sold per week of the history range, starting the history at
+// the week of its first sale so the weeks before it was sold do not weigh down the forecast
+func forecastBook(book StructureData.Book, weekly map[int]int, historyStart, currentWeek time.Time, query forecastQuery) StructureData.BookForecast {
+	forecast := StructureData.BookForecast{
+		BookID:     book.ID,
+		Title:      book.Title,
+		Method:     query.method,
+		Confidence: query.confidence,
+		Forecast:   []StructureData.ForecastPoint{},
+	}
+
+	firstWeek := query.historyWeeks
+	for week := range weekly {
+		firstWeek = min(firstWeek, week)
+	}
+	var history []float64
+	for week := firstWeek; week < query.historyWeeks; week++ {
+		history = append(history, float64(weekly[week]))
+		forecast.UnitsSold += weekly[week]
+		if query.withHistory {
+			start := historyStart.AddDate(0, 0, 7*week)
+			forecast.History = append(forecast.History, StructureData.WeeklyUnits{
+				Period: intervalPeriod(StructureData.IntervalWeek, start),
+				Start:  start,
+				Units:  weekly[week],
+			})
+		}
+	}
+	forecast.HistoryWeeks = len(history)
+
+	// Pick the method and predict
+	if forecast.Method == StructureData.ForecastAuto {
+		forecast.Method = StructureData.ForecastExponentialSmoothing
+		if len(history) < minSmoothingHistoryWeeks {
+			forecast.Method = StructureData.ForecastMovingAverage
+		}
+	}
+	z := utils.ZScore(query.confidence)
+	var predicted utils.Forecast
+	if forecast.Method == StructureData.ForecastExponentialSmoothing && len(history) >= 2 {
+		predicted = utils.ExponentialSmoothingForecast(history, query.seasonWeeks, query.weeks, z)
+		forecast.Seasonal = predicted.Seasonal
+		forecast.Parameters = &StructureData.ForecastParameters{Alpha: predicted.Alpha, Beta: predicted.Beta}
+		if predicted.Seasonal {
+			forecast.SeasonWeeks = query.seasonWeeks
+			forecast.Parameters.Gamma = predicted.Gamma
+		}
+	} else {
+		forecast.Method = StructureData.ForecastMovingAverage
+		forecast.Window = query.window
+		predicted = utils.MovingAverageForecast(history, query.window, query.weeks, z)
+	}
+	forecast.MeanAbsError = roundCents(predicted.MAE)
+
+	for h := range predicted.Values {
+		start := currentWeek.AddDate(0, 0, 7*h)
+		forecast.Forecast = append(forecast.Forecast, StructureData.ForecastPoint{
+			Period: intervalPeriod(StructureData.IntervalWeek, start),
+			Start:  start,
+			Units:  roundCents(predicted.Values[h]),
+			Lower:  roundCents(predicted.Lower[h]),
+			Upper:  roundCents(predicted.Upper[h]),
+		})
+		forecast.ForecastUnits += predicted.Values[h]
+		forecast.ForecastLower += predicted.Lower[h]
+		forecast.ForecastUpper += predicted.Upper[h]
+	}
+	forecast.ForecastUnits = roundCents(forecast.ForecastUnits)
+	forecast.ForecastLower = roundCents(forecast.ForecastLower)
+	forecast.ForecastUpper = roundCents(forecast.ForecastUpper)
+	return forecast
+}
+
+// forecastDemand returns the forecast units and the upper bound of the first days of a forecast, counting a
+// fraction of the week the days end in
+func forecastDemand(forecast StructureData.BookForecast, days int) (float64, float64) {
+	var units, upper float64
+	for h, point := range forecast.Forecast {
+		share := math.Min(float64(days-7*h)/7, 1)
+		if share <= 0 {
+			break
+		}
+		units += point.Units * share
+		upper += point.Upper * share
+	}
+	return units, upper
+}
@@ -0,0 +1,253 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file paths for gift card and store credit persistence
+var (
+	giftCardFile    = "gift_cards.json"
+	storeCreditFile = "store_credit.json"
+)
+
+// giftCardFilesMu keeps concurrent requests from writing the gift card files at the same time
+var giftCardFilesMu sync.Mutex
+
+// InitializeGiftCardFiles loads the gift cards and the store-credit ledger from the JSON files into the
+// in-memory store
+func InitializeGiftCardFiles() {
+	store := inmemoryStores.GetGiftCardStoreInstance()
+
+	var cards []StructureData.GiftCard
+	if err := readJSONFile(giftCardFile, &cards); err != nil {
+		panic("Failed to decode gift card file")
+	}
+	for _, card := range cards {
+		store.AddGiftCardDirectly(card)
+	}
+
+	var entries []StructureData.StoreCreditEntry
+	if err := readJSONFile(storeCreditFile, &entries); err != nil {
+		panic("Failed to decode store credit file")
+	}
+	for _, entry := range entries {
+		store.AddStoreCreditEntryDirectly(entry)
+	}
+}
+
+// ExpireGiftCards expires the gift cards past their expiry date, forfeiting their balance
+func ExpireGiftCards() {
+	expired := inmemoryStores.GetGiftCardStoreInstance().ExpireGiftCards(time.Now(), StructureData.AuditContext{Actor: systemActor})
+	if len(expired) == 0 {
+		return
+	}
+	log.Printf("Expired %d gift cards", len(expired))
+	if errResp := persistGiftCardData(); errResp != nil {
+		log.Printf("Failed to save gift cards: %v", errResp)
+	}
+}
+
+// GetAllGiftCards handles the GET /gift-cards request, filtered by the optional and repeatable query
+// parameters code, status and customer_id
+func GetAllGiftCards(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetGiftCardStoreInstance()
+	query := r.URL.Query()
+
+	// Build the search criteria from the query parameters
+	criteria := StructureData.GiftCardSearchCriteria{Codes: query["code"]}
+	for _, status := range query["status"] {
+		criteria.Statuses = append(criteria.Statuses, StructureData.GiftCardStatus(status))
+	}
+	for _, idStr := range query["customer_id"] {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			writeError(w, r, StructureData.NewValidationError("Invalid customer_id"))
+			return
+		}
+		criteria.CustomerIDs = append(criteria.CustomerIDs, id)
+	}
+
+	// Return the search results as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.SearchGiftCards(criteria))
+}
+
+// GetGiftCardByID handles the GET /gift-cards/{id} request
+func GetGiftCardByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetGiftCardStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/gift-cards/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid gift card ID"))
+		return
+	}
+
+	card, errResp := store.GetGiftCard(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
+
+// IssueGiftCard handles the POST /gift-cards request, selling a gift card with a new code
+func IssueGiftCard(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetGiftCardStoreInstance()
+
+	// Decode the request body
+	var request StructureData.GiftCardIssueRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	if request.CustomerID != 0 {
+		if _, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(request.CustomerID); errResp != nil {
+			writeError(w, r, StructureData.NewValidationError("Customer not found"))
+			return
+		}
+	}
+
+	card, errResp := store.IssueGiftCard(request, auditContext(r).Actor)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceGiftCards, card.ID, StructureData.AuditCreate, nil, card)
+
+	// Persist to JSON file
+	if errResp := persistGiftCardData(); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(card)
+}
+
+// GetStoreCredit handles the GET /customers/{id}/store-credit request, returning the customer's balance
+// and ledger
+func GetStoreCredit(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+	if _, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inmemoryStores.GetGiftCardStoreInstance().GetStoreCredit(id))
+}
+
+// AddStoreCredit handles the POST /customers/{id}/store-credit request. It moves the balance of the gift
+// card given to the customer's store credit, or otherwise adjusts the credit by the amount given.
+func AddStoreCredit(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetGiftCardStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+	if _, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Decode the request body
+	var request StructureData.StoreCreditRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	var entry StructureData.StoreCreditEntry
+	var errResp *StructureData.ErrorResponse
+	if request.GiftCardCode != "" {
+		if request.Amount != 0 {
+			writeError(w, r, StructureData.NewValidationError("Give either a gift card code or an amount"))
+			return
+		}
+		before, _ := store.GetGiftCardByCode(request.GiftCardCode)
+		entry, errResp = store.TransferToStoreCredit(request.GiftCardCode, id, auditContext(r).Actor)
+		if errResp != nil {
+			writeError(w, r, errResp)
+			return
+		}
+		after, _ := store.GetGiftCard(entry.GiftCardID)
+		recordAudit(r, StructureData.ResourceGiftCards, after.ID, StructureData.AuditUpdate, before, after)
+	} else {
+		entry, errResp = store.AddStoreCredit(StructureData.StoreCreditEntry{
+			CustomerID: id,
+			Type:       StructureData.StoreCreditAdjustment,
+			Amount:     request.Amount,
+			Note:       request.Note,
+			Actor:      auditContext(r).Actor,
+		})
+		if errResp != nil {
+			writeError(w, r, errResp)
+			return
+		}
+	}
+
+	// Persist to JSON files
+	if errResp := persistGiftCardData(); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// persistGiftCardData saves the gift cards and the store-credit ledger to their JSON files
+func persistGiftCardData() *StructureData.ErrorResponse {
+	giftCardFilesMu.Lock()
+	defer giftCardFilesMu.Unlock()
+
+	store := inmemoryStores.GetGiftCardStoreInstance()
+	if err := writeGiftCardFile(giftCardFile, store.GetAllGiftCards()); err != nil {
+		return StructureData.NewInternalError("Error saving gift card data", err)
+	}
+	if err := writeGiftCardFile(storeCreditFile, store.GetAllStoreCreditEntries()); err != nil {
+		return StructureData.NewInternalError("Error saving store credit data", err)
+	}
+	return nil
+}
+
+// writeGiftCardFile saves records to a JSON file in a pretty JSON format
+func writeGiftCardFile(path string, records interface{}) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(records)
+}
@@ -0,0 +1,260 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file path for stock alert persistence
+var stockAlertFile = "stock_alerts.json"
+
+// Defaults of the replenishment report query parameters
+const (
+	defaultReplenishmentWindowDays = 30
+	defaultLeadTimeDays            = 7
+)
+
+// Sources of the demand expected by the replenishment report
+const (
+	replenishmentDemandHistory  = "history"  // The sales velocity over the window
+	replenishmentDemandForecast = "forecast" // The weekly demand forecast
+)
+
+// InitializeStockAlertFile loads the stock alerts from the JSON file into the in-memory store
+func InitializeStockAlertFile() {
+	var alerts []StructureData.StockAlert
+	if err := readJSONFile(stockAlertFile, &alerts); err != nil {
+		panic("Failed to decode stock alert file")
+	}
+
+	store := inmemoryStores.GetStockAlertStoreInstance()
+	for _, alert := range alerts {
+		store.AddAlertDirectly(alert)
+	}
+}
+
+// StartInventoryMonitor raises alerts for books already low on stock and watches later stock changes
+func StartInventoryMonitor() {
+	monitor := inmemoryStores.GetInventoryMonitorInstance()
+	monitor.OnAlert = func() {
+		if err := persistStockAlertsToFile(); err != nil {
+			log.Printf("Failed to save stock alerts: %v", err)
+		}
+	}
+	monitor.Start()
+}
+
+// GetStockAlerts handles the GET /inventory/alerts request. ?status=open or ?status=resolved filters the alerts.
+func GetStockAlerts(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetStockAlertStoreInstance()
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "open" && status != "resolved" {
+		writeError(w, r, StructureData.NewValidationError("status must be open or resolved"))
+		return
+	}
+
+	alerts := []StructureData.StockAlert{}
+	for _, alert := range store.GetAllAlerts() {
+		if status == "open" && !alert.IsOpen() || status == "resolved" && alert.IsOpen() {
+			continue
+		}
+		alerts = append(alerts, alert)
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alerts)
+}
+
+// GetReplenishmentReport handles the GET /inventory/replenishment request. It suggests reorder quantities
+// for the books whose stock will not last through the supplier lead time, based on the sales of the last
+// window_days days (default 30) and a lead time of lead_time_days days (default 7).
+func GetReplenishmentReport(w http.ResponseWriter, r *http.Request) {
+	windowDays, errResp := positiveQueryInt(r, "window_days", defaultReplenishmentWindowDays)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	leadTimeDays, errResp := positiveQueryInt(r, "lead_time_days", defaultLeadTimeDays)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	demand := r.URL.Query().Get("demand")
+	if demand == "" {
+		demand = replenishmentDemandHistory
+	}
+	if demand != replenishmentDemandHistory && demand != replenishmentDemandForecast {
+		writeError(w, r, StructureData.NewValidationError("demand must be history or forecast"))
+		return
+	}
+
+	report, errResp := buildReplenishmentReport(r.Context(), time.Now(), windowDays, leadTimeDays, demand)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// buildReplenishmentReport computes the sales velocity of every book over the window and suggests
+// restocking the books at or below their reorder level. The reorder level is the threshold plus the
+// sales expected during the lead time, and books are restocked up to their target stock, or to the
+// reorder level plus another window of sales when no target is set. With the forecast demand, the expected
+// sales come from the weekly forecast of each book, and the reorder level adds a safety stock up to the
+// upper bound of the lead time demand.
+func buildReplenishmentReport(ctx context.Context, now time.Time, windowDays, leadTimeDays int, demand string) (StructureData.ReplenishmentReport, *StructureData.ErrorResponse) {
+	orders, err := inmemoryStores.GetOrderStoreInstance().GetOrdersInTimeRange(now.AddDate(0, 0, -windowDays), now)
+	if err != nil {
+		return StructureData.ReplenishmentReport{}, StructureData.NewInternalError("Error computing replenishment report", err)
+	}
+
+	// Forecasts covering the lead time and a window after it
+	var forecasts map[int]StructureData.BookForecast
+	if demand == replenishmentDemandForecast {
+		query := defaultForecastQuery()
+		query.weeks = int(math.Ceil(float64(leadTimeDays+windowDays) / 7))
+		var errResp *StructureData.ErrorResponse
+		if forecasts, errResp = computeDemandForecasts(ctx, now, query); errResp != nil {
+			return StructureData.ReplenishmentReport{}, errResp
+		}
+	}
+
+	unitsSold := map[int]int{}
+	for _, order := range orders {
+		for _, item := range order.Items {
+			unitsSold[item.BookID] += item.Quantity
+		}
+	}
+
+	suggestions := []StructureData.ReplenishmentSuggestion{}
+	for _, book := range inmemoryStores.GetBookStoreInstance().GetAllBooks() {
+		velocity := float64(unitsSold[book.ID]) / float64(windowDays)
+		leadTimeDemand := velocity * float64(leadTimeDays)
+		windowDemand := velocity * float64(windowDays)
+		safetyStock := 0
+		forecast, forecasted := forecasts[book.ID]
+		if forecasted {
+			// The upper bound of the lead time demand covers its uncertainty
+			var upper float64
+			leadTimeDemand, upper = forecastDemand(forecast, leadTimeDays)
+			total, _ := forecastDemand(forecast, leadTimeDays+windowDays)
+			windowDemand = total - leadTimeDemand
+			velocity = windowDemand / float64(windowDays)
+			safetyStock = int(math.Ceil(upper - leadTimeDemand))
+		}
+		reorderLevel := book.ReorderThreshold + int(math.Ceil(leadTimeDemand)) + safetyStock
+		if book.Stock > reorderLevel {
+			continue
+		}
+
+		target := book.TargetStock
+		if target == 0 {
+			target = reorderLevel + int(math.Ceil(windowDemand))
+		}
+		quantity := target - book.Stock
+		if quantity <= 0 {
+			continue
+		}
+
+		suggestion := StructureData.ReplenishmentSuggestion{
+			BookID:            book.ID,
+			Title:             book.Title,
+			Stock:             book.Stock,
+			ReorderThreshold:  book.ReorderThreshold,
+			TargetStock:       target,
+			UnitsSold:         unitsSold[book.ID],
+			DailyVelocity:     velocity,
+			ReorderLevel:      reorderLevel,
+			SuggestedQuantity: quantity,
+		}
+		if forecasted {
+			leadTimeDemand = roundCents(leadTimeDemand)
+			suggestion.ForecastMethod = forecast.Method
+			suggestion.LeadTimeDemand = &leadTimeDemand
+			suggestion.SafetyStock = &safetyStock
+		}
+		if velocity > 0 {
+			cover := float64(book.Stock) / velocity
+			suggestion.DaysOfCover = &cover
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	// Most urgent first: books that will run out soonest, then the unsold ones by stock
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if (a.DaysOfCover == nil) != (b.DaysOfCover == nil) {
+			return a.DaysOfCover != nil
+		}
+		if a.DaysOfCover != nil && *a.DaysOfCover != *b.DaysOfCover {
+			return *a.DaysOfCover < *b.DaysOfCover
+		}
+		if a.Stock != b.Stock {
+			return a.Stock < b.Stock
+		}
+		return a.BookID < b.BookID
+	})
+
+	return StructureData.ReplenishmentReport{
+		GeneratedAt:  now,
+		WindowDays:   windowDays,
+		LeadTimeDays: leadTimeDays,
+		Demand:       demand,
+		Suggestions:  suggestions,
+	}, nil
+}
+
+// positiveQueryInt reads a positive integer query parameter, returning def when it is missing
+func positiveQueryInt(r *http.Request, name string, def int) (int, *StructureData.ErrorResponse) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, StructureData.NewValidationError(name + " must be a positive integer")
+	}
+	return n, nil
+}
+
+// validateStockLevels checks the reorder threshold and target stock of a book
+func validateStockLevels(book StructureData.Book) *StructureData.ErrorResponse {
+	if book.ReorderThreshold < 0 || book.TargetStock < 0 {
+		return StructureData.NewValidationError("reorder_threshold and target_stock cannot be negative")
+	}
+	if book.TargetStock > 0 && book.TargetStock <= book.ReorderThreshold {
+		return StructureData.NewValidationError("target_stock must be greater than reorder_threshold")
+	}
+	return nil
+}
+
+// persistStockAlertsToFile saves all stock alerts to the JSON file in a pretty JSON format
+func persistStockAlertsToFile() error {
+	file, err := os.Create(stockAlertFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetStockAlertStoreInstance().GetAllAlerts())
+}
@@ -0,0 +1,267 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file paths for invoice persistence
+var (
+	invoiceFile         = "invoices.json"
+	invoiceSettingsFile = "invoice_settings.json"
+)
+
+var invoicesMu sync.Mutex // Serializes writes of the invoice file from requests and the invoicer
+
+// InitializeInvoiceFiles loads the invoices and credit notes into the in-memory store and the invoice
+// settings into the invoicer, writing the default settings if the file is missing
+func InitializeInvoiceFiles() {
+	var invoices []StructureData.Invoice
+	if err := readJSONFile(invoiceFile, &invoices); err != nil {
+		panic("Failed to decode invoice file")
+	}
+	store := inmemoryStores.GetInvoiceStoreInstance()
+	for _, invoice := range invoices {
+		store.AddInvoiceDirectly(invoice)
+	}
+
+	invoicer := inmemoryStores.GetInvoicerInstance()
+	if _, err := os.Stat(invoiceSettingsFile); os.IsNotExist(err) {
+		if err := persistInvoiceSettingsToFile(invoicer.Settings()); err != nil {
+			log.Printf("Failed to create invoice settings file: %v", err)
+		}
+		return
+	}
+	var settings StructureData.InvoiceSettings
+	if err := readJSONFile(invoiceSettingsFile, &settings); err != nil {
+		panic("Failed to decode invoice settings file")
+	}
+	if _, errResp := invoicer.SetSettings(settings); errResp != nil {
+		log.Printf("Ignoring invalid invoice settings from %s: %s", invoiceSettingsFile, errResp.Message)
+	}
+}
+
+// StartInvoicer invoices the orders placed from now on and saves the invoices whenever documents are issued
+func StartInvoicer() {
+	invoicer := inmemoryStores.GetInvoicerInstance()
+	invoicer.OnIssue = func() {
+		if err := persistInvoicesToFile(); err != nil {
+			log.Printf("Failed to save invoices: %v", err)
+		}
+	}
+	invoicer.Start()
+}
+
+// GetAllInvoices handles the GET /invoices request, returning invoices and credit notes in the order they
+// were issued
+func GetAllInvoices(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetInvoiceStoreInstance()
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetAllInvoices())
+}
+
+// GetInvoiceByID handles the GET /invoices/{id} request. The document is returned as JSON, or rendered as
+// HTML or PDF when asked with ?format= or the Accept header.
+func GetInvoiceByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetInvoiceStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/invoices/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid invoice ID"))
+		return
+	}
+
+	// Choose the response format
+	format, errResp := negotiateExportFormat(r)
+	if errResp == nil && (format == StructureData.ExportCSV || format == StructureData.ExportXLSX) ||
+		errResp != nil && errResp.ErrorCode() == StructureData.ErrorCodeNotAcceptable {
+		errResp = StructureData.NewNotAcceptableError("Invoices are available as JSON, HTML or PDF")
+	}
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Retrieve the invoice from the store
+	invoice, errResp := store.GetInvoice(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	if format != StructureData.ExportJSON {
+		writeInvoiceDocument(w, r, format, invoice)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoice)
+}
+
+// SearchInvoices handles the POST /invoices/search request
+func SearchInvoices(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetInvoiceStoreInstance()
+
+	// Decode the search criteria from the request body
+	var criteria StructureData.InvoiceSearchCriteria
+	if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid search criteria"))
+		return
+	}
+
+	// Return the search results as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.SearchInvoices(criteria))
+}
+
+// CreateCreditNote handles the POST /invoices/{id}/credit-notes request, refunding the quantities of the
+// books given in lines, or everything not credited yet without lines
+func CreateCreditNote(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/invoices/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid invoice ID"))
+		return
+	}
+
+	// Decode the request body
+	var request StructureData.CreditNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Issue the credit note; the invoicer saves it
+	creditNote, errResp := inmemoryStores.GetInvoicerInstance().CreditInvoice(id, request, auditContext(r))
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return the credit note
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(creditNote)
+}
+
+// GetOrderInvoices handles the GET /orders/{id}/invoices request, returning the invoices and credit notes
+// of an order in the order they were issued
+func GetOrderInvoices(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/orders/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid order ID"))
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inmemoryStores.GetInvoiceStoreInstance().GetInvoicesByOrder(id))
+}
+
+// InvoiceOrder handles the POST /orders/{id}/invoice request, invoicing an order placed before invoicing
+// started or whose invoice was fully credited
+func InvoiceOrder(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/orders/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid order ID"))
+		return
+	}
+	order, errResp := inmemoryStores.GetOrderStoreInstance().GetOrder(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Issue the invoice; the invoicer saves it
+	invoice, errResp := inmemoryStores.GetInvoicerInstance().InvoiceOrder(order, auditContext(r))
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return the invoice
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invoice)
+}
+
+// GetInvoiceSettings handles the GET /invoice-settings request
+func GetInvoiceSettings(w http.ResponseWriter, r *http.Request) {
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inmemoryStores.GetInvoicerInstance().Settings())
+}
+
+// UpdateInvoiceSettings handles the PUT /invoice-settings request. The settings apply to the documents issued
+// afterwards; documents already issued keep the details they were issued with.
+func UpdateInvoiceSettings(w http.ResponseWriter, r *http.Request) {
+	// Decode the request body
+	var settings StructureData.InvoiceSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate and apply the settings
+	updatedSettings, errResp := inmemoryStores.GetInvoicerInstance().SetSettings(settings)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistInvoiceSettingsToFile(updatedSettings); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedSettings)
+}
+
+// persistInvoicesToFile saves all invoices and credit notes to the JSON file in a pretty JSON format
+func persistInvoicesToFile() error {
+	invoicesMu.Lock()
+	defer invoicesMu.Unlock()
+
+	file, err := os.Create(invoiceFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetInvoiceStoreInstance().GetAllInvoices())
+}
+
+// persistInvoiceSettingsToFile saves the invoice settings to the JSON file in a pretty JSON format
+func persistInvoiceSettingsToFile(settings StructureData.InvoiceSettings) error {
+	file, err := os.Create(invoiceSettingsFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(settings)
+}
@@ -0,0 +1,228 @@
+package Controllers
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// invoiceView is an invoice or credit note laid out for printing
+type invoiceView struct {
+	Title    string // "Invoice" or "Credit note"
+	Invoice  StructureData.Invoice
+	Details  []string // Dates and references printed under the title
+	Seller   []string // Lines of the seller's block
+	Customer []string // Lines of the customer's block
+	Lines    []invoiceViewLine
+	Totals   []exportTotal
+}
+
+// invoiceViewLine is a line of a printed invoice, its amounts formatted
+type invoiceViewLine struct {
+	Description string
+	Quantity    string
+	UnitPrice   string
+	TaxRate     string
+	NetAmount   string
+	TaxAmount   string
+	Total       string
+}
+
+// writeInvoiceDocument renders an invoice or credit note as HTML, shown in the browser unless ?download=true,
+// or as a PDF attachment
+func writeInvoiceDocument(w http.ResponseWriter, r *http.Request, format StructureData.ExportFormat, invoice StructureData.Invoice) {
+	view := newInvoiceView(invoice)
+
+	w.Header().Set("Content-Type", exportMediaTypes[format])
+	disposition := "attachment"
+	if format == StructureData.ExportHTML && r.URL.Query().Get("download") != "true" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": invoice.Number + "." + string(format)}))
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, so failures can only be logged from here
+	var err error
+	if format == StructureData.ExportPDF {
+		err = writeInvoicePDF(w, view)
+	} else {
+		err = invoiceTemplate.Execute(w, view)
+	}
+	if err != nil {
+		log.Printf("Failed to render invoice %s as %s: %v", invoice.Number, format, err)
+	}
+}
+
+// newInvoiceView lays out an invoice or credit note
+func newInvoiceView(invoice StructureData.Invoice) invoiceView {
+	view := invoiceView{Title: "Invoice", Invoice: invoice}
+	if invoice.Type == StructureData.InvoiceTypeCreditNote {
+		view.Title = "Credit note"
+	}
+
+	view.Details = []string{
+		"Number: " + invoice.Number,
+		"Date: " + invoice.IssuedAt.Format("2006-01-02"),
+		fmt.Sprintf("Order: %d", invoice.OrderID),
+	}
+	if invoice.Type == StructureData.InvoiceTypeCreditNote {
+		view.Details = append(view.Details, "Credits invoice: "+invoice.InvoiceNumber, "Reason: "+invoice.Reason)
+	}
+
+	view.Seller = partyLines(invoice.Seller)
+	view.Customer = partyLines(invoice.Customer)
+
+	for _, line := range invoice.Lines {
+		view.Lines = append(view.Lines, invoiceViewLine{
+			Description: line.Description,
+			Quantity:    strconv.Itoa(line.Quantity),
+			UnitPrice:   exportText(line.UnitPrice),
+			TaxRate:     strconv.FormatFloat(line.TaxRate*100, 'f', -1, 64) + "%",
+			NetAmount:   exportText(line.NetAmount),
+			TaxAmount:   exportText(line.TaxAmount),
+			Total:       exportText(line.Total),
+		})
+	}
+
+	view.Totals = []exportTotal{
+		{"Net total", exportText(invoice.NetTotal)},
+		{"Tax", exportText(invoice.TaxTotal)},
+		{"Total " + invoice.Currency, exportText(invoice.Total)},
+	}
+	if invoice.CreditedTotal > 0 {
+		view.Totals = append(view.Totals, exportTotal{"Credited", exportText(invoice.CreditedTotal)})
+	}
+	return view
+}
+
+// partyLines returns the lines of the seller's or the customer's block: name, address, email and tax ID
+func partyLines(party StructureData.InvoiceParty) []string {
+	lines := []string{party.Name}
+	address := party.Address
+	if address.Street != "" {
+		lines = append(lines, address.Street)
+	}
+	if place := strings.Join(strings.Fields(address.PostalCode+" "+address.City+" "+address.State), " "); place != "" {
+		lines = append(lines, place)
+	}
+	if address.Country != "" {
+		lines = append(lines, address.Country)
+	}
+	if party.Email != "" {
+		lines = append(lines, party.Email)
+	}
+	if party.TaxID != "" {
+		lines = append(lines, "Tax ID: "+party.TaxID)
+	}
+	return lines
+}
+
+// invoicePDFLine is a line of a PDF invoice
+type invoicePDFLine struct {
+	text string
+	bold bool
+}
+
+// writeInvoicePDF writes an invoice or credit note as a PDF document with fixed-width columns
+func writeInvoicePDF(w http.ResponseWriter, view invoiceView) error {
+	document, err := utils.NewPDFWriter(w)
+	if err != nil {
+		return err
+	}
+
+	lines := []invoicePDFLine{{strings.ToUpper(view.Title), true}}
+	add := func(text string, bold bool) {
+		lines = append(lines, invoicePDFLine{text, bold})
+	}
+	for _, detail := range view.Details {
+		add(detail, false)
+	}
+
+	// Seller and customer side by side
+	add("", false)
+	add(fmt.Sprintf("%-60s %s", "From", "Bill to"), true)
+	for j := 0; j < max(len(view.Seller), len(view.Customer)); j++ {
+		seller, customer := "", ""
+		if j < len(view.Seller) {
+			seller = view.Seller[j]
+		}
+		if j < len(view.Customer) {
+			customer = view.Customer[j]
+		}
+		add(fmt.Sprintf("%-60s %s", seller, customer), false)
+	}
+
+	// Lines
+	columns := []exportColumn{
+		{"Description", 50, false}, {"Qty", 5, true}, {"Unit price", 12, true}, {"Tax rate", 8, true},
+		{"Net", 12, true}, {"Tax", 12, true}, {"Total", 12, true},
+	}
+	add("", false)
+	add(statementLine(columns, columnHeader(columns)), true)
+	for _, line := range view.Lines {
+		add(statementLine(columns, []interface{}{line.Description, line.Quantity, line.UnitPrice, line.TaxRate, line.NetAmount, line.TaxAmount, line.Total}), false)
+	}
+
+	// Totals aligned under the total column
+	add("", false)
+	width := len(statementLine(columns, columnHeader(columns)))
+	for _, total := range view.Totals {
+		add(fmt.Sprintf("%*s", width, total.Label+": "+total.Value), true)
+	}
+
+	for _, line := range lines {
+		if err := document.WriteLine(line.text, line.bold); err != nil {
+			return err
+		}
+	}
+	return document.Close()
+}
+
+// invoiceTemplate renders a printable invoice or credit note
+var invoiceTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.Invoice.Number}}</title>
+<style>
+  body { font-family: Helvetica, Arial, sans-serif; font-size: 12px; margin: 2em; color: #222; }
+  h1 { font-size: 22px; margin-bottom: 0.2em; }
+  p.detail { margin: 0; color: #555; }
+  div.parties { display: flex; gap: 4em; margin-top: 1.5em; }
+  div.parties h2 { font-size: 13px; margin: 0 0 0.3em; }
+  div.parties p { margin: 0; }
+  table { border-collapse: collapse; width: 100%; margin-top: 1.5em; }
+  th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+  th { background: #f2f2f2; }
+  td.number, th.number { text-align: right; white-space: nowrap; }
+  table.totals { width: auto; margin-left: auto; }
+  table.totals th { background: none; }
+  @media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Details}}<p class="detail">{{.}}</p>
+{{end}}<div class="parties">
+<div><h2>From</h2>{{range .Seller}}<p>{{.}}</p>{{end}}</div>
+<div><h2>Bill to</h2>{{range .Customer}}<p>{{.}}</p>{{end}}</div>
+</div>
+<table>
+<thead><tr><th>Description</th><th class="number">Qty</th><th class="number">Unit price</th><th class="number">Tax rate</th><th class="number">Net</th><th class="number">Tax</th><th class="number">Total</th></tr></thead>
+<tbody>
+{{range .Lines}}<tr><td>{{.Description}}</td><td class="number">{{.Quantity}}</td><td class="number">{{.UnitPrice}}</td><td class="number">{{.TaxRate}}</td><td class="number">{{.NetAmount}}</td><td class="number">{{.TaxAmount}}</td><td class="number">{{.Total}}</td></tr>
+{{end}}</tbody>
+</table>
+<table class="totals">
+{{range .Totals}}<tr><th>{{.Label}}</th><td class="number">{{.Value}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
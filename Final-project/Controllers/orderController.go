@@ -3,6 +3,7 @@ package Controllers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	inmemoryStores "finalProject/InmemoryStores"
+	interfaces "finalProject/Interfaces"
 	"finalProject/StructureData"
 )
 
@@ -44,23 +46,25 @@ func InitializeOrderFile() {
 		var failedOrders []StructureData.Order
 
 		for _, order := range orders {
-			customer, customerErr := customerStore.GetCustomer(order.Customer.ID)
-			if customerErr != nil {
-				log.Printf("Skipping order ID %d: Customer with ID %d not found", order.ID, order.Customer.ID)
-				failedOrders = append(failedOrders, order)
-				continue
+			// An ID of 0 marks a customer or book detached by a relation policy; the snapshot is kept as-is
+			if order.CustomerID != 0 {
+				if _, customerErr := customerStore.GetCustomer(order.CustomerID); customerErr != nil {
+					log.Printf("Skipping order ID %d: Customer with ID %d not found", order.ID, order.CustomerID)
+					failedOrders = append(failedOrders, order)
+					continue
+				}
 			}
-			order.Customer = customer
 
 			validOrder := true
-			for i, item := range order.Items {
-				book, bookErr := bookStore.GetBook(item.Book.ID)
-				if bookErr != nil {
-					log.Printf("Skipping order ID %d: Book ID %d not found", order.ID, item.Book.ID)
+			for _, item := range order.Items {
+				if item.BookID == 0 {
+					continue
+				}
+				if _, bookErr := bookStore.GetBook(item.BookID); bookErr != nil {
+					log.Printf("Skipping order ID %d: Book ID %d not found", order.ID, item.BookID)
 					validOrder = false
 					break
 				}
-				order.Items[i].Book = book
 			}
 
 			if !validOrder {
@@ -68,10 +72,8 @@ func InitializeOrderFile() {
 				continue
 			}
 
-			if _, errResp := store.CreateOrder(order); errResp != nil {
-				log.Printf("Error creating order ID %d: %v", order.ID, errResp)
-				failedOrders = append(failedOrders, order)
-			}
+			// Keep the stored ID, creation time and total so references and reports stay valid
+			store.AddOrderDirectly(order)
 		}
 
 		if len(failedOrders) > 0 {
@@ -101,12 +103,19 @@ func saveFailedOrders(orders []StructureData.Order) {
 func GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	store := inmemoryStores.GetOrderStoreInstance()
 
+	// Parse the relations to inline
+	expand, errResp := parseExpand(r, orderExpansions)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
 	// Retrieve all orders
 	orders := store.GetAllOrders()
 
 	// Return JSON response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(orders)
+	json.NewEncoder(w).Encode(expandOrders(orders, expand))
 }
 
 // GetOrderByID handles the GET /orders/{id} request
@@ -117,22 +126,27 @@ func GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Path[len("/orders/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid order ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid order ID"))
+		return
+	}
+
+	// Parse the relations to inline
+	expand, errResp := parseExpand(r, orderExpansions)
+	if errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Retrieve the order by ID
 	order, errResp := store.GetOrder(id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Return JSON response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(order)
+	json.NewEncoder(w).Encode(expandOrder(order, expand))
 }
 
 func CreateOrder(w http.ResponseWriter, r *http.Request) {
@@ -143,91 +157,115 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 	// Decode the request body
 	var order StructureData.Order
 	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
 		return
 	}
 
 	// Validate customer
-	customer, errResp := customerStore.GetCustomer(order.Customer.ID)
+	customer, errResp := resolveOrderCustomer(customerStore, order)
 	if errResp != nil {
-		// If ID is not found, try validating by email
-		for _, existingCustomer := range customerStore.GetAllCustomers() {
-			if existingCustomer.Email == order.Customer.Email {
-				customer = existingCustomer
-				break
-			}
-		}
-		// If still not found
-		if customer.ID == 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer does not exist"})
-			return
-		}
+		writeError(w, r, errResp)
+		return
 	}
 	// Fill customer details in the order
-	order.Customer = customer
+	order.CustomerID = customer.ID
+	order.CustomerSnapshot = snapshotCustomer(customer)
+	if errResp := resolveOrderAddresses(customer, &order); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Choose how the items are allocated to warehouses
+	order.AllocationStrategy, errResp = allocationStrategy(order.AllocationStrategy)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Collect the stock taken below, recorded once the order is created
+	change := newStockChange(StructureData.StockMovement{Type: StructureData.StockMovementSale, SourceType: StructureData.ResourceOrders})
 
 	// Validate books in the order
 	validItems := []StructureData.OrderItem{} // Store valid items
+	stockShortage := false                    // Whether any item was skipped for lack of stock
+	skippedBooks := []int{}                   // Books skipped for lack of stock
 	for _, item := range order.Items {
-		book, bookErr := bookStore.GetBook(item.Book.ID)
+		book, bookErr := bookStore.GetBook(orderItemBookID(item))
 		if bookErr != nil {
-			log.Printf("Skipping book ID %d: Does not exist", item.Book.ID)
+			log.Printf("Skipping book ID %d: Does not exist", orderItemBookID(item))
 			continue
 		}
 
 		// Check if the stock is sufficient
 		if item.Quantity > book.Stock || book.Stock == 0 {
 			log.Printf("Skipping book ID %d: Insufficient stock (stock=%d)", book.ID, book.Stock)
+			stockShortage = true
+			skippedBooks = append(skippedBooks, book.ID)
 			continue
 		}
 
-		// Deduct stock
-		book.Stock -= item.Quantity
-
-		// Update the book in the store
-		_, updateErr := bookStore.UpdateBook(book.ID, book)
-		if updateErr != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Failed to update book stock"})
-			return
+		// Take the quantity from the warehouses chosen by the allocation strategy and deduct it from the stock
+		allocations, allocErr := change.allocate(book.ID, item.Quantity, order.AllocationStrategy, order.CustomerSnapshot.Address.Country)
+		if allocErr != nil {
+			log.Printf("Skipping book ID %d: %s", book.ID, allocErr.Message)
+			stockShortage = true
+			skippedBooks = append(skippedBooks, book.ID)
+			continue
 		}
+		item.Allocations = allocations
 
 		// Add the item to the valid items list
-		item.Book = book // Ensure all fields in the book are updated
+		item.BookID = book.ID
 		validItems = append(validItems, item)
 	}
 
-	// If no valid items are present, return an error
+	// If no valid items are present, return an error. An order refused because every book was out of
+	// stock still subscribes the customer to their restock.
 	if len(validItems) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "No valid books available to create the order"})
+		if stockShortage {
+			if order.NotifyOnRestock {
+				subscribeToRestocks(customer.ID, skippedBooks)
+			}
+			writeError(w, r, StructureData.NewInsufficientStockError("Insufficient stock for the requested books"))
+			return
+		}
+		writeError(w, r, StructureData.NewValidationError("No valid books available to create the order"))
 		return
 	}
 
 	// Update the order with valid items
 	order.Items = validItems
 
-	// Create the order in the store
-	createdOrder, errResp := orderStore.CreateOrder(order)
+	// Create the order in the store, paying with the gift cards and store credit given
+	createdOrder, errResp := orderStore.CreateOrder(order, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		change.undo()
+		writeError(w, r, errResp)
 		return
 	}
+	change.source.SourceID = createdOrder.ID
+	change.commit(r)
+
+	// Let the customer know when the skipped books are back in stock
+	if order.NotifyOnRestock {
+		subscribeToRestocks(customer.ID, skippedBooks)
+	}
 
 	// Persist to JSON file
-	if err := persistOrdersToFile(orderStore.GetAllOrders()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving data"})
+	if err := persistOrdersToFile(orderStore); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
 		return
 	}
+	if len(createdOrder.Payments) > 0 {
+		if errResp := persistGiftCardData(); errResp != nil {
+			writeError(w, r, errResp)
+			return
+		}
+	}
 
 	// Persist updated books to the JSON file
-	if err := persistBooksToFile(bookStore.GetAllBooks()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving updated book data"})
+	if err := persistBooksToFile(bookStore); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving updated book data", err))
 		return
 	}
 
@@ -246,107 +284,141 @@ func UpdateOrder(w http.ResponseWriter, r *http.Request) {
 	idStr := r.URL.Path[len("/orders/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid order ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid order ID"))
 		return
 	}
 
 	// Retrieve the existing order
 	existingOrder, errResp := orderStore.GetOrder(id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
+	// Returned goods were refunded at the prices the order was placed with
+	for _, ret := range inmemoryStores.GetReturnStoreInstance().GetReturnsByOrder(id) {
+		if ret.Status != StructureData.ReturnRejected {
+			writeError(w, r, StructureData.NewConflictError(fmt.Sprintf("Order %d has return %s; orders with returns cannot be edited", id, ret.Number)))
+			return
+		}
+	}
+
 	// Decode the request body
 	var updatedOrder StructureData.Order
 	if err := json.NewDecoder(r.Body).Decode(&updatedOrder); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	if len(updatedOrder.GiftCardCodes) > 0 || updatedOrder.UseStoreCredit {
+		writeError(w, r, StructureData.NewValidationError("Gift cards and store credit can only be used when an order is placed"))
 		return
 	}
 
 	// Validate customer
-	customer, errResp := customerStore.GetCustomer(updatedOrder.Customer.ID)
+	customer, errResp := resolveOrderCustomer(customerStore, updatedOrder)
 	if errResp != nil {
-		for _, existingCustomer := range customerStore.GetAllCustomers() {
-			if existingCustomer.Email == updatedOrder.Customer.Email {
-				customer = existingCustomer
-				break
-			}
-		}
-		if customer.ID == 0 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer does not exist"})
-			return
-		}
+		writeError(w, r, errResp)
+		return
+	}
+	// Fill customer details in the order
+	updatedOrder.CustomerID = customer.ID
+	updatedOrder.CustomerSnapshot = snapshotCustomer(customer)
+
+	// Keep the addresses the order was placed with unless new ones are given
+	if updatedOrder.CustomerID == existingOrder.CustomerID && updatedOrder.ShipTo == nil && updatedOrder.BillTo == nil {
+		updatedOrder.ShipTo, updatedOrder.BillTo = existingOrder.ShipTo, existingOrder.BillTo
+		updatedOrder.CustomerSnapshot.Address = existingOrder.CustomerSnapshot.Address
+	} else if errResp := resolveOrderAddresses(customer, &updatedOrder); errResp != nil {
+		writeError(w, r, errResp)
+		return
 	}
-	updatedOrder.Customer = customer
 
-	// Adjust stock based on changes to the order
+	// Keep the order's allocation strategy unless a new one is given
+	if updatedOrder.AllocationStrategy == "" {
+		updatedOrder.AllocationStrategy = existingOrder.AllocationStrategy
+	}
+	updatedOrder.AllocationStrategy, errResp = allocationStrategy(updatedOrder.AllocationStrategy)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// The stock held by the old order is available to the new items
+	var previousItems []StructureData.OrderItem
+	held := map[int]int{}
 	for _, item := range existingOrder.Items {
-		book, bookErr := bookStore.GetBook(item.Book.ID)
-		if bookErr == nil {
-			book.Stock += item.Quantity                // Revert the stock changes from the old order
-			_, _ = bookStore.UpdateBook(book.ID, book) // Update silently
+		if _, bookErr := bookStore.GetBook(item.BookID); bookErr == nil {
+			previousItems = append(previousItems, item)
+			held[item.BookID] += item.Quantity
 		}
 	}
 
-	// Validate and update books for the new order
-	validItems := []StructureData.OrderItem{} // Store valid items
+	// Validate books for the new order
+	requestedItems := []StructureData.OrderItem{} // Items whose book exists and has enough stock
+	stockShortage := false                        // Whether any item was skipped for lack of stock
 	for _, item := range updatedOrder.Items {
-		book, bookErr := bookStore.GetBook(item.Book.ID)
+		book, bookErr := bookStore.GetBook(orderItemBookID(item))
 		if bookErr != nil {
-			log.Printf("Skipping book ID %d: Does not exist", item.Book.ID)
+			log.Printf("Skipping book ID %d: Does not exist", orderItemBookID(item))
 			continue
 		}
 
-		if item.Quantity > book.Stock || book.Stock == 0 {
+		if item.Quantity > book.Stock+held[book.ID] || book.Stock+held[book.ID] == 0 {
 			log.Printf("Skipping book ID %d: Insufficient stock (stock=%d)", book.ID, book.Stock)
+			stockShortage = true
 			continue
 		}
 
-		book.Stock -= item.Quantity
-		_, updateErr := bookStore.UpdateBook(book.ID, book)
-		if updateErr != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Failed to update book stock"})
+		item.BookID = book.ID
+		requestedItems = append(requestedItems, item)
+	}
+	if len(requestedItems) == 0 {
+		if stockShortage {
+			writeError(w, r, StructureData.NewInsufficientStockError("Insufficient stock for the requested books"))
 			return
 		}
-
-		item.Book = book // Ensure all fields in the book are updated
-		validItems = append(validItems, item)
+		writeError(w, r, StructureData.NewValidationError("No valid books available to update the order"))
+		return
 	}
 
-	// If no valid items are present, return an error
-	if len(validItems) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "No valid books available to update the order"})
+	// Swap the old items' stock for the new items' in one step, so a refused update leaves the stock as it
+	// was. The moves are recorded once the order is updated.
+	change := newStockChange(StructureData.StockMovement{Type: StructureData.StockMovementOrderEdit, SourceType: StructureData.ResourceOrders, SourceID: id})
+	validItems, errResp := change.reallocate(previousItems, requestedItems, updatedOrder.AllocationStrategy, updatedOrder.CustomerSnapshot.Address.Country)
+	if errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
+	if skipped := len(requestedItems) - len(validItems); skipped > 0 {
+		log.Printf("Skipping %d items of order %d: Cannot allocate them", skipped, id)
+	}
 
 	// Update the order with valid items
 	updatedOrder.Items = validItems
 
 	// Update the order in the store
-	updatedOrder, errResp = orderStore.UpdateOrder(id, updatedOrder)
+	updatedOrder, errResp = orderStore.UpdateOrder(id, updatedOrder, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		change.undo()
+		writeError(w, r, errResp)
 		return
 	}
+	change.commit(r)
 
 	// Persist the updated order and books
-	if err := persistOrdersToFile(orderStore.GetAllOrders()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving order data"})
+	if err := persistOrdersToFile(orderStore); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving order data", err))
 		return
 	}
+	if len(updatedOrder.Payments) > len(existingOrder.Payments) {
+		if errResp := persistGiftCardData(); errResp != nil {
+			writeError(w, r, errResp)
+			return
+		}
+	}
 
-	if err := persistBooksToFile(bookStore.GetAllBooks()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving updated book data"})
+	if err := persistBooksToFile(bookStore); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving updated book data", err))
 		return
 	}
 
@@ -357,64 +429,26 @@ func UpdateOrder(w http.ResponseWriter, r *http.Request) {
 
 
 func DeleteOrder(w http.ResponseWriter, r *http.Request) {
-	orderStore := inmemoryStores.GetOrderStoreInstance()
-	bookStore := inmemoryStores.GetBookStoreInstance()
+	enforcer := inmemoryStores.GetIntegrityEnforcerInstance()
 
 	// Extract ID from the URL
 	idStr := r.URL.Path[len("/orders/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid order ID"})
+		writeError(w, r, StructureData.NewValidationError("Invalid order ID"))
 		return
 	}
 
-	// Retrieve the order to be deleted
-	order, errResp := orderStore.GetOrder(id)
+	// Delete the order and apply the relation policies to the records referencing it
+	affected, errResp := enforcer.Delete(StructureData.ResourceOrders, id, auditContext(r))
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
-	// Adjust the stock for the books in the order
-	for _, item := range order.Items {
-		book, bookErr := bookStore.GetBook(item.Book.ID)
-		if bookErr != nil {
-			log.Printf("Warning: Book with ID %d not found while deleting order %d", item.Book.ID, id)
-			continue
-		}
-
-		// Increase the stock by the quantity in the order
-		book.Stock += item.Quantity
-
-		// Update the book in the store
-		_, updateErr := bookStore.UpdateBook(book.ID, book)
-		if updateErr != nil {
-			log.Printf("Error updating stock for book ID %d: %s", book.ID, updateErr.Message)
-			continue
-		}
-	}
-
-	// Delete the order from the store
-	errResp = orderStore.DeleteOrder(id)
-	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
-		return
-	}
-
-	// Persist the updated orders to the JSON file
-	if err := persistOrdersToFile(orderStore.GetAllOrders()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving order data"})
-		return
-	}
-
-	// Persist the updated books to the JSON file
-	if err := persistBooksToFile(bookStore.GetAllBooks()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error saving book data"})
+	// Persist every resource touched by the delete
+	if errResp := persistResources(affected); errResp != nil {
+		writeError(w, r, errResp)
 		return
 	}
 
@@ -426,29 +460,37 @@ func DeleteOrder(w http.ResponseWriter, r *http.Request) {
 func SearchOrders(w http.ResponseWriter, r *http.Request) {
 	store := inmemoryStores.GetOrderStoreInstance()
 
+	// Parse the relations to inline
+	expand, errResp := parseExpand(r, orderExpansions)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
 	// Decode the search criteria from the request body
 	var criteria StructureData.OrderSearchCriteria
 	if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid search criteria"})
+		writeError(w, r, StructureData.NewValidationError("Invalid search criteria"))
 		return
 	}
 
 	// Perform the search
 	searchResults, errResp := store.SearchOrders(criteria)
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		writeError(w, r, errResp)
 		return
 	}
 
 	// Return the search results as JSON
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(searchResults)
+	json.NewEncoder(w).Encode(expandOrders(searchResults, expand))
 }
 
-// persistOrdersToFile saves all orders to the JSON file in a pretty JSON format
-func persistOrdersToFile(orders []StructureData.Order) error {
+// persistOrdersToFile saves all orders, including the trashed ones, to the JSON file in a pretty JSON format
+func persistOrdersToFile(store interfaces.OrderStore) error {
+	orders := append(store.GetAllOrders(), store.GetDeletedOrders()...)
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+
 	file, err := os.Create(orderFile)
 	if err != nil {
 		return err
@@ -466,225 +508,184 @@ func persistOrdersToFile(orders []StructureData.Order) error {
 	return nil
 }
 
-// generateSalesReport generates a sales report for the last 24 hours using a context.
-func GenerateSalesReport(ctx context.Context) {
-	store := inmemoryStores.GetOrderStoreInstance()
-
-	// Define the time range for the report
-	endTime := time.Now()
-	startTime := endTime.Add(-24 * time.Hour)
-
-	// Retrieve orders within the time range
-	orders, err := store.GetOrdersInTimeRange(startTime, endTime)
-	if err != nil {
-		log.Printf("Error fetching orders for sales report: %v\n", err)
-		return
+// resolveOrderCustomer finds the customer of an order by ID, falling back to the email of an embedded customer
+func resolveOrderCustomer(customerStore interfaces.CustomerStore, order StructureData.Order) (StructureData.Customer, *StructureData.ErrorResponse) {
+	customerID := order.CustomerID
+	if customerID == 0 && order.Customer != nil {
+		customerID = order.Customer.ID
 	}
-
-	// Ensure there are orders to process
-	if len(orders) == 0 {
-		log.Println("No orders found for the sales report generation.")
-
-		// Create an empty sales report with just the timestamp
-		report := StructureData.SalesReport{
-			Timestamp:       endTime,
-			TotalRevenue:    0,
-			TotalOrders:     0,
-			TopSellingBooks: []StructureData.TopSellingBook{},
-		}
-
-		// Save the empty sales report to the file
-		if err := SaveSalesReport(ctx, report); err != nil {
-			log.Printf("Error saving empty sales report: %v\n", err)
-		}
-
-		return
+	if customer, errResp := customerStore.GetCustomer(customerID); errResp == nil {
+		return customer, nil
 	}
 
-	// Calculate sales report details
-	var totalRevenue float64
-	var totalOrders int
-	bookSales := make(map[int]*StructureData.TopSellingBook) // book ID -> TopSellingBook
-
-	bookStore := inmemoryStores.GetBookStoreInstance()
-
-	for _, order := range orders {
-		select {
-		case <-ctx.Done(): // Check for cancellation
-			log.Println("GenerateSalesReport was canceled.")
-			return
-		default:
-		}
-
-		totalRevenue += order.TotalPrice
-		totalOrders++
-		for _, item := range order.Items {
-			select {
-			case <-ctx.Done(): // Check for cancellation
-				log.Println("GenerateSalesReport was canceled.")
-				return
-			default:
+	// If ID is not found, try validating by email
+	if order.Customer != nil && order.Customer.Email != "" {
+		for _, existingCustomer := range customerStore.GetAllCustomers() {
+			if existingCustomer.Email == order.Customer.Email {
+				return existingCustomer, nil
 			}
+		}
+	}
+	return StructureData.Customer{}, StructureData.NewValidationError("Customer does not exist")
+}
 
-			// Ensure book exists in the store
-			book, bookErr := bookStore.GetBook(item.Book.ID)
-			if bookErr != nil {
-				log.Printf("Skipping order ID %d: Book ID %d not found in the in-memory store. Verify book loading.", order.ID, item.Book.ID)
-				continue
-			}
+// snapshotCustomer records the customer details kept on an order
+func snapshotCustomer(customer StructureData.Customer) StructureData.CustomerSnapshot {
+	return StructureData.CustomerSnapshot{
+		Name:    customer.Name,
+		Email:   customer.Email,
+		Address: customer.Address,
+	}
+}
 
-			// Initialize if not exists
-			if _, exists := bookSales[book.ID]; !exists {
-				bookSales[book.ID] = &StructureData.TopSellingBook{
-					Book:         book,
-					QuantitySold: 0,
-				}
-			}
-			// Increment quantity sold
-			bookSales[book.ID].QuantitySold += item.Quantity
-		}
+// orderItemBookID returns the book ID of an order item, accepting an embedded book as input
+func orderItemBookID(item StructureData.OrderItem) int {
+	if item.BookID == 0 && item.Book != nil {
+		return item.Book.ID
 	}
+	return item.BookID
+}
 
-	// Convert bookSales map to slice for sorting
-	topSellingBooks := make([]StructureData.TopSellingBook, 0, len(bookSales))
-	for _, bookSale := range bookSales {
-		topSellingBooks = append(topSellingBooks, *bookSale)
+// GenerateSalesReport generates the daily sales report for the last 24 hours
+func GenerateSalesReport(ctx context.Context) {
+	if err := generateSalesReportAt(ctx, time.Now()); err != nil {
+		log.Printf("Error generating sales report: %v\n", err)
 	}
+}
 
-	// Sort Top-Selling Books by Revenue (Price * Quantity Sold)
-	sort.Slice(topSellingBooks, func(i, j int) bool {
-		revenueI := topSellingBooks[i].Book.Price * float64(topSellingBooks[i].QuantitySold)
-		revenueJ := topSellingBooks[j].Book.Price * float64(topSellingBooks[j].QuantitySold)
-		return revenueI > revenueJ
+// generateSalesReportAt generates the daily sales report ending at the given time: the revenue and orders
+// of the 24 hours before it and the top 5 books by revenue, computed by the reporting engine.
+func generateSalesReportAt(ctx context.Context, endTime time.Time) error {
+	result, errResp := runSalesReportQuery(ctx, StructureData.SalesReportQuery{
+		From:       endTime.Add(-24 * time.Hour),
+		To:         endTime,
+		Dimensions: []StructureData.ReportDimension{StructureData.ReportByBook},
+		Metrics:    []StructureData.ReportMetric{StructureData.MetricRevenue, StructureData.MetricUnits, StructureData.MetricOrders},
+		SortBy:     StructureData.MetricRevenue,
 	})
+	if errResp != nil {
+		return errResp
+	}
+	if len(result.Rows) == 0 {
+		log.Println("No orders found for the sales report generation.")
+	}
 
-	// Limit to Top 5 Books
-	if len(topSellingBooks) > 5 {
-		topSellingBooks = topSellingBooks[:5]
+	// Keep the top 5 books still in the store
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	topSellingBooks := []StructureData.TopSellingBook{}
+	for _, row := range result.Rows {
+		if len(topSellingBooks) == 5 {
+			break
+		}
+		if row.Metrics[StructureData.MetricUnits] <= 0 {
+			continue // More copies were returned than sold
+		}
+		bookID, _ := strconv.Atoi(row.Group[0].Value)
+		book, bookErr := bookStore.GetBook(bookID)
+		if bookErr != nil {
+			log.Printf("Skipping book ID %d: not found in the in-memory store.", bookID)
+			continue
+		}
+		topSellingBooks = append(topSellingBooks, StructureData.TopSellingBook{
+			Book:         book,
+			QuantitySold: int(row.Metrics[StructureData.MetricUnits]),
+		})
 	}
 
 	// Create the sales report
 	report := StructureData.SalesReport{
 		Timestamp:       endTime,
-		TotalRevenue:    totalRevenue,
-		TotalOrders:     totalOrders,
+		TotalRevenue:    result.Totals[StructureData.MetricRevenue],
+		TotalOrders:     int(result.Totals[StructureData.MetricOrders]),
 		TopSellingBooks: topSellingBooks,
 	}
 
 	// Save the sales report to the file
-	if err := SaveSalesReport(ctx, report); err != nil {
-		log.Printf("Error saving sales report: %v\n", err)
-	}
+	return SaveSalesReport(ctx, report)
 }
 
-// SaveSalesReport saves the sales report to the sales_reports.json file with a pretty JSON format
-func SaveSalesReport(ctx context.Context, report StructureData.SalesReport) error {
-	var reports []StructureData.SalesReport
+// salesReportStore holds the sales report history, saved to the sales_reports.json file once
+// InitializeSalesReportFile is called
+var salesReportStore interfaces.SalesReportStore = inmemoryStores.GetSalesReportStoreInstance()
 
-	// Load existing reports
-	if _, err := os.Stat(salesReportFile); !os.IsNotExist(err) {
-		file, err := os.Open(salesReportFile)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		if err := json.NewDecoder(file).Decode(&reports); err != nil {
-			return err
-		}
-	}
-
-	select {
-	case <-ctx.Done(): // Check for cancellation
-		return ctx.Err()
-	default:
-	}
-
-	// Append the new report and save it
-	reports = append(reports, report)
-	file, err := os.Create(salesReportFile)
+// InitializeSalesReportFile loads the sales report history from the JSON file. Every change of the history
+// is saved to the file from then on.
+func InitializeSalesReportFile() {
+	fileStore, err := inmemoryStores.NewFileSalesReportStore(salesReportFile, inmemoryStores.GetSalesReportStoreInstance())
 	if err != nil {
-		return err
+		panic("Failed to decode sales report file")
 	}
-	defer file.Close()
-
-	// Use a pretty JSON encoder
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ") // Add indentation for better readability
+	salesReportStore = fileStore
+	log.Printf("%d sales reports loaded into store", len(salesReportStore.GetAllReports()))
+}
 
+// SaveSalesReport adds the sales report to the sales report history
+func SaveSalesReport(ctx context.Context, report StructureData.SalesReport) error {
 	select {
 	case <-ctx.Done(): // Check for cancellation
 		return ctx.Err()
 	default:
 	}
 
-	if err := encoder.Encode(reports); err != nil {
-		return err
+	if _, errResp := salesReportStore.SaveReport(report); errResp != nil {
+		return errResp
 	}
 
 	log.Println("Sales report saved successfully.")
 	return nil
 }
 
+// GetSalesReport handles the GET /reports/sales request, optionally filtered by start_date and end_date.
+// The reports can be exported in the formats of negotiateExportFormat.
 func GetSalesReport(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	// Load sales reports from the file
-	var reports []StructureData.SalesReport
-	if _, err := os.Stat(salesReportFile); os.IsNotExist(err) {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "No sales reports available"})
-		return
-	}
-	file, err := os.Open(salesReportFile)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error loading sales reports file"})
-		return
-	}
-	defer file.Close()
-	if err := json.NewDecoder(file).Decode(&reports); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error decoding sales reports data"})
-		return
-	}
-
 	select {
 	case <-ctx.Done(): // Check for cancellation
-		w.WriteHeader(http.StatusRequestTimeout)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Request canceled by client"})
+		writeError(w, r, StructureData.NewCanceledError("Request canceled by client", ctx.Err()))
 		return
 	default:
 	}
 
-	// Extract query parameters
-	startDateStr := r.URL.Query().Get("start_date")
-	endDateStr := r.URL.Query().Get("end_date")
-
-	var filteredReports []StructureData.SalesReport
+	// Choose the response format
+	format, errResp := negotiateExportFormat(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
 
-	// Parse the dates if provided
-	if startDateStr != "" && endDateStr != "" {
+	// Build the search criteria from the query parameters
+	var criteria StructureData.SalesReportSearchCriteria
+	fileName := "sales-reports"
+	var notes []string
+	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
 		startDate, err := time.Parse("2006-01-02", startDateStr)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid start_date format. Use YYYY-MM-DD."})
+			writeError(w, r, StructureData.NewValidationError("Invalid start_date format. Use YYYY-MM-DD."))
 			return
 		}
-
+		criteria.MinTimestamp = startDate
+		fileName += "-from-" + startDateStr
+		notes = append(notes, "From "+startDateStr)
+	}
+	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
 		endDate, err := time.Parse("2006-01-02", endDateStr)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid end_date format. Use YYYY-MM-DD."})
+			writeError(w, r, StructureData.NewValidationError("Invalid end_date format. Use YYYY-MM-DD."))
 			return
 		}
+		criteria.MaxTimestamp = endDate.Add(24*time.Hour - time.Nanosecond) // The whole end day
+		fileName += "-to-" + endDateStr
+		notes = append(notes, "To "+endDateStr)
+	}
 
-		// Filter the reports by date range
-		for _, report := range reports {
-			if report.Timestamp.After(startDate) && report.Timestamp.Before(endDate.Add(24*time.Hour)) {
-				filteredReports = append(filteredReports, report)
-			}
-		}
-	} else {
-		// If no dates provided, return all reports
-		filteredReports = reports
+	// Filter the reports by date range
+	filteredReports, errResp := salesReportStore.SearchReports(criteria)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	if format != StructureData.ExportJSON {
+		writeExport(w, r, http.StatusOK, format, salesReportsExport(filteredReports, format, fileName, notes))
+		return
 	}
 
 	// Use a pretty JSON encoder for the response
@@ -693,8 +694,62 @@ func GetSalesReport(ctx context.Context, w http.ResponseWriter, r *http.Request)
 	encoder.SetIndent("", "  ") // Add indentation for better readability
 
 	if err := encoder.Encode(filteredReports); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error encoding response data"})
+		writeError(w, r, StructureData.NewInternalError("Error encoding response data", err))
 		return
 	}
 }
+
+// SearchSalesReports handles the POST /reports/sales/search request. The results can be exported in the
+// formats of negotiateExportFormat.
+func SearchSalesReports(w http.ResponseWriter, r *http.Request) {
+	// Choose the response format
+	format, errResp := negotiateExportFormat(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Decode the search criteria from the request body
+	var criteria StructureData.SalesReportSearchCriteria
+	if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid search criteria"))
+		return
+	}
+
+	// Perform the search
+	searchResults, errResp := salesReportStore.SearchReports(criteria)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	if format != StructureData.ExportJSON {
+		writeExport(w, r, http.StatusOK, format, salesReportsExport(searchResults, format, "sales-reports-search", nil))
+		return
+	}
+
+	// Return the search results as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(searchResults)
+}
+
+// MaintainSalesReports merges the sales reports older than compactAfter into one report per month and
+// deletes those older than retention. A zero duration disables the step.
+func MaintainSalesReports(compactAfter, retention time.Duration) {
+	now := time.Now()
+	if retention > 0 {
+		deleted, errResp := salesReportStore.DeleteReportsBefore(now.Add(-retention))
+		if errResp != nil {
+			log.Printf("Failed to delete old sales reports: %v", errResp)
+		} else if deleted > 0 {
+			log.Printf("Deleted %d sales reports older than %s", deleted, retention)
+		}
+	}
+	if compactAfter > 0 {
+		merged, errResp := salesReportStore.CompactReportsBefore(now.Add(-compactAfter))
+		if errResp != nil {
+			log.Printf("Failed to compact old sales reports: %v", errResp)
+		} else if merged > 0 {
+			log.Printf("Compacted %d sales reports older than %s into monthly reports", merged, compactAfter)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file path for purchase order persistence
+var purchaseOrderFile = "purchase_orders.json"
+
+// InitializePurchaseOrderFile loads the purchase orders from the JSON file into the in-memory store
+func InitializePurchaseOrderFile() {
+	var orders []StructureData.PurchaseOrder
+	if err := readJSONFile(purchaseOrderFile, &orders); err != nil {
+		panic("Failed to decode purchase order file")
+	}
+
+	store := inmemoryStores.GetPurchaseOrderStoreInstance()
+	for _, order := range orders {
+		store.AddPurchaseOrderDirectly(order)
+	}
+}
+
+// GetAllPurchaseOrders handles the GET /purchase-orders request, filtered by the optional and
+// repeatable query parameters supplier_id, status and book_id
+func GetAllPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetPurchaseOrderStoreInstance()
+	query := r.URL.Query()
+
+	// Build the search criteria from the query parameters
+	var criteria StructureData.PurchaseOrderSearchCriteria
+	for param, ids := range map[string]*[]int{"supplier_id": &criteria.SupplierIDs, "book_id": &criteria.BookIDs} {
+		for _, idStr := range query[param] {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				writeError(w, r, StructureData.NewValidationError("Invalid "+param))
+				return
+			}
+			*ids = append(*ids, id)
+		}
+	}
+	for _, status := range query["status"] {
+		criteria.Statuses = append(criteria.Statuses, StructureData.PurchaseOrderStatus(status))
+	}
+
+	// Return the search results as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.SearchPurchaseOrders(criteria))
+}
+
+// GetPurchaseOrderByID handles the GET /purchase-orders/{id} request
+func GetPurchaseOrderByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetPurchaseOrderStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/purchase-orders/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid purchase order ID"))
+		return
+	}
+
+	// Retrieve the purchase order by ID
+	order, errResp := store.GetPurchaseOrder(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// CreatePurchaseOrder handles the POST /purchase-orders request. New purchase orders are drafts.
+func CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetPurchaseOrderStoreInstance()
+
+	// Decode the request body
+	var order StructureData.PurchaseOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the supplier, warehouse and lines
+	if errResp := validatePurchaseOrder(&order); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Create the purchase order in the store
+	createdOrder, errResp := store.CreatePurchaseOrder(order)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourcePurchaseOrders, createdOrder.ID, StructureData.AuditCreate, nil, createdOrder)
+
+	// Persist to JSON file
+	if err := persistPurchaseOrdersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created purchase order
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdOrder)
+}
+
+// UpdatePurchaseOrder handles the PUT /purchase-orders/{id} request. Only drafts can be edited.
+func UpdatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetPurchaseOrderStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/purchase-orders/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid purchase order ID"))
+		return
+	}
+
+	// Decode the request body
+	var order StructureData.PurchaseOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the supplier, warehouse and lines
+	if errResp := validatePurchaseOrder(&order); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Update the purchase order in the store, keeping the previous version for the audit log
+	previous, _ := store.GetPurchaseOrder(id)
+	updatedOrder, errResp := store.UpdatePurchaseOrder(id, order)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourcePurchaseOrders, id, StructureData.AuditUpdate, previous, updatedOrder)
+
+	// Persist to JSON file
+	if err := persistPurchaseOrdersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated purchase order
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedOrder)
+}
+
+// DeletePurchaseOrder handles the DELETE /purchase-orders/{id} request. Only drafts can be deleted.
+func DeletePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetPurchaseOrderStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/purchase-orders/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid purchase order ID"))
+		return
+	}
+
+	// Delete the purchase order from the store
+	previous, _ := store.GetPurchaseOrder(id)
+	if errResp := store.DeletePurchaseOrder(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourcePurchaseOrders, id, StructureData.AuditDelete, previous, nil)
+
+	// Persist to JSON file
+	if err := persistPurchaseOrdersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return success response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendPurchaseOrder handles the POST /purchase-orders/{id}/send request, marking a draft as sent to the supplier
+func SendPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetPurchaseOrderStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/purchase-orders/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid purchase order ID"))
+		return
+	}
+
+	// Mark the purchase order as sent
+	previous, _ := store.GetPurchaseOrder(id)
+	sentOrder, errResp := store.SendPurchaseOrder(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourcePurchaseOrders, id, StructureData.AuditUpdate, previous, sentOrder)
+
+	// Persist to JSON file
+	if err := persistPurchaseOrdersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the sent purchase order
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sentOrder)
+}
+
+// ReceiveGoods handles the POST /purchase-orders/{id}/receive request. The body lists the quantities
+// actually delivered per book; each one is added to the book's stock and its unit cost becomes the
+// book's cost price.
+func ReceiveGoods(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetPurchaseOrderStoreInstance()
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	warehouseStore := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/purchase-orders/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid purchase order ID"))
+		return
+	}
+
+	// Decode the request body
+	var receipt StructureData.GoodsReceipt
+	if err := json.NewDecoder(r.Body).Decode(&receipt); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// The books must still exist to take stock
+	for _, line := range receipt.Lines {
+		if _, errResp := bookStore.GetBook(line.BookID); errResp != nil {
+			writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Book %d does not exist", line.BookID)))
+			return
+		}
+	}
+
+	// Record the receipt against the purchase order
+	receipt.ReceivedBy = auditContext(r).Actor
+	previous, _ := store.GetPurchaseOrder(id)
+	receivedOrder, recorded, errResp := store.ReceiveGoods(id, receipt)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourcePurchaseOrders, id, StructureData.AuditUpdate, previous, receivedOrder)
+
+	// Purchase orders created before warehouses existed are delivered to the default one
+	warehouseID := receivedOrder.WarehouseID
+	if warehouseID == 0 {
+		defaultWarehouse, errResp := warehouseStore.DefaultWarehouse()
+		if errResp != nil {
+			writeError(w, r, errResp)
+			return
+		}
+		warehouseID = defaultWarehouse.ID
+	}
+
+	// Take the delivered quantities into stock at the receiving warehouse, at the invoiced cost
+	change := newStockChange(StructureData.StockMovement{
+		Type:       StructureData.StockMovementReceipt,
+		SourceType: StructureData.ResourcePurchaseOrders,
+		SourceID:   id,
+		Note:       fmt.Sprintf("Receipt %d", recorded.ID),
+	})
+	for _, line := range recorded.Lines {
+		if errResp := change.adjust(line.BookID, warehouseID, line.Quantity); errResp != nil {
+			change.commit(r)
+			writeError(w, r, errResp)
+			return
+		}
+	}
+	change.commit(r)
+	for _, line := range recorded.Lines {
+		if _, errResp := bookStore.SetCostPrice(line.BookID, line.UnitCost, auditContext(r)); errResp != nil {
+			writeError(w, r, errResp)
+			return
+		}
+	}
+
+	// Persist to JSON files
+	if err := persistBooksToFile(bookStore); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+	if err := persistPurchaseOrdersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated purchase order
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receivedOrder)
+}
+
+// validatePurchaseOrder checks that a purchase order names an existing supplier and warehouse, and orders
+// each existing book once, in a positive quantity at a non-negative unit cost. Orders without a warehouse
+// are delivered to the default one.
+func validatePurchaseOrder(order *StructureData.PurchaseOrder) *StructureData.ErrorResponse {
+	if _, errResp := inmemoryStores.GetSupplierStoreInstance().GetSupplier(order.SupplierID); errResp != nil {
+		return StructureData.NewValidationError(fmt.Sprintf("Supplier %d does not exist", order.SupplierID))
+	}
+	warehouseStore := inmemoryStores.GetWarehouseStoreInstance()
+	if order.WarehouseID == 0 {
+		defaultWarehouse, errResp := warehouseStore.DefaultWarehouse()
+		if errResp != nil {
+			return errResp
+		}
+		order.WarehouseID = defaultWarehouse.ID
+	}
+	if _, errResp := warehouseStore.GetWarehouse(order.WarehouseID); errResp != nil {
+		return StructureData.NewValidationError(fmt.Sprintf("Warehouse %d does not exist", order.WarehouseID))
+	}
+	if len(order.Lines) == 0 {
+		return StructureData.NewValidationError("A purchase order needs at least one line")
+	}
+
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	seen := map[int]bool{}
+	for _, line := range order.Lines {
+		if _, errResp := bookStore.GetBook(line.BookID); errResp != nil {
+			return StructureData.NewValidationError(fmt.Sprintf("Book %d does not exist", line.BookID))
+		}
+		if seen[line.BookID] {
+			return StructureData.NewValidationError(fmt.Sprintf("Book %d appears on more than one line", line.BookID))
+		}
+		seen[line.BookID] = true
+		if line.QuantityOrdered < 1 {
+			return StructureData.NewValidationError("quantity_ordered must be at least 1")
+		}
+		if line.UnitCost < 0 {
+			return StructureData.NewValidationError("unit_cost cannot be negative")
+		}
+	}
+	return nil
+}
+
+// persistPurchaseOrdersToFile saves all purchase orders to the JSON file in a pretty JSON format
+func persistPurchaseOrdersToFile() error {
+	file, err := os.Create(purchaseOrderFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetPurchaseOrderStoreInstance().GetAllPurchaseOrders())
+}
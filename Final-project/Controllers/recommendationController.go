@@ -0,0 +1,87 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// Bounds of the number of recommendations
+const (
+	defaultRecommendationLimit = 10
+	maxRecommendationLimit     = 50
+)
+
+// StartRecommendationEngine counts the books bought together in the order history and follows later orders
+func StartRecommendationEngine() {
+	inmemoryStores.GetRecommendationEngineInstance().Start()
+}
+
+// GetRelatedBooks handles the GET /books/{id}/related request. It returns the books frequently bought
+// together with the book, then books by the same author or of the same genres.
+func GetRelatedBooks(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	idStr := r.URL.Path[len("/books/"):]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
+		return
+	}
+	limit, errResp := recommendationLimit(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	book, errResp := inmemoryStores.GetBookStoreInstance().GetBook(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inmemoryStores.GetRecommendationEngineInstance().Related(book, limit))
+}
+
+// GetCustomerRecommendations handles the GET /customers/{id}/recommendations request. Books the customer
+// bought and books out of stock are never recommended.
+func GetCustomerRecommendations(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	idStr := r.URL.Path[len("/customers/"):]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+	limit, errResp := recommendationLimit(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	if _, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inmemoryStores.GetRecommendationEngineInstance().Recommend(id, limit))
+}
+
+// recommendationLimit reads the limit query parameter
+func recommendationLimit(r *http.Request) (int, *StructureData.ErrorResponse) {
+	limit, errResp := positiveQueryInt(r, "limit", defaultRecommendationLimit)
+	if errResp != nil {
+		return 0, errResp
+	}
+	if limit > maxRecommendationLimit {
+		return 0, StructureData.NewValidationError(fmt.Sprintf("limit cannot be more than %d", maxRecommendationLimit))
+	}
+	return limit, nil
+}
@@ -0,0 +1,139 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// JSON file path for relation policy persistence
+var relationFile = "relations.json"
+
+// InitializeRelationFile loads the configured relation policies, writing the defaults if the file is missing
+func InitializeRelationFile() {
+	store := inmemoryStores.GetRelationStoreInstance()
+
+	if _, err := os.Stat(relationFile); os.IsNotExist(err) {
+		if err := persistRelationsToFile(store.GetAllRelations()); err != nil {
+			log.Printf("Failed to create relation file: %v", err)
+		}
+		return
+	}
+
+	file, err := os.Open(relationFile)
+	if err != nil {
+		panic("Failed to open relation file")
+	}
+	defer file.Close()
+
+	var relations []StructureData.Relation
+	if err := json.NewDecoder(file).Decode(&relations); err != nil {
+		panic("Failed to decode relation file")
+	}
+
+	for _, relation := range relations {
+		if _, errResp := store.SetPolicy(relation.Name, relation.Policy); errResp != nil {
+			log.Printf("Ignoring relation %q from %s: %s", relation.Name, relationFile, errResp.Message)
+		}
+	}
+}
+
+// GetAllRelations handles the GET /relations request
+func GetAllRelations(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetRelationStoreInstance()
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetAllRelations())
+}
+
+// UpdateRelation handles the PUT /relations/{name} request, changing the policy of a relation
+func UpdateRelation(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetRelationStoreInstance()
+
+	// Extract the name from the URL
+	name := r.URL.Path[len("/relations/"):]
+
+	// Decode the request body
+	var body struct {
+		Policy StructureData.RelationPolicy `json:"policy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Update the policy in the store
+	relation, errResp := store.SetPolicy(name, body.Policy)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistRelationsToFile(store.GetAllRelations()); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated relation
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(relation)
+}
+
+// persistRelationsToFile saves all relation policies to the JSON file in a pretty JSON format
+func persistRelationsToFile(relations []StructureData.Relation) error {
+	file, err := os.Create(relationFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(relations)
+}
+
+// persistResources saves the JSON files of every resource touched by an integrity-enforced delete, restore or purge
+func persistResources(resources []string) *StructureData.ErrorResponse {
+	for _, resource := range resources {
+		var err error
+		switch resource {
+		case StructureData.ResourceAuthors:
+			if errResp := persistAuthorsToFile(inmemoryStores.GetAuthorStoreInstance()); errResp != nil {
+				return errResp
+			}
+		case StructureData.ResourceBooks:
+			err = persistBooksToFile(inmemoryStores.GetBookStoreInstance())
+		case StructureData.ResourceCustomers:
+			err = persistCustomersToFile(inmemoryStores.GetCustomerStoreInstance())
+		case StructureData.ResourceOrders:
+			err = persistOrdersToFile(inmemoryStores.GetOrderStoreInstance())
+		case StructureData.ResourceStoreCredit:
+			if errResp := persistGiftCardData(); errResp != nil {
+				return errResp
+			}
+		}
+		if err != nil {
+			return StructureData.NewInternalError("Error saving "+resource+" data", err)
+		}
+	}
+
+	// Stock changed by the enforcer is recorded in the stock ledger and the warehouses
+	if utils.ContainsString(resources, StructureData.ResourceBooks) {
+		if err := persistStockMovementsToFile(); err != nil {
+			return StructureData.NewInternalError("Error saving stock movement data", err)
+		}
+		if err := persistStockLevelsToFile(); err != nil {
+			return StructureData.NewInternalError("Error saving stock level data", err)
+		}
+	}
+	return nil
+}
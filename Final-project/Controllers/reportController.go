@@ -0,0 +1,481 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// JSON file path for named report persistence
+var namedReportFile = "named_reports.json"
+
+// InitializeNamedReportFile loads the named reports from the JSON file into the in-memory store
+func InitializeNamedReportFile() {
+	var reports []StructureData.SalesReportResult
+	if err := readJSONFile(namedReportFile, &reports); err != nil {
+		panic("Failed to decode named report file")
+	}
+
+	store := inmemoryStores.GetNamedReportStoreInstance()
+	for _, report := range reports {
+		store.AddReportDirectly(report)
+	}
+	log.Printf("%d named reports loaded into store", len(reports))
+}
+
+// GenerateSalesReportOnDemand handles the POST /reports/sales/generate request. Without a body it adds the
+// daily sales report to the sales report history. With a query body it runs the query and returns the
+// report, in the formats of negotiateExportFormat; a query with a name is also saved as a named report.
+func GenerateSalesReportOnDemand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Decode the query, if any
+	var query StructureData.SalesReportQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); errors.Is(err, io.EOF) {
+		GenerateSalesReport(ctx)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Sales report generated successfully"))
+		return
+	} else if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Choose the response format
+	format, errResp := negotiateExportFormat(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Run the query
+	report, errResp := runSalesReportQuery(ctx, query)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	report.GeneratedBy = auditContext(r).Actor
+
+	// Return unnamed reports directly
+	if strings.TrimSpace(query.Name) == "" {
+		writeSalesReportResult(w, r, http.StatusOK, format, report)
+		return
+	}
+
+	// Save the named report
+	report.Name = strings.TrimSpace(query.Name)
+	savedReport, errResp := inmemoryStores.GetNamedReportStoreInstance().SaveReport(report)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistNamedReportsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the saved report
+	writeSalesReportResult(w, r, http.StatusCreated, format, savedReport)
+}
+
+// GetNamedReports handles the GET /reports/named request
+func GetNamedReports(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetNamedReportStoreInstance()
+
+	// Return all named reports as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetAllReports())
+}
+
+// GetNamedReportByID handles the GET /reports/named/{id} request. The report can be exported in the formats
+// of negotiateExportFormat.
+func GetNamedReportByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetNamedReportStoreInstance()
+
+	// Choose the response format
+	format, errResp := negotiateExportFormat(r)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/reports/named/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid report ID"))
+		return
+	}
+
+	// Retrieve the report by ID
+	report, errResp := store.GetReport(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	writeSalesReportResult(w, r, http.StatusOK, format, report)
+}
+
+// writeSalesReportResult writes a report of the reporting engine as JSON or exports it
+func writeSalesReportResult(w http.ResponseWriter, r *http.Request, status int, format StructureData.ExportFormat, report StructureData.SalesReportResult) {
+	if format != StructureData.ExportJSON {
+		writeExport(w, r, status, format, salesReportResultExport(report))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// DeleteNamedReport handles the DELETE /reports/named/{id} request
+func DeleteNamedReport(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetNamedReportStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/reports/named/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid report ID"))
+		return
+	}
+
+	// Delete the report
+	if errResp := store.DeleteReport(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistNamedReportsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// salesBucket accumulates the sales of one group of a report
+type salesBucket struct {
+	group   []StructureData.ReportGroupKey
+	revenue float64
+	units   int
+	orders  map[int]bool
+}
+
+// runSalesReportQuery computes a sales report from the orders created in the query's time range. Revenue
+// and units come from the order lines at the price they were sold for, less the goods received back from
+// returns in the range, and the books, authors and genres are the ones recorded on the order when it was
+// placed.
+func runSalesReportQuery(ctx context.Context, query StructureData.SalesReportQuery) (StructureData.SalesReportResult, *StructureData.ErrorResponse) {
+	location, errResp := validateSalesReportQuery(&query)
+	if errResp != nil {
+		return StructureData.SalesReportResult{}, errResp
+	}
+
+	// Retrieve orders within the time range
+	orders, err := inmemoryStores.GetOrderStoreInstance().GetOrdersInTimeRange(query.From, query.To)
+	if err != nil {
+		return StructureData.SalesReportResult{}, StructureData.NewInternalError("Error fetching orders", err)
+	}
+
+	// Add every order line to the groups it belongs to. Returned goods are added as lines with negative
+	// quantities, which do not count as orders.
+	buckets := map[string]*salesBucket{}
+	total := salesBucket{orders: map[int]bool{}}
+	authorNames := map[int]string{}
+	addLine := func(order StructureData.Order, item StructureData.OrderItem, sale bool) {
+		revenue := item.UnitPrice * float64(item.Quantity)
+		total.revenue += revenue
+		total.units += item.Quantity
+		if sale {
+			total.orders[order.ID] = true
+		}
+
+		for _, group := range reportGroups(query.Dimensions, order, item, location, authorNames) {
+			key := reportGroupID(group)
+			bucket, exists := buckets[key]
+			if !exists {
+				bucket = &salesBucket{group: group, orders: map[int]bool{}}
+				buckets[key] = bucket
+			}
+			bucket.revenue += revenue
+			bucket.units += item.Quantity
+			if sale {
+				bucket.orders[order.ID] = true
+			}
+		}
+	}
+	for _, order := range orders {
+		select {
+		case <-ctx.Done(): // Check for cancellation
+			return StructureData.SalesReportResult{}, StructureData.NewCanceledError("Report was canceled", ctx.Err())
+		default:
+		}
+
+		for _, item := range order.Items {
+			addLine(order, item, true)
+		}
+	}
+	for _, ret := range inmemoryStores.GetReturnStoreInstance().GetAllReturns() {
+		order, errResp := inmemoryStores.GetOrderStoreInstance().GetOrder(ret.OrderID)
+		if errResp != nil {
+			continue
+		}
+		for _, receipt := range ret.Receipts {
+			if !receipt.ReceivedAt.After(query.From) || !receipt.ReceivedAt.Before(query.To) {
+				continue
+			}
+
+			// Returns fall in the day, week and month they were received
+			returned := order
+			returned.CreatedAt = receipt.ReceivedAt
+			for _, line := range receipt.Lines {
+				for _, returnLine := range ret.Lines {
+					if returnLine.BookID == line.BookID {
+						addLine(returned, StructureData.OrderItem{
+							BookID:    line.BookID,
+							Quantity:  -line.Quantity,
+							UnitPrice: returnLine.UnitPrice,
+							Snapshot:  returnLine.Snapshot,
+						}, false)
+					}
+				}
+			}
+		}
+	}
+
+	// Compute the metrics of every group
+	report := StructureData.SalesReportResult{
+		Query:       query,
+		GeneratedAt: time.Now(),
+		Rows:        []StructureData.SalesReportRow{},
+		Totals:      bucketMetrics(total, query.Metrics),
+	}
+	type rankedRow struct {
+		row   StructureData.SalesReportRow
+		value float64 // Value of the sort metric
+	}
+	var ranked []rankedRow
+	for _, bucket := range buckets {
+		ranked = append(ranked, rankedRow{
+			row:   StructureData.SalesReportRow{Group: bucket.group, Metrics: bucketMetrics(*bucket, query.Metrics)},
+			value: bucketMetrics(*bucket, []StructureData.ReportMetric{query.SortBy})[query.SortBy],
+		})
+	}
+
+	// Sort by the metric, highest first, then by group
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].value != ranked[j].value {
+			return ranked[i].value > ranked[j].value
+		}
+		return lessReportGroup(ranked[i].row.Group, ranked[j].row.Group)
+	})
+	if query.Limit > 0 && len(ranked) > query.Limit {
+		ranked = ranked[:query.Limit]
+	}
+	for _, entry := range ranked {
+		report.Rows = append(report.Rows, entry.row)
+	}
+	return report, nil
+}
+
+// validateSalesReportQuery checks a query and fills in its defaults: the last 24 hours, every metric and
+// UTC. It returns the time zone of the query.
+func validateSalesReportQuery(query *StructureData.SalesReportQuery) (*time.Location, *StructureData.ErrorResponse) {
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-24 * time.Hour)
+	}
+	if !query.From.Before(query.To) {
+		return nil, StructureData.NewValidationError("from must be before to")
+	}
+
+	if query.TimeZone == "" {
+		query.TimeZone = "UTC"
+	}
+	location, err := time.LoadLocation(query.TimeZone)
+	if err != nil {
+		return nil, StructureData.NewValidationError(fmt.Sprintf("Unknown time zone %q", query.TimeZone))
+	}
+
+	seen := map[StructureData.ReportDimension]bool{}
+	for _, dimension := range query.Dimensions {
+		if !StructureData.IsValidReportDimension(dimension) {
+			return nil, StructureData.NewValidationError(fmt.Sprintf("Unknown dimension %q; use one of %v", dimension, StructureData.ReportDimensions))
+		}
+		if seen[dimension] {
+			return nil, StructureData.NewValidationError(fmt.Sprintf("Dimension %q is given twice", dimension))
+		}
+		seen[dimension] = true
+	}
+
+	if len(query.Metrics) == 0 {
+		query.Metrics = StructureData.ReportMetrics
+	}
+	for _, metric := range append(query.Metrics, query.SortBy) {
+		if metric != "" && !StructureData.IsValidReportMetric(metric) {
+			return nil, StructureData.NewValidationError(fmt.Sprintf("Unknown metric %q; use one of %v", metric, StructureData.ReportMetrics))
+		}
+	}
+
+	if query.Limit < 0 {
+		return nil, StructureData.NewValidationError("limit cannot be negative")
+	}
+	return location, nil
+}
+
+// reportGroups returns the groups an order line belongs to: one per combination of the values of the
+// dimensions, as a book with several genres belongs to each of them
+func reportGroups(dimensions []StructureData.ReportDimension, order StructureData.Order, item StructureData.OrderItem, location *time.Location, authorNames map[int]string) [][]StructureData.ReportGroupKey {
+	groups := [][]StructureData.ReportGroupKey{{}}
+	for _, dimension := range dimensions {
+		var keys []StructureData.ReportGroupKey
+		switch dimension {
+		case StructureData.ReportByBook:
+			title := item.Snapshot.Title
+			if title == "" {
+				title = fmt.Sprintf("Book %d", item.BookID)
+			}
+			keys = append(keys, StructureData.ReportGroupKey{Value: strconv.Itoa(item.BookID), Label: title})
+		case StructureData.ReportByAuthor:
+			authorID := item.Snapshot.AuthorID
+			if _, known := authorNames[authorID]; !known {
+				authorNames[authorID] = fmt.Sprintf("Author %d", authorID)
+				if author, errResp := inmemoryStores.GetAuthorStoreInstance().GetAuthor(authorID); errResp == nil {
+					authorNames[authorID] = strings.TrimSpace(author.FirstName + " " + author.LastName)
+				}
+			}
+			keys = append(keys, StructureData.ReportGroupKey{Value: strconv.Itoa(authorID), Label: authorNames[authorID]})
+		case StructureData.ReportByGenre:
+			for _, genre := range item.Snapshot.Genres {
+				keys = append(keys, StructureData.ReportGroupKey{Value: genre, Label: genre})
+			}
+			if len(keys) == 0 {
+				keys = append(keys, StructureData.ReportGroupKey{Value: "", Label: "(none)"})
+			}
+		case StructureData.ReportByCustomer:
+			keys = append(keys, StructureData.ReportGroupKey{Value: strconv.Itoa(order.CustomerID), Label: order.CustomerSnapshot.Name})
+		case StructureData.ReportByCountry:
+			country := order.CustomerSnapshot.Address.Country
+			if code, ok := utils.CountryCode(country); ok {
+				country = code // Orders placed before addresses were normalized may name the country
+			}
+			label := country
+			if label == "" {
+				label = "(unknown)"
+			}
+			keys = append(keys, StructureData.ReportGroupKey{Value: country, Label: label})
+		case StructureData.ReportByDay, StructureData.ReportByWeek, StructureData.ReportByMonth:
+			period := reportPeriod(dimension, order.CreatedAt.In(location))
+			keys = append(keys, StructureData.ReportGroupKey{Value: period, Label: period})
+		}
+
+		// Combine the values with the groups of the previous dimensions
+		var combined [][]StructureData.ReportGroupKey
+		for _, group := range groups {
+			for _, key := range keys {
+				key.Dimension = dimension
+				extended := append(append([]StructureData.ReportGroupKey{}, group...), key)
+				combined = append(combined, extended)
+			}
+		}
+		groups = combined
+	}
+	return groups
+}
+
+// reportPeriod formats the day, ISO week or month a time falls in
+func reportPeriod(dimension StructureData.ReportDimension, t time.Time) string {
+	switch dimension {
+	case StructureData.ReportByWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case StructureData.ReportByMonth:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// reportGroupID identifies a group by the values of its dimensions
+func reportGroupID(group []StructureData.ReportGroupKey) string {
+	values := make([]string, len(group))
+	for i, key := range group {
+		values[i] = key.Value
+	}
+	return strings.Join(values, "\x00")
+}
+
+// lessReportGroup orders groups dimension by dimension, numerically for IDs
+func lessReportGroup(a, b []StructureData.ReportGroupKey) bool {
+	for i := range a {
+		if a[i].Value == b[i].Value {
+			continue
+		}
+		numberA, errA := strconv.Atoi(a[i].Value)
+		numberB, errB := strconv.Atoi(b[i].Value)
+		if errA == nil && errB == nil {
+			return numberA < numberB
+		}
+		return a[i].Value < b[i].Value
+	}
+	return false
+}
+
+// bucketMetrics computes the requested metrics of a group, rounding amounts to the cent
+func bucketMetrics(bucket salesBucket, metrics []StructureData.ReportMetric) map[StructureData.ReportMetric]float64 {
+	values := map[StructureData.ReportMetric]float64{}
+	for _, metric := range metrics {
+		switch metric {
+		case StructureData.MetricRevenue:
+			values[metric] = roundCents(bucket.revenue)
+		case StructureData.MetricUnits:
+			values[metric] = float64(bucket.units)
+		case StructureData.MetricOrders:
+			values[metric] = float64(len(bucket.orders))
+		case StructureData.MetricAverageOrderValue:
+			values[metric] = 0
+			if len(bucket.orders) > 0 {
+				values[metric] = roundCents(bucket.revenue / float64(len(bucket.orders)))
+			}
+		}
+	}
+	return values
+}
+
+// roundCents rounds an amount to two decimals
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// persistNamedReportsToFile saves all named reports to the JSON file in a pretty JSON format
+func persistNamedReportsToFile() error {
+	file, err := os.Create(namedReportFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetNamedReportStoreInstance().GetAllReports())
+}
@@ -0,0 +1,482 @@
+package Controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"iter"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// exportMediaTypes is the media type of every export format
+var exportMediaTypes = map[StructureData.ExportFormat]string{
+	StructureData.ExportJSON: "application/json",
+	StructureData.ExportCSV:  "text/csv; charset=utf-8",
+	StructureData.ExportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	StructureData.ExportHTML: "text/html; charset=utf-8",
+	StructureData.ExportPDF:  "application/pdf",
+}
+
+// acceptedMediaTypes maps the media types of an Accept header to the export formats
+var acceptedMediaTypes = map[string]StructureData.ExportFormat{
+	"application/json": StructureData.ExportJSON,
+	"application/*":    StructureData.ExportJSON,
+	"*/*":              StructureData.ExportJSON,
+	"text/csv":         StructureData.ExportCSV,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": StructureData.ExportXLSX,
+	"application/vnd.ms-excel": StructureData.ExportXLSX,
+	"text/html":                StructureData.ExportHTML,
+	"text/*":                   StructureData.ExportHTML,
+	"application/pdf":          StructureData.ExportPDF,
+}
+
+// exportFlushRows is how many rows are written between two flushes of a streamed export
+const exportFlushRows = 500
+
+// exportTable is a report laid out for the export formats. Its rows hold strings, ints and float64s, the
+// latter being amounts.
+type exportTable struct {
+	Title    string // Heading of the statement
+	FileName string // Name of the downloaded file, without extension
+	Notes    []string
+	Columns  []exportColumn
+	Rows     iter.Seq[[]interface{}]
+	Totals   []exportTotal
+}
+
+// exportColumn is a column of an export, its width in characters in the PDF statement and whether it holds
+// numbers, aligned right in statements
+type exportColumn struct {
+	Name    string
+	Width   int
+	Numeric bool
+}
+
+// exportTotal is a line of the totals printed under a statement
+type exportTotal struct {
+	Label string
+	Value string
+}
+
+// negotiateExportFormat returns the format asked for with ?format=, or else the one preferred by the Accept
+// header. JSON is the default.
+func negotiateExportFormat(r *http.Request) (StructureData.ExportFormat, *StructureData.ErrorResponse) {
+	if value := r.URL.Query().Get("format"); value != "" {
+		format := StructureData.ExportFormat(strings.ToLower(value))
+		if _, known := exportMediaTypes[format]; !known {
+			return "", StructureData.NewValidationError(fmt.Sprintf("Unknown format %q, use json, csv, xlsx, html or pdf", value))
+		}
+		return format, nil
+	}
+
+	accept := strings.TrimSpace(r.Header.Get("Accept"))
+	if accept == "" {
+		return StructureData.ExportJSON, nil
+	}
+	var best StructureData.ExportFormat
+	bestQuality := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		format, supported := acceptedMediaTypes[mediaType]
+		if !supported {
+			continue
+		}
+		quality := 1.0
+		if value, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if quality > bestQuality { // The first of equally preferred types wins
+			best, bestQuality = format, quality
+		}
+	}
+	if best == "" {
+		return "", StructureData.NewNotAcceptableError("Reports are available as JSON, CSV, XLSX, HTML or PDF")
+	}
+	return best, nil
+}
+
+// writeExport streams a table in a format other than JSON. CSV, XLSX and PDF files are sent as attachments,
+// HTML statements are shown in the browser unless ?download=true.
+func writeExport(w http.ResponseWriter, r *http.Request, status int, format StructureData.ExportFormat, table exportTable) {
+	w.Header().Set("Content-Type", exportMediaTypes[format])
+	disposition := "attachment"
+	if format == StructureData.ExportHTML && r.URL.Query().Get("download") != "true" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": table.FileName + "." + string(format)}))
+	w.WriteHeader(status)
+
+	// The status is sent, so failures can only be logged from here
+	var err error
+	switch format {
+	case StructureData.ExportCSV:
+		err = writeCSVExport(w, table)
+	case StructureData.ExportXLSX:
+		err = writeXLSXExport(w, table)
+	case StructureData.ExportHTML:
+		err = writeHTMLExport(w, table)
+	case StructureData.ExportPDF:
+		err = writePDFExport(w, table)
+	}
+	if err != nil {
+		log.Printf("Failed to export %s as %s: %v", table.FileName, format, err)
+	}
+}
+
+// writeCSVExport writes the columns and rows of a table as CSV, flushing every exportFlushRows rows
+func writeCSVExport(w http.ResponseWriter, table exportTable) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columnNames(table.Columns)); err != nil {
+		return err
+	}
+	rows := 0
+	for row := range table.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = exportText(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			if err := flushExport(w, writer); err != nil {
+				return err
+			}
+		}
+	}
+	return flushExport(w, writer)
+}
+
+// writeXLSXExport writes the columns and rows of a table as a spreadsheet with numeric amounts
+func writeXLSXExport(w http.ResponseWriter, table exportTable) error {
+	workbook, err := utils.NewXLSXWriter(w, table.Title)
+	if err != nil {
+		return err
+	}
+	if err := workbook.WriteRow(columnHeader(table.Columns), true); err != nil {
+		return err
+	}
+	for row := range table.Rows {
+		if err := workbook.WriteRow(row, false); err != nil {
+			return err
+		}
+	}
+	return workbook.Close()
+}
+
+// writeHTMLExport writes a table as a printable statement, row by row
+func writeHTMLExport(w http.ResponseWriter, table exportTable) error {
+	if err := reportStatementTemplate.ExecuteTemplate(w, "header", table); err != nil {
+		return err
+	}
+	rows := 0
+	for row := range table.Rows {
+		cells := make([]statementCell, len(row))
+		for i, value := range row {
+			cells[i] = statementCell{Text: exportText(value), Numeric: isExportNumber(value)}
+		}
+		if err := reportStatementTemplate.ExecuteTemplate(w, "row", cells); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			http.NewResponseController(w).Flush()
+		}
+	}
+	return reportStatementTemplate.ExecuteTemplate(w, "footer", table)
+}
+
+// writePDFExport writes a table as a PDF statement with fixed-width columns, page by page
+func writePDFExport(w http.ResponseWriter, table exportTable) error {
+	document, err := utils.NewPDFWriter(w)
+	if err != nil {
+		return err
+	}
+
+	headerLine := statementLine(table.Columns, columnHeader(table.Columns))
+	document.Header = []string{table.Title, headerLine}
+
+	// Title, notes and column names
+	if err := document.WriteLine(table.Title, true); err != nil {
+		return err
+	}
+	for _, note := range append(table.Notes, "") {
+		if err := document.WriteLine(note, false); err != nil {
+			return err
+		}
+	}
+	if err := document.WriteLine(headerLine, true); err != nil {
+		return err
+	}
+	for row := range table.Rows {
+		if err := document.WriteLine(statementLine(table.Columns, row), false); err != nil {
+			return err
+		}
+	}
+
+	// Totals
+	if err := document.WriteLine("", false); err != nil {
+		return err
+	}
+	for _, total := range table.Totals {
+		if err := document.WriteLine(fmt.Sprintf("%-24s %s", total.Label+":", total.Value), true); err != nil {
+			return err
+		}
+	}
+	return document.Close()
+}
+
+// statementLine lays out a row in the fixed-width columns of the PDF statement, amounts aligned right
+func statementLine(columns []exportColumn, row []interface{}) string {
+	var line strings.Builder
+	for i, column := range columns {
+		text := ""
+		if i < len(row) {
+			text = exportText(row[i])
+		}
+		if runes := []rune(text); column.Width > 0 && len(runes) > column.Width {
+			text = string(runes[:column.Width-1]) + "~"
+		}
+		if column.Numeric {
+			fmt.Fprintf(&line, "%*s ", column.Width, text)
+		} else {
+			fmt.Fprintf(&line, "%-*s ", column.Width, text)
+		}
+	}
+	return strings.TrimRight(line.String(), " ")
+}
+
+// flushExport sends the CSV written so far to the client
+func flushExport(w http.ResponseWriter, writer *csv.Writer) error {
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	http.NewResponseController(w).Flush()
+	return nil
+}
+
+// exportText formats a cell, amounts with two decimals
+func exportText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isExportNumber reports whether a cell is a number, aligned right in statements
+func isExportNumber(value interface{}) bool {
+	switch value.(type) {
+	case int, float64:
+		return true
+	}
+	return false
+}
+
+// columnNames returns the names of the columns
+func columnNames(columns []exportColumn) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// columnHeader returns the names of the columns as a row
+func columnHeader(columns []exportColumn) []interface{} {
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	return header
+}
+
+// salesReportsExport lays out sales reports. Spreadsheet formats get one row per top-selling book, repeating
+// the figures of the report, so they can be filtered by book; a report without sales gets a single row.
+// Statements get one line per report listing its top-selling books.
+func salesReportsExport(reports []StructureData.SalesReport, format StructureData.ExportFormat, fileName string, notes []string) exportTable {
+	table := exportTable{
+		Title:    "Sales reports",
+		FileName: fileName,
+		Notes:    append([]string{"Generated " + time.Now().UTC().Format("2006-01-02 15:04 MST")}, notes...),
+	}
+
+	revenue, orders := 0.0, 0
+	for _, report := range reports {
+		revenue += report.TotalRevenue
+		orders += report.TotalOrders
+	}
+	table.Totals = []exportTotal{
+		{"Reports", strconv.Itoa(len(reports))},
+		{"Orders", strconv.Itoa(orders)},
+		{"Revenue", strconv.FormatFloat(roundCents(revenue), 'f', 2, 64)},
+	}
+
+	if format == StructureData.ExportCSV || format == StructureData.ExportXLSX {
+		table.Columns = []exportColumn{
+			{"report_id", 0, true}, {"timestamp", 0, false}, {"period", 0, false}, {"total_revenue", 0, true}, {"total_orders", 0, true},
+			{"book_id", 0, true}, {"book_title", 0, false}, {"quantity_sold", 0, true},
+		}
+		table.Rows = func(yield func([]interface{}) bool) {
+			for _, report := range reports {
+				figures := []interface{}{report.ID, report.Timestamp.Format(time.RFC3339), report.Period, report.TotalRevenue, report.TotalOrders}
+				if len(report.TopSellingBooks) == 0 {
+					if !yield(append(figures, nil, "", nil)) {
+						return
+					}
+				}
+				for _, topBook := range report.TopSellingBooks {
+					row := append(append([]interface{}{}, figures...), topBook.Book.ID, topBook.Book.Title, topBook.QuantitySold)
+					if !yield(row) {
+						return
+					}
+				}
+			}
+		}
+		return table
+	}
+
+	table.Columns = []exportColumn{{"Date", 16, false}, {"Period", 7, false}, {"Orders", 7, true}, {"Revenue", 12, true}, {"Top-selling books", 95, false}}
+	table.Rows = func(yield func([]interface{}) bool) {
+		for _, report := range reports {
+			books := make([]string, len(report.TopSellingBooks))
+			for i, topBook := range report.TopSellingBooks {
+				books[i] = fmt.Sprintf("%s x%d", topBook.Book.Title, topBook.QuantitySold)
+			}
+			row := []interface{}{report.Timestamp.Format("2006-01-02 15:04"), report.Period, report.TotalOrders, report.TotalRevenue, strings.Join(books, ", ")}
+			if !yield(row) {
+				return
+			}
+		}
+	}
+	return table
+}
+
+// salesReportResultExport lays out a report of the reporting engine: one column per dimension, then one
+// per metric, with the totals of the metrics
+func salesReportResultExport(report StructureData.SalesReportResult) exportTable {
+	title := report.Name
+	fileName := "sales-report"
+	if title == "" {
+		title = "Sales report"
+	}
+	if report.ID != 0 {
+		fileName = fmt.Sprintf("report-%d", report.ID)
+	}
+	if slug := exportSlug(report.Name); slug != "" {
+		fileName += "-" + slug
+	}
+
+	location, err := time.LoadLocation(report.Query.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	table := exportTable{
+		Title:    title,
+		FileName: fileName,
+		Notes: []string{
+			fmt.Sprintf("From %s to %s", report.Query.From.In(location).Format("2006-01-02 15:04"), report.Query.To.In(location).Format("2006-01-02 15:04 MST")),
+			"Generated " + report.GeneratedAt.In(location).Format("2006-01-02 15:04 MST"),
+		},
+	}
+
+	for _, dimension := range report.Query.Dimensions {
+		table.Columns = append(table.Columns, exportColumn{string(dimension), 30, false})
+	}
+	for _, metric := range report.Query.Metrics {
+		table.Columns = append(table.Columns, exportColumn{string(metric), 19, true})
+		table.Totals = append(table.Totals, exportTotal{string(metric), exportText(metricValue(metric, report.Totals[metric]))})
+	}
+	table.Rows = func(yield func([]interface{}) bool) {
+		for _, row := range report.Rows {
+			cells := []interface{}{}
+			for _, key := range row.Group {
+				cells = append(cells, key.Label)
+			}
+			for _, metric := range report.Query.Metrics {
+				cells = append(cells, metricValue(metric, row.Metrics[metric]))
+			}
+			if !yield(cells) {
+				return
+			}
+		}
+	}
+	return table
+}
+
+// metricValue returns counts as ints and amounts as float64s
+func metricValue(metric StructureData.ReportMetric, value float64) interface{} {
+	if metric == StructureData.MetricUnits || metric == StructureData.MetricOrders {
+		return int(value)
+	}
+	return value
+}
+
+// exportSlug turns a report name into a file name part: lowercase letters and digits joined by dashes
+func exportSlug(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), "-")
+}
+
+// statementCell is a cell of an HTML statement
+type statementCell struct {
+	Text    string
+	Numeric bool
+}
+
+// reportStatementTemplate renders a printable statement in three parts, so rows are written as they come
+var reportStatementTemplate = template.Must(template.New("statement").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+  body { font-family: Helvetica, Arial, sans-serif; font-size: 12px; margin: 2em; color: #222; }
+  h1 { font-size: 20px; margin-bottom: 0.2em; }
+  p.note { margin: 0; color: #555; }
+  table { border-collapse: collapse; width: 100%; margin-top: 1.5em; }
+  th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+  th { background: #f2f2f2; }
+  td.number, th.number { text-align: right; white-space: nowrap; }
+  table.totals { width: auto; margin-left: auto; }
+  table.totals th { background: none; }
+  @media print { body { margin: 0; } thead { display: table-header-group; } tr { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Notes}}<p class="note">{{.}}</p>
+{{end}}<table>
+<thead><tr>{{range .Columns}}<th{{if .Numeric}} class="number"{{end}}>{{.Name}}</th>{{end}}</tr></thead>
+<tbody>
+{{end}}
+{{define "row"}}<tr>{{range .}}<td{{if .Numeric}} class="number"{{end}}>{{.Text}}</td>{{end}}</tr>
+{{end}}
+{{define "footer"}}</tbody>
+</table>
+<table class="totals">
+{{range .Totals}}<tr><th>{{.Label}}</th><td class="number">{{.Value}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+{{end}}`))
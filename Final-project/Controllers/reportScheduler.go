@@ -0,0 +1,303 @@
+package Controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	interfaces "finalProject/Interfaces"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// maxCatchUpRuns is how many missed occurrences of a schedule are run after downtime. Older ones are skipped.
+const maxCatchUpRuns = 24
+
+// ReportScheduler runs the report schedules when they fall due. It keeps no state of its own: the next
+// occurrence of every schedule is stored with it, so occurrences missed while the server was down are
+// found again on start.
+type ReportScheduler struct {
+	store    interfaces.ScheduleStore
+	wake     chan struct{}
+	runMu    sync.Mutex // One run at a time, scheduled or manual
+	cancel   context.CancelFunc
+	done     chan struct{}
+	OnChange func() // Called after a run or a change of the next occurrences, e.g. to persist them
+}
+
+// NewReportScheduler creates a scheduler for the schedules of the store
+func NewReportScheduler(store interfaces.ScheduleStore) *ReportScheduler {
+	return &ReportScheduler{
+		store: store,
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Start runs the occurrences missed while the server was down, then runs the schedules as they fall due
+// until the context is cancelled or Stop is called
+func (s *ReportScheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.catchUp(ctx)
+		s.loop(ctx)
+	}()
+}
+
+// Stop cancels the running report, if any, and waits for the scheduler to exit or the context to expire
+func (s *ReportScheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wake makes the scheduler look at the schedules again after one was created, changed or deleted
+func (s *ReportScheduler) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RunNow runs a schedule immediately, outside its cron expression
+func (s *ReportScheduler) RunNow(ctx context.Context, schedule StructureData.ReportSchedule) StructureData.ScheduleRun {
+	return s.run(ctx, schedule, time.Now(), false, true)
+}
+
+// loop sleeps until the earliest next occurrence, then runs the schedules that are due
+func (s *ReportScheduler) loop(ctx context.Context) {
+	for {
+		var timer *time.Timer
+		var due <-chan time.Time
+		if next, ok := s.earliestOccurrence(); ok {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			log.Println("Report scheduler stopped.")
+			return
+		case <-s.wake:
+		case <-due:
+			s.runDue(ctx)
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// earliestOccurrence returns the earliest next occurrence of the active schedules
+func (s *ReportScheduler) earliestOccurrence() (time.Time, bool) {
+	var earliest time.Time
+	for _, schedule := range s.store.GetAllSchedules() {
+		if schedule.Paused || schedule.NextRunAt == nil {
+			continue
+		}
+		if earliest.IsZero() || schedule.NextRunAt.Before(earliest) {
+			earliest = *schedule.NextRunAt
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// runDue runs every active schedule whose next occurrence has come, then moves it to the following one
+func (s *ReportScheduler) runDue(ctx context.Context) {
+	for _, schedule := range s.store.GetAllSchedules() {
+		if ctx.Err() != nil {
+			return
+		}
+		if schedule.Paused || schedule.NextRunAt == nil || schedule.NextRunAt.After(time.Now()) {
+			continue
+		}
+		occurrence := *schedule.NextRunAt
+		s.run(ctx, schedule, occurrence, false, false)
+		s.advance(schedule, occurrence, &occurrence)
+	}
+}
+
+// catchUp runs the occurrences of every schedule missed while the server was down, the most recent
+// maxCatchUpRuns of them, if the schedule asks for it. Other schedules skip to their next occurrence.
+func (s *ReportScheduler) catchUp(ctx context.Context) {
+	now := time.Now()
+	for _, schedule := range s.store.GetAllSchedules() {
+		if schedule.Paused || schedule.NextRunAt == nil || schedule.NextRunAt.After(now) {
+			continue
+		}
+
+		// List the missed occurrences
+		var missed []time.Time
+		for occurrence := *schedule.NextRunAt; !occurrence.IsZero() && !occurrence.After(now); {
+			missed = append(missed, occurrence)
+			next, err := nextOccurrence(schedule, occurrence)
+			if err != nil || next == nil {
+				break
+			}
+			occurrence = *next
+		}
+
+		if !schedule.CatchUp {
+			log.Printf("Schedule %d (%s) skipped %d missed runs", schedule.ID, schedule.Name, len(missed))
+			s.advance(schedule, now, nil)
+			continue
+		}
+		if len(missed) > maxCatchUpRuns {
+			log.Printf("Schedule %d (%s) skipped %d missed runs beyond the last %d", schedule.ID, schedule.Name, len(missed)-maxCatchUpRuns, maxCatchUpRuns)
+			missed = missed[len(missed)-maxCatchUpRuns:]
+		}
+		for _, occurrence := range missed {
+			if ctx.Err() != nil {
+				return
+			}
+			s.run(ctx, schedule, occurrence, true, false)
+		}
+		s.advance(schedule, now, &missed[len(missed)-1])
+	}
+}
+
+// advance moves a schedule to its first occurrence after both now and the given time, recording the
+// occurrence it last ran for unless lastRun is nil
+func (s *ReportScheduler) advance(schedule StructureData.ReportSchedule, after time.Time, lastRun *time.Time) {
+	if now := time.Now(); now.After(after) {
+		after = now
+	}
+	next, err := nextOccurrence(schedule, after)
+	if err != nil {
+		log.Printf("Schedule %d (%s) cannot be scheduled: %v", schedule.ID, schedule.Name, err)
+	}
+	if errResp := s.store.SetRunTimes(schedule.ID, lastRun, next); errResp != nil {
+		return // Deleted while it ran
+	}
+	if s.OnChange != nil {
+		s.OnChange()
+	}
+}
+
+// run runs a schedule for one occurrence and records the run in the history
+func (s *ReportScheduler) run(ctx context.Context, schedule StructureData.ReportSchedule, occurrence time.Time, catchUp, manual bool) StructureData.ScheduleRun {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	run := StructureData.ScheduleRun{
+		ScheduleID:   schedule.ID,
+		ScheduledFor: occurrence,
+		StartedAt:    time.Now(),
+		CatchUp:      catchUp,
+		Manual:       manual,
+	}
+	reportID, err := executeSchedule(ctx, schedule, occurrence)
+	run.FinishedAt = time.Now()
+	run.ReportID = reportID
+	switch {
+	case err == nil:
+		run.Status = StructureData.ScheduleRunSucceeded
+	case ctx.Err() != nil:
+		run.Status = StructureData.ScheduleRunCancelled
+		run.Error = ctx.Err().Error()
+	default:
+		run.Status = StructureData.ScheduleRunFailed
+		run.Error = err.Error()
+	}
+	run = s.store.RecordRun(run)
+	log.Printf("Schedule %d (%s) run for %s %s", schedule.ID, schedule.Name, occurrence.Format(time.RFC3339), run.Status)
+
+	if s.OnChange != nil {
+		s.OnChange()
+	}
+	return run
+}
+
+// executeSchedule produces the report of a schedule for one occurrence: the daily sales report ending
+// then, or the schedule's query over the window before it, saved as a named report whose ID is returned.
+// The query uses the schedule's time zone unless it has its own.
+func executeSchedule(ctx context.Context, schedule StructureData.ReportSchedule, occurrence time.Time) (int, error) {
+	if schedule.Query == nil {
+		return 0, generateSalesReportAt(ctx, occurrence)
+	}
+
+	window, err := scheduleWindow(schedule)
+	if err != nil {
+		return 0, err
+	}
+	location, err := time.LoadLocation(scheduleTimeZone(schedule))
+	if err != nil {
+		return 0, err
+	}
+
+	// Run the query over the window ending at the occurrence
+	query := *schedule.Query
+	query.From = occurrence.Add(-window)
+	query.To = occurrence
+	query.Name = fmt.Sprintf("%s %s", schedule.Name, occurrence.In(location).Format("2006-01-02 15:04"))
+	if query.TimeZone == "" {
+		query.TimeZone = scheduleTimeZone(schedule)
+	}
+	report, errResp := runSalesReportQuery(ctx, query)
+	if errResp != nil {
+		return 0, errResp
+	}
+
+	// Save it as a named report
+	report.Name = query.Name
+	report.GeneratedBy = systemActor
+	savedReport, errResp := inmemoryStores.GetNamedReportStoreInstance().SaveReport(report)
+	if errResp != nil {
+		return 0, errResp
+	}
+	if err := persistNamedReportsToFile(); err != nil {
+		return savedReport.ID, err
+	}
+	return savedReport.ID, nil
+}
+
+// nextOccurrence returns the first occurrence of a schedule after the given time, or nil if there is none
+func nextOccurrence(schedule StructureData.ReportSchedule, after time.Time) (*time.Time, error) {
+	cron, err := utils.ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(scheduleTimeZone(schedule))
+	if err != nil {
+		return nil, err
+	}
+	next := cron.Next(after.In(location))
+	if next.IsZero() {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+// scheduleTimeZone returns the time zone of a schedule, UTC by default
+func scheduleTimeZone(schedule StructureData.ReportSchedule) string {
+	if schedule.TimeZone == "" {
+		return "UTC"
+	}
+	return schedule.TimeZone
+}
+
+// scheduleWindow returns the duration the query of a schedule covers, 24 hours by default
+func scheduleWindow(schedule StructureData.ReportSchedule) (time.Duration, error) {
+	if schedule.Window == "" {
+		return 24 * time.Hour, nil
+	}
+	window, err := time.ParseDuration(schedule.Window)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("window must be a positive duration such as 24h")
+	}
+	return window, nil
+}
@@ -0,0 +1,410 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file path for return persistence
+var returnFile = "returns.json"
+
+// InitializeReturnFile loads the returns from the JSON file into the in-memory store
+func InitializeReturnFile() {
+	var returns []StructureData.Return
+	if err := readJSONFile(returnFile, &returns); err != nil {
+		panic("Failed to decode return file")
+	}
+
+	store := inmemoryStores.GetReturnStoreInstance()
+	for _, ret := range returns {
+		store.AddReturnDirectly(ret)
+	}
+}
+
+// GetAllReturns handles the GET /returns request, filtered by the optional and repeatable query parameters
+// order_id, customer_id, book_id and status
+func GetAllReturns(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetReturnStoreInstance()
+	query := r.URL.Query()
+
+	// Build the search criteria from the query parameters
+	var criteria StructureData.ReturnSearchCriteria
+	for param, ids := range map[string]*[]int{"order_id": &criteria.OrderIDs, "customer_id": &criteria.CustomerIDs, "book_id": &criteria.BookIDs} {
+		for _, idStr := range query[param] {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				writeError(w, r, StructureData.NewValidationError("Invalid "+param))
+				return
+			}
+			*ids = append(*ids, id)
+		}
+	}
+	for _, status := range query["status"] {
+		criteria.Statuses = append(criteria.Statuses, StructureData.ReturnStatus(status))
+	}
+
+	// Return the search results as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.SearchReturns(criteria))
+}
+
+// GetReturnByID handles the GET /returns/{id} request
+func GetReturnByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetReturnStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/returns/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid return ID"))
+		return
+	}
+
+	// Retrieve the return by ID
+	ret, errResp := store.GetReturn(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ret)
+}
+
+// GetOrderReturns handles the GET /orders/{id}/returns request
+func GetOrderReturns(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/orders/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid order ID"))
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inmemoryStores.GetReturnStoreInstance().GetReturnsByOrder(id))
+}
+
+// CreateReturn handles the POST /returns request, opened by a customer or staff for books of an order. New
+// returns wait for approval.
+func CreateReturn(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetReturnStoreInstance()
+
+	// Decode the request body
+	var request StructureData.Return
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Only orders that are not deleted can be returned
+	order, errResp := inmemoryStores.GetOrderStoreInstance().GetOrder(request.OrderID)
+	if errResp != nil {
+		writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Order %d does not exist", request.OrderID)))
+		return
+	}
+
+	// Create the return in the store
+	request.RequestedBy = auditContext(r).Actor
+	createdReturn, errResp := store.CreateReturn(request, order)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceReturns, createdReturn.ID, StructureData.AuditCreate, nil, createdReturn)
+
+	// Persist to JSON file
+	if err := persistReturnsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created return
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdReturn)
+}
+
+// ApproveReturn handles the POST /returns/{id}/approve request, letting the customer send the goods back
+func ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	decideReturn(w, r, false)
+}
+
+// RejectReturn handles the POST /returns/{id}/reject request. The body gives the reason of the rejection.
+func RejectReturn(w http.ResponseWriter, r *http.Request) {
+	decideReturn(w, r, true)
+}
+
+// decideReturn approves or rejects a requested return
+func decideReturn(w http.ResponseWriter, r *http.Request, reject bool) {
+	store := inmemoryStores.GetReturnStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/returns/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid return ID"))
+		return
+	}
+
+	// Decode the reason of a rejection
+	var decision StructureData.ReturnDecision
+	if reject {
+		if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+			writeError(w, r, StructureData.NewValidationError("Invalid input"))
+			return
+		}
+	}
+
+	// Record the decision
+	actor := auditContext(r).Actor
+	previous, _ := store.GetReturn(id)
+	var decided StructureData.Return
+	var errResp *StructureData.ErrorResponse
+	if reject {
+		decided, errResp = store.RejectReturn(id, decision.Reason, actor)
+	} else {
+		decided, errResp = store.ApproveReturn(id, actor)
+	}
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceReturns, id, StructureData.AuditUpdate, previous, decided)
+
+	// Persist to JSON file
+	if err := persistReturnsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated return
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(decided)
+}
+
+// ReceiveReturn handles the POST /returns/{id}/receive request. The body lists the quantities received back
+// per book and their condition. Sellable copies are put back in stock, and the goods received are refunded
+// with a credit note on the order's invoice, or at the ordered prices for orders that were not invoiced.
+func ReceiveReturn(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetReturnStoreInstance()
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	warehouseStore := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/returns/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid return ID"))
+		return
+	}
+
+	// Decode the request body
+	var receipt StructureData.ReturnReceipt
+	if err := json.NewDecoder(r.Body).Decode(&receipt); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Choose where sellable copies are restocked; the books must still exist to take stock
+	previous, errResp := store.GetReturn(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	if receipt.RefundToStoreCredit && previous.CustomerID == 0 {
+		writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Return %s has no customer to refund to store credit", previous.Number)))
+		return
+	}
+	for i, line := range receipt.Lines {
+		if !line.Condition.Sellable() {
+			receipt.Lines[i].WarehouseID = 0
+			continue
+		}
+		if _, errResp := bookStore.GetBook(line.BookID); errResp != nil {
+			writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Book %d does not exist; restore it to restock the return", line.BookID)))
+			return
+		}
+		if line.WarehouseID == 0 {
+			warehouseID, errResp := returnWarehouse(previous.OrderID, line.BookID)
+			if errResp != nil {
+				writeError(w, r, errResp)
+				return
+			}
+			receipt.Lines[i].WarehouseID = warehouseID
+		}
+		if _, errResp := warehouseStore.GetWarehouse(receipt.Lines[i].WarehouseID); errResp != nil {
+			writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Warehouse %d does not exist", receipt.Lines[i].WarehouseID)))
+			return
+		}
+	}
+
+	// Stage the restock of the sellable copies before recording anything
+	change := newStockChange(StructureData.StockMovement{
+		Type:       StructureData.StockMovementReturn,
+		SourceType: StructureData.ResourceReturns,
+		SourceID:   id,
+	})
+	for _, line := range receipt.Lines {
+		if !line.Condition.Sellable() {
+			continue
+		}
+		if errResp := change.adjust(line.BookID, line.WarehouseID, line.Quantity); errResp != nil {
+			change.undo()
+			writeError(w, r, errResp)
+			return
+		}
+	}
+
+	// Record the receipt against the return
+	receipt.ReceivedBy = auditContext(r).Actor
+	receivedReturn, recorded, errResp := store.ReceiveReturn(id, receipt)
+	if errResp != nil {
+		change.undo()
+		writeError(w, r, errResp)
+		return
+	}
+	note := fmt.Sprintf("%s receipt %d", receivedReturn.Number, recorded.ID)
+	for i := range change.lines {
+		change.lines[i].note = note // The receipt number is only known once it is recorded
+	}
+
+	// Refund the goods received, whatever their condition, taking the receipt and the restock back if the
+	// refund fails
+	cancel := func(errResp *StructureData.ErrorResponse) {
+		change.undo()
+		if _, removeErr := store.RemoveReceipt(id, recorded.ID); removeErr != nil {
+			log.Printf("Failed to remove receipt %d of return %s: %s", recorded.ID, receivedReturn.Number, removeErr.Message)
+		}
+		writeError(w, r, errResp)
+	}
+	refunded, errResp := refundReturnReceipt(r, receivedReturn, recorded)
+	if errResp != nil {
+		cancel(errResp)
+		return
+	}
+	if refunded.RefundToStoreCredit && refunded.Refund > 0 {
+		entry, errResp := inmemoryStores.GetGiftCardStoreInstance().AddStoreCredit(StructureData.StoreCreditEntry{
+			CustomerID: receivedReturn.CustomerID,
+			Type:       StructureData.StoreCreditRefund,
+			Amount:     refunded.Refund,
+			OrderID:    receivedReturn.OrderID,
+			ReturnID:   id,
+			Note:       note,
+			Actor:      auditContext(r).Actor,
+		})
+		if errResp != nil {
+			if refunded.CreditNoteID != 0 {
+				log.Printf("Return %s: credit note %s was issued but receipt %d is taken back", receivedReturn.Number, refunded.CreditNoteNumber, recorded.ID)
+			}
+			cancel(errResp)
+			return
+		}
+		refunded.StoreCreditEntryID = entry.ID
+	}
+	receivedReturn, errResp = store.RecordRefund(id, refunded)
+	if errResp != nil {
+		cancel(errResp)
+		return
+	}
+	change.commit(r)
+	recordAudit(r, StructureData.ResourceReturns, id, StructureData.AuditUpdate, previous, receivedReturn)
+
+	// Persist to JSON files, writing every file before reporting the first failure
+	var saveErr *StructureData.ErrorResponse
+	if refunded.StoreCreditEntryID != 0 {
+		saveErr = persistGiftCardData()
+	}
+	if err := persistBooksToFile(bookStore); err != nil && saveErr == nil {
+		saveErr = StructureData.NewInternalError("Error saving data", err)
+	}
+	if err := persistReturnsToFile(); err != nil && saveErr == nil {
+		saveErr = StructureData.NewInternalError("Error saving data", err)
+	}
+	if saveErr != nil {
+		writeError(w, r, saveErr)
+		return
+	}
+
+	// Return the updated return
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receivedReturn)
+}
+
+// returnWarehouse returns the warehouse a book of an order was shipped from, or the default warehouse for
+// orders placed before stock was kept per warehouse
+func returnWarehouse(orderID, bookID int) (int, *StructureData.ErrorResponse) {
+	if order, errResp := inmemoryStores.GetOrderStoreInstance().GetOrder(orderID); errResp == nil {
+		for _, item := range order.Items {
+			if item.BookID == bookID && len(item.Allocations) > 0 {
+				return item.Allocations[0].WarehouseID, nil
+			}
+		}
+	}
+	defaultWarehouse, errResp := inmemoryStores.GetWarehouseStoreInstance().DefaultWarehouse()
+	if errResp != nil {
+		return 0, errResp
+	}
+	return defaultWarehouse.ID, nil
+}
+
+// refundReturnReceipt refunds the goods of a receipt with a credit note on the order's open invoice. Orders
+// that were never invoiced are refunded at the ordered prices; quantities already credited on the invoice,
+// such as by a manual credit note, are not refunded twice.
+func refundReturnReceipt(r *http.Request, ret StructureData.Return, receipt StructureData.ReturnReceipt) (StructureData.ReturnReceipt, *StructureData.ErrorResponse) {
+	received := map[int]int{}
+	request := StructureData.CreditNoteRequest{Reason: fmt.Sprintf("Return %s: %s", ret.Number, ret.Reason)}
+	for _, line := range receipt.Lines {
+		if received[line.BookID] == 0 {
+			request.Lines = append(request.Lines, StructureData.CreditNoteLine{BookID: line.BookID})
+		}
+		received[line.BookID] += line.Quantity
+	}
+	for i, line := range request.Lines {
+		request.Lines[i].Quantity = received[line.BookID]
+	}
+
+	creditNote, credited, errResp := inmemoryStores.GetInvoicerInstance().CreditOrder(ret.OrderID, request, auditContext(r))
+	if errResp != nil {
+		return StructureData.ReturnReceipt{}, errResp
+	}
+	if credited {
+		receipt.Refund = creditNote.Total
+		receipt.CreditNoteID = creditNote.ID
+		receipt.CreditNoteNumber = creditNote.Number
+		return receipt, nil
+	}
+
+	for _, document := range inmemoryStores.GetInvoiceStoreInstance().GetInvoicesByOrder(ret.OrderID) {
+		if document.Type == StructureData.InvoiceTypeInvoice {
+			log.Printf("Return %s: the goods of receipt %d were already credited on the invoices of order %d", ret.Number, receipt.ID, ret.OrderID)
+			return receipt, nil
+		}
+	}
+	refund := 0.0
+	for _, line := range ret.Lines {
+		refund += line.UnitPrice * float64(received[line.BookID])
+	}
+	receipt.Refund = roundCents(refund)
+	return receipt, nil
+}
+
+// persistReturnsToFile saves all returns to the JSON file in a pretty JSON format
+func persistReturnsToFile() error {
+	file, err := os.Create(returnFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetReturnStoreInstance().GetAllReturns())
+}
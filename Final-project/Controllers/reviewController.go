@@ -0,0 +1,404 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file path for review persistence
+var reviewFile = "reviews.json"
+
+// Pagination of the reviews of a book
+const (
+	defaultReviewsPerPage = 20
+	maxReviewsPerPage     = 100
+)
+
+// InitializeReviewFile loads the reviews from the JSON file into the in-memory store
+func InitializeReviewFile() {
+	var reviews []StructureData.Review
+	if err := readJSONFile(reviewFile, &reviews); err != nil {
+		panic("Failed to decode review file")
+	}
+
+	store := inmemoryStores.GetReviewStoreInstance()
+	for _, review := range reviews {
+		store.AddReviewDirectly(review)
+	}
+}
+
+// GetBookReviews handles the GET /books/{id}/reviews request. It returns a page of the approved reviews,
+// newest first, with the rating summary of the book. ?status= shows the reviews of another status, or all of
+// them; ?rating= and ?verified= filter them; ?sort= is newest, oldest, highest or lowest.
+func GetBookReviews(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/books/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
+		return
+	}
+	if _, errResp := inmemoryStores.GetBookStoreInstance().GetBook(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Parse the filters and the page
+	status := StructureData.ReviewStatus(params.Get("status"))
+	if status == "" {
+		status = StructureData.ReviewApproved
+	}
+	if status != "all" && !StructureData.IsValidReviewStatus(status) {
+		writeError(w, r, StructureData.NewValidationError("status must be pending, approved, rejected or all"))
+		return
+	}
+	rating := 0
+	if value := params.Get("rating"); value != "" {
+		rating, err = strconv.Atoi(value)
+		if err != nil || rating < 1 || rating > 5 {
+			writeError(w, r, StructureData.NewValidationError("rating must be between 1 and 5"))
+			return
+		}
+	}
+	verified := params.Get("verified")
+	if verified != "" && verified != "true" && verified != "false" {
+		writeError(w, r, StructureData.NewValidationError("verified must be true or false"))
+		return
+	}
+	sortOrder := params.Get("sort")
+	switch sortOrder {
+	case "", "newest", "oldest", "highest", "lowest":
+	default:
+		writeError(w, r, StructureData.NewValidationError("sort must be newest, oldest, highest or lowest"))
+		return
+	}
+	page, errResp := positiveQueryInt(r, "page", 1)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	perPage, errResp := positiveQueryInt(r, "per_page", defaultReviewsPerPage)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	perPage = min(perPage, maxReviewsPerPage)
+
+	// Filter and sort the reviews, which come newest first
+	store := inmemoryStores.GetReviewStoreInstance()
+	reviews := []StructureData.Review{}
+	for _, review := range store.GetReviewsByBook(id) {
+		if status != "all" && review.Status != status {
+			continue
+		}
+		if rating > 0 && review.Rating != rating {
+			continue
+		}
+		if verified != "" && review.VerifiedPurchase != (verified == "true") {
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+	switch sortOrder {
+	case "oldest":
+		for i, j := 0, len(reviews)-1; i < j; i, j = i+1, j-1 {
+			reviews[i], reviews[j] = reviews[j], reviews[i]
+		}
+	case "highest":
+		sort.SliceStable(reviews, func(i, j int) bool { return reviews[i].Rating > reviews[j].Rating })
+	case "lowest":
+		sort.SliceStable(reviews, func(i, j int) bool { return reviews[i].Rating < reviews[j].Rating })
+	}
+
+	result := StructureData.ReviewPage{
+		Reviews:    []StructureData.Review{},
+		Page:       page,
+		PerPage:    perPage,
+		Total:      len(reviews),
+		TotalPages: (len(reviews) + perPage - 1) / perPage,
+		Summary:    store.GetRatingSummary(id),
+	}
+	if start := (page - 1) * perPage; start < len(reviews) {
+		result.Reviews = reviews[start:min(start+perPage, len(reviews))]
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// CreateBookReview handles the POST /books/{id}/reviews request. The review waits for moderation, and is a
+// verified purchase if the customer has ordered the book.
+func CreateBookReview(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetReviewStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/books/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
+		return
+	}
+
+	// Decode the request body
+	var review StructureData.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	review.BookID = id
+
+	// Validate the review, its book and its customer
+	if errResp := validateReview(review); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	if _, errResp := inmemoryStores.GetBookStoreInstance().GetBook(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	if _, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(review.CustomerID); errResp != nil {
+		writeError(w, r, StructureData.NewValidationError("Customer not found"))
+		return
+	}
+	review.Status = StructureData.ReviewPending
+	review.ModerationNote = ""
+	review.VerifiedPurchase = customerBoughtBook(review.CustomerID, id)
+
+	// Create the review in the store
+	createdReview, errResp := store.CreateReview(review)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceReviews, createdReview.ID, StructureData.AuditCreate, nil, createdReview)
+
+	// Persist to JSON file
+	if err := persistReviewsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created review
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdReview)
+}
+
+// GetAllReviews handles the GET /reviews request. ?status= filters the reviews, e.g. to list those
+// waiting for moderation.
+func GetAllReviews(w http.ResponseWriter, r *http.Request) {
+	status := StructureData.ReviewStatus(r.URL.Query().Get("status"))
+	if status != "" && !StructureData.IsValidReviewStatus(status) {
+		writeError(w, r, StructureData.NewValidationError("status must be pending, approved or rejected"))
+		return
+	}
+
+	reviews := []StructureData.Review{}
+	for _, review := range inmemoryStores.GetReviewStoreInstance().GetAllReviews() {
+		if status == "" || review.Status == status {
+			reviews = append(reviews, review)
+		}
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// GetReviewByID handles the GET /reviews/{id} request
+func GetReviewByID(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/reviews/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid review ID"))
+		return
+	}
+
+	// Retrieve the review by ID
+	review, errResp := inmemoryStores.GetReviewStoreInstance().GetReview(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// UpdateReview handles the PUT /reviews/{id} request. The rating, title and body are replaced, and the
+// review goes back to moderation.
+func UpdateReview(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetReviewStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/reviews/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid review ID"))
+		return
+	}
+	previous, errResp := store.GetReview(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Decode the request body
+	var review StructureData.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	review.CustomerID = previous.CustomerID
+
+	// Validate the review
+	if errResp := validateReview(review); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	review.Status = StructureData.ReviewPending
+	review.ModerationNote = ""
+	review.VerifiedPurchase = customerBoughtBook(previous.CustomerID, previous.BookID)
+
+	// Update the review in the store
+	updatedReview, errResp := store.UpdateReview(id, review)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceReviews, id, StructureData.AuditUpdate, previous, updatedReview)
+
+	// Persist to JSON file
+	if err := persistReviewsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated review
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedReview)
+}
+
+// ModerateReview handles the POST /reviews/{id}/moderate request, approving or rejecting a review
+func ModerateReview(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetReviewStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/reviews/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid review ID"))
+		return
+	}
+
+	// Decode the request body
+	var moderation StructureData.ReviewModeration
+	if err := json.NewDecoder(r.Body).Decode(&moderation); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	if !StructureData.IsValidReviewStatus(moderation.Status) {
+		writeError(w, r, StructureData.NewValidationError("status must be pending, approved or rejected"))
+		return
+	}
+
+	// Moderate the review in the store
+	previous, _ := store.GetReview(id)
+	moderatedReview, errResp := store.ModerateReview(id, moderation)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceReviews, id, StructureData.AuditUpdate, previous, moderatedReview)
+
+	// Persist to JSON file
+	if err := persistReviewsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the moderated review
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(moderatedReview)
+}
+
+// DeleteReview handles the DELETE /reviews/{id} request
+func DeleteReview(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetReviewStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/reviews/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid review ID"))
+		return
+	}
+
+	// Delete the review from the store
+	previous, _ := store.GetReview(id)
+	if errResp := store.DeleteReview(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceReviews, id, StructureData.AuditDelete, previous, nil)
+
+	// Persist to JSON file
+	if err := persistReviewsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return success response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateReview checks the rating, title and customer of a review
+func validateReview(review StructureData.Review) *StructureData.ErrorResponse {
+	if review.Rating < 1 || review.Rating > 5 {
+		return StructureData.NewValidationError("rating must be between 1 and 5")
+	}
+	if strings.TrimSpace(review.Title) == "" {
+		return StructureData.NewValidationError("title is required")
+	}
+	if review.CustomerID == 0 {
+		return StructureData.NewValidationError("customer_id is required")
+	}
+	return nil
+}
+
+// customerBoughtBook reports whether the customer has an order containing the book
+func customerBoughtBook(customerID, bookID int) bool {
+	orders, errResp := inmemoryStores.GetOrderStoreInstance().SearchOrders(StructureData.OrderSearchCriteria{CustomerIDs: []int{customerID}})
+	if errResp != nil {
+		return false
+	}
+	for _, order := range orders {
+		for _, item := range order.Items {
+			if item.BookID == bookID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// persistReviewsToFile saves all reviews to the JSON file in a pretty JSON format
+func persistReviewsToFile() error {
+	file, err := os.Create(reviewFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetReviewStoreInstance().GetAllReviews())
+}
@@ -0,0 +1,356 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// JSON file paths for schedule persistence
+var (
+	scheduleFile    = "schedules.json"
+	scheduleRunFile = "schedule_runs.json"
+)
+
+var schedulesMu sync.Mutex // Serializes writes of the schedule files from the scheduler and from requests
+
+// reportScheduler runs the schedules once StartReportScheduler is called
+var reportScheduler *ReportScheduler
+
+// InitializeScheduleFiles loads the schedules and their run history from the JSON files into the in-memory
+// store. On first start it creates a schedule producing the daily sales report at midnight UTC.
+func InitializeScheduleFiles() {
+	_, statErr := os.Stat(scheduleFile)
+	var schedules []StructureData.ReportSchedule
+	if err := readJSONFile(scheduleFile, &schedules); err != nil {
+		panic("Failed to decode schedule file")
+	}
+	var runs []StructureData.ScheduleRun
+	if err := readJSONFile(scheduleRunFile, &runs); err != nil {
+		panic("Failed to decode schedule run file")
+	}
+
+	store := inmemoryStores.GetScheduleStoreInstance()
+	for _, schedule := range schedules {
+		store.AddScheduleDirectly(schedule)
+	}
+	for _, run := range runs {
+		store.AddRunDirectly(run)
+	}
+
+	if os.IsNotExist(statErr) {
+		schedule := StructureData.ReportSchedule{Name: "Daily sales report", Cron: "@daily", CatchUp: true}
+		schedule.NextRunAt, _ = nextOccurrence(schedule, time.Now())
+		if _, errResp := store.CreateSchedule(schedule); errResp != nil {
+			log.Printf("Failed to create the daily sales report schedule: %s", errResp.Message)
+		}
+		if err := persistSchedulesToFile(); err != nil {
+			log.Printf("Failed to save schedules: %v", err)
+		}
+	}
+	log.Printf("%d schedules and %d schedule runs loaded into store", len(store.GetAllSchedules()), len(runs))
+}
+
+// StartReportScheduler starts running the schedules, first catching up on the runs missed while the
+// server was down
+func StartReportScheduler() {
+	reportScheduler = NewReportScheduler(inmemoryStores.GetScheduleStoreInstance())
+	reportScheduler.OnChange = func() {
+		if err := persistSchedulesToFile(); err != nil {
+			log.Printf("Failed to save schedules: %v", err)
+		}
+		if err := persistScheduleRunsToFile(); err != nil {
+			log.Printf("Failed to save schedule runs: %v", err)
+		}
+	}
+	reportScheduler.Start(context.Background())
+}
+
+// StopReportScheduler cancels the running report, if any, and waits for the scheduler to exit or the
+// context to expire
+func StopReportScheduler(ctx context.Context) error {
+	if reportScheduler == nil {
+		return nil
+	}
+	return reportScheduler.Stop(ctx)
+}
+
+// GetAllSchedules handles the GET /schedules request
+func GetAllSchedules(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetScheduleStoreInstance()
+
+	// Return all schedules as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetAllSchedules())
+}
+
+// GetScheduleByID handles the GET /schedules/{id} request
+func GetScheduleByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetScheduleStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/schedules/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid schedule ID"))
+		return
+	}
+
+	// Retrieve the schedule by ID
+	schedule, errResp := store.GetSchedule(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// CreateSchedule handles the POST /schedules request
+func CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetScheduleStoreInstance()
+
+	// Decode the request body
+	var schedule StructureData.ReportSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the schedule and find its first occurrence
+	if errResp := validateSchedule(&schedule); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Create the schedule
+	createdSchedule, errResp := store.CreateSchedule(schedule)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	wakeReportScheduler()
+
+	// Persist to JSON file
+	if err := persistSchedulesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created schedule
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdSchedule)
+}
+
+// UpdateSchedule handles the PUT /schedules/{id} request. The next occurrence is computed again from now,
+// so resuming a paused schedule does not catch up on the runs missed while it was paused.
+func UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetScheduleStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/schedules/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid schedule ID"))
+		return
+	}
+
+	// Decode the request body
+	var schedule StructureData.ReportSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the schedule and find its next occurrence
+	if errResp := validateSchedule(&schedule); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Update the schedule
+	updatedSchedule, errResp := store.UpdateSchedule(id, schedule)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	wakeReportScheduler()
+
+	// Persist to JSON file
+	if err := persistSchedulesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated schedule
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedSchedule)
+}
+
+// DeleteSchedule handles the DELETE /schedules/{id} request. The runs of the schedule stay in the history.
+func DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetScheduleStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/schedules/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid schedule ID"))
+		return
+	}
+
+	// Delete the schedule
+	if errResp := store.DeleteSchedule(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	wakeReportScheduler()
+
+	// Persist to JSON file
+	if err := persistSchedulesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetScheduleRuns handles the GET /schedules/{id}/runs request, listing the runs of a schedule newest first
+func GetScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetScheduleStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/schedules/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid schedule ID"))
+		return
+	}
+
+	// Return the run history as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetRuns(id))
+}
+
+// RunSchedule handles the POST /schedules/{id}/run request, running a schedule immediately. Its next
+// scheduled occurrence is unchanged.
+func RunSchedule(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetScheduleStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/schedules/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid schedule ID"))
+		return
+	}
+
+	// Retrieve the schedule by ID
+	schedule, errResp := store.GetSchedule(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	if reportScheduler == nil {
+		writeError(w, r, StructureData.NewConflictError("The report scheduler is not running"))
+		return
+	}
+
+	// Run it and return the run
+	run := reportScheduler.RunNow(r.Context(), schedule)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(run)
+}
+
+// validateSchedule checks the name, cron expression, time zone, window and query of a schedule and sets
+// its next occurrence, none while it is paused
+func validateSchedule(schedule *StructureData.ReportSchedule) *StructureData.ErrorResponse {
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	if schedule.Name == "" {
+		return StructureData.NewValidationError("name is required")
+	}
+	if _, err := utils.ParseCron(schedule.Cron); err != nil {
+		return StructureData.NewValidationError(err.Error())
+	}
+	if _, err := time.LoadLocation(scheduleTimeZone(*schedule)); err != nil {
+		return StructureData.NewValidationError("Unknown time zone " + strconv.Quote(schedule.TimeZone))
+	}
+	window, err := scheduleWindow(*schedule)
+	if err != nil {
+		return StructureData.NewValidationError(err.Error())
+	}
+	if schedule.Query != nil {
+		schedule.Query.Name = ""
+		query := *schedule.Query
+		query.To = time.Now()
+		query.From = query.To.Add(-window)
+		if _, errResp := validateSalesReportQuery(&query); errResp != nil {
+			return errResp
+		}
+	}
+
+	schedule.NextRunAt = nil
+	if !schedule.Paused {
+		next, err := nextOccurrence(*schedule, time.Now())
+		if err != nil {
+			return StructureData.NewValidationError(err.Error())
+		}
+		if next == nil {
+			return StructureData.NewValidationError("The cron expression never matches")
+		}
+		schedule.NextRunAt = next
+	}
+	return nil
+}
+
+// wakeReportScheduler makes the scheduler pick up a change of the schedules
+func wakeReportScheduler() {
+	if reportScheduler != nil {
+		reportScheduler.Wake()
+	}
+}
+
+// persistSchedulesToFile saves all schedules to the JSON file in a pretty JSON format
+func persistSchedulesToFile() error {
+	schedulesMu.Lock()
+	defer schedulesMu.Unlock()
+
+	file, err := os.Create(scheduleFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetScheduleStoreInstance().GetAllSchedules())
+}
+
+// persistScheduleRunsToFile saves the run history to the JSON file in a pretty JSON format
+func persistScheduleRunsToFile() error {
+	schedulesMu.Lock()
+	defer schedulesMu.Unlock()
+
+	file, err := os.Create(scheduleRunFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetScheduleStoreInstance().GetAllRuns())
+}
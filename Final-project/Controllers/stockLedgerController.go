@@ -0,0 +1,304 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file path for stock ledger persistence
+var stockMovementFile = "stock_movements.json"
+
+var stockMovementsMu sync.Mutex // Serializes writes of the ledger file from concurrent requests
+
+// InitializeStockMovementFile loads the stock ledger from the JSON file into the in-memory store. Books
+// without movements get an opening balance, so that every book's ledger sums to its stock; books whose
+// ledger has drifted from their stock are logged. It must run after the books and warehouses are loaded.
+func InitializeStockMovementFile() {
+	var movements []StructureData.StockMovement
+	if err := readJSONFile(stockMovementFile, &movements); err != nil {
+		panic("Failed to decode stock movement file")
+	}
+
+	ledger := inmemoryStores.GetStockLedgerInstance()
+	for _, movement := range movements {
+		ledger.AddMovementDirectly(movement)
+	}
+
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	opened := 0
+	for _, book := range append(bookStore.GetAllBooks(), bookStore.GetDeletedBooks()...) {
+		balance, found := ledger.Balance(book.ID)
+		if !found {
+			stock := 0
+			for _, level := range inmemoryStores.GetWarehouseStoreInstance().GetStockLevels(book.ID) {
+				stock += level.Quantity
+				ledger.RecordMovement(StructureData.StockMovement{
+					BookID:      book.ID,
+					Type:        StructureData.StockMovementAdjustment,
+					Quantity:    level.Quantity,
+					StockAfter:  stock,
+					WarehouseID: level.WarehouseID,
+					SourceType:  StructureData.ResourceBooks,
+					SourceID:    book.ID,
+					Note:        "Opening balance",
+					Actor:       systemActor,
+				})
+			}
+			opened++
+		} else if balance != book.Stock {
+			log.Printf("Warning: book %d has stock %d but its ledger sums to %d", book.ID, book.Stock, balance)
+		}
+	}
+	if opened > 0 {
+		if err := persistStockMovementsToFile(); err != nil {
+			log.Printf("Failed to save stock movements: %v", err)
+		}
+	}
+	log.Printf("%d stock movements loaded into store, %d opening balances recorded", len(movements), opened)
+}
+
+// GetStockMovements handles the GET /books/{id}/stock-movements request, listing the ledger of a book
+// oldest first and checking that it sums to the book's stock
+func GetStockMovements(w http.ResponseWriter, r *http.Request) {
+	ledger := inmemoryStores.GetStockLedgerInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/books/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
+		return
+	}
+
+	// Retrieve the book, which may be in the trash
+	book, found := findBook(id)
+	if !found {
+		writeError(w, r, StructureData.NewNotFoundError("Book not found"))
+		return
+	}
+
+	// Check the ledger against the stock
+	balance, _ := ledger.Balance(id)
+	view := StructureData.StockLedgerView{
+		BookID:        id,
+		Stock:         book.Stock,
+		LedgerBalance: balance,
+		Consistent:    balance == book.Stock,
+		Movements:     ledger.GetMovements(id),
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+// recordStockMovements records the moves of a book's stock in the stock ledger, one movement per warehouse,
+// counting the stock up from before. source names the type and source document of the movements.
+func recordStockMovements(r *http.Request, source StructureData.StockMovement, before StructureData.Book, moves []StructureData.StockAllocation) {
+	ledger := inmemoryStores.GetStockLedgerInstance()
+	ctx := auditContext(r)
+
+	stock := before.Stock
+	for _, move := range moves {
+		stock += move.Quantity
+		movement := source
+		movement.BookID = before.ID
+		movement.WarehouseID = move.WarehouseID
+		movement.Quantity = move.Quantity
+		movement.StockAfter = stock
+		movement.Actor = ctx.Actor
+		movement.RequestID = ctx.RequestID
+		ledger.RecordMovement(movement)
+	}
+}
+
+// persistStockLedger saves the stock ledger and the stock levels, logging failures as the stock has
+// already moved
+func persistStockLedger() {
+	if err := persistStockMovementsToFile(); err != nil {
+		log.Printf("Failed to save stock movements: %v", err)
+	}
+	if err := persistStockLevelsToFile(); err != nil {
+		log.Printf("Failed to save stock levels: %v", err)
+	}
+}
+
+// stockChange collects the stock a request moves, so that it is audited and recorded in the stock ledger
+// once the request succeeds, or put back when the request is refused
+type stockChange struct {
+	source StructureData.StockMovement // Type, source document and note of the movements; the note is read as each book is added
+	lines  []stockChangeLine
+}
+
+// stockChangeLine is the stock of a book moved at its warehouses, with the book before and after the move
+type stockChangeLine struct {
+	before, after StructureData.Book
+	moves         []StructureData.StockAllocation // Quantity per warehouse, negative when the stock goes out
+	note          string                          // Note of the source when the stock was moved
+}
+
+// newStockChange starts collecting stock changes whose movements have the type and source of source
+func newStockChange(source StructureData.StockMovement) *stockChange {
+	return &stockChange{source: source}
+}
+
+// add changes the stock of a book by the quantities already moved at its warehouses. The warehouses are put
+// back if the book refuses the change.
+func (c *stockChange) add(bookID int, moves []StructureData.StockAllocation) *StructureData.ErrorResponse {
+	delta := 0
+	for _, move := range moves {
+		delta += move.Quantity
+	}
+	before, after, errResp := inmemoryStores.GetBookStoreInstance().AdjustStock(bookID, delta)
+	if errResp != nil {
+		revertMoves(bookID, moves)
+		return errResp
+	}
+	c.lines = append(c.lines, stockChangeLine{before: before, after: after, moves: moves, note: c.source.Note})
+	return nil
+}
+
+// allocate takes the quantity of a book from the warehouses chosen by the strategy
+func (c *stockChange) allocate(bookID, quantity int, strategy StructureData.AllocationStrategy, country string) ([]StructureData.StockAllocation, *StructureData.ErrorResponse) {
+	allocations, errResp := inmemoryStores.GetWarehouseStoreInstance().Allocate(bookID, quantity, strategy, country)
+	if errResp != nil {
+		return nil, errResp
+	}
+	if errResp := c.add(bookID, outgoing(allocations)); errResp != nil {
+		return nil, errResp
+	}
+	return allocations, nil
+}
+
+// release puts the quantity of a book back to the warehouses it was allocated from
+func (c *stockChange) release(bookID, quantity int, allocations []StructureData.StockAllocation) *StructureData.ErrorResponse {
+	if _, errResp := inmemoryStores.GetBookStoreInstance().GetBook(bookID); errResp != nil {
+		return errResp
+	}
+	return c.add(bookID, inmemoryStores.GetWarehouseStoreInstance().Release(bookID, quantity, allocations))
+}
+
+// reallocate moves an order's stock from its previous items to the requested ones in a single step at the
+// warehouses. It returns the requested items that could be allocated.
+func (c *stockChange) reallocate(previous, requested []StructureData.OrderItem, strategy StructureData.AllocationStrategy, country string) ([]StructureData.OrderItem, *StructureData.ErrorResponse) {
+	released, allocated, errResp := inmemoryStores.GetWarehouseStoreInstance().Reallocate(previous, requested, strategy, country)
+	if errResp != nil {
+		return nil, errResp
+	}
+
+	// Adjust the books by what moved, putting everything back if a book refuses
+	var bookIDs []int
+	var moves [][]StructureData.StockAllocation
+	for _, item := range released {
+		bookIDs = append(bookIDs, item.BookID)
+		moves = append(moves, item.Allocations)
+	}
+	for _, item := range allocated {
+		bookIDs = append(bookIDs, item.BookID)
+		moves = append(moves, outgoing(item.Allocations))
+	}
+	for i, bookID := range bookIDs {
+		if errResp := c.add(bookID, moves[i]); errResp != nil {
+			for j := i + 1; j < len(bookIDs); j++ {
+				revertMoves(bookIDs[j], moves[j])
+			}
+			c.undo()
+			return nil, errResp
+		}
+	}
+	return allocated, nil
+}
+
+// adjust adds delta to the stock of a book at a warehouse
+func (c *stockChange) adjust(bookID, warehouseID, delta int) *StructureData.ErrorResponse {
+	if _, errResp := inmemoryStores.GetWarehouseStoreInstance().AdjustStock(bookID, warehouseID, delta); errResp != nil {
+		return errResp
+	}
+	return c.add(bookID, []StructureData.StockAllocation{{WarehouseID: warehouseID, Quantity: delta}})
+}
+
+// undo puts back the stock moved so far, newest first, when the request is refused
+func (c *stockChange) undo() {
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	for i := len(c.lines) - 1; i >= 0; i-- {
+		line := c.lines[i]
+		if _, _, errResp := bookStore.AdjustStock(line.after.ID, line.before.Stock-line.after.Stock); errResp != nil {
+			log.Printf("Failed to put back the stock of book %d: %s", line.after.ID, errResp.Message)
+		}
+		revertMoves(line.after.ID, line.moves)
+	}
+	c.lines = nil
+}
+
+// commit records a stock_change for every book whose stock moved, with the book before and after the
+// move, and one stock movement per warehouse in the stock ledger
+func (c *stockChange) commit(r *http.Request) {
+	if len(c.lines) == 0 {
+		return
+	}
+	for _, line := range c.lines {
+		recordAudit(r, StructureData.ResourceBooks, line.after.ID, StructureData.AuditStockChange, line.before, line.after)
+		source := c.source
+		source.Note = line.note
+		recordStockMovements(r, source, line.before, line.moves)
+	}
+	c.lines = nil
+	persistStockLedger()
+}
+
+// outgoing turns the allocations stock was taken from into negative moves
+func outgoing(allocations []StructureData.StockAllocation) []StructureData.StockAllocation {
+	moves := make([]StructureData.StockAllocation, len(allocations))
+	for i, allocation := range allocations {
+		moves[i] = StructureData.StockAllocation{WarehouseID: allocation.WarehouseID, Quantity: -allocation.Quantity}
+	}
+	return moves
+}
+
+// revertMoves takes back the quantities moved at the warehouses of a book
+func revertMoves(bookID int, moves []StructureData.StockAllocation) {
+	warehouseStore := inmemoryStores.GetWarehouseStoreInstance()
+	for _, move := range moves {
+		if _, errResp := warehouseStore.AdjustStock(bookID, move.WarehouseID, -move.Quantity); errResp != nil {
+			log.Printf("Failed to put back %d of book %d at warehouse %d: %s", -move.Quantity, bookID, move.WarehouseID, errResp.Message)
+		}
+	}
+}
+
+// findBook returns a book whether it is active or in the trash
+func findBook(id int) (StructureData.Book, bool) {
+	store := inmemoryStores.GetBookStoreInstance()
+	if book, errResp := store.GetBook(id); errResp == nil {
+		return book, true
+	}
+	for _, book := range store.GetDeletedBooks() {
+		if book.ID == id {
+			return book, true
+		}
+	}
+	return StructureData.Book{}, false
+}
+
+// persistStockMovementsToFile saves the stock ledger to the JSON file in a pretty JSON format
+func persistStockMovementsToFile() error {
+	stockMovementsMu.Lock()
+	defer stockMovementsMu.Unlock()
+
+	file, err := os.Create(stockMovementFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetStockLedgerInstance().GetAllMovements())
+}
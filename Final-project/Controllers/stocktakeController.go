@@ -0,0 +1,442 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// JSON file path for stocktake persistence
+var stocktakeFile = "stocktakes.json"
+
+// InitializeStocktakeFile loads the stocktakes from the JSON file into the in-memory store and freezes
+// again the books of open stocktakes that freeze sales. It must run after the warehouses are loaded.
+func InitializeStocktakeFile() {
+	var stocktakes []StructureData.Stocktake
+	if err := readJSONFile(stocktakeFile, &stocktakes); err != nil {
+		panic("Failed to decode stocktake file")
+	}
+
+	store := inmemoryStores.GetStocktakeStoreInstance()
+	for _, stocktake := range stocktakes {
+		store.AddStocktakeDirectly(stocktake)
+		if stocktake.Status == StructureData.StocktakeOpen {
+			freezeStocktake(stocktake, true)
+		}
+	}
+	log.Printf("%d stocktakes loaded into store", len(stocktakes))
+}
+
+// GetAllStocktakes handles the GET /stocktakes request
+func GetAllStocktakes(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetStocktakeStoreInstance()
+
+	// Return all stocktakes as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetAllStocktakes())
+}
+
+// GetStocktakeByID handles the GET /stocktakes/{id} request
+func GetStocktakeByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetStocktakeStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/stocktakes/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid stocktake ID"))
+		return
+	}
+
+	// Retrieve the stocktake by ID
+	stocktake, errResp := store.GetStocktake(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stocktake)
+}
+
+// CreateStocktake handles the POST /stocktakes request. It opens a count at a warehouse, the default one
+// if none is given, of the listed books and of every book in the listed genres, and snapshots their stock
+// there. With freeze_sales the books cannot be allocated to orders from that warehouse until the stocktake
+// is closed; otherwise the stock movements during the count are taken into account when it is reviewed.
+func CreateStocktake(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetStocktakeStoreInstance()
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	warehouseStore := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Decode the request body
+	var stocktake StructureData.Stocktake
+	if err := json.NewDecoder(r.Body).Decode(&stocktake); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Count at the default warehouse unless another one is given
+	if stocktake.WarehouseID == 0 {
+		defaultWarehouse, errResp := warehouseStore.DefaultWarehouse()
+		if errResp != nil {
+			writeError(w, r, errResp)
+			return
+		}
+		stocktake.WarehouseID = defaultWarehouse.ID
+	} else if _, errResp := warehouseStore.GetWarehouse(stocktake.WarehouseID); errResp != nil {
+		writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Warehouse %d does not exist", stocktake.WarehouseID)))
+		return
+	}
+
+	// Resolve the books to count from the book IDs and the genres
+	var bookIDs []int
+	for _, id := range stocktake.BookIDs {
+		if _, errResp := bookStore.GetBook(id); errResp != nil {
+			writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Book %d does not exist", id)))
+			return
+		}
+		if !utils.ContainsInt(bookIDs, id) {
+			bookIDs = append(bookIDs, id)
+		}
+	}
+	if len(stocktake.Genres) > 0 {
+		for _, book := range bookStore.GetAllBooks() {
+			if utils.ContainsAnyString(stocktake.Genres, book.Genres) && !utils.ContainsInt(bookIDs, book.ID) {
+				bookIDs = append(bookIDs, book.ID)
+			}
+		}
+	}
+	if len(bookIDs) == 0 {
+		writeError(w, r, StructureData.NewValidationError("A stocktake must count at least one book; give book_ids or genres"))
+		return
+	}
+	sort.Ints(bookIDs)
+
+	// Mark where the ledger stands and snapshot the stock at the warehouse from the same movements, so
+	// that every later movement is counted once in the expected quantity
+	movements := inmemoryStores.GetStockLedgerInstance().GetAllMovements()
+	stocktake.LedgerMark = 0
+	if len(movements) > 0 {
+		stocktake.LedgerMark = movements[len(movements)-1].ID
+	}
+	snapshot := map[int]int{}
+	for _, movement := range movements {
+		if movement.WarehouseID == stocktake.WarehouseID {
+			snapshot[movement.BookID] += movement.Quantity
+		}
+	}
+	stocktake.Lines = nil
+	for _, id := range bookIDs {
+		stocktake.Lines = append(stocktake.Lines, StructureData.StocktakeLine{BookID: id, Snapshot: snapshot[id]})
+	}
+	stocktake.OpenedBy = auditContext(r).Actor
+
+	// Open the stocktake
+	createdStocktake, errResp := store.CreateStocktake(stocktake)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	freezeStocktake(createdStocktake, true)
+	recordAudit(r, StructureData.ResourceStocktakes, createdStocktake.ID, StructureData.AuditCreate, nil, createdStocktake)
+
+	// Persist to JSON file
+	if err := persistStocktakesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created stocktake
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdStocktake)
+}
+
+// SubmitStocktakeCounts handles the POST /stocktakes/{id}/counts request. Several counters may count the
+// same book, for example on different shelves: their counts add up, and a counter's recount of a book
+// replaces their earlier count.
+func SubmitStocktakeCounts(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetStocktakeStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/stocktakes/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid stocktake ID"))
+		return
+	}
+
+	// Decode the request body
+	var submission StructureData.StocktakeSubmission
+	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	if len(submission.Counts) == 0 {
+		writeError(w, r, StructureData.NewValidationError("counts cannot be empty"))
+		return
+	}
+
+	// Attribute the counts to the counter
+	counter := strings.TrimSpace(submission.Counter)
+	if counter == "" {
+		counter = auditContext(r).Actor
+	}
+	for i := range submission.Counts {
+		submission.Counts[i].Counter = counter
+	}
+
+	// Record the counts
+	previous, _ := store.GetStocktake(id)
+	updatedStocktake, errResp := store.RecordCounts(id, submission.Counts)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceStocktakes, id, StructureData.AuditUpdate, previous, updatedStocktake)
+
+	// Persist to JSON file
+	if err := persistStocktakesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated stocktake
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedStocktake)
+}
+
+// GetStocktakeVariance handles the GET /stocktakes/{id}/variance request, comparing the counted quantities
+// with the expected ones
+func GetStocktakeVariance(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetStocktakeStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/stocktakes/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid stocktake ID"))
+		return
+	}
+
+	// Retrieve the stocktake by ID
+	stocktake, errResp := store.GetStocktake(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return the variance report as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reviewStocktake(stocktake))
+}
+
+// ApproveStocktake handles the POST /stocktakes/{id}/approve request. The variance of every counted book
+// is posted to its stock at the warehouse as a stocktake correction; books nobody counted are left as they
+// are. Nothing is posted if a correction would take a warehouse below zero.
+func ApproveStocktake(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetStocktakeStoreInstance()
+	bookStore := inmemoryStores.GetBookStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/stocktakes/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid stocktake ID"))
+		return
+	}
+
+	// Review the stocktake
+	previous, errResp := store.GetStocktake(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	if previous.Status != StructureData.StocktakeOpen {
+		writeError(w, r, StructureData.NewConflictError(fmt.Sprintf("Stocktake %d is already %s", id, previous.Status)))
+		return
+	}
+	review := reviewStocktake(previous)
+
+	// Check every correction before posting any of them
+	var corrections []StructureData.StocktakeVariance
+	for _, line := range review.Lines {
+		if line.Variance == nil || *line.Variance == 0 {
+			continue
+		}
+		if _, errResp := bookStore.GetBook(line.BookID); errResp != nil {
+			writeError(w, r, StructureData.NewConflictError(fmt.Sprintf("Book %d no longer exists; cancel the stocktake or restore the book", line.BookID)))
+			return
+		}
+		if level := warehouseLevels(line.BookID)[previous.WarehouseID]; level+*line.Variance < 0 {
+			writeError(w, r, StructureData.NewConflictError(fmt.Sprintf("Correcting book %d by %d would leave warehouse %d with %d", line.BookID, *line.Variance, previous.WarehouseID, level+*line.Variance)))
+			return
+		}
+		corrections = append(corrections, line)
+	}
+
+	// Close the stocktake, so it cannot be approved twice
+	approvedStocktake, errResp := store.CloseStocktake(id, StructureData.StocktakeApproved, auditContext(r).Actor)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	freezeStocktake(approvedStocktake, false)
+	recordAudit(r, StructureData.ResourceStocktakes, id, StructureData.AuditUpdate, previous, approvedStocktake)
+
+	// Post the corrections
+	change := newStockChange(StructureData.StockMovement{
+		Type:       StructureData.StockMovementStocktake,
+		SourceType: StructureData.ResourceStocktakes,
+		SourceID:   id,
+	})
+	for _, line := range corrections {
+		change.source.Note = fmt.Sprintf("Counted %d, expected %d", *line.Counted, line.Expected)
+		if errResp := change.adjust(line.BookID, previous.WarehouseID, *line.Variance); errResp != nil {
+			change.commit(r)
+			writeError(w, r, errResp)
+			return
+		}
+	}
+	change.commit(r)
+
+	// Persist to JSON files
+	if err := persistBooksToFile(bookStore); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+	if err := persistStocktakesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the variance report as posted
+	review.Status = approvedStocktake.Status
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// CancelStocktake handles the POST /stocktakes/{id}/cancel request, closing the stocktake without posting
+// any correction
+func CancelStocktake(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetStocktakeStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/stocktakes/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid stocktake ID"))
+		return
+	}
+
+	// Close the stocktake
+	previous, _ := store.GetStocktake(id)
+	cancelledStocktake, errResp := store.CloseStocktake(id, StructureData.StocktakeCancelled, auditContext(r).Actor)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	freezeStocktake(cancelledStocktake, false)
+	recordAudit(r, StructureData.ResourceStocktakes, id, StructureData.AuditUpdate, previous, cancelledStocktake)
+
+	// Persist to JSON file
+	if err := persistStocktakesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the cancelled stocktake
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cancelledStocktake)
+}
+
+// reviewStocktake computes the variance of every book of a stocktake. The expected quantity is the
+// snapshot plus the stock movements recorded at the warehouse after the stocktake was opened, so sales,
+// receipts and transfers during the count do not show up as variance.
+func reviewStocktake(stocktake StructureData.Stocktake) StructureData.StocktakeReview {
+	ledger := inmemoryStores.GetStockLedgerInstance()
+	review := StructureData.StocktakeReview{
+		StocktakeID: stocktake.ID,
+		WarehouseID: stocktake.WarehouseID,
+		Status:      stocktake.Status,
+		Lines:       []StructureData.StocktakeVariance{},
+	}
+
+	// Keep the latest count of every counter, per book
+	counted := map[int]map[string]int{}
+	counters := map[int][]string{}
+	for _, count := range stocktake.Counts {
+		if counted[count.BookID] == nil {
+			counted[count.BookID] = map[string]int{}
+		}
+		if _, seen := counted[count.BookID][count.Counter]; !seen {
+			counters[count.BookID] = append(counters[count.BookID], count.Counter)
+		}
+		counted[count.BookID][count.Counter] = count.Quantity
+	}
+
+	for _, line := range stocktake.Lines {
+		variance := StructureData.StocktakeVariance{
+			BookID:   line.BookID,
+			Snapshot: line.Snapshot,
+			Counters: counters[line.BookID],
+		}
+		if book, found := findBook(line.BookID); found {
+			variance.Title = book.Title
+		}
+		for _, movement := range ledger.GetMovements(line.BookID) {
+			if movement.ID > stocktake.LedgerMark && movement.WarehouseID == stocktake.WarehouseID &&
+				!(movement.SourceType == StructureData.ResourceStocktakes && movement.SourceID == stocktake.ID) {
+				variance.Movements += movement.Quantity
+			}
+		}
+		variance.Expected = variance.Snapshot + variance.Movements
+
+		if counts, ok := counted[line.BookID]; ok {
+			total := 0
+			for _, quantity := range counts {
+				total += quantity
+			}
+			difference := total - variance.Expected
+			variance.Counted = &total
+			variance.Variance = &difference
+			review.Counted++
+			review.NetVariance += difference
+		} else {
+			review.Uncounted++
+		}
+		review.Lines = append(review.Lines, variance)
+	}
+	return review
+}
+
+// freezeStocktake freezes the books of a stocktake that freezes sales at its warehouse, or unfreezes them
+func freezeStocktake(stocktake StructureData.Stocktake, frozen bool) {
+	if !stocktake.FreezeSales {
+		return
+	}
+	warehouseStore := inmemoryStores.GetWarehouseStoreInstance()
+	for _, line := range stocktake.Lines {
+		warehouseStore.SetFrozen(line.BookID, stocktake.WarehouseID, frozen)
+	}
+}
+
+// persistStocktakesToFile saves all stocktakes to the JSON file in a pretty JSON format
+func persistStocktakesToFile() error {
+	file, err := os.Create(stocktakeFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetStocktakeStoreInstance().GetAllStocktakes())
+}
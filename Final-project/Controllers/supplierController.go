@@ -0,0 +1,204 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file path for supplier persistence
+var supplierFile = "suppliers.json"
+
+// InitializeSupplierFile loads the suppliers from the JSON file into the in-memory store
+func InitializeSupplierFile() {
+	var suppliers []StructureData.Supplier
+	if err := readJSONFile(supplierFile, &suppliers); err != nil {
+		panic("Failed to decode supplier file")
+	}
+
+	store := inmemoryStores.GetSupplierStoreInstance()
+	for _, supplier := range suppliers {
+		store.AddSupplierDirectly(supplier)
+	}
+}
+
+// GetAllSuppliers handles the GET /suppliers request
+func GetAllSuppliers(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetSupplierStoreInstance()
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetAllSuppliers())
+}
+
+// GetSupplierByID handles the GET /suppliers/{id} request
+func GetSupplierByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetSupplierStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/suppliers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid supplier ID"))
+		return
+	}
+
+	// Retrieve the supplier by ID
+	supplier, errResp := store.GetSupplier(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(supplier)
+}
+
+// CreateSupplier handles the POST /suppliers request
+func CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetSupplierStoreInstance()
+
+	// Decode the request body
+	var supplier StructureData.Supplier
+	if err := json.NewDecoder(r.Body).Decode(&supplier); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the supplier
+	if errResp := validateSupplier(supplier); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Create the supplier in the store
+	createdSupplier, errResp := store.CreateSupplier(supplier)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceSuppliers, createdSupplier.ID, StructureData.AuditCreate, nil, createdSupplier)
+
+	// Persist to JSON file
+	if err := persistSuppliersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created supplier
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdSupplier)
+}
+
+// UpdateSupplier handles the PUT /suppliers/{id} request
+func UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetSupplierStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/suppliers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid supplier ID"))
+		return
+	}
+
+	// Decode the request body
+	var supplier StructureData.Supplier
+	if err := json.NewDecoder(r.Body).Decode(&supplier); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the supplier
+	if errResp := validateSupplier(supplier); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Update the supplier in the store, keeping the previous version for the audit log
+	previous, _ := store.GetSupplier(id)
+	updatedSupplier, errResp := store.UpdateSupplier(id, supplier)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceSuppliers, id, StructureData.AuditUpdate, previous, updatedSupplier)
+
+	// Persist to JSON file
+	if err := persistSuppliersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated supplier
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedSupplier)
+}
+
+// DeleteSupplier handles the DELETE /suppliers/{id} request. Suppliers with purchase orders cannot be deleted.
+func DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetSupplierStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/suppliers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid supplier ID"))
+		return
+	}
+
+	// Refuse to orphan purchase orders
+	orders := inmemoryStores.GetPurchaseOrderStoreInstance().SearchPurchaseOrders(StructureData.PurchaseOrderSearchCriteria{SupplierIDs: []int{id}})
+	if len(orders) > 0 {
+		writeError(w, r, StructureData.NewConflictError(fmt.Sprintf("Supplier %d has %d purchase orders", id, len(orders))))
+		return
+	}
+
+	// Delete the supplier from the store
+	previous, _ := store.GetSupplier(id)
+	if errResp := store.DeleteSupplier(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceSuppliers, id, StructureData.AuditDelete, previous, nil)
+
+	// Persist to JSON file
+	if err := persistSuppliersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return success response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateSupplier checks that a supplier has a name and a sensible lead time
+func validateSupplier(supplier StructureData.Supplier) *StructureData.ErrorResponse {
+	if strings.TrimSpace(supplier.Name) == "" {
+		return StructureData.NewValidationError("name is required")
+	}
+	if supplier.LeadTimeDays < 0 {
+		return StructureData.NewValidationError("lead_time_days cannot be negative")
+	}
+	return nil
+}
+
+// persistSuppliersToFile saves all suppliers to the JSON file in a pretty JSON format
+func persistSuppliersToFile() error {
+	file, err := os.Create(supplierFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetSupplierStoreInstance().GetAllSuppliers())
+}
@@ -0,0 +1,116 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// GetTrash handles the GET /trash/{resource} request, listing the deleted records of a resource
+func GetTrash(w http.ResponseWriter, r *http.Request) {
+	// Extract the resource from the URL
+	resource := r.URL.Path[len("/trash/"):]
+
+	var records interface{}
+	switch resource {
+	case StructureData.ResourceAuthors:
+		records = inmemoryStores.GetAuthorStoreInstance().GetDeletedAuthors()
+	case StructureData.ResourceBooks:
+		records = inmemoryStores.GetBookStoreInstance().GetDeletedBooks()
+	case StructureData.ResourceCustomers:
+		records = inmemoryStores.GetCustomerStoreInstance().GetDeletedCustomers()
+	case StructureData.ResourceOrders:
+		records = inmemoryStores.GetOrderStoreInstance().GetDeletedOrders()
+	default:
+		writeError(w, r, StructureData.NewNotFoundError("Unknown resource "+resource))
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// RestoreRecord handles the POST /{resource}/{id}/restore request, taking a record out of the trash
+func RestoreRecord(w http.ResponseWriter, r *http.Request, resource string) {
+	enforcer := inmemoryStores.GetIntegrityEnforcerInstance()
+
+	// Extract ID from the URL
+	idStr := r.URL.Path[len("/"+resource+"/"):]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid ID"))
+		return
+	}
+
+	// Restore the record once the records it references are available
+	affected, errResp := enforcer.Restore(resource, id, auditContext(r))
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist every resource touched by the restore
+	if errResp := persistResources(affected); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return success response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeRecord handles the DELETE /trash/{resource}/{id} request, permanently removing a trashed record
+func PurgeRecord(w http.ResponseWriter, r *http.Request) {
+	enforcer := inmemoryStores.GetIntegrityEnforcerInstance()
+
+	// Extract the resource and ID from the URL
+	parts := strings.Split(r.URL.Path[len("/trash/"):], "/")
+	if len(parts) != 2 {
+		writeError(w, r, StructureData.NewNotFoundError("Not found"))
+		return
+	}
+	resource := parts[0]
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid ID"))
+		return
+	}
+
+	// Remove the record from the trash
+	if errResp := enforcer.Purge(resource, id, auditContext(r)); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if errResp := persistResources([]string{resource}); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return success response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeExpiredTrash permanently removes the records that have been in the trash for longer than retention
+func PurgeExpiredTrash(retention time.Duration) {
+	enforcer := inmemoryStores.GetIntegrityEnforcerInstance()
+
+	affected, purged := enforcer.PurgeExpired(time.Now().Add(-retention), StructureData.AuditContext{Actor: systemActor})
+	if purged == 0 {
+		return
+	}
+
+	if errResp := persistResources(affected); errResp != nil {
+		log.Printf("Failed to persist purged trash: %v", errResp)
+		return
+	}
+	log.Printf("Purged %d records deleted more than %s ago", purged, retention)
+}
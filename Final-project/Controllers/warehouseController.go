@@ -0,0 +1,432 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file paths for warehouse persistence
+var (
+	warehouseFile     = "warehouses.json"
+	stockLevelFile    = "stock_levels.json"
+	stockTransferFile = "stock_transfers.json"
+)
+
+// InitializeWarehouseFiles loads the warehouses, stock levels and transfers from their JSON files into the
+// in-memory store. A default warehouse is created if there is none, and books without stock levels have
+// their whole stock placed there. It must run after the books are loaded.
+func InitializeWarehouseFiles() {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	var warehouses []StructureData.Warehouse
+	if err := readJSONFile(warehouseFile, &warehouses); err != nil {
+		panic("Failed to decode warehouse file")
+	}
+	for _, warehouse := range warehouses {
+		store.AddWarehouseDirectly(warehouse)
+	}
+
+	var levels []StructureData.StockLevel
+	if err := readJSONFile(stockLevelFile, &levels); err != nil {
+		panic("Failed to decode stock level file")
+	}
+	for _, level := range levels {
+		store.SetStockDirectly(level)
+	}
+
+	var transfers []StructureData.StockTransfer
+	if err := readJSONFile(stockTransferFile, &transfers); err != nil {
+		panic("Failed to decode stock transfer file")
+	}
+	for _, transfer := range transfers {
+		store.AddTransferDirectly(transfer)
+	}
+
+	// Create the default warehouse on first start
+	if len(warehouses) == 0 {
+		store.CreateWarehouse(StructureData.Warehouse{Code: "MAIN", Name: "Main warehouse"})
+		if err := persistWarehousesToFile(); err != nil {
+			log.Printf("Failed to save warehouses: %v", err)
+		}
+	}
+
+	// Place the stock of books without a location at the default warehouse
+	defaultWarehouse, _ := store.DefaultWarehouse()
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	placed := 0
+	for _, book := range append(bookStore.GetAllBooks(), bookStore.GetDeletedBooks()...) {
+		bookLevels := store.GetStockLevels(book.ID)
+		if len(bookLevels) == 0 {
+			store.SetStockDirectly(StructureData.StockLevel{BookID: book.ID, WarehouseID: defaultWarehouse.ID, Quantity: book.Stock})
+			placed++
+			continue
+		}
+		total := 0
+		for _, level := range bookLevels {
+			total += level.Quantity
+		}
+		if total != book.Stock {
+			log.Printf("Warning: book %d has stock %d but its warehouses hold %d", book.ID, book.Stock, total)
+		}
+	}
+	if placed > 0 {
+		if err := persistStockLevelsToFile(); err != nil {
+			log.Printf("Failed to save stock levels: %v", err)
+		}
+	}
+	log.Printf("%d warehouses loaded into store, stock of %d books placed at %s", len(store.GetAllWarehouses()), placed, defaultWarehouse.Code)
+}
+
+// GetAllWarehouses handles the GET /warehouses request
+func GetAllWarehouses(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetAllWarehouses())
+}
+
+// GetWarehouseByID handles the GET /warehouses/{id} request
+func GetWarehouseByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/warehouses/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid warehouse ID"))
+		return
+	}
+
+	// Retrieve the warehouse by ID
+	warehouse, errResp := store.GetWarehouse(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(warehouse)
+}
+
+// CreateWarehouse handles the POST /warehouses request
+func CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Decode the request body
+	var warehouse StructureData.Warehouse
+	if err := json.NewDecoder(r.Body).Decode(&warehouse); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the warehouse
+	if errResp := validateWarehouse(warehouse); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Create the warehouse in the store
+	createdWarehouse, errResp := store.CreateWarehouse(warehouse)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceWarehouses, createdWarehouse.ID, StructureData.AuditCreate, nil, createdWarehouse)
+
+	// Persist to JSON file
+	if err := persistWarehousesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created warehouse
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdWarehouse)
+}
+
+// UpdateWarehouse handles the PUT /warehouses/{id} request
+func UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/warehouses/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid warehouse ID"))
+		return
+	}
+
+	// Decode the request body
+	var warehouse StructureData.Warehouse
+	if err := json.NewDecoder(r.Body).Decode(&warehouse); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the warehouse
+	if errResp := validateWarehouse(warehouse); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Update the warehouse in the store, keeping the previous version for the audit log
+	previous, _ := store.GetWarehouse(id)
+	updatedWarehouse, errResp := store.UpdateWarehouse(id, warehouse)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceWarehouses, id, StructureData.AuditUpdate, previous, updatedWarehouse)
+
+	// Persist to JSON file
+	if err := persistWarehousesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated warehouse
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedWarehouse)
+}
+
+// DeleteWarehouse handles the DELETE /warehouses/{id} request. Warehouses holding stock or awaiting
+// goods cannot be deleted, nor can the last one.
+func DeleteWarehouse(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/warehouses/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid warehouse ID"))
+		return
+	}
+
+	// Refuse to strand goods still expected from suppliers
+	for _, order := range inmemoryStores.GetPurchaseOrderStoreInstance().GetAllPurchaseOrders() {
+		if order.WarehouseID == id && order.Status != StructureData.PurchaseOrderReceived {
+			writeError(w, r, StructureData.NewConflictError(fmt.Sprintf("Purchase order %d is still to be delivered to warehouse %d", order.ID, id)))
+			return
+		}
+	}
+
+	// Delete the warehouse from the store
+	previous, _ := store.GetWarehouse(id)
+	if errResp := store.DeleteWarehouse(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	recordAudit(r, StructureData.ResourceWarehouses, id, StructureData.AuditDelete, previous, nil)
+
+	// Persist to JSON files
+	if err := persistWarehousesToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+	if err := persistStockLevelsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return success response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWarehouseStock handles the GET /warehouses/{id}/stock request, listing the stock of every book held at a warehouse
+func GetWarehouseStock(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/warehouses/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid warehouse ID"))
+		return
+	}
+	if _, errResp := store.GetWarehouse(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetWarehouseStock(id))
+}
+
+// GetBookStockLevels handles the GET /books/{id}/stock-levels request, listing the stock of a book at every warehouse
+func GetBookStockLevels(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/books/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
+		return
+	}
+	if _, found := findBook(id); !found {
+		writeError(w, r, StructureData.NewNotFoundError("Book not found"))
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.GetStockLevels(id))
+}
+
+// CreateStockTransfer handles the POST /stock-transfers request, moving stock of a book between two warehouses
+func CreateStockTransfer(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+
+	// Decode the request body
+	var transfer StructureData.StockTransfer
+	if err := json.NewDecoder(r.Body).Decode(&transfer); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// The book must exist to move its stock
+	book, errResp := inmemoryStores.GetBookStoreInstance().GetBook(transfer.BookID)
+	if errResp != nil {
+		writeError(w, r, StructureData.NewValidationError(fmt.Sprintf("Book %d does not exist", transfer.BookID)))
+		return
+	}
+
+	// Move the stock
+	transfer.Actor = auditContext(r).Actor
+	createdTransfer, errResp := store.Transfer(transfer)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Record both ends of the transfer in the stock ledger; the book's total stock is unchanged
+	source := StructureData.StockMovement{
+		Type:       StructureData.StockMovementTransfer,
+		SourceType: StructureData.ResourceStockTransfers,
+		SourceID:   createdTransfer.ID,
+		Note:       createdTransfer.Note,
+	}
+	recordStockMovements(r, source, book, []StructureData.StockAllocation{
+		{WarehouseID: createdTransfer.FromWarehouseID, Quantity: -createdTransfer.Quantity},
+		{WarehouseID: createdTransfer.ToWarehouseID, Quantity: createdTransfer.Quantity},
+	})
+	persistStockLedger()
+
+	// Persist to JSON file
+	if err := persistStockTransfersToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created transfer
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdTransfer)
+}
+
+// GetStockTransfers handles the GET /stock-transfers request, newest first, filtered by the optional and
+// repeatable query parameters book_id and warehouse_id
+func GetStockTransfers(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWarehouseStoreInstance()
+	query := r.URL.Query()
+
+	// Build the search criteria from the query parameters
+	var criteria StructureData.StockTransferSearchCriteria
+	for param, ids := range map[string]*[]int{"book_id": &criteria.BookIDs, "warehouse_id": &criteria.WarehouseIDs} {
+		for _, idStr := range query[param] {
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				writeError(w, r, StructureData.NewValidationError("Invalid "+param))
+				return
+			}
+			*ids = append(*ids, id)
+		}
+	}
+
+	// Return the search results as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.SearchTransfers(criteria))
+}
+
+// validateWarehouse checks that a warehouse has a code and a name
+func validateWarehouse(warehouse StructureData.Warehouse) *StructureData.ErrorResponse {
+	if strings.TrimSpace(warehouse.Code) == "" || strings.TrimSpace(warehouse.Name) == "" {
+		return StructureData.NewValidationError("code and name are required")
+	}
+	return nil
+}
+
+// allocationStrategy returns the strategy requested for an order, the nearest warehouse by default
+func allocationStrategy(strategy StructureData.AllocationStrategy) (StructureData.AllocationStrategy, *StructureData.ErrorResponse) {
+	if strategy == "" {
+		return StructureData.AllocateNearest, nil
+	}
+	if !StructureData.IsValidAllocationStrategy(strategy) {
+		return "", StructureData.NewValidationError("allocation_strategy must be nearest, most_stock or split")
+	}
+	return strategy, nil
+}
+
+// warehouseLevels returns the stock of a book per warehouse
+func warehouseLevels(bookID int) map[int]int {
+	levels := map[int]int{}
+	for _, level := range inmemoryStores.GetWarehouseStoreInstance().GetStockLevels(bookID) {
+		levels[level.WarehouseID] = level.Quantity
+	}
+	return levels
+}
+
+// persistWarehousesToFile saves all warehouses to the JSON file in a pretty JSON format
+func persistWarehousesToFile() error {
+	file, err := os.Create(warehouseFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetWarehouseStoreInstance().GetAllWarehouses())
+}
+
+// persistStockLevelsToFile saves the stock of every book per warehouse to the JSON file in a pretty JSON format
+func persistStockLevelsToFile() error {
+	stockMovementsMu.Lock()
+	defer stockMovementsMu.Unlock()
+
+	file, err := os.Create(stockLevelFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetWarehouseStoreInstance().GetAllStockLevels())
+}
+
+// persistStockTransfersToFile saves all stock transfers to the JSON file in a pretty JSON format
+func persistStockTransfersToFile() error {
+	file, err := os.Create(stockTransferFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetWarehouseStoreInstance().SearchTransfers(StructureData.StockTransferSearchCriteria{}))
+}
@@ -0,0 +1,325 @@
+package Controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file paths for webhook persistence
+var (
+	webhookFile         = "webhooks.json"
+	webhookDeliveryFile = "webhook_deliveries.json"
+)
+
+var (
+	webhookDispatcher   *WebhookDispatcher
+	webhookDeliveriesMu sync.Mutex // Serializes writes of the delivery file from concurrent deliveries
+)
+
+// InitializeWebhookFiles loads the webhook subscriptions and deliveries from their JSON files into the in-memory store
+func InitializeWebhookFiles() {
+	store := inmemoryStores.GetWebhookStoreInstance()
+
+	var subscriptions []StructureData.WebhookSubscription
+	if err := readJSONFile(webhookFile, &subscriptions); err != nil {
+		panic("Failed to decode webhook file")
+	}
+	for _, subscription := range subscriptions {
+		store.AddSubscriptionDirectly(subscription)
+	}
+
+	var deliveries []StructureData.WebhookDelivery
+	if err := readJSONFile(webhookDeliveryFile, &deliveries); err != nil {
+		panic("Failed to decode webhook delivery file")
+	}
+	for _, delivery := range deliveries {
+		store.AddDeliveryDirectly(delivery)
+	}
+	log.Printf("%d webhook subscriptions and %d deliveries loaded into store", len(subscriptions), len(deliveries))
+}
+
+// StartWebhookDispatcher subscribes the webhook dispatcher to the event bus and resumes pending deliveries
+func StartWebhookDispatcher(policy RetryPolicy) {
+	webhookDispatcher = NewWebhookDispatcher(inmemoryStores.GetWebhookStoreInstance(), &http.Client{Timeout: 10 * time.Second}, policy)
+	webhookDispatcher.OnChange = func() {
+		if err := persistWebhookDeliveriesToFile(); err != nil {
+			log.Printf("Failed to save webhook deliveries: %v", err)
+		}
+	}
+	inmemoryStores.GetEventBusInstance().Subscribe(webhookDispatcher.HandleEvent)
+	webhookDispatcher.Resume()
+}
+
+// GetAllWebhooks handles the GET /webhooks request
+func GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWebhookStoreInstance()
+
+	subscriptions := []StructureData.WebhookSubscription{}
+	for _, subscription := range store.GetAllSubscriptions() {
+		subscriptions = append(subscriptions, hideSecret(subscription))
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// GetWebhookByID handles the GET /webhooks/{id} request
+func GetWebhookByID(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWebhookStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/webhooks/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid webhook ID"))
+		return
+	}
+
+	// Retrieve the subscription by ID
+	subscription, errResp := store.GetSubscription(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hideSecret(subscription))
+}
+
+// CreateWebhook handles the POST /webhooks request. The response is the only one containing the signing secret.
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWebhookStoreInstance()
+
+	// Decode the request body
+	var subscription StructureData.WebhookSubscription
+	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the URL and event types
+	if errResp := validateWebhook(subscription); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Generate a secret if the partner did not choose one
+	if subscription.Secret == "" {
+		buf := make([]byte, 32)
+		rand.Read(buf)
+		subscription.Secret = hex.EncodeToString(buf)
+	}
+	subscription.Active = true
+
+	// Create the subscription in the store
+	createdSubscription, errResp := store.CreateSubscription(subscription)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistWebhooksToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the created subscription
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdSubscription)
+}
+
+// UpdateWebhook handles the PUT /webhooks/{id} request. The secret is kept unless a new one is given.
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWebhookStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/webhooks/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid webhook ID"))
+		return
+	}
+
+	// Retrieve the existing subscription
+	existing, errResp := store.GetSubscription(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Decode the request body
+	var subscription StructureData.WebhookSubscription
+	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+
+	// Validate the URL and event types
+	if errResp := validateWebhook(subscription); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = existing.Secret
+	}
+
+	// Update the subscription in the store
+	updatedSubscription, errResp := store.UpdateSubscription(id, subscription)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistWebhooksToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the updated subscription
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hideSecret(updatedSubscription))
+}
+
+// DeleteWebhook handles the DELETE /webhooks/{id} request
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWebhookStoreInstance()
+
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/webhooks/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid webhook ID"))
+		return
+	}
+
+	// Delete the subscription from the store
+	if errResp := store.DeleteSubscription(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistWebhooksToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return success response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries handles the GET /webhook-deliveries request, filtered by the optional and
+// repeatable query parameters subscription_id, status and event_type. status=dead lists the dead letters.
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	store := inmemoryStores.GetWebhookStoreInstance()
+	query := r.URL.Query()
+
+	// Build the search criteria from the query parameters
+	var criteria StructureData.WebhookDeliverySearchCriteria
+	for _, idStr := range query["subscription_id"] {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			writeError(w, r, StructureData.NewValidationError("Invalid subscription_id"))
+			return
+		}
+		criteria.SubscriptionIDs = append(criteria.SubscriptionIDs, id)
+	}
+	for _, status := range query["status"] {
+		criteria.Statuses = append(criteria.Statuses, StructureData.DeliveryStatus(status))
+	}
+	for _, eventType := range query["event_type"] {
+		criteria.EventTypes = append(criteria.EventTypes, StructureData.EventType(eventType))
+	}
+
+	// Return the search results as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(store.SearchDeliveries(criteria))
+}
+
+// RedeliverWebhook handles the POST /webhook-deliveries/{id}/redeliver request
+func RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/webhook-deliveries/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid delivery ID"))
+		return
+	}
+
+	// Queue the delivery again with a fresh set of retries
+	delivery, errResp := webhookDispatcher.Redeliver(id)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return the queued delivery
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// validateWebhook checks that a subscription has an absolute http(s) URL and only known event types
+func validateWebhook(subscription StructureData.WebhookSubscription) *StructureData.ErrorResponse {
+	parsed, err := url.Parse(subscription.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return StructureData.NewValidationError("url must be an absolute http or https URL")
+	}
+	for _, eventType := range subscription.EventTypes {
+		if !StructureData.IsValidEventType(eventType) {
+			return StructureData.NewValidationError("Unknown event type " + string(eventType))
+		}
+	}
+	return nil
+}
+
+// hideSecret removes the signing secret from a subscription before it is returned
+func hideSecret(subscription StructureData.WebhookSubscription) StructureData.WebhookSubscription {
+	subscription.Secret = ""
+	return subscription
+}
+
+// persistWebhooksToFile saves all webhook subscriptions to the JSON file in a pretty JSON format
+func persistWebhooksToFile() error {
+	file, err := os.Create(webhookFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetWebhookStoreInstance().GetAllSubscriptions())
+}
+
+// persistWebhookDeliveriesToFile saves all webhook deliveries to the JSON file in a pretty JSON format
+func persistWebhookDeliveriesToFile() error {
+	webhookDeliveriesMu.Lock()
+	defer webhookDeliveriesMu.Unlock()
+
+	file, err := os.Create(webhookDeliveryFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetWebhookStoreInstance().GetAllDeliveries())
+}
@@ -0,0 +1,230 @@
+package Controllers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	"finalProject/StructureData"
+)
+
+// Headers sent with every webhook delivery
+const (
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// RetryPolicy controls how often a failed webhook delivery is retried. The delay doubles after
+// every failed attempt, starting at BaseDelay and capped at MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy retries for roughly a day before giving up
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 8,
+	BaseDelay:   time.Minute,
+	MaxDelay:    6 * time.Hour,
+}
+
+// Delay returns how long to wait after the given number of failed attempts
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// WebhookDispatcher turns domain events into signed HTTP deliveries to the matching subscriptions,
+// retrying failures with exponential backoff and moving deliveries to the dead-letter list once
+// the retries are exhausted.
+type WebhookDispatcher struct {
+	store    interfaces.WebhookStore
+	client   *http.Client
+	policy   RetryPolicy
+	OnChange func() // Called after every delivery state change, e.g. to persist the deliveries
+}
+
+// NewWebhookDispatcher creates a dispatcher sending deliveries with the given client
+func NewWebhookDispatcher(store interfaces.WebhookStore, client *http.Client, policy RetryPolicy) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:  store,
+		client: client,
+		policy: policy,
+	}
+}
+
+// HandleEvent creates a delivery for every active subscription wanting the event and sends them in the background
+func (d *WebhookDispatcher) HandleEvent(event StructureData.DomainEvent) {
+	created := false
+	for _, subscription := range d.store.GetAllSubscriptions() {
+		if !subscription.Matches(event.Type) {
+			continue
+		}
+		now := time.Now()
+		delivery := d.store.CreateDelivery(StructureData.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			Event:          event,
+			Status:         StructureData.DeliveryPending,
+			NextAttemptAt:  &now,
+		})
+		created = true
+		go d.attempt(delivery.ID)
+	}
+	if created {
+		d.changed()
+	}
+}
+
+// Resume schedules the pending deliveries, for example those left over from before a restart
+func (d *WebhookDispatcher) Resume() {
+	for _, delivery := range d.store.SearchDeliveries(StructureData.WebhookDeliverySearchCriteria{
+		Statuses: []StructureData.DeliveryStatus{StructureData.DeliveryPending},
+	}) {
+		delay := time.Duration(0)
+		if delivery.NextAttemptAt != nil {
+			delay = time.Until(*delivery.NextAttemptAt)
+		}
+		d.schedule(delivery.ID, delay)
+	}
+}
+
+// Redeliver sends a dead or delivered delivery again, with a fresh set of retries
+func (d *WebhookDispatcher) Redeliver(id int) (StructureData.WebhookDelivery, *StructureData.ErrorResponse) {
+	delivery, errResp := d.store.GetDelivery(id)
+	if errResp != nil {
+		return StructureData.WebhookDelivery{}, errResp
+	}
+	if delivery.Status == StructureData.DeliveryPending {
+		return StructureData.WebhookDelivery{}, StructureData.NewConflictError("Delivery is already pending")
+	}
+	if _, errResp := d.store.GetSubscription(delivery.SubscriptionID); errResp != nil {
+		return StructureData.WebhookDelivery{}, StructureData.NewConflictError(fmt.Sprintf("Subscription %d no longer exists", delivery.SubscriptionID))
+	}
+
+	now := time.Now()
+	delivery.Status = StructureData.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	delivery.DeliveredAt = nil
+	delivery, errResp = d.store.UpdateDelivery(id, delivery)
+	if errResp != nil {
+		return StructureData.WebhookDelivery{}, errResp
+	}
+	d.changed()
+
+	go d.attempt(id)
+	return delivery, nil
+}
+
+// schedule attempts a delivery after a delay
+func (d *WebhookDispatcher) schedule(id int, delay time.Duration) {
+	if delay <= 0 {
+		go d.attempt(id)
+		return
+	}
+	time.AfterFunc(delay, func() { d.attempt(id) })
+}
+
+// attempt sends a pending delivery once and records the outcome, scheduling a retry if it failed
+func (d *WebhookDispatcher) attempt(id int) {
+	delivery, errResp := d.store.GetDelivery(id)
+	if errResp != nil || delivery.Status != StructureData.DeliveryPending {
+		return
+	}
+	subscription, errResp := d.store.GetSubscription(delivery.SubscriptionID)
+	if errResp != nil {
+		delivery.Status = StructureData.DeliveryDead
+		delivery.LastError = "Subscription no longer exists"
+		delivery.NextAttemptAt = nil
+		d.store.UpdateDelivery(id, delivery)
+		d.changed()
+		return
+	}
+
+	delivery.Attempts++
+	statusCode, err := d.send(subscription, delivery)
+	delivery.LastStatusCode = statusCode
+	now := time.Now()
+
+	switch {
+	case err == nil:
+		delivery.Status = StructureData.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.policy.MaxAttempts:
+		delivery.Status = StructureData.DeliveryDead
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+		log.Printf("Webhook delivery %d to %s failed after %d attempts: %v", id, subscription.URL, delivery.Attempts, err)
+	default:
+		delay := d.policy.Delay(delivery.Attempts)
+		next := now.Add(delay)
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+		d.schedule(id, delay)
+	}
+
+	d.store.UpdateDelivery(id, delivery)
+	d.changed()
+}
+
+// send posts the event to the subscription URL, treating anything but a 2xx response as a failure
+func (d *WebhookDispatcher) send(subscription StructureData.WebhookSubscription, delivery StructureData.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, string(delivery.Event.Type))
+	req.Header.Set(webhookDeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(webhookSignatureHeader, SignWebhookPayload(subscription.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) changed() {
+	if d.OnChange != nil {
+		d.OnChange()
+	}
+}
+
+// SignWebhookPayload returns the signature header value for a delivery body: the hex HMAC-SHA256
+// of the body keyed with the subscription secret, prefixed with "sha256="
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,154 @@
+package Controllers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// testRetryPolicy retries quickly so that a test runs through every attempt in milliseconds
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+}
+
+// webhookReceiver is a test server answering webhook deliveries with the given status codes in turn,
+// repeating the last one, and keeping the requests it received
+type webhookReceiver struct {
+	mu         sync.Mutex
+	statuses   []int
+	bodies     [][]byte
+	signatures []string
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	status := rcv.statuses[len(rcv.statuses)-1]
+	if len(rcv.bodies) < len(rcv.statuses) {
+		status = rcv.statuses[len(rcv.bodies)]
+	}
+	rcv.bodies = append(rcv.bodies, body)
+	rcv.signatures = append(rcv.signatures, r.Header.Get(webhookSignatureHeader))
+	w.WriteHeader(status)
+}
+
+// requests returns how many deliveries the receiver got
+func (rcv *webhookReceiver) requests() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.bodies)
+}
+
+// subscribeReceiver starts a test server for the receiver and subscribes it to every event
+func subscribeReceiver(t *testing.T, rcv *webhookReceiver, secret string) StructureData.WebhookSubscription {
+	t.Helper()
+	server := httptest.NewServer(rcv)
+	t.Cleanup(server.Close)
+
+	store := inmemoryStores.GetWebhookStoreInstance()
+	subscription, errResp := store.CreateSubscription(StructureData.WebhookSubscription{
+		URL:    server.URL,
+		Secret: secret,
+		Active: true,
+	})
+	if errResp != nil {
+		t.Fatalf("Failed to create subscription: %s", errResp.Message)
+	}
+	t.Cleanup(func() { store.DeleteSubscription(subscription.ID) })
+	return subscription
+}
+
+// waitForDelivery waits until the only delivery of a subscription has left the pending status
+func waitForDelivery(t *testing.T, subscriptionID int) StructureData.WebhookDelivery {
+	t.Helper()
+	store := inmemoryStores.GetWebhookStoreInstance()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries := store.SearchDeliveries(StructureData.WebhookDeliverySearchCriteria{SubscriptionIDs: []int{subscriptionID}})
+		if len(deliveries) > 1 {
+			t.Fatalf("Expected a single delivery, got %d", len(deliveries))
+		}
+		if len(deliveries) == 1 && deliveries[0].Status != StructureData.DeliveryPending {
+			return deliveries[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Delivery to subscription %d is still pending", subscriptionID)
+	return StructureData.WebhookDelivery{}
+}
+
+func TestWebhookDispatcherSignsAndRetriesDeliveries(t *testing.T) {
+	rcv := &webhookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusOK}}
+	subscription := subscribeReceiver(t, rcv, "retry-secret")
+	dispatcher := NewWebhookDispatcher(inmemoryStores.GetWebhookStoreInstance(), http.DefaultClient, testRetryPolicy)
+
+	dispatcher.HandleEvent(StructureData.DomainEvent{
+		ID:         1,
+		Type:       StructureData.EventOrderCreated,
+		Resource:   StructureData.ResourceOrders,
+		ResourceID: 1,
+		Actor:      "test",
+		OccurredAt: time.Now(),
+	})
+	delivery := waitForDelivery(t, subscription.ID)
+
+	if delivery.Status != StructureData.DeliveryDelivered {
+		t.Fatalf("Expected status %s, got %s (%s)", StructureData.DeliveryDelivered, delivery.Status, delivery.LastError)
+	}
+	if delivery.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", delivery.Attempts)
+	}
+	if delivery.LastStatusCode != http.StatusOK || delivery.DeliveredAt == nil {
+		t.Errorf("Expected a delivered 200, got status code %d and delivered at %v", delivery.LastStatusCode, delivery.DeliveredAt)
+	}
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if len(rcv.bodies) != 2 {
+		t.Fatalf("Expected the receiver to get 2 requests, got %d", len(rcv.bodies))
+	}
+	for i, body := range rcv.bodies {
+		if want := SignWebhookPayload("retry-secret", body); rcv.signatures[i] != want {
+			t.Errorf("Request %d: expected signature %s, got %s", i+1, want, rcv.signatures[i])
+		}
+	}
+}
+
+func TestWebhookDispatcherDeadLettersAfterMaxAttempts(t *testing.T) {
+	rcv := &webhookReceiver{statuses: []int{http.StatusInternalServerError}}
+	subscription := subscribeReceiver(t, rcv, "dead-secret")
+	dispatcher := NewWebhookDispatcher(inmemoryStores.GetWebhookStoreInstance(), http.DefaultClient, testRetryPolicy)
+
+	dispatcher.HandleEvent(StructureData.DomainEvent{
+		ID:         2,
+		Type:       StructureData.EventBookUpdated,
+		Resource:   StructureData.ResourceBooks,
+		ResourceID: 1,
+		Actor:      "test",
+		OccurredAt: time.Now(),
+	})
+	delivery := waitForDelivery(t, subscription.ID)
+
+	if delivery.Status != StructureData.DeliveryDead {
+		t.Fatalf("Expected status %s, got %s", StructureData.DeliveryDead, delivery.Status)
+	}
+	if delivery.Attempts != testRetryPolicy.MaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", testRetryPolicy.MaxAttempts, delivery.Attempts)
+	}
+	if delivery.LastStatusCode != http.StatusInternalServerError || delivery.LastError == "" || delivery.NextAttemptAt != nil {
+		t.Errorf("Expected a dead 500 with an error and no next attempt, got %+v", delivery)
+	}
+	if got := rcv.requests(); got != testRetryPolicy.MaxAttempts {
+		t.Errorf("Expected the receiver to get %d requests, got %d", testRetryPolicy.MaxAttempts, got)
+	}
+}
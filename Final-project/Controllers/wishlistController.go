@@ -0,0 +1,436 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/StructureData"
+)
+
+// JSON file paths for wishlist and back-in-stock subscription persistence
+var (
+	wishlistFile          = "wishlists.json"
+	stockSubscriptionFile = "stock_subscriptions.json"
+	stockNotificationFile = "stock_notifications.json"
+)
+
+var stockSubscriptionsMu sync.Mutex // Serializes writes of the subscription files from requests and the monitor
+
+// InitializeWishlistFiles loads the wishlists, the back-in-stock subscriptions and the notifications sent
+// from their JSON files into the in-memory stores
+func InitializeWishlistFiles() {
+	var wishlists []StructureData.Wishlist
+	if err := readJSONFile(wishlistFile, &wishlists); err != nil {
+		panic("Failed to decode wishlist file")
+	}
+	wishlistStore := inmemoryStores.GetWishlistStoreInstance()
+	for _, wishlist := range wishlists {
+		wishlistStore.AddWishlistDirectly(wishlist)
+	}
+
+	store := inmemoryStores.GetStockSubscriptionStoreInstance()
+	var subscriptions []StructureData.StockSubscription
+	if err := readJSONFile(stockSubscriptionFile, &subscriptions); err != nil {
+		panic("Failed to decode stock subscription file")
+	}
+	for _, subscription := range subscriptions {
+		store.AddSubscriptionDirectly(subscription)
+	}
+
+	var notifications []StructureData.StockNotification
+	if err := readJSONFile(stockNotificationFile, &notifications); err != nil {
+		panic("Failed to decode stock notification file")
+	}
+	for _, notification := range notifications {
+		store.AddNotificationDirectly(notification)
+	}
+}
+
+// StartBackInStockMonitor notifies the subscribers of the books restocked while the server was down and
+// watches later stock changes. Notifications go through the monitor's notifier, which only logs them.
+func StartBackInStockMonitor() {
+	monitor := inmemoryStores.GetBackInStockMonitorInstance()
+	monitor.OnNotify = func() {
+		if err := persistStockSubscriptionsToFile(); err != nil {
+			log.Printf("Failed to save stock subscriptions: %v", err)
+		}
+	}
+	monitor.Start()
+}
+
+// GetWishlist handles the GET /customers/{id}/wishlist request. Every book comes with its current stock and
+// whether the customer is notified when it is back in stock.
+func GetWishlist(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+	if _, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Fill the books' details, leaving out deleted books
+	bookStore := inmemoryStores.GetBookStoreInstance()
+	subscribed := activeSubscriptions(id)
+	wishlist := inmemoryStores.GetWishlistStoreInstance().GetWishlist(id)
+	items := []StructureData.WishlistItem{}
+	for _, item := range wishlist.Items {
+		book, errResp := bookStore.GetBook(item.BookID)
+		if errResp != nil {
+			continue
+		}
+		item.Title = book.Title
+		item.Stock = book.Stock
+		item.Notify = subscribed[item.BookID]
+		items = append(items, item)
+	}
+	wishlist.Items = items
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wishlist)
+}
+
+// AddToWishlist handles the POST /customers/{id}/wishlist request. The body names the book_id, and
+// "notify": true also subscribes the customer to back-in-stock notifications for it. Saving a book already
+// in the wishlist returns it with 200 OK.
+func AddToWishlist(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+
+	// Decode the request body
+	var request struct {
+		BookID int  `json:"book_id"`
+		Notify bool `json:"notify"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	book, errResp := validateCustomerBook(id, request.BookID)
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Save the book and subscribe if asked to
+	item, added := inmemoryStores.GetWishlistStoreInstance().AddItem(id, book.ID)
+	if request.Notify {
+		inmemoryStores.GetStockSubscriptionStoreInstance().Subscribe(id, book.ID)
+	}
+	item.Title = book.Title
+	item.Stock = book.Stock
+	item.Notify = activeSubscriptions(id)[book.ID]
+
+	// Persist to JSON files
+	if err := persistWishlistsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+	if err := persistStockSubscriptionsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the saved item
+	w.Header().Set("Content-Type", "application/json")
+	if added {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(item)
+}
+
+// RemoveFromWishlist handles the DELETE /customers/{id}/wishlist/{book_id} request. It also cancels the
+// customer's back-in-stock subscription to the book, if any.
+func RemoveFromWishlist(w http.ResponseWriter, r *http.Request) {
+	// Extract the customer and book IDs from the URL
+	id, bookID, errResp := customerChildIDs(r, "book")
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Remove the book and its subscription
+	if errResp := inmemoryStores.GetWishlistStoreInstance().RemoveItem(id, bookID); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+	inmemoryStores.GetStockSubscriptionStoreInstance().Unsubscribe(id, bookID)
+
+	// Persist to JSON files
+	if err := persistWishlistsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+	if err := persistStockSubscriptionsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStockSubscriptions handles the GET /customers/{id}/subscriptions request. ?status= filters the
+// subscriptions on active, notified or cancelled.
+func GetStockSubscriptions(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+	if _, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	subscriptions, errResp := filterSubscriptions(r, inmemoryStores.GetStockSubscriptionStoreInstance().GetSubscriptionsByCustomer(id))
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// SubscribeToBook handles the POST /customers/{id}/subscriptions request, subscribing the customer to be
+// notified the next time the book_id of the body goes from out of stock to in stock. Subscribing again
+// returns the active subscription with 200 OK.
+func SubscribeToBook(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+
+	// Decode the request body
+	var request struct {
+		BookID int `json:"book_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid input"))
+		return
+	}
+	if _, errResp := validateCustomerBook(id, request.BookID); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Subscribe the customer
+	subscription, created := inmemoryStores.GetStockSubscriptionStoreInstance().Subscribe(id, request.BookID)
+
+	// Persist to JSON file
+	if err := persistStockSubscriptionsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	// Return the subscription
+	w.Header().Set("Content-Type", "application/json")
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(subscription)
+}
+
+// UnsubscribeFromBook handles the DELETE /customers/{id}/subscriptions/{book_id} request, cancelling the
+// customer's active subscription to the book
+func UnsubscribeFromBook(w http.ResponseWriter, r *http.Request) {
+	// Extract the customer and book IDs from the URL
+	id, bookID, errResp := customerChildIDs(r, "book")
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Cancel the subscription
+	if _, errResp := inmemoryStores.GetStockSubscriptionStoreInstance().Unsubscribe(id, bookID); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Persist to JSON file
+	if err := persistStockSubscriptionsToFile(); err != nil {
+		writeError(w, r, StructureData.NewInternalError("Error saving data", err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStockNotifications handles the GET /customers/{id}/notifications request, returning the back-in-stock
+// notifications sent to the customer, newest first
+func GetStockNotifications(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid customer ID"))
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inmemoryStores.GetStockSubscriptionStoreInstance().GetNotificationsByCustomer(id))
+}
+
+// GetBookSubscriptions handles the GET /books/{id}/subscriptions request, listing the customers waiting for
+// the book. ?status= filters the subscriptions on active, notified or cancelled.
+func GetBookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from the URL
+	id, err := strconv.Atoi(r.URL.Path[len("/books/"):])
+	if err != nil {
+		writeError(w, r, StructureData.NewValidationError("Invalid book ID"))
+		return
+	}
+	if _, errResp := inmemoryStores.GetBookStoreInstance().GetBook(id); errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	subscriptions, errResp := filterSubscriptions(r, inmemoryStores.GetStockSubscriptionStoreInstance().GetSubscriptionsByBook(id))
+	if errResp != nil {
+		writeError(w, r, errResp)
+		return
+	}
+
+	// Return JSON response
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// subscribeToRestocks subscribes a customer to the books an order skipped for lack of stock
+func subscribeToRestocks(customerID int, bookIDs []int) {
+	if len(bookIDs) == 0 {
+		return
+	}
+	store := inmemoryStores.GetStockSubscriptionStoreInstance()
+	for _, bookID := range bookIDs {
+		store.Subscribe(customerID, bookID)
+	}
+	if err := persistStockSubscriptionsToFile(); err != nil {
+		log.Printf("Failed to save stock subscriptions: %v", err)
+	}
+}
+
+// validateCustomerBook checks that the customer and the book exist, returning the book
+func validateCustomerBook(customerID, bookID int) (StructureData.Book, *StructureData.ErrorResponse) {
+	if _, errResp := inmemoryStores.GetCustomerStoreInstance().GetCustomer(customerID); errResp != nil {
+		return StructureData.Book{}, errResp
+	}
+	if bookID <= 0 {
+		return StructureData.Book{}, StructureData.NewValidationError("book_id is required")
+	}
+	book, errResp := inmemoryStores.GetBookStoreInstance().GetBook(bookID)
+	if errResp != nil {
+		return StructureData.Book{}, StructureData.NewValidationError("Book not found")
+	}
+	return book, nil
+}
+
+// customerChildIDs extracts the customer ID and the ID of a book, address or other child from a
+// /customers/{id}/{child_id} path
+func customerChildIDs(r *http.Request, child string) (int, int, *StructureData.ErrorResponse) {
+	parts := strings.Split(r.URL.Path[len("/customers/"):], "/")
+	if len(parts) != 2 {
+		return 0, 0, StructureData.NewNotFoundError("Not found")
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, StructureData.NewValidationError("Invalid customer ID")
+	}
+	childID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, StructureData.NewValidationError("Invalid " + child + " ID")
+	}
+	return id, childID, nil
+}
+
+// activeSubscriptions returns the books a customer is subscribed to
+func activeSubscriptions(customerID int) map[int]bool {
+	books := map[int]bool{}
+	for _, subscription := range inmemoryStores.GetStockSubscriptionStoreInstance().GetSubscriptionsByCustomer(customerID) {
+		if subscription.Status == StructureData.SubscriptionActive {
+			books[subscription.BookID] = true
+		}
+	}
+	return books
+}
+
+// filterSubscriptions keeps the subscriptions of the status given by ?status=, if any
+func filterSubscriptions(r *http.Request, subscriptions []StructureData.StockSubscription) ([]StructureData.StockSubscription, *StructureData.ErrorResponse) {
+	status := StructureData.StockSubscriptionStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		return subscriptions, nil
+	}
+	if !StructureData.IsValidSubscriptionStatus(status) {
+		return nil, StructureData.NewValidationError("status must be active, notified or cancelled")
+	}
+	filtered := []StructureData.StockSubscription{}
+	for _, subscription := range subscriptions {
+		if subscription.Status == status {
+			filtered = append(filtered, subscription)
+		}
+	}
+	return filtered, nil
+}
+
+// persistWishlistsToFile saves all wishlists to the JSON file in a pretty JSON format
+func persistWishlistsToFile() error {
+	file, err := os.Create(wishlistFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	return encoder.Encode(inmemoryStores.GetWishlistStoreInstance().GetAllWishlists())
+}
+
+// persistStockSubscriptionsToFile saves all back-in-stock subscriptions and notifications to their JSON files
+// in a pretty JSON format
+func persistStockSubscriptionsToFile() error {
+	stockSubscriptionsMu.Lock()
+	defer stockSubscriptionsMu.Unlock()
+
+	store := inmemoryStores.GetStockSubscriptionStoreInstance()
+	files := map[string]interface{}{
+		stockSubscriptionFile: store.GetAllSubscriptions(),
+		stockNotificationFile: store.GetAllNotifications(),
+	}
+	for path, records := range files {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+
+		// Use a pretty JSON encoder
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ") // Add indentation for better readability
+
+		err = encoder.Encode(records)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
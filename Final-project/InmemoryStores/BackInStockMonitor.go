@@ -0,0 +1,143 @@
+package InmemoryStores
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// BackInStockMonitor watches book events and notifies the customers subscribed to a book when its stock goes
+// from zero to positive, whichever change restocked it. Each subscription is notified once.
+type BackInStockMonitor struct {
+	mu            sync.Mutex
+	books         interfaces.BookStore
+	customers     interfaces.CustomerStore
+	subscriptions interfaces.StockSubscriptionStore
+	bus           interfaces.EventBus
+	stock         map[int]int // Last known stock of every book
+	Notifier      interfaces.Notifier
+	OnNotify      func() // Called after subscribers are notified, e.g. to persist the subscriptions
+}
+
+// LogNotifier is the default notifier. It only logs the notifications.
+type LogNotifier struct{}
+
+// Notify logs the notification
+func (LogNotifier) Notify(notification data.StockNotification) error {
+	log.Printf("Back in stock: notifying %s <%s> that book %d (%s) is available",
+		notification.Name, notification.Email, notification.BookID, notification.Title)
+	return nil
+}
+
+var (
+	backInStockMonitorInstance *BackInStockMonitor
+	backInStockMonitorOnce     sync.Once
+)
+
+// GetBackInStockMonitorInstance returns the singleton instance of BackInStockMonitor
+func GetBackInStockMonitorInstance() *BackInStockMonitor {
+	backInStockMonitorOnce.Do(func() {
+		backInStockMonitorInstance = &BackInStockMonitor{
+			books:         GetBookStoreInstance(),
+			customers:     GetCustomerStoreInstance(),
+			subscriptions: GetStockSubscriptionStoreInstance(),
+			bus:           GetEventBusInstance(),
+			stock:         make(map[int]int),
+			Notifier:      LogNotifier{},
+		}
+	})
+	return backInStockMonitorInstance
+}
+
+// Start records the current stock of every book, notifying the subscribers of the books restocked while the
+// server was down, then follows book changes on the event bus
+func (m *BackInStockMonitor) Start() {
+	for _, book := range m.books.GetAllBooks() {
+		m.Check(book)
+	}
+	m.bus.Subscribe(m.handleEvent)
+}
+
+func (m *BackInStockMonitor) handleEvent(event data.DomainEvent) {
+	switch event.Type {
+	case data.EventBookCreated, data.EventBookUpdated, data.EventBookStockChanged, data.EventBookRestored:
+	case data.EventBookDeleted, data.EventBookPurged:
+		m.mu.Lock()
+		delete(m.stock, event.ResourceID)
+		m.mu.Unlock()
+		return
+	default:
+		return
+	}
+
+	var book data.Book
+	if err := json.Unmarshal(event.Data, &book); err != nil {
+		log.Printf("Back-in-stock monitor: cannot decode book from event %d: %v", event.ID, err)
+		return
+	}
+	m.Check(book)
+}
+
+// Check records the stock of a book and notifies its subscribers if it was out of stock, or unknown, before
+func (m *BackInStockMonitor) Check(book data.Book) {
+	m.mu.Lock()
+	previous, known := m.stock[book.ID]
+	m.stock[book.ID] = book.Stock
+	restocked := book.Stock > 0 && (!known || previous <= 0)
+	m.mu.Unlock()
+	if !restocked {
+		return
+	}
+
+	subscriptions := m.subscriptions.ClaimSubscriptions(book.ID)
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	backInStock := data.BackInStock{BookID: book.ID, Title: book.Title, Stock: book.Stock, Subscribers: []int{}}
+	for _, subscription := range subscriptions {
+		notification := data.StockNotification{
+			SubscriptionID: subscription.ID,
+			CustomerID:     subscription.CustomerID,
+			BookID:         book.ID,
+			Title:          book.Title,
+			Stock:          book.Stock,
+			Delivered:      true,
+			SentAt:         time.Now(),
+		}
+		customer, errResp := m.customers.GetCustomer(subscription.CustomerID)
+		if errResp != nil {
+			// The customer was deleted since subscribing
+			notification.Delivered = false
+			notification.Error = errResp.Message
+			m.subscriptions.RecordNotification(notification)
+			continue
+		}
+		notification.Name = customer.Name
+		notification.Email = customer.Email
+		if err := m.Notifier.Notify(notification); err != nil {
+			log.Printf("Back-in-stock monitor: cannot notify customer %d of book %d: %v", subscription.CustomerID, book.ID, err)
+			notification.Delivered = false
+			notification.Error = err.Error()
+		}
+		m.subscriptions.RecordNotification(notification)
+		backInStock.Subscribers = append(backInStock.Subscribers, subscription.CustomerID)
+	}
+
+	payload, _ := json.Marshal(backInStock)
+	m.bus.Publish(data.DomainEvent{
+		Type:       data.EventBookBackInStock,
+		Resource:   data.ResourceBooks,
+		ResourceID: book.ID,
+		Actor:      "system",
+		Data:       payload,
+	})
+
+	if m.OnNotify != nil {
+		m.OnNotify()
+	}
+}
@@ -0,0 +1,80 @@
+package InmemoryStores
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// InMemoryEventBus delivers domain events to in-process subscribers in the order they are published
+type InMemoryEventBus struct {
+	mu       sync.Mutex
+	handlers []func(event data.DomainEvent)
+	nextID   int
+}
+
+var (
+	eventBusInstance *InMemoryEventBus
+	eventBusOnce     sync.Once
+)
+
+// GetEventBusInstance returns the singleton instance of InMemoryEventBus
+func GetEventBusInstance() interfaces.EventBus {
+	eventBusOnce.Do(func() {
+		eventBusInstance = &InMemoryEventBus{
+			nextID: 1,
+		}
+	})
+	return eventBusInstance
+}
+
+// Publish numbers an event and hands it to every subscriber. Subscribers run synchronously,
+// so they should hand slow work such as network calls off to a goroutine.
+func (bus *InMemoryEventBus) Publish(event data.DomainEvent) data.DomainEvent {
+	bus.mu.Lock()
+	event.ID = bus.nextID
+	bus.nextID++
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	handlers := make([]func(event data.DomainEvent), len(bus.handlers))
+	copy(handlers, bus.handlers)
+	bus.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return event
+}
+
+// PublishChange publishes the domain event matching an audited change, with the changed record as payload
+func (bus *InMemoryEventBus) PublishChange(change data.AuditEvent, record interface{}) {
+	eventType := data.DomainEventType(change.Resource, change.Action)
+	if eventType == "" {
+		return
+	}
+
+	event := data.DomainEvent{
+		Type:       eventType,
+		Resource:   change.Resource,
+		ResourceID: change.ResourceID,
+		Actor:      change.Actor,
+		RequestID:  change.RequestID,
+		OccurredAt: change.Timestamp,
+	}
+	if record != nil {
+		event.Data, _ = json.Marshal(record)
+	}
+	bus.Publish(event)
+}
+
+// Subscribe registers a handler called for every event published afterwards
+func (bus *InMemoryEventBus) Subscribe(handler func(event data.DomainEvent)) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.handlers = append(bus.handlers, handler)
+}
@@ -0,0 +1,126 @@
+package InmemoryStores
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// FileSalesReportStore keeps the sales reports of another store in a JSON file. Reports are read from the
+// file once and the file is written again after every change, so reads never touch it.
+type FileSalesReportStore struct {
+	mu    sync.Mutex // Serializes the changes so the file is written in the order they were made
+	path  string
+	store interfaces.SalesReportStore
+}
+
+// NewFileSalesReportStore loads the reports of the JSON file into the store and returns a store saving
+// every change of it to the file. A missing file is an empty history.
+func NewFileSalesReportStore(path string, store interfaces.SalesReportStore) (*FileSalesReportStore, error) {
+	var reports []data.SalesReport
+	file, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer file.Close()
+		if err := json.NewDecoder(file).Decode(&reports); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, report := range reports {
+		store.AddReportDirectly(report)
+	}
+	return &FileSalesReportStore{path: path, store: store}, nil
+}
+
+// SaveReport adds a report to the history and saves the file
+func (fileStore *FileSalesReportStore) SaveReport(report data.SalesReport) (data.SalesReport, *data.ErrorResponse) {
+	fileStore.mu.Lock()
+	defer fileStore.mu.Unlock()
+
+	savedReport, errResp := fileStore.store.SaveReport(report)
+	if errResp != nil {
+		return data.SalesReport{}, errResp
+	}
+	if err := fileStore.persist(); err != nil {
+		return savedReport, data.NewInternalError("Error saving sales reports", err)
+	}
+	return savedReport, nil
+}
+
+// GetAllReports retrieves all reports sorted by timestamp
+func (fileStore *FileSalesReportStore) GetAllReports() []data.SalesReport {
+	return fileStore.store.GetAllReports()
+}
+
+// SearchReports retrieves the reports matching the criteria sorted by timestamp
+func (fileStore *FileSalesReportStore) SearchReports(criteria data.SalesReportSearchCriteria) ([]data.SalesReport, *data.ErrorResponse) {
+	return fileStore.store.SearchReports(criteria)
+}
+
+// DeleteReportsBefore removes the reports older than the cutoff and saves the file if any was removed
+func (fileStore *FileSalesReportStore) DeleteReportsBefore(cutoff time.Time) (int, *data.ErrorResponse) {
+	fileStore.mu.Lock()
+	defer fileStore.mu.Unlock()
+
+	deleted, errResp := fileStore.store.DeleteReportsBefore(cutoff)
+	if errResp != nil || deleted == 0 {
+		return deleted, errResp
+	}
+	if err := fileStore.persist(); err != nil {
+		return deleted, data.NewInternalError("Error saving sales reports", err)
+	}
+	return deleted, nil
+}
+
+// CompactReportsBefore merges the reports older than the cutoff into monthly reports and saves the file if
+// any was merged
+func (fileStore *FileSalesReportStore) CompactReportsBefore(cutoff time.Time) (int, *data.ErrorResponse) {
+	fileStore.mu.Lock()
+	defer fileStore.mu.Unlock()
+
+	merged, errResp := fileStore.store.CompactReportsBefore(cutoff)
+	if errResp != nil || merged == 0 {
+		return merged, errResp
+	}
+	if err := fileStore.persist(); err != nil {
+		return merged, data.NewInternalError("Error saving sales reports", err)
+	}
+	return merged, nil
+}
+
+// AddReportDirectly adds a report to the store without saving the file
+func (fileStore *FileSalesReportStore) AddReportDirectly(report data.SalesReport) {
+	fileStore.store.AddReportDirectly(report)
+}
+
+// persist writes all reports to a temporary file in a pretty JSON format and renames it over the JSON
+// file, so a failed write never loses the history
+func (fileStore *FileSalesReportStore) persist() error {
+	tempPath := fileStore.path + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+
+	// Use a pretty JSON encoder
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ") // Add indentation for better readability
+
+	if err := encoder.Encode(fileStore.store.GetAllReports()); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return os.Rename(tempPath, fileStore.path)
+}
@@ -0,0 +1,228 @@
+package InmemoryStores
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// InMemoryAuditStore is an append-only log of audit events. Events are never updated or removed.
+type InMemoryAuditStore struct {
+	mu       sync.RWMutex
+	events   []data.AuditEvent
+	nextID   int
+	onRecord func() // Called after every recorded event, e.g. to persist the log
+}
+
+var (
+	auditStoreInstance *InMemoryAuditStore
+	auditOnce          sync.Once
+)
+
+// GetAuditStoreInstance returns the singleton instance of InMemoryAuditStore
+func GetAuditStoreInstance() interfaces.AuditStore {
+	auditOnce.Do(func() {
+		auditStoreInstance = &InMemoryAuditStore{
+			nextID: 1,
+		}
+	})
+	return auditStoreInstance
+}
+
+// Record appends an event describing the fields that differ between the before and after state of a record.
+// before is nil for a created record and after is nil for a purged one.
+func (store *InMemoryAuditStore) Record(resource string, resourceID int, action data.AuditAction, ctx data.AuditContext, before, after interface{}) data.AuditEvent {
+	store.mu.Lock()
+	event := data.AuditEvent{
+		ID:         store.nextID,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Action:     action,
+		Actor:      ctx.Actor,
+		RequestID:  ctx.RequestID,
+		Timestamp:  time.Now(),
+		Changes:    diffFields(before, after),
+	}
+	store.nextID++
+	store.events = append(store.events, event)
+	onRecord := store.onRecord
+	store.mu.Unlock()
+
+	if onRecord != nil {
+		onRecord()
+	}
+	return event
+}
+
+// SetOnRecord sets the function called after every recorded event
+func (store *InMemoryAuditStore) SetOnRecord(onRecord func()) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.onRecord = onRecord
+}
+
+// storeChange is a change made by a store mutator. The mutator fills it in under its lock and records it
+// with a deferred call once the lock is released, as the subscribers to the domain event read the stores
+// back. A change left empty because the mutator refused it records nothing.
+type storeChange struct {
+	resource      string
+	id            int
+	action        data.AuditAction
+	before, after interface{}
+}
+
+// record adds the change to the audit log and publishes the matching domain event
+func (change *storeChange) record(ctx data.AuditContext) {
+	if change.resource == "" {
+		return
+	}
+	recordChange(change.resource, change.id, change.action, ctx, change.before, change.after)
+}
+
+// recordChange adds a change to the audit log and publishes the matching domain event, with the record
+// before the change as payload when it was purged
+func recordChange(resource string, id int, action data.AuditAction, ctx data.AuditContext, before, after interface{}) {
+	event := GetAuditStoreInstance().Record(resource, id, action, ctx, before, after)
+	if after == nil {
+		after = before
+	}
+	GetEventBusInstance().PublishChange(event, after)
+}
+
+// GetHistory retrieves the events of a single record, oldest first
+func (store *InMemoryAuditStore) GetHistory(resource string, resourceID int) []data.AuditEvent {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var events []data.AuditEvent
+	for _, event := range store.events {
+		if event.Resource == resource && event.ResourceID == resourceID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// GetAllAuditEvents retrieves every event, oldest first
+func (store *InMemoryAuditStore) GetAllAuditEvents() []data.AuditEvent {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	events := make([]data.AuditEvent, len(store.events))
+	copy(events, store.events)
+	return events
+}
+
+// SearchAuditEvents retrieves the events matching the criteria, oldest first
+func (store *InMemoryAuditStore) SearchAuditEvents(criteria data.AuditSearchCriteria) ([]data.AuditEvent, *data.ErrorResponse) {
+	if errResp := utils.ValidateAuditSearchCriteria(criteria); errResp != nil {
+		return nil, errResp
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var result []data.AuditEvent
+	for _, event := range store.events {
+		if len(criteria.Resources) > 0 && !utils.ContainsString(criteria.Resources, event.Resource) {
+			continue
+		}
+		if len(criteria.ResourceIDs) > 0 && !utils.ContainsInt(criteria.ResourceIDs, event.ResourceID) {
+			continue
+		}
+		if len(criteria.Actions) > 0 && !containsAction(criteria.Actions, event.Action) {
+			continue
+		}
+		if len(criteria.Actors) > 0 && !utils.ContainsString(criteria.Actors, event.Actor) {
+			continue
+		}
+		if len(criteria.RequestIDs) > 0 && !utils.ContainsString(criteria.RequestIDs, event.RequestID) {
+			continue
+		}
+		if len(criteria.Fields) > 0 && !changesAnyField(event.Changes, criteria.Fields) {
+			continue
+		}
+		if !criteria.MinTimestamp.IsZero() && event.Timestamp.Before(criteria.MinTimestamp) {
+			continue
+		}
+		if !criteria.MaxTimestamp.IsZero() && event.Timestamp.After(criteria.MaxTimestamp) {
+			continue
+		}
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+// AddAuditEventDirectly appends a previously recorded event, keeping its ID
+func (store *InMemoryAuditStore) AddAuditEventDirectly(event data.AuditEvent) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Ensure the next ID is updated to prevent ID collisions
+	if event.ID >= store.nextID {
+		store.nextID = event.ID + 1
+	}
+	store.events = append(store.events, event)
+}
+
+// diffFields compares the top-level JSON fields of two records and returns those that differ, sorted by name
+func diffFields(before, after interface{}) []data.FieldChange {
+	beforeFields, afterFields := jsonFields(before), jsonFields(after)
+
+	names := map[string]bool{}
+	for name := range beforeFields {
+		names[name] = true
+	}
+	for name := range afterFields {
+		names[name] = true
+	}
+
+	changes := []data.FieldChange{}
+	for name := range names {
+		if bytes.Equal(beforeFields[name], afterFields[name]) {
+			continue
+		}
+		changes = append(changes, data.FieldChange{Field: name, Before: beforeFields[name], After: afterFields[name]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// jsonFields encodes a record and splits it into its top-level fields
+func jsonFields(record interface{}) map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	if record == nil {
+		return fields
+	}
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(encoded, &fields)
+	return fields
+}
+
+func containsAction(actions []data.AuditAction, action data.AuditAction) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func changesAnyField(changes []data.FieldChange, fields []string) bool {
+	for _, change := range changes {
+		if utils.ContainsString(fields, change.Field) {
+			return true
+		}
+	}
+	return false
+}
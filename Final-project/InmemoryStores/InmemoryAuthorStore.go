@@ -2,6 +2,7 @@ package InmemoryStores
 
 import (
 	"sync"
+	"time"
 
 	interfaces "finalProject/Interfaces"
 	data "finalProject/StructureData"
@@ -31,13 +32,18 @@ func GetAuthorStoreInstance() interfaces.AuthorStore {
 }
 
 // CreateAuthor adds a new author to the store
-func (store *InMemoryAuthorStore) CreateAuthor(author data.Author) (data.Author, *data.ErrorResponse) {
+func (store *InMemoryAuthorStore) CreateAuthor(author data.Author, ctx data.AuditContext) (data.Author, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	author.ID = store.nextID
+
+	author.SoftDelete = data.SoftDelete{}
 	store.nextID++
 	store.authors[author.ID] = author
+	change = storeChange{resource: data.ResourceAuthors, id: author.ID, action: data.AuditCreate, after: author}
 	return author, nil
 }
 
@@ -47,8 +53,8 @@ func (store *InMemoryAuthorStore) GetAuthor(id int) (data.Author, *data.ErrorRes
 	defer store.mu.RUnlock()
 
 	author, exists := store.authors[id]
-	if !exists {
-		return data.Author{}, &data.ErrorResponse{Message: "Author not found"}
+	if !exists || author.IsDeleted() {
+		return data.Author{}, data.NewNotFoundError("Author not found")
 	}
 	return author, nil
 }
@@ -60,35 +66,97 @@ func (store *InMemoryAuthorStore) GetAllAuthors() []data.Author {
 
 	var authors []data.Author
 	for _, author := range store.authors {
+		if author.IsDeleted() {
+			continue
+		}
 		authors = append(authors, author)
 	}
 	return authors
 }
 
 // UpdateAuthor updates an author's details
-func (store *InMemoryAuthorStore) UpdateAuthor(id int, author data.Author) (data.Author, *data.ErrorResponse) {
+func (store *InMemoryAuthorStore) UpdateAuthor(id int, author data.Author, ctx data.AuditContext) (data.Author, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	_, exists := store.authors[id]
-	if !exists {
-		return data.Author{}, &data.ErrorResponse{Message: "Author not found"}
+	existing, exists := store.authors[id]
+	if !exists || existing.IsDeleted() {
+		return data.Author{}, data.NewNotFoundError("Author not found")
 	}
 	author.ID = id
+	author.SoftDelete = data.SoftDelete{} // Trash state only changes through Delete and Restore
+	store.authors[id] = author
+	change = storeChange{resource: data.ResourceAuthors, id: id, action: data.AuditUpdate, before: existing, after: author}
+	return author, nil
+}
+
+// DeleteAuthor moves an author to the trash, recording when and by whom it was deleted
+func (store *InMemoryAuthorStore) DeleteAuthor(id int, ctx data.AuditContext) *data.ErrorResponse {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	author, exists := store.authors[id]
+	if !exists || author.IsDeleted() {
+		return data.NewNotFoundError("Author not found")
+	}
+	before := author
+	now := time.Now()
+	author.DeletedAt = &now
+	author.DeletedBy = ctx.Actor
+	store.authors[id] = author
+	change = storeChange{resource: data.ResourceAuthors, id: id, action: data.AuditDelete, before: before, after: author}
+	return nil
+}
+
+// GetDeletedAuthors retrieves the authors in the trash
+func (store *InMemoryAuthorStore) GetDeletedAuthors() []data.Author {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var authors []data.Author
+	for _, author := range store.authors {
+		if author.IsDeleted() {
+			authors = append(authors, author)
+		}
+	}
+	return authors
+}
+
+// RestoreAuthor takes an author out of the trash
+func (store *InMemoryAuthorStore) RestoreAuthor(id int, ctx data.AuditContext) (data.Author, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	author, exists := store.authors[id]
+	if !exists || !author.IsDeleted() {
+		return data.Author{}, data.NewNotFoundError("Author not found in trash")
+	}
+	before := author
+	author.SoftDelete = data.SoftDelete{}
 	store.authors[id] = author
+	change = storeChange{resource: data.ResourceAuthors, id: id, action: data.AuditRestore, before: before, after: author}
 	return author, nil
 }
 
-// DeleteAuthor removes an author by ID
-func (store *InMemoryAuthorStore) DeleteAuthor(id int) *data.ErrorResponse {
+// PurgeAuthor permanently removes an author from the trash
+func (store *InMemoryAuthorStore) PurgeAuthor(id int, ctx data.AuditContext) *data.ErrorResponse {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	_, exists := store.authors[id]
-	if !exists {
-		return &data.ErrorResponse{Message: "Author not found"}
+	author, exists := store.authors[id]
+	if !exists || !author.IsDeleted() {
+		return data.NewNotFoundError("Author not found in trash")
 	}
 	delete(store.authors, id)
+	change = storeChange{resource: data.ResourceAuthors, id: id, action: data.AuditPurge, before: author}
 	return nil
 }
 
@@ -99,6 +167,9 @@ func (store *InMemoryAuthorStore) SearchAuthors(criteria data.AuthorSearchCriter
 
 	var result []data.Author
 	for _, author := range store.authors {
+		if author.IsDeleted() {
+			continue
+		}
 		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, author.ID) {
 			continue
 		}
@@ -126,3 +197,16 @@ func (store *InMemoryAuthorStore) SearchAuthors(criteria data.AuthorSearchCriter
 	}
 	return result, nil
 }
+
+// AddAuthorDirectly stores an author as-is, keeping its ID, and advances nextID past it
+func (store *InMemoryAuthorStore) AddAuthorDirectly(author data.Author) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Ensure the next ID is updated to prevent ID collisions
+	if author.ID >= store.nextID {
+		store.nextID = author.ID + 1
+	}
+
+	store.authors[author.ID] = author
+}
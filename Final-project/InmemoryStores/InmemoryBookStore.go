@@ -1,7 +1,11 @@
 package InmemoryStores
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	interfaces "finalProject/Interfaces"
 	data "finalProject/StructureData"
@@ -31,19 +35,25 @@ func GetBookStoreInstance() interfaces.BookStore {
 }
 
 // CreateBook adds a new book to the store
-
-func (store *InMemoryBookStore) CreateBook(book data.Book) (data.Book, *data.ErrorResponse) {
+func (store *InMemoryBookStore) CreateBook(book data.Book, ctx data.AuditContext) (data.Book, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	// Validate that the stock is at least 1
 	if book.Stock < 1 {
-		return data.Book{}, &data.ErrorResponse{Message: "Book stock must be at least 1"}
+		return data.Book{}, data.NewValidationError("Book stock must be at least 1")
 	}
 
 	book.ID = store.nextID
+
+	book.SoftDelete = data.SoftDelete{}
+	book.Author = nil // Books only keep the author ID
+	book.Rating = nil // Ratings come from the reviews
 	store.nextID++
 	store.books[book.ID] = book
+	change = storeChange{resource: data.ResourceBooks, id: book.ID, action: data.AuditCreate, after: book}
 	return book, nil
 }
 
@@ -53,36 +63,141 @@ func (store *InMemoryBookStore) GetBook(id int) (data.Book, *data.ErrorResponse)
 	defer store.mu.RUnlock()
 
 	book, exists := store.books[id]
-	if !exists {
-		return data.Book{}, &data.ErrorResponse{Message: "Book not found"}
+	if !exists || book.IsDeleted() {
+		return data.Book{}, data.NewNotFoundError("Book not found")
 	}
 	return book, nil
 }
 
-// UpdateBook updates the details of an existing book
-func (store *InMemoryBookStore) UpdateBook(id int, book data.Book) (data.Book, *data.ErrorResponse) {
+// UpdateBook updates the details of an existing book. The stock and the cost price are kept; they only
+// change through AdjustStock and SetCostPrice.
+func (store *InMemoryBookStore) UpdateBook(id int, book data.Book, ctx data.AuditContext) (data.Book, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	_, exists := store.books[id]
-	if !exists {
-		return data.Book{}, &data.ErrorResponse{Message: "Book not found"}
+	existing, exists := store.books[id]
+	if !exists || existing.IsDeleted() {
+		return data.Book{}, data.NewNotFoundError("Book not found")
 	}
 	book.ID = id
+	book.Stock = existing.Stock
+	book.CostPrice = existing.CostPrice
+	book.SoftDelete = data.SoftDelete{} // Trash state only changes through Delete and Restore
+	book.Author = nil                   // Books only keep the author ID
+	book.Rating = nil                   // Ratings come from the reviews
+	store.books[id] = book
+	change = storeChange{resource: data.ResourceBooks, id: id, action: data.AuditUpdate, before: existing, after: book}
+	return book, nil
+}
+
+// AdjustStock adds delta to the stock of a book in a single step, refusing to go below zero. It returns the
+// book before and after the change. It is not audited here: the caller records the stock_change once the
+// request succeeds, as a refused request puts the stock back.
+func (store *InMemoryBookStore) AdjustStock(id int, delta int) (data.Book, data.Book, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	before, exists := store.books[id]
+	if !exists || before.IsDeleted() {
+		return data.Book{}, data.Book{}, data.NewNotFoundError("Book not found")
+	}
+	if before.Stock+delta < 0 {
+		return data.Book{}, data.Book{}, data.NewInsufficientStockError(fmt.Sprintf("Book %d has only %d in stock", id, before.Stock))
+	}
+	after := before
+	after.Stock += delta
+	store.books[id] = after
+	return before, after, nil
+}
+
+// SetCostPrice records the unit cost a book was last received at
+func (store *InMemoryBookStore) SetCostPrice(id int, costPrice float64, ctx data.AuditContext) (data.Book, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	book, exists := store.books[id]
+	if !exists || book.IsDeleted() {
+		return data.Book{}, data.NewNotFoundError("Book not found")
+	}
+	if book.CostPrice == costPrice {
+		return book, nil
+	}
+	before := book
+	book.CostPrice = costPrice
+	store.books[id] = book
+	change = storeChange{resource: data.ResourceBooks, id: id, action: data.AuditUpdate, before: before, after: book}
+	return book, nil
+}
+
+// DeleteBook moves a book to the trash, recording when and by whom it was deleted
+func (store *InMemoryBookStore) DeleteBook(id int, ctx data.AuditContext) *data.ErrorResponse {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	book, exists := store.books[id]
+	if !exists || book.IsDeleted() {
+		return data.NewNotFoundError("Book not found")
+	}
+	before := book
+	now := time.Now()
+	book.DeletedAt = &now
+	book.DeletedBy = ctx.Actor
+	store.books[id] = book
+	change = storeChange{resource: data.ResourceBooks, id: id, action: data.AuditDelete, before: before, after: book}
+	return nil
+}
+
+// GetDeletedBooks retrieves the books in the trash
+func (store *InMemoryBookStore) GetDeletedBooks() []data.Book {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var books []data.Book
+	for _, book := range store.books {
+		if book.IsDeleted() {
+			books = append(books, book)
+		}
+	}
+	return books
+}
+
+// RestoreBook takes a book out of the trash
+func (store *InMemoryBookStore) RestoreBook(id int, ctx data.AuditContext) (data.Book, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	book, exists := store.books[id]
+	if !exists || !book.IsDeleted() {
+		return data.Book{}, data.NewNotFoundError("Book not found in trash")
+	}
+	before := book
+	book.SoftDelete = data.SoftDelete{}
 	store.books[id] = book
+	change = storeChange{resource: data.ResourceBooks, id: id, action: data.AuditRestore, before: before, after: book}
 	return book, nil
 }
 
-// DeleteBook removes a book from the store
-func (store *InMemoryBookStore) DeleteBook(id int) *data.ErrorResponse {
+// PurgeBook permanently removes a book from the trash
+func (store *InMemoryBookStore) PurgeBook(id int, ctx data.AuditContext) *data.ErrorResponse {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	_, exists := store.books[id]
-	if !exists {
-		return &data.ErrorResponse{Message: "Book not found"}
+	book, exists := store.books[id]
+	if !exists || !book.IsDeleted() {
+		return data.NewNotFoundError("Book not found in trash")
 	}
 	delete(store.books, id)
+	change = storeChange{resource: data.ResourceBooks, id: id, action: data.AuditPurge, before: book}
 	return nil
 }
 
@@ -93,6 +208,9 @@ func (store *InMemoryBookStore) GetAllBooks() []data.Book {
 
 	var books []data.Book
 	for _, book := range store.books {
+		if book.IsDeleted() {
+			continue
+		}
 		books = append(books, book)
 	}
 	return books
@@ -100,11 +218,20 @@ func (store *InMemoryBookStore) GetAllBooks() []data.Book {
 
 // SearchBooks filters books based on the search criteria
 func (store *InMemoryBookStore) SearchBooks(criteria data.BookSearchCriteria) ([]data.Book, *data.ErrorResponse) {
+	if err := utils.ValidateBookSearchCriteria(criteria); err != nil {
+		return nil, err
+	}
+
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
+	authorStore := GetAuthorStoreInstance()
+
 	var result []data.Book
 	for _, book := range store.books {
+		if book.IsDeleted() {
+			continue
+		}
 		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, book.ID) {
 			continue
 		}
@@ -132,14 +259,73 @@ func (store *InMemoryBookStore) SearchBooks(criteria data.BookSearchCriteria) ([
 		if criteria.MaxStock > 0 && book.Stock > criteria.MaxStock {
 			continue
 		}
-		if !utils.MatchAuthorCriteria(book.Author, criteria.AuthorCriteria) {
+		author, _ := authorStore.GetAuthor(book.AuthorID)
+		if !utils.MatchAuthorCriteria(author, criteria.AuthorCriteria) {
+			continue
+		}
+		if !matchRatingCriteria(book.ID, criteria) {
 			continue
 		}
 		result = append(result, book)
 	}
 
+	if criteria.SortBy != "" {
+		sortBooks(result, criteria.SortBy, criteria.SortOrder)
+	}
 	return result, nil
 }
+
+// sortBooks sorts books by a field, ascending unless the order is desc. Ratings and review counts sort
+// descending by default, and books without reviews come last. Ties are broken by ID.
+func sortBooks(books []data.Book, field data.BookSortField, order string) {
+	descending := order == "desc" || (order == "" && (field == data.SortByRating || field == data.SortByReviewCount))
+	summaries := map[int]data.RatingSummary{}
+	if field == data.SortByRating || field == data.SortByReviewCount {
+		for _, book := range books {
+			summaries[book.ID] = GetReviewStoreInstance().GetRatingSummary(book.ID)
+		}
+	}
+
+	sort.SliceStable(books, func(i, j int) bool {
+		a, b := books[i], books[j]
+		var cmp int
+		switch field {
+		case data.SortByTitle:
+			cmp = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		case data.SortByPrice:
+			cmp = compareFloats(a.Price, b.Price)
+		case data.SortByPublishedAt:
+			cmp = a.PublishedAt.Compare(b.PublishedAt)
+		case data.SortByStock:
+			cmp = a.Stock - b.Stock
+		case data.SortByRating:
+			if (summaries[a.ID].ReviewCount == 0) != (summaries[b.ID].ReviewCount == 0) {
+				return summaries[b.ID].ReviewCount == 0
+			}
+			cmp = compareFloats(summaries[a.ID].AverageRating, summaries[b.ID].AverageRating)
+		case data.SortByReviewCount:
+			cmp = summaries[a.ID].ReviewCount - summaries[b.ID].ReviewCount
+		}
+		if cmp == 0 {
+			return a.ID < b.ID
+		}
+		if descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// compareFloats returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
 func (store *InMemoryBookStore) AddBookDirectly(book data.Book) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
@@ -149,5 +335,7 @@ func (store *InMemoryBookStore) AddBookDirectly(book data.Book) {
 		store.nextID = book.ID + 1
 	}
 
+	book.Author = nil // Books only keep the author ID
+	book.Rating = nil // Ratings come from the reviews
 	store.books[book.ID] = book
 }
@@ -1,6 +1,8 @@
 package InmemoryStores
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -32,14 +34,21 @@ func GetCustomerStoreInstance() interfaces.CustomerStore {
 }
 
 // CreateCustomer adds a new customer to the store
-func (store *InMemoryCustomerStore) CreateCustomer(customer data.Customer) (data.Customer, *data.ErrorResponse) {
+func (store *InMemoryCustomerStore) CreateCustomer(customer data.Customer, ctx data.AuditContext) (data.Customer, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	customer.CreatedAt = time.Now()
 	customer.ID = store.nextID
+	customer.SoftDelete = data.SoftDelete{}
+	adoptLegacyAddress(&customer)
+	customer.Addresses = numberAddresses(customer.Addresses, nil)
+	settleAddresses(&customer, 0)
 	store.nextID++
 	store.customers[customer.ID] = customer
+	change = storeChange{resource: data.ResourceCustomers, id: customer.ID, action: data.AuditCreate, after: customer}
 	return customer, nil
 }
 
@@ -49,8 +58,8 @@ func (store *InMemoryCustomerStore) GetCustomer(id int) (data.Customer, *data.Er
 	defer store.mu.RUnlock()
 
 	customer, exists := store.customers[id]
-	if !exists {
-		return data.Customer{}, &data.ErrorResponse{Message: "Customer not found"}
+	if !exists || customer.IsDeleted() {
+		return data.Customer{}, data.NewNotFoundError("Customer not found")
 	}
 	return customer, nil
 }
@@ -62,45 +71,221 @@ func (store *InMemoryCustomerStore) GetAllCustomers() []data.Customer {
 
 	var customers []data.Customer
 	for _, customer := range store.customers {
+		if customer.IsDeleted() {
+			continue
+		}
 		customers = append(customers, customer)
 	}
 	return customers
 }
 
 // UpdateCustomer updates the details of an existing customer
-func (store *InMemoryCustomerStore) UpdateCustomer(id int, customer data.Customer) (data.Customer, *data.ErrorResponse) {
+func (store *InMemoryCustomerStore) UpdateCustomer(id int, customer data.Customer, ctx data.AuditContext) (data.Customer, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	_, exists := store.customers[id]
-	if !exists {
-		return data.Customer{}, &data.ErrorResponse{Message: "Customer not found"}
+	existing, exists := store.customers[id]
+	if !exists || existing.IsDeleted() {
+		return data.Customer{}, data.NewNotFoundError("Customer not found")
 	}
 	customer.ID = id
+	customer.SoftDelete = data.SoftDelete{} // Trash state only changes through Delete and Restore
+	if customer.Addresses == nil {
+		// Keep the address book, applying a change of the single address to the default shipping address
+		customer.Addresses = append([]data.CustomerAddress{}, existing.Addresses...)
+		if !customer.Address.IsEmpty() && customer.Address != existing.Address {
+			if current, ok := existing.DefaultAddress(data.AddressUseShipping); ok {
+				for i := range customer.Addresses {
+					if customer.Addresses[i].ID == current.ID {
+						customer.Addresses[i].Address = customer.Address
+					}
+				}
+			} else {
+				customer.Addresses = numberAddresses(append(customer.Addresses, data.CustomerAddress{
+					Use: data.AddressUseAny, Address: customer.Address, DefaultShipping: true,
+				}), existing.Addresses)
+			}
+		}
+	} else {
+		customer.Addresses = numberAddresses(customer.Addresses, existing.Addresses)
+	}
+	settleAddresses(&customer, 0)
 	store.customers[id] = customer
+	change = storeChange{resource: data.ResourceCustomers, id: id, action: data.AuditUpdate, before: existing, after: customer}
 	return customer, nil
 }
 
-// DeleteCustomer removes a customer from the store
-func (store *InMemoryCustomerStore) DeleteCustomer(id int) *data.ErrorResponse {
+// AddAddress adds an entry to the address book of a customer
+func (store *InMemoryCustomerStore) AddAddress(customerID int, address data.CustomerAddress, ctx data.AuditContext) (data.CustomerAddress, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	customer, exists := store.customers[customerID]
+	if !exists || customer.IsDeleted() {
+		return data.CustomerAddress{}, data.NewNotFoundError("Customer not found")
+	}
+	before := customer
+	address.ID = 0
+	customer.Addresses = numberAddresses(append(append([]data.CustomerAddress{}, customer.Addresses...), address), customer.Addresses)
+	address = customer.Addresses[len(customer.Addresses)-1]
+	settleAddresses(&customer, address.ID)
+	store.customers[customerID] = customer
+	change = storeChange{resource: data.ResourceCustomers, id: customerID, action: data.AuditUpdate, before: before, after: customer}
+	address, _ = customer.FindAddress(address.ID)
+	return address, nil
+}
+
+// UpdateAddress replaces an entry of the address book of a customer. It stays a default address unless it can
+// no longer be used for it.
+func (store *InMemoryCustomerStore) UpdateAddress(customerID, addressID int, address data.CustomerAddress, ctx data.AuditContext) (data.CustomerAddress, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	_, exists := store.customers[id]
-	if !exists {
-		return &data.ErrorResponse{Message: "Customer not found"}
+	customer, exists := store.customers[customerID]
+	if !exists || customer.IsDeleted() {
+		return data.CustomerAddress{}, data.NewNotFoundError("Customer not found")
+	}
+	addresses := append([]data.CustomerAddress{}, customer.Addresses...)
+	found := false
+	for i := range addresses {
+		if addresses[i].ID == addressID {
+			// An address stays the default until another one is made the default
+			address.ID = addressID
+			address.DefaultShipping = address.DefaultShipping || addresses[i].DefaultShipping
+			address.DefaultBilling = address.DefaultBilling || addresses[i].DefaultBilling
+			addresses[i] = address
+			found = true
+		}
+	}
+	if !found {
+		return data.CustomerAddress{}, data.NewNotFoundError("Address not found")
+	}
+	before := customer
+	customer.Addresses = addresses
+	settleAddresses(&customer, addressID)
+	store.customers[customerID] = customer
+	change = storeChange{resource: data.ResourceCustomers, id: customerID, action: data.AuditUpdate, before: before, after: customer}
+	address, _ = customer.FindAddress(addressID)
+	return address, nil
+}
+
+// DeleteAddress removes an entry from the address book of a customer. If it was a default address, the
+// first remaining address that can replace it becomes the default.
+func (store *InMemoryCustomerStore) DeleteAddress(customerID, addressID int, ctx data.AuditContext) *data.ErrorResponse {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	customer, exists := store.customers[customerID]
+	if !exists || customer.IsDeleted() {
+		return data.NewNotFoundError("Customer not found")
+	}
+	addresses := []data.CustomerAddress{}
+	for _, address := range customer.Addresses {
+		if address.ID != addressID {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == len(customer.Addresses) {
+		return data.NewNotFoundError("Address not found")
+	}
+	before := customer
+	customer.Addresses = addresses
+	settleAddresses(&customer, 0)
+	store.customers[customerID] = customer
+	change = storeChange{resource: data.ResourceCustomers, id: customerID, action: data.AuditUpdate, before: before, after: customer}
+	return nil
+}
+
+// DeleteCustomer moves a customer to the trash, recording when and by whom it was deleted
+func (store *InMemoryCustomerStore) DeleteCustomer(id int, ctx data.AuditContext) *data.ErrorResponse {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	customer, exists := store.customers[id]
+	if !exists || customer.IsDeleted() {
+		return data.NewNotFoundError("Customer not found")
+	}
+	before := customer
+	now := time.Now()
+	customer.DeletedAt = &now
+	customer.DeletedBy = ctx.Actor
+	store.customers[id] = customer
+	change = storeChange{resource: data.ResourceCustomers, id: id, action: data.AuditDelete, before: before, after: customer}
+	return nil
+}
+
+// GetDeletedCustomers retrieves the customers in the trash
+func (store *InMemoryCustomerStore) GetDeletedCustomers() []data.Customer {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var customers []data.Customer
+	for _, customer := range store.customers {
+		if customer.IsDeleted() {
+			customers = append(customers, customer)
+		}
+	}
+	return customers
+}
+
+// RestoreCustomer takes a customer out of the trash
+func (store *InMemoryCustomerStore) RestoreCustomer(id int, ctx data.AuditContext) (data.Customer, *data.ErrorResponse) {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	customer, exists := store.customers[id]
+	if !exists || !customer.IsDeleted() {
+		return data.Customer{}, data.NewNotFoundError("Customer not found in trash")
+	}
+	before := customer
+	customer.SoftDelete = data.SoftDelete{}
+	store.customers[id] = customer
+	change = storeChange{resource: data.ResourceCustomers, id: id, action: data.AuditRestore, before: before, after: customer}
+	return customer, nil
+}
+
+// PurgeCustomer permanently removes a customer from the trash
+func (store *InMemoryCustomerStore) PurgeCustomer(id int, ctx data.AuditContext) *data.ErrorResponse {
+	var change storeChange
+	defer change.record(ctx) // After the lock is released
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	customer, exists := store.customers[id]
+	if !exists || !customer.IsDeleted() {
+		return data.NewNotFoundError("Customer not found in trash")
 	}
 	delete(store.customers, id)
+	change = storeChange{resource: data.ResourceCustomers, id: id, action: data.AuditPurge, before: customer}
 	return nil
 }
 
 // SearchCustomers filters customers based on the search criteria
 func (store *InMemoryCustomerStore) SearchCustomers(criteria data.CustomerSearchCriteria) ([]data.Customer, *data.ErrorResponse) {
+	if err := utils.ValidateCustomerSearchCriteria(criteria); err != nil {
+		return nil, err
+	}
+
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
 	var result []data.Customer
 	for _, customer := range store.customers {
+		if customer.IsDeleted() {
+			continue
+		}
 		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, customer.ID) {
 			continue
 		}
@@ -110,7 +295,7 @@ func (store *InMemoryCustomerStore) SearchCustomers(criteria data.CustomerSearch
 		if len(criteria.Emails) > 0 && !utils.ContainsString(criteria.Emails, customer.Email) {
 			continue
 		}
-		if !matchAddressCriteria(customer.Address, criteria.AddressCriteria) {
+		if !matchAnyAddress(customer, criteria.AddressCriteria) {
 			continue
 		}
 		if !criteria.MinCreatedAt.IsZero() && customer.CreatedAt.Before(criteria.MinCreatedAt) {
@@ -124,22 +309,152 @@ func (store *InMemoryCustomerStore) SearchCustomers(criteria data.CustomerSearch
 	return result, nil
 }
 
-// matchAddressCriteria matches a customer's address with the specified address search criteria
-func matchAddressCriteria(address data.Address, criteria data.AddressSearchCriteria) bool {
-	if len(criteria.Streets) > 0 && !utils.ContainsString(criteria.Streets, address.Street) {
+// matchAnyAddress reports whether one of the entries of a customer's address book matches all the address
+// search criteria
+func matchAnyAddress(customer data.Customer, criteria data.AddressSearchCriteria) bool {
+	if len(criteria.Streets) == 0 && len(criteria.Cities) == 0 && len(criteria.States) == 0 && len(criteria.PostalCodes) == 0 &&
+		len(criteria.Countries) == 0 && len(criteria.Labels) == 0 && len(criteria.Uses) == 0 {
+		return true
+	}
+	for _, address := range customer.Addresses {
+		if matchAddressCriteria(address, criteria) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAddressCriteria matches an address book entry with the specified address search criteria. Names are
+// compared ignoring case, postal codes ignoring spaces and countries by their ISO code. A use matches the
+// entries that can be used for it.
+func matchAddressCriteria(address data.CustomerAddress, criteria data.AddressSearchCriteria) bool {
+	if len(criteria.Streets) > 0 && !containsFold(criteria.Streets, address.Street) {
+		return false
+	}
+	if len(criteria.Cities) > 0 && !containsFold(criteria.Cities, address.City) {
 		return false
 	}
-	if len(criteria.Cities) > 0 && !utils.ContainsString(criteria.Cities, address.City) {
+	if len(criteria.States) > 0 && !containsFold(criteria.States, address.State) {
 		return false
 	}
-	if len(criteria.States) > 0 && !utils.ContainsString(criteria.States, address.State) {
+	if len(criteria.PostalCodes) > 0 && !containsMatch(criteria.PostalCodes, func(code string) bool {
+		return strings.EqualFold(strings.ReplaceAll(code, " ", ""), strings.ReplaceAll(address.PostalCode, " ", ""))
+	}) {
 		return false
 	}
-	if len(criteria.PostalCodes) > 0 && !utils.ContainsString(criteria.PostalCodes, address.PostalCode) {
+	if len(criteria.Countries) > 0 && !containsMatch(criteria.Countries, func(country string) bool {
+		return utils.SameCountry(country, address.Country)
+	}) {
 		return false
 	}
-	if len(criteria.Countries) > 0 && !utils.ContainsString(criteria.Countries, address.Country) {
+	if len(criteria.Labels) > 0 && !containsFold(criteria.Labels, address.Label) {
+		return false
+	}
+	if len(criteria.Uses) > 0 && !containsMatch(criteria.Uses, func(use data.AddressUse) bool {
+		return use == address.Use || use == data.AddressUseShipping && address.CanShip() || use == data.AddressUseBilling && address.CanBill()
+	}) {
 		return false
 	}
 	return true
 }
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	return containsMatch(values, func(v string) bool { return strings.EqualFold(strings.TrimSpace(v), value) })
+}
+
+// containsMatch reports whether any of the values matches
+func containsMatch[T any](values []T, match func(T) bool) bool {
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// adoptLegacyAddress turns the single address of a customer written without an address book into its
+// first entry
+func adoptLegacyAddress(customer *data.Customer) {
+	if len(customer.Addresses) == 0 && !customer.Address.IsEmpty() {
+		customer.Addresses = []data.CustomerAddress{{Use: data.AddressUseAny, Address: customer.Address}}
+	}
+}
+
+// numberAddresses gives an ID to the address book entries without one, or with the ID of an earlier entry,
+// counting on from the highest ID of the given and previous entries. Entries without a use can be used for any.
+func numberAddresses(addresses, previous []data.CustomerAddress) []data.CustomerAddress {
+	nextID := 1
+	for _, address := range append(append([]data.CustomerAddress{}, previous...), addresses...) {
+		nextID = max(nextID, address.ID+1)
+	}
+	numbered := make([]data.CustomerAddress, 0, len(addresses))
+	used := map[int]bool{}
+	for _, address := range addresses {
+		if address.ID <= 0 || used[address.ID] {
+			address.ID = nextID
+			nextID++
+		}
+		used[address.ID] = true
+		if address.Use == "" {
+			address.Use = data.AddressUseAny
+		}
+		numbered = append(numbered, address)
+	}
+	return numbered
+}
+
+// settleAddresses sorts the address book and gives it one default shipping and one default billing address:
+// the entry with ID preferred if it asks to be the default, otherwise the first entry asking for it, or the
+// first entry that can be used for it. The default shipping address is mirrored in the customer's Address.
+func settleAddresses(customer *data.Customer, preferred int) {
+	if customer.Addresses == nil {
+		customer.Addresses = []data.CustomerAddress{}
+	}
+	addresses := customer.Addresses
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].ID < addresses[j].ID })
+
+	pick := func(wants func(data.CustomerAddress) bool, can func(data.CustomerAddress) bool) int {
+		if address, ok := customer.FindAddress(preferred); ok && wants(address) && can(address) {
+			return preferred
+		}
+		for _, address := range addresses {
+			if wants(address) && can(address) {
+				return address.ID
+			}
+		}
+		for _, address := range addresses {
+			if can(address) {
+				return address.ID
+			}
+		}
+		return 0
+	}
+	shipping := pick(func(a data.CustomerAddress) bool { return a.DefaultShipping }, data.CustomerAddress.CanShip)
+	billing := pick(func(a data.CustomerAddress) bool { return a.DefaultBilling }, data.CustomerAddress.CanBill)
+
+	customer.Address = data.Address{}
+	for i := range addresses {
+		addresses[i].DefaultShipping = addresses[i].ID == shipping
+		addresses[i].DefaultBilling = addresses[i].ID == billing
+		if addresses[i].DefaultShipping {
+			customer.Address = addresses[i].Address
+		}
+	}
+}
+
+// AddCustomerDirectly stores a customer as-is, keeping its ID, and advances nextID past it
+func (store *InMemoryCustomerStore) AddCustomerDirectly(customer data.Customer) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Ensure the next ID is updated to prevent ID collisions
+	if customer.ID >= store.nextID {
+		store.nextID = customer.ID + 1
+	}
+
+	adoptLegacyAddress(&customer)
+	customer.Addresses = numberAddresses(customer.Addresses, nil)
+	settleAddresses(&customer, 0)
+	store.customers[customer.ID] = customer
+}
@@ -0,0 +1,427 @@
+package InmemoryStores
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// giftCardAlphabet leaves out 0, O, 1 and I, which are easily mistaken for each other when a code is typed in.
+// Its 32 characters divide 256, so every character is equally likely in a random code.
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// InMemoryGiftCardStore keeps the gift cards and the store-credit ledger behind one lock, so money moving
+// from a card to a customer's credit is never seen half-moved
+type InMemoryGiftCardStore struct {
+	mu          sync.RWMutex
+	cards       map[int]data.GiftCard
+	codes       map[string]int // Gift card IDs by normalized code
+	entries     []data.StoreCreditEntry
+	balances    map[int]float64 // Store credit by customer ID
+	nextID      int
+	nextEntryID int
+}
+
+var (
+	giftCardStoreInstance *InMemoryGiftCardStore
+	giftCardOnce          sync.Once
+)
+
+// GetGiftCardStoreInstance returns the singleton instance of InMemoryGiftCardStore
+func GetGiftCardStoreInstance() interfaces.GiftCardStore {
+	giftCardOnce.Do(func() {
+		giftCardStoreInstance = &InMemoryGiftCardStore{
+			cards:       make(map[int]data.GiftCard),
+			codes:       make(map[string]int),
+			balances:    make(map[int]float64),
+			nextID:      1,
+			nextEntryID: 1,
+		}
+	})
+	return giftCardStoreInstance
+}
+
+// IssueGiftCard sells a gift card with a new unique code. The card expires a year after it is issued unless
+// the request gives another expiry date.
+func (store *InMemoryGiftCardStore) IssueGiftCard(request data.GiftCardIssueRequest, actor string) (data.GiftCard, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	amount := roundAmount(request.Amount)
+	if amount <= 0 {
+		return data.GiftCard{}, data.NewValidationError("A gift card needs a positive amount")
+	}
+	now := time.Now()
+	expiresAt := now.AddDate(1, 0, 0)
+	if request.ExpiresAt != nil {
+		if !request.ExpiresAt.After(now) {
+			return data.GiftCard{}, data.NewValidationError("A gift card must expire in the future")
+		}
+		expiresAt = *request.ExpiresAt
+	}
+
+	code := store.newCode()
+	card := data.GiftCard{
+		ID:             store.nextID,
+		Code:           formatGiftCardCode(code),
+		Status:         data.GiftCardActive,
+		InitialBalance: amount,
+		Balance:        amount,
+		CustomerID:     request.CustomerID,
+		Note:           strings.TrimSpace(request.Note),
+		IssuedAt:       now,
+		IssuedBy:       actor,
+		ExpiresAt:      expiresAt,
+		Transactions: []data.GiftCardTransaction{{
+			Type:         data.GiftCardIssue,
+			Amount:       amount,
+			BalanceAfter: amount,
+			Actor:        actor,
+			CreatedAt:    now,
+		}},
+	}
+	store.nextID++
+	store.cards[card.ID] = card
+	store.codes[code] = card.ID
+	return card, nil
+}
+
+// GetGiftCard retrieves a gift card by its ID
+func (store *InMemoryGiftCardStore) GetGiftCard(id int) (data.GiftCard, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	card, exists := store.cards[id]
+	if !exists {
+		return data.GiftCard{}, data.NewNotFoundError("Gift card not found")
+	}
+	return card, nil
+}
+
+// GetGiftCardByCode retrieves a gift card by its code, ignoring case, spaces and dashes
+func (store *InMemoryGiftCardStore) GetGiftCardByCode(code string) (data.GiftCard, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	id, exists := store.codes[normalizeGiftCardCode(code)]
+	if !exists {
+		return data.GiftCard{}, data.NewNotFoundError("Gift card not found")
+	}
+	return store.cards[id], nil
+}
+
+// GetAllGiftCards retrieves all gift cards sorted by ID
+func (store *InMemoryGiftCardStore) GetAllGiftCards() []data.GiftCard {
+	return store.SearchGiftCards(data.GiftCardSearchCriteria{})
+}
+
+// SearchGiftCards retrieves the gift cards matching the criteria, sorted by ID
+func (store *InMemoryGiftCardStore) SearchGiftCards(criteria data.GiftCardSearchCriteria) []data.GiftCard {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	cards := []data.GiftCard{}
+	for _, card := range store.cards {
+		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, card.ID) {
+			continue
+		}
+		if len(criteria.Codes) > 0 && !containsMatch(criteria.Codes, func(c string) bool { return normalizeGiftCardCode(c) == normalizeGiftCardCode(card.Code) }) {
+			continue
+		}
+		if len(criteria.Statuses) > 0 && !containsMatch(criteria.Statuses, func(s data.GiftCardStatus) bool { return s == card.Status }) {
+			continue
+		}
+		if len(criteria.CustomerIDs) > 0 && !utils.ContainsInt(criteria.CustomerIDs, card.CustomerID) {
+			continue
+		}
+		cards = append(cards, card)
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	return cards
+}
+
+// Redeem pays up to amount towards an order, first from the gift cards in the order given, each up to what
+// is still due, then from the customer's store credit when useStoreCredit is set. Every card must be known,
+// unexpired and have a balance left; nothing is spent if one is refused. Cards not needed to cover the
+// amount are left untouched. It returns the payments made.
+func (store *InMemoryGiftCardStore) Redeem(customerID int, codes []string, useStoreCredit bool, amount float64, orderID int, actor string) ([]data.OrderPayment, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Check every card before spending anything
+	now := time.Now()
+	cards := make([]data.GiftCard, 0, len(codes))
+	seen := map[int]bool{}
+	for _, code := range codes {
+		card, errResp := store.spendable(code, now)
+		if errResp != nil {
+			return nil, errResp
+		}
+		if seen[card.ID] {
+			return nil, data.NewValidationError(fmt.Sprintf("Gift card %s is given more than once", maskGiftCardCode(card.Code)))
+		}
+		seen[card.ID] = true
+		cards = append(cards, card)
+	}
+
+	payments := []data.OrderPayment{}
+	remaining := roundAmount(amount)
+	for _, card := range cards {
+		if remaining <= 0 {
+			break
+		}
+		taken := min(card.Balance, remaining)
+		card.Balance = roundAmount(card.Balance - taken)
+		card.Transactions = append(append([]data.GiftCardTransaction(nil), card.Transactions...), data.GiftCardTransaction{
+			Type:         data.GiftCardRedemption,
+			Amount:       -taken,
+			BalanceAfter: card.Balance,
+			OrderID:      orderID,
+			Actor:        actor,
+			CreatedAt:    now,
+		})
+		store.cards[card.ID] = card
+		remaining = roundAmount(remaining - taken)
+		payments = append(payments, data.OrderPayment{
+			Method:       data.PaymentGiftCard,
+			Amount:       taken,
+			GiftCardID:   card.ID,
+			GiftCardCode: maskGiftCardCode(card.Code),
+			CreatedAt:    now,
+		})
+	}
+
+	if useStoreCredit && remaining > 0 && customerID != 0 {
+		if balance := store.balances[customerID]; balance > 0 {
+			taken := min(balance, remaining)
+			store.appendEntry(data.StoreCreditEntry{
+				CustomerID: customerID,
+				Type:       data.StoreCreditPayment,
+				Amount:     -taken,
+				OrderID:    orderID,
+				Actor:      actor,
+				CreatedAt:  now,
+			})
+			payments = append(payments, data.OrderPayment{Method: data.PaymentStoreCredit, Amount: taken, CreatedAt: now})
+		}
+	}
+	return payments, nil
+}
+
+// TransferToStoreCredit moves the whole balance of a gift card to a customer's store credit, which does not
+// expire
+func (store *InMemoryGiftCardStore) TransferToStoreCredit(code string, customerID int, actor string) (data.StoreCreditEntry, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if customerID < 1 {
+		return data.StoreCreditEntry{}, data.NewValidationError("A customer is needed to receive store credit")
+	}
+	now := time.Now()
+	card, errResp := store.spendable(code, now)
+	if errResp != nil {
+		return data.StoreCreditEntry{}, errResp
+	}
+
+	amount := card.Balance
+	card.Balance = 0
+	card.Transactions = append(append([]data.GiftCardTransaction(nil), card.Transactions...), data.GiftCardTransaction{
+		Type:       data.GiftCardCreditTransfer,
+		Amount:     -amount,
+		CustomerID: customerID,
+		Actor:      actor,
+		CreatedAt:  now,
+	})
+	store.cards[card.ID] = card
+
+	return store.appendEntry(data.StoreCreditEntry{
+		CustomerID: customerID,
+		Type:       data.StoreCreditGiftCard,
+		Amount:     amount,
+		GiftCardID: card.ID,
+		Actor:      actor,
+		CreatedAt:  now,
+	}), nil
+}
+
+// AddStoreCredit adds an entry to a customer's store-credit ledger, an adjustment unless another type is
+// given. A negative amount takes credit away, but never more than the customer has.
+func (store *InMemoryGiftCardStore) AddStoreCredit(entry data.StoreCreditEntry) (data.StoreCreditEntry, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry.Amount = roundAmount(entry.Amount)
+	if entry.CustomerID < 1 {
+		return data.StoreCreditEntry{}, data.NewValidationError("A customer is needed to receive store credit")
+	}
+	if entry.Amount == 0 {
+		return data.StoreCreditEntry{}, data.NewValidationError("A store credit change needs a non-zero amount")
+	}
+	if balance := store.balances[entry.CustomerID]; roundAmount(balance+entry.Amount) < 0 {
+		return data.StoreCreditEntry{}, data.NewValidationError(fmt.Sprintf("Customer %d has only %.2f of store credit", entry.CustomerID, balance))
+	}
+	if entry.Type == "" {
+		entry.Type = data.StoreCreditAdjustment
+	}
+	entry.Note = strings.TrimSpace(entry.Note)
+	entry.CreatedAt = time.Now()
+	return store.appendEntry(entry), nil
+}
+
+// GetStoreCredit retrieves a customer's store-credit balance and ledger, oldest entry first
+func (store *InMemoryGiftCardStore) GetStoreCredit(customerID int) data.StoreCreditAccount {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	account := data.StoreCreditAccount{CustomerID: customerID, Balance: store.balances[customerID], Entries: []data.StoreCreditEntry{}}
+	for _, entry := range store.entries {
+		if entry.CustomerID == customerID {
+			account.Entries = append(account.Entries, entry)
+		}
+	}
+	return account
+}
+
+// GetAllStoreCreditEntries retrieves the whole store-credit ledger, oldest entry first
+func (store *InMemoryGiftCardStore) GetAllStoreCreditEntries() []data.StoreCreditEntry {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return append([]data.StoreCreditEntry{}, store.entries...)
+}
+
+// ExpireGiftCards expires the active cards whose expiry date has passed, forfeiting their balance.
+// It returns the cards it expired and records each of them in the audit log.
+func (store *InMemoryGiftCardStore) ExpireGiftCards(now time.Time, ctx data.AuditContext) []data.GiftCard {
+	var changes []storeChange
+	defer func() {
+		for i := range changes {
+			changes[i].record(ctx) // After the lock is released
+		}
+	}()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	expired := []data.GiftCard{}
+	befores := map[int]data.GiftCard{}
+	for id, card := range store.cards {
+		if card.Status != data.GiftCardActive || now.Before(card.ExpiresAt) {
+			continue
+		}
+		befores[id] = card
+		card.Status = data.GiftCardExpired
+		if card.Balance > 0 {
+			card.Transactions = append(append([]data.GiftCardTransaction(nil), card.Transactions...), data.GiftCardTransaction{
+				Type:      data.GiftCardExpiry,
+				Amount:    -card.Balance,
+				Actor:     ctx.Actor,
+				CreatedAt: now,
+			})
+			card.Balance = 0
+		}
+		store.cards[id] = card
+		expired = append(expired, card)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ID < expired[j].ID })
+	for _, card := range expired {
+		changes = append(changes, storeChange{resource: data.ResourceGiftCards, id: card.ID, action: data.AuditUpdate, before: befores[card.ID], after: card})
+	}
+	return expired
+}
+
+// AddGiftCardDirectly adds a gift card with a specific ID, ensuring no ID collisions
+func (store *InMemoryGiftCardStore) AddGiftCardDirectly(card data.GiftCard) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if card.ID >= store.nextID {
+		store.nextID = card.ID + 1
+	}
+	store.cards[card.ID] = card
+	store.codes[normalizeGiftCardCode(card.Code)] = card.ID
+}
+
+// AddStoreCreditEntryDirectly appends a saved ledger entry, keeping its ID. Entries must be added in the
+// order they were made.
+func (store *InMemoryGiftCardStore) AddStoreCreditEntryDirectly(entry data.StoreCreditEntry) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if entry.ID >= store.nextEntryID {
+		store.nextEntryID = entry.ID + 1
+	}
+	store.entries = append(store.entries, entry)
+	store.balances[entry.CustomerID] = roundAmount(store.balances[entry.CustomerID] + entry.Amount)
+}
+
+// spendable finds the gift card with a code and checks it can pay for something. The caller holds the lock.
+func (store *InMemoryGiftCardStore) spendable(code string, now time.Time) (data.GiftCard, *data.ErrorResponse) {
+	id, exists := store.codes[normalizeGiftCardCode(code)]
+	if !exists {
+		return data.GiftCard{}, data.NewValidationError("Unknown gift card code")
+	}
+	card := store.cards[id]
+	if card.Expired(now) {
+		return data.GiftCard{}, data.NewValidationError(fmt.Sprintf("Gift card %s expired on %s", maskGiftCardCode(card.Code), card.ExpiresAt.Format("2006-01-02")))
+	}
+	if card.Balance <= 0 {
+		return data.GiftCard{}, data.NewValidationError(fmt.Sprintf("Gift card %s has no balance left", maskGiftCardCode(card.Code)))
+	}
+	return card, nil
+}
+
+// appendEntry numbers a ledger entry and applies it to the customer's balance. The caller holds the lock.
+func (store *InMemoryGiftCardStore) appendEntry(entry data.StoreCreditEntry) data.StoreCreditEntry {
+	entry.ID = store.nextEntryID
+	store.nextEntryID++
+	entry.BalanceAfter = roundAmount(store.balances[entry.CustomerID] + entry.Amount)
+	store.balances[entry.CustomerID] = entry.BalanceAfter
+	store.entries = append(store.entries, entry)
+	return entry
+}
+
+// newCode draws random codes until one is not in use. The caller holds the lock.
+func (store *InMemoryGiftCardStore) newCode() string {
+	buf := make([]byte, 16)
+	for {
+		rand.Read(buf)
+		code := make([]byte, len(buf))
+		for i, b := range buf {
+			code[i] = giftCardAlphabet[int(b)%len(giftCardAlphabet)]
+		}
+		if _, taken := store.codes[string(code)]; !taken {
+			return string(code)
+		}
+	}
+}
+
+// normalizeGiftCardCode uppercases a code and drops its spaces and dashes, so codes match however they are typed
+func normalizeGiftCardCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// formatGiftCardCode splits a normalized code into dash-separated groups of four characters
+func formatGiftCardCode(code string) string {
+	groups := []string{}
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
+
+// maskGiftCardCode hides every group of a code but the last, so receipts and errors do not reveal spendable codes
+func maskGiftCardCode(code string) string {
+	groups := strings.Split(code, "-")
+	for i := range groups[:len(groups)-1] {
+		groups[i] = "****"
+	}
+	return strings.Join(groups, "-")
+}
@@ -0,0 +1,157 @@
+package InmemoryStores
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+type InMemoryInvoiceStore struct {
+	mu        sync.RWMutex
+	invoices  map[int]data.Invoice
+	sequences map[invoiceSequence]int // Last number issued in every sequence
+	nextID    int
+}
+
+// invoiceSequence identifies a numbering sequence: one per document type and fiscal year
+type invoiceSequence struct {
+	invoiceType data.InvoiceType
+	fiscalYear  int
+}
+
+var (
+	invoiceStoreInstance *InMemoryInvoiceStore
+	invoiceOnce          sync.Once
+)
+
+// GetInvoiceStoreInstance returns the singleton instance of InMemoryInvoiceStore
+func GetInvoiceStoreInstance() interfaces.InvoiceStore {
+	invoiceOnce.Do(func() {
+		invoiceStoreInstance = &InMemoryInvoiceStore{
+			invoices:  make(map[int]data.Invoice),
+			sequences: make(map[invoiceSequence]int),
+			nextID:    1,
+		}
+	})
+	return invoiceStoreInstance
+}
+
+// IssueInvoice adds an invoice or credit note to the store, numbering it after the last document of the same
+// type and fiscal year, for example INV-2025-00042
+func (store *InMemoryInvoiceStore) IssueInvoice(invoice data.Invoice, prefix string) data.Invoice {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	sequence := invoiceSequence{invoice.Type, invoice.FiscalYear}
+	store.sequences[sequence]++
+	invoice.ID = store.nextID
+	invoice.Sequence = store.sequences[sequence]
+	invoice.Number = fmt.Sprintf("%s-%d-%05d", prefix, invoice.FiscalYear, invoice.Sequence)
+	store.nextID++
+	store.invoices[invoice.ID] = invoice
+	return invoice
+}
+
+// GetInvoice retrieves an invoice or credit note by its ID
+func (store *InMemoryInvoiceStore) GetInvoice(id int) (data.Invoice, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	invoice, exists := store.invoices[id]
+	if !exists {
+		return data.Invoice{}, data.NewNotFoundError("Invoice not found")
+	}
+	return invoice, nil
+}
+
+// GetAllInvoices retrieves all invoices and credit notes in the order they were issued
+func (store *InMemoryInvoiceStore) GetAllInvoices() []data.Invoice {
+	return store.SearchInvoices(data.InvoiceSearchCriteria{})
+}
+
+// GetInvoicesByOrder retrieves the invoices and credit notes of an order in the order they were issued
+func (store *InMemoryInvoiceStore) GetInvoicesByOrder(orderID int) []data.Invoice {
+	return store.SearchInvoices(data.InvoiceSearchCriteria{OrderIDs: []int{orderID}})
+}
+
+// UpdateInvoice replaces an issued invoice, recording what was credited of it. Its number cannot change.
+func (store *InMemoryInvoiceStore) UpdateInvoice(invoice data.Invoice) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, exists := store.invoices[invoice.ID]
+	if !exists {
+		return data.NewNotFoundError("Invoice not found")
+	}
+	invoice.Number = existing.Number
+	invoice.Sequence = existing.Sequence
+	invoice.FiscalYear = existing.FiscalYear
+	store.invoices[invoice.ID] = invoice
+	return nil
+}
+
+// SearchInvoices filters invoices and credit notes based on search criteria, in the order they were issued
+func (store *InMemoryInvoiceStore) SearchInvoices(criteria data.InvoiceSearchCriteria) []data.Invoice {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	invoices := []data.Invoice{}
+	for _, invoice := range store.invoices {
+		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, invoice.ID) {
+			continue
+		}
+		if len(criteria.Numbers) > 0 && !containsFold(criteria.Numbers, invoice.Number) {
+			continue
+		}
+		if len(criteria.Types) > 0 && !containsMatch(criteria.Types, func(t data.InvoiceType) bool { return t == invoice.Type }) {
+			continue
+		}
+		if len(criteria.Statuses) > 0 && !containsMatch(criteria.Statuses, func(s data.InvoiceStatus) bool { return s == invoice.Status }) {
+			continue
+		}
+		if len(criteria.OrderIDs) > 0 && !utils.ContainsInt(criteria.OrderIDs, invoice.OrderID) {
+			continue
+		}
+		if len(criteria.CustomerIDs) > 0 && !utils.ContainsInt(criteria.CustomerIDs, invoice.CustomerID) {
+			continue
+		}
+		if len(criteria.FiscalYears) > 0 && !utils.ContainsInt(criteria.FiscalYears, invoice.FiscalYear) {
+			continue
+		}
+		if criteria.MinTotal > 0 && invoice.Total < criteria.MinTotal {
+			continue
+		}
+		if criteria.MaxTotal > 0 && invoice.Total > criteria.MaxTotal {
+			continue
+		}
+		if !criteria.MinIssuedAt.IsZero() && invoice.IssuedAt.Before(criteria.MinIssuedAt) {
+			continue
+		}
+		if !criteria.MaxIssuedAt.IsZero() && invoice.IssuedAt.After(criteria.MaxIssuedAt) {
+			continue
+		}
+		invoices = append(invoices, invoice)
+	}
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].ID < invoices[j].ID })
+	return invoices
+}
+
+// AddInvoiceDirectly adds an invoice with a specific ID, ensuring no ID collisions and continuing its
+// numbering sequence after it
+func (store *InMemoryInvoiceStore) AddInvoiceDirectly(invoice data.Invoice) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if invoice.ID >= store.nextID {
+		store.nextID = invoice.ID + 1
+	}
+	sequence := invoiceSequence{invoice.Type, invoice.FiscalYear}
+	if invoice.Sequence > store.sequences[sequence] {
+		store.sequences[sequence] = invoice.Sequence
+	}
+	store.invoices[invoice.ID] = invoice
+}
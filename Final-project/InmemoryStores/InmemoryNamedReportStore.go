@@ -0,0 +1,97 @@
+package InmemoryStores
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+type InMemoryNamedReportStore struct {
+	mu      sync.RWMutex
+	reports map[int]data.SalesReportResult
+	nextID  int
+}
+
+var (
+	namedReportStoreInstance *InMemoryNamedReportStore
+	namedReportOnce          sync.Once
+)
+
+// GetNamedReportStoreInstance returns the singleton instance of InMemoryNamedReportStore
+func GetNamedReportStoreInstance() interfaces.NamedReportStore {
+	namedReportOnce.Do(func() {
+		namedReportStoreInstance = &InMemoryNamedReportStore{
+			reports: make(map[int]data.SalesReportResult),
+			nextID:  1,
+		}
+	})
+	return namedReportStoreInstance
+}
+
+// SaveReport saves a report under its name. Names are unique, ignoring case.
+func (store *InMemoryNamedReportStore) SaveReport(report data.SalesReportResult) (data.SalesReportResult, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, existing := range store.reports {
+		if strings.EqualFold(existing.Name, report.Name) {
+			return data.SalesReportResult{}, data.NewConflictError(fmt.Sprintf("A report named %q already exists", existing.Name))
+		}
+	}
+	report.ID = store.nextID
+	store.nextID++
+	store.reports[report.ID] = report
+	return report, nil
+}
+
+// GetReport retrieves a saved report by its ID
+func (store *InMemoryNamedReportStore) GetReport(id int) (data.SalesReportResult, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	report, exists := store.reports[id]
+	if !exists {
+		return data.SalesReportResult{}, data.NewNotFoundError("Report not found")
+	}
+	return report, nil
+}
+
+// GetAllReports retrieves all saved reports sorted by ID
+func (store *InMemoryNamedReportStore) GetAllReports() []data.SalesReportResult {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	reports := []data.SalesReportResult{}
+	for _, report := range store.reports {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID < reports[j].ID })
+	return reports
+}
+
+// DeleteReport removes a saved report by its ID
+func (store *InMemoryNamedReportStore) DeleteReport(id int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.reports[id]; !exists {
+		return data.NewNotFoundError("Report not found")
+	}
+	delete(store.reports, id)
+	return nil
+}
+
+// AddReportDirectly adds a report with a specific ID, ensuring no ID collisions
+func (store *InMemoryNamedReportStore) AddReportDirectly(report data.SalesReportResult) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if report.ID >= store.nextID {
+		store.nextID = report.ID + 1
+	}
+	store.reports[report.ID] = report
+}
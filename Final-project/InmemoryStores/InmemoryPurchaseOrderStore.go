@@ -0,0 +1,250 @@
+package InmemoryStores
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+type InMemoryPurchaseOrderStore struct {
+	mu     sync.RWMutex
+	orders map[int]data.PurchaseOrder
+	nextID int
+}
+
+var (
+	purchaseOrderStoreInstance *InMemoryPurchaseOrderStore
+	purchaseOrderOnce          sync.Once
+)
+
+// GetPurchaseOrderStoreInstance returns the singleton instance of InMemoryPurchaseOrderStore
+func GetPurchaseOrderStoreInstance() interfaces.PurchaseOrderStore {
+	purchaseOrderOnce.Do(func() {
+		purchaseOrderStoreInstance = &InMemoryPurchaseOrderStore{
+			orders: make(map[int]data.PurchaseOrder),
+			nextID: 1,
+		}
+	})
+	return purchaseOrderStoreInstance
+}
+
+// CreatePurchaseOrder adds a new draft purchase order to the store
+func (store *InMemoryPurchaseOrderStore) CreatePurchaseOrder(order data.PurchaseOrder) (data.PurchaseOrder, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	order.ID = store.nextID
+	order.Status = data.PurchaseOrderDraft
+	order.Lines = draftLines(order.Lines)
+	order.Receipts = nil
+	order.CreatedAt = time.Now()
+	order.SentAt, order.ReceivedAt = nil, nil
+	store.nextID++
+	store.orders[order.ID] = order
+	return order, nil
+}
+
+// GetPurchaseOrder retrieves a purchase order by its ID
+func (store *InMemoryPurchaseOrderStore) GetPurchaseOrder(id int) (data.PurchaseOrder, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	order, exists := store.orders[id]
+	if !exists {
+		return data.PurchaseOrder{}, data.NewNotFoundError("Purchase order not found")
+	}
+	return order, nil
+}
+
+// GetAllPurchaseOrders retrieves all purchase orders sorted by ID
+func (store *InMemoryPurchaseOrderStore) GetAllPurchaseOrders() []data.PurchaseOrder {
+	return store.SearchPurchaseOrders(data.PurchaseOrderSearchCriteria{})
+}
+
+// UpdatePurchaseOrder replaces the supplier, lines, expected date and notes of a draft purchase order
+func (store *InMemoryPurchaseOrderStore) UpdatePurchaseOrder(id int, order data.PurchaseOrder) (data.PurchaseOrder, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, exists := store.orders[id]
+	if !exists {
+		return data.PurchaseOrder{}, data.NewNotFoundError("Purchase order not found")
+	}
+	if existing.Status != data.PurchaseOrderDraft {
+		return data.PurchaseOrder{}, data.NewConflictError("Only draft purchase orders can be edited")
+	}
+	existing.SupplierID = order.SupplierID
+	existing.WarehouseID = order.WarehouseID
+	existing.Lines = draftLines(order.Lines)
+	existing.ExpectedAt = order.ExpectedAt
+	existing.Notes = order.Notes
+	store.orders[id] = existing
+	return existing, nil
+}
+
+// DeletePurchaseOrder removes a draft purchase order from the store
+func (store *InMemoryPurchaseOrderStore) DeletePurchaseOrder(id int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	order, exists := store.orders[id]
+	if !exists {
+		return data.NewNotFoundError("Purchase order not found")
+	}
+	if order.Status != data.PurchaseOrderDraft {
+		return data.NewConflictError("Only draft purchase orders can be deleted")
+	}
+	delete(store.orders, id)
+	return nil
+}
+
+// SendPurchaseOrder marks a draft purchase order as sent to the supplier
+func (store *InMemoryPurchaseOrderStore) SendPurchaseOrder(id int) (data.PurchaseOrder, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	order, exists := store.orders[id]
+	if !exists {
+		return data.PurchaseOrder{}, data.NewNotFoundError("Purchase order not found")
+	}
+	if order.Status != data.PurchaseOrderDraft {
+		return data.PurchaseOrder{}, data.NewConflictError("Only draft purchase orders can be sent")
+	}
+	now := time.Now()
+	order.Status = data.PurchaseOrderSent
+	order.SentAt = &now
+	store.orders[id] = order
+	return order, nil
+}
+
+// ReceiveGoods records a delivery against a sent purchase order. Every receipt line must match an order
+// line and stay within its outstanding quantity; missing unit costs default to the order line's cost.
+// It returns the updated purchase order and the recorded receipt.
+func (store *InMemoryPurchaseOrderStore) ReceiveGoods(id int, receipt data.GoodsReceipt) (data.PurchaseOrder, data.GoodsReceipt, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	order, exists := store.orders[id]
+	if !exists {
+		return data.PurchaseOrder{}, data.GoodsReceipt{}, data.NewNotFoundError("Purchase order not found")
+	}
+	if order.Status != data.PurchaseOrderSent && order.Status != data.PurchaseOrderPartiallyReceived {
+		return data.PurchaseOrder{}, data.GoodsReceipt{}, data.NewConflictError(fmt.Sprintf("Cannot receive goods for a %s purchase order", order.Status))
+	}
+	if len(receipt.Lines) == 0 {
+		return data.PurchaseOrder{}, data.GoodsReceipt{}, data.NewValidationError("A receipt needs at least one line")
+	}
+
+	// Validate the whole receipt before changing the order
+	lineIndex := map[int]int{}
+	for i, line := range order.Lines {
+		lineIndex[line.BookID] = i
+	}
+	receiving := map[int]int{}
+	for i, line := range receipt.Lines {
+		index, onOrder := lineIndex[line.BookID]
+		if !onOrder {
+			return data.PurchaseOrder{}, data.GoodsReceipt{}, data.NewValidationError(fmt.Sprintf("Book %d is not on purchase order %d", line.BookID, id))
+		}
+		if line.Quantity < 1 {
+			return data.PurchaseOrder{}, data.GoodsReceipt{}, data.NewValidationError("Received quantities must be at least 1")
+		}
+		if line.UnitCost < 0 {
+			return data.PurchaseOrder{}, data.GoodsReceipt{}, data.NewValidationError("unit_cost cannot be negative")
+		}
+		receiving[line.BookID] += line.Quantity
+		if outstanding := order.Lines[index].Outstanding(); receiving[line.BookID] > outstanding {
+			return data.PurchaseOrder{}, data.GoodsReceipt{}, data.NewValidationError(fmt.Sprintf("Book %d has only %d outstanding", line.BookID, outstanding))
+		}
+		if line.UnitCost == 0 {
+			receipt.Lines[i].UnitCost = order.Lines[index].UnitCost
+		}
+	}
+
+	receipt.ID = len(order.Receipts) + 1
+	receipt.ReceivedAt = time.Now()
+	for bookID, quantity := range receiving {
+		order.Lines[lineIndex[bookID]].QuantityReceived += quantity
+	}
+	order.Receipts = append(order.Receipts, receipt)
+
+	order.Status = data.PurchaseOrderReceived
+	for _, line := range order.Lines {
+		if line.Outstanding() > 0 {
+			order.Status = data.PurchaseOrderPartiallyReceived
+			break
+		}
+	}
+	if order.Status == data.PurchaseOrderReceived {
+		order.ReceivedAt = &receipt.ReceivedAt
+	}
+
+	store.orders[id] = order
+	return order, receipt, nil
+}
+
+// SearchPurchaseOrders retrieves the purchase orders matching the criteria, sorted by ID
+func (store *InMemoryPurchaseOrderStore) SearchPurchaseOrders(criteria data.PurchaseOrderSearchCriteria) []data.PurchaseOrder {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	orders := []data.PurchaseOrder{}
+	for _, order := range store.orders {
+		if len(criteria.SupplierIDs) > 0 && !utils.ContainsInt(criteria.SupplierIDs, order.SupplierID) {
+			continue
+		}
+		if len(criteria.Statuses) > 0 && !containsPurchaseOrderStatus(criteria.Statuses, order.Status) {
+			continue
+		}
+		if len(criteria.BookIDs) > 0 && !purchaseOrderHasBook(order, criteria.BookIDs) {
+			continue
+		}
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+	return orders
+}
+
+// AddPurchaseOrderDirectly adds a purchase order with a specific ID, ensuring no ID collisions
+func (store *InMemoryPurchaseOrderStore) AddPurchaseOrderDirectly(order data.PurchaseOrder) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if order.ID >= store.nextID {
+		store.nextID = order.ID + 1
+	}
+	store.orders[order.ID] = order
+}
+
+// draftLines copies the lines of a draft, which cannot have received anything yet
+func draftLines(lines []data.PurchaseOrderLine) []data.PurchaseOrderLine {
+	copied := make([]data.PurchaseOrderLine, len(lines))
+	for i, line := range lines {
+		line.QuantityReceived = 0
+		copied[i] = line
+	}
+	return copied
+}
+
+func containsPurchaseOrderStatus(statuses []data.PurchaseOrderStatus, status data.PurchaseOrderStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func purchaseOrderHasBook(order data.PurchaseOrder, bookIDs []int) bool {
+	for _, line := range order.Lines {
+		if utils.ContainsInt(bookIDs, line.BookID) {
+			return true
+		}
+	}
+	return false
+}
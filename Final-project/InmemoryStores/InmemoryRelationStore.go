@@ -0,0 +1,99 @@
+package InmemoryStores
+
+import (
+	"sync"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// Names of the declared relationships
+const (
+	RelationAuthorBooks    = "author_books"
+	RelationBookOrderItems = "book_order_items"
+	RelationCustomerOrders = "customer_orders"
+)
+
+type InMemoryRelationStore struct {
+	mu        sync.RWMutex
+	relations []data.Relation
+}
+
+var (
+	relationStoreInstance *InMemoryRelationStore
+	relationOnce          sync.Once
+)
+
+// defaultRelations declares every relationship between entities with its default policy
+func defaultRelations() []data.Relation {
+	return []data.Relation{
+		{Name: RelationAuthorBooks, Parent: data.ResourceAuthors, Child: data.ResourceBooks, Policy: data.PolicyCascade},
+		{Name: RelationBookOrderItems, Parent: data.ResourceBooks, Child: data.ResourceOrders, Policy: data.PolicyRestrict},
+		{Name: RelationCustomerOrders, Parent: data.ResourceCustomers, Child: data.ResourceOrders, Policy: data.PolicyRestrict},
+	}
+}
+
+// GetRelationStoreInstance returns the singleton instance of InMemoryRelationStore
+func GetRelationStoreInstance() interfaces.RelationStore {
+	relationOnce.Do(func() {
+		relationStoreInstance = &InMemoryRelationStore{
+			relations: defaultRelations(),
+		}
+	})
+	return relationStoreInstance
+}
+
+// GetAllRelations retrieves every declared relationship
+func (store *InMemoryRelationStore) GetAllRelations() []data.Relation {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	relations := make([]data.Relation, len(store.relations))
+	copy(relations, store.relations)
+	return relations
+}
+
+// GetRelation retrieves a relationship by name
+func (store *InMemoryRelationStore) GetRelation(name string) (data.Relation, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, relation := range store.relations {
+		if relation.Name == name {
+			return relation, nil
+		}
+	}
+	return data.Relation{}, data.NewNotFoundError("Relation not found")
+}
+
+// GetRelationsByParent retrieves the relationships whose parent is the given resource
+func (store *InMemoryRelationStore) GetRelationsByParent(parent string) []data.Relation {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var relations []data.Relation
+	for _, relation := range store.relations {
+		if relation.Parent == parent {
+			relations = append(relations, relation)
+		}
+	}
+	return relations
+}
+
+// SetPolicy changes the delete policy of a relationship
+func (store *InMemoryRelationStore) SetPolicy(name string, policy data.RelationPolicy) (data.Relation, *data.ErrorResponse) {
+	if !data.IsValidRelationPolicy(policy) {
+		return data.Relation{}, data.NewValidationError("Policy must be one of restrict, cascade, set-null or soft-detach")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for i, relation := range store.relations {
+		if relation.Name == name {
+			store.relations[i].Policy = policy
+			return store.relations[i], nil
+		}
+	}
+	return data.Relation{}, data.NewNotFoundError("Relation not found")
+}
@@ -0,0 +1,342 @@
+package InmemoryStores
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+type InMemoryReturnStore struct {
+	mu      sync.RWMutex
+	returns map[int]data.Return
+	nextID  int
+}
+
+var (
+	returnStoreInstance *InMemoryReturnStore
+	returnOnce          sync.Once
+)
+
+// GetReturnStoreInstance returns the singleton instance of InMemoryReturnStore
+func GetReturnStoreInstance() interfaces.ReturnStore {
+	returnOnce.Do(func() {
+		returnStoreInstance = &InMemoryReturnStore{
+			returns: make(map[int]data.Return),
+			nextID:  1,
+		}
+	})
+	return returnStoreInstance
+}
+
+// CreateReturn opens a return for books of an order. Every line must name a book of the order once, within
+// the quantity ordered less what other returns of the order not rejected already take back. The lines
+// keep the price and details the books were ordered with.
+func (store *InMemoryReturnStore) CreateReturn(request data.Return, order data.Order) (data.Return, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		return data.Return{}, data.NewValidationError("A return needs a reason")
+	}
+	if len(request.Lines) == 0 {
+		return data.Return{}, data.NewValidationError("A return needs at least one line")
+	}
+
+	// Quantities still returnable per book
+	ordered := map[int]int{}
+	items := map[int]data.OrderItem{}
+	for _, item := range order.Items {
+		if item.BookID == 0 {
+			continue // The book was detached from the order
+		}
+		ordered[item.BookID] += item.Quantity
+		if _, seen := items[item.BookID]; !seen {
+			items[item.BookID] = item
+		}
+	}
+	for _, existing := range store.returns {
+		if existing.OrderID != order.ID || existing.Status == data.ReturnRejected {
+			continue
+		}
+		for _, line := range existing.Lines {
+			ordered[line.BookID] -= line.Quantity
+		}
+	}
+
+	lines := make([]data.ReturnLine, 0, len(request.Lines))
+	seen := map[int]bool{}
+	for _, line := range request.Lines {
+		item, onOrder := items[line.BookID]
+		if !onOrder {
+			return data.Return{}, data.NewValidationError(fmt.Sprintf("Book %d is not on order %d", line.BookID, order.ID))
+		}
+		if seen[line.BookID] {
+			return data.Return{}, data.NewValidationError(fmt.Sprintf("Book %d appears on more than one line", line.BookID))
+		}
+		seen[line.BookID] = true
+		if line.Quantity < 1 {
+			return data.Return{}, data.NewValidationError("Returned quantities must be at least 1")
+		}
+		if returnable := ordered[line.BookID]; line.Quantity > returnable {
+			return data.Return{}, data.NewValidationError(fmt.Sprintf("Only %d of book %d can still be returned", max(returnable, 0), line.BookID))
+		}
+		lines = append(lines, data.ReturnLine{
+			BookID:    line.BookID,
+			Quantity:  line.Quantity,
+			UnitPrice: item.UnitPrice,
+			Snapshot:  item.Snapshot,
+		})
+	}
+
+	ret := data.Return{
+		ID:          store.nextID,
+		Number:      fmt.Sprintf("RMA-%05d", store.nextID),
+		OrderID:     order.ID,
+		CustomerID:  order.CustomerID,
+		Status:      data.ReturnRequested,
+		Reason:      request.Reason,
+		Lines:       lines,
+		RequestedBy: request.RequestedBy,
+		RequestedAt: time.Now(),
+	}
+	store.nextID++
+	store.returns[ret.ID] = ret
+	return ret, nil
+}
+
+// GetReturn retrieves a return by its ID
+func (store *InMemoryReturnStore) GetReturn(id int) (data.Return, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	ret, exists := store.returns[id]
+	if !exists {
+		return data.Return{}, data.NewNotFoundError("Return not found")
+	}
+	return ret, nil
+}
+
+// GetAllReturns retrieves all returns sorted by ID
+func (store *InMemoryReturnStore) GetAllReturns() []data.Return {
+	return store.SearchReturns(data.ReturnSearchCriteria{})
+}
+
+// GetReturnsByOrder retrieves the returns of an order sorted by ID
+func (store *InMemoryReturnStore) GetReturnsByOrder(orderID int) []data.Return {
+	return store.SearchReturns(data.ReturnSearchCriteria{OrderIDs: []int{orderID}})
+}
+
+// ApproveReturn lets the customer send the goods of a requested return back
+func (store *InMemoryReturnStore) ApproveReturn(id int, actor string) (data.Return, *data.ErrorResponse) {
+	return store.decide(id, data.ReturnApproved, "", actor)
+}
+
+// RejectReturn refuses a requested return, releasing its quantities for other returns
+func (store *InMemoryReturnStore) RejectReturn(id int, reason string, actor string) (data.Return, *data.ErrorResponse) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return data.Return{}, data.NewValidationError("A rejection needs a reason")
+	}
+	return store.decide(id, data.ReturnRejected, reason, actor)
+}
+
+// decide approves or rejects a requested return
+func (store *InMemoryReturnStore) decide(id int, status data.ReturnStatus, reason string, actor string) (data.Return, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ret, exists := store.returns[id]
+	if !exists {
+		return data.Return{}, data.NewNotFoundError("Return not found")
+	}
+	if ret.Status != data.ReturnRequested {
+		return data.Return{}, data.NewConflictError(fmt.Sprintf("Return %s is already %s", ret.Number, ret.Status))
+	}
+	now := time.Now()
+	ret.Status = status
+	ret.RejectionReason = reason
+	ret.DecidedBy = actor
+	ret.DecidedAt = &now
+	store.returns[id] = ret
+	return ret, nil
+}
+
+// ReceiveReturn records goods received back against an approved return. Every receipt line must match a
+// return line, be graded with a known condition and stay within the line's outstanding quantity; a book
+// can be split over several lines to grade its copies differently. It returns the updated return and the
+// recorded receipt.
+func (store *InMemoryReturnStore) ReceiveReturn(id int, receipt data.ReturnReceipt) (data.Return, data.ReturnReceipt, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ret, exists := store.returns[id]
+	if !exists {
+		return data.Return{}, data.ReturnReceipt{}, data.NewNotFoundError("Return not found")
+	}
+	if ret.Status != data.ReturnApproved && ret.Status != data.ReturnPartiallyReceived {
+		return data.Return{}, data.ReturnReceipt{}, data.NewConflictError(fmt.Sprintf("Cannot receive goods for a %s return", ret.Status))
+	}
+	if len(receipt.Lines) == 0 {
+		return data.Return{}, data.ReturnReceipt{}, data.NewValidationError("A receipt needs at least one line")
+	}
+
+	// Validate the whole receipt before changing the return
+	lineIndex := map[int]int{}
+	for i, line := range ret.Lines {
+		lineIndex[line.BookID] = i
+	}
+	receiving := map[int]int{}
+	for i, line := range receipt.Lines {
+		index, onReturn := lineIndex[line.BookID]
+		if !onReturn {
+			return data.Return{}, data.ReturnReceipt{}, data.NewValidationError(fmt.Sprintf("Book %d is not on return %s", line.BookID, ret.Number))
+		}
+		if line.Quantity < 1 {
+			return data.Return{}, data.ReturnReceipt{}, data.NewValidationError("Received quantities must be at least 1")
+		}
+		if !data.IsValidReturnCondition(line.Condition) {
+			return data.Return{}, data.ReturnReceipt{}, data.NewValidationError(fmt.Sprintf("Unknown condition %q; use one of %v", line.Condition, data.ReturnConditions))
+		}
+		receiving[line.BookID] += line.Quantity
+		if outstanding := ret.Lines[index].Outstanding(); receiving[line.BookID] > outstanding {
+			return data.Return{}, data.ReturnReceipt{}, data.NewValidationError(fmt.Sprintf("Book %d has only %d outstanding", line.BookID, outstanding))
+		}
+		receipt.Lines[i].Restocked = line.Condition.Sellable()
+	}
+
+	ret.Lines = append([]data.ReturnLine(nil), ret.Lines...)
+	receipt.ID = len(ret.Receipts) + 1
+	receipt.ReceivedAt = time.Now()
+	receipt.Refund, receipt.CreditNoteID, receipt.CreditNoteNumber, receipt.StoreCreditEntryID = 0, 0, "", 0
+	for bookID, quantity := range receiving {
+		ret.Lines[lineIndex[bookID]].QuantityReceived += quantity
+	}
+	ret.Receipts = append(append([]data.ReturnReceipt(nil), ret.Receipts...), receipt)
+
+	ret.Status = data.ReturnReceived
+	for _, line := range ret.Lines {
+		if line.Outstanding() > 0 {
+			ret.Status = data.ReturnPartiallyReceived
+			break
+		}
+	}
+	if ret.Status == data.ReturnReceived {
+		ret.ReceivedAt = &receipt.ReceivedAt
+	}
+
+	store.returns[id] = ret
+	return ret, receipt, nil
+}
+
+// RecordRefund records how the goods of a receipt were refunded, adding the refund to the return's total
+func (store *InMemoryReturnStore) RecordRefund(id int, receipt data.ReturnReceipt) (data.Return, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ret, exists := store.returns[id]
+	if !exists {
+		return data.Return{}, data.NewNotFoundError("Return not found")
+	}
+	if receipt.ID < 1 || receipt.ID > len(ret.Receipts) {
+		return data.Return{}, data.NewNotFoundError("Receipt not found")
+	}
+
+	ret.Receipts = append([]data.ReturnReceipt(nil), ret.Receipts...)
+	recorded := &ret.Receipts[receipt.ID-1]
+	ret.RefundTotal = roundAmount(ret.RefundTotal - recorded.Refund + receipt.Refund)
+	recorded.Refund = receipt.Refund
+	recorded.CreditNoteID = receipt.CreditNoteID
+	recorded.CreditNoteNumber = receipt.CreditNoteNumber
+	recorded.StoreCreditEntryID = receipt.StoreCreditEntryID
+	store.returns[id] = ret
+	return ret, nil
+}
+
+// RemoveReceipt takes back the latest receipt of a return when its goods could not be restocked or refunded
+func (store *InMemoryReturnStore) RemoveReceipt(id, receiptID int) (data.Return, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ret, exists := store.returns[id]
+	if !exists {
+		return data.Return{}, data.NewNotFoundError("Return not found")
+	}
+	if receiptID < 1 || receiptID != len(ret.Receipts) {
+		return data.Return{}, data.NewConflictError("Only the latest receipt of a return can be removed")
+	}
+	receipt := ret.Receipts[receiptID-1]
+
+	lineIndex := map[int]int{}
+	for i, line := range ret.Lines {
+		lineIndex[line.BookID] = i
+	}
+	ret.Lines = append([]data.ReturnLine(nil), ret.Lines...)
+	for _, line := range receipt.Lines {
+		ret.Lines[lineIndex[line.BookID]].QuantityReceived -= line.Quantity
+	}
+	ret.Receipts = ret.Receipts[: receiptID-1 : receiptID-1]
+	ret.RefundTotal = roundAmount(ret.RefundTotal - receipt.Refund)
+	ret.ReceivedAt = nil
+
+	ret.Status = data.ReturnApproved
+	for _, line := range ret.Lines {
+		if line.QuantityReceived > 0 {
+			ret.Status = data.ReturnPartiallyReceived
+			break
+		}
+	}
+	store.returns[id] = ret
+	return ret, nil
+}
+
+// SearchReturns retrieves the returns matching the criteria, sorted by ID
+func (store *InMemoryReturnStore) SearchReturns(criteria data.ReturnSearchCriteria) []data.Return {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	returns := []data.Return{}
+	for _, ret := range store.returns {
+		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, ret.ID) {
+			continue
+		}
+		if len(criteria.OrderIDs) > 0 && !utils.ContainsInt(criteria.OrderIDs, ret.OrderID) {
+			continue
+		}
+		if len(criteria.CustomerIDs) > 0 && !utils.ContainsInt(criteria.CustomerIDs, ret.CustomerID) {
+			continue
+		}
+		if len(criteria.Statuses) > 0 && !containsMatch(criteria.Statuses, func(s data.ReturnStatus) bool { return s == ret.Status }) {
+			continue
+		}
+		if len(criteria.BookIDs) > 0 && !containsMatch(ret.Lines, func(l data.ReturnLine) bool { return utils.ContainsInt(criteria.BookIDs, l.BookID) }) {
+			continue
+		}
+		if !criteria.MinRequestedAt.IsZero() && ret.RequestedAt.Before(criteria.MinRequestedAt) {
+			continue
+		}
+		if !criteria.MaxRequestedAt.IsZero() && ret.RequestedAt.After(criteria.MaxRequestedAt) {
+			continue
+		}
+		returns = append(returns, ret)
+	}
+	sort.Slice(returns, func(i, j int) bool { return returns[i].ID < returns[j].ID })
+	return returns
+}
+
+// AddReturnDirectly adds a return with a specific ID, ensuring no ID collisions
+func (store *InMemoryReturnStore) AddReturnDirectly(ret data.Return) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if ret.ID >= store.nextID {
+		store.nextID = ret.ID + 1
+	}
+	store.returns[ret.ID] = ret
+}
@@ -0,0 +1,223 @@
+package InmemoryStores
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+type InMemoryReviewStore struct {
+	mu        sync.RWMutex
+	reviews   map[int]data.Review
+	summaries map[int]data.RatingSummary // Rating summary of every reviewed book, kept up to date on each change
+	nextID    int
+}
+
+var (
+	reviewStoreInstance *InMemoryReviewStore
+	reviewOnce          sync.Once
+)
+
+// GetReviewStoreInstance returns the singleton instance of InMemoryReviewStore
+func GetReviewStoreInstance() interfaces.ReviewStore {
+	reviewOnce.Do(func() {
+		reviewStoreInstance = &InMemoryReviewStore{
+			reviews:   make(map[int]data.Review),
+			summaries: make(map[int]data.RatingSummary),
+			nextID:    1,
+		}
+	})
+	return reviewStoreInstance
+}
+
+// CreateReview adds a new review to the store. A customer can only review a book once.
+func (store *InMemoryReviewStore) CreateReview(review data.Review) (data.Review, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, existing := range store.reviews {
+		if existing.BookID == review.BookID && existing.CustomerID == review.CustomerID {
+			return data.Review{}, data.NewConflictError("The customer has already reviewed this book")
+		}
+	}
+
+	review.ID = store.nextID
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = review.CreatedAt
+	store.nextID++
+	store.reviews[review.ID] = review
+	store.summarize(review.BookID)
+	return review, nil
+}
+
+// GetReview retrieves a review by its ID
+func (store *InMemoryReviewStore) GetReview(id int) (data.Review, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	review, exists := store.reviews[id]
+	if !exists {
+		return data.Review{}, data.NewNotFoundError("Review not found")
+	}
+	return review, nil
+}
+
+// GetAllReviews retrieves all reviews sorted by ID
+func (store *InMemoryReviewStore) GetAllReviews() []data.Review {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	reviews := []data.Review{}
+	for _, review := range store.reviews {
+		reviews = append(reviews, review)
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].ID < reviews[j].ID })
+	return reviews
+}
+
+// GetReviewsByBook retrieves the reviews of a book in every status, newest first
+func (store *InMemoryReviewStore) GetReviewsByBook(bookID int) []data.Review {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	reviews := []data.Review{}
+	for _, review := range store.reviews {
+		if review.BookID == bookID {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		if !reviews[i].CreatedAt.Equal(reviews[j].CreatedAt) {
+			return reviews[i].CreatedAt.After(reviews[j].CreatedAt)
+		}
+		return reviews[i].ID > reviews[j].ID
+	})
+	return reviews
+}
+
+// UpdateReview replaces the content of a review, keeping its book, customer and creation date
+func (store *InMemoryReviewStore) UpdateReview(id int, review data.Review) (data.Review, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, exists := store.reviews[id]
+	if !exists {
+		return data.Review{}, data.NewNotFoundError("Review not found")
+	}
+	review.ID = id
+	review.BookID = existing.BookID
+	review.CustomerID = existing.CustomerID
+	review.CreatedAt = existing.CreatedAt
+	review.UpdatedAt = time.Now()
+	store.reviews[id] = review
+	store.summarize(review.BookID)
+	return review, nil
+}
+
+// ModerateReview sets the moderation status and note of a review
+func (store *InMemoryReviewStore) ModerateReview(id int, moderation data.ReviewModeration) (data.Review, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	review, exists := store.reviews[id]
+	if !exists {
+		return data.Review{}, data.NewNotFoundError("Review not found")
+	}
+	review.Status = moderation.Status
+	review.ModerationNote = moderation.Note
+	review.UpdatedAt = time.Now()
+	store.reviews[id] = review
+	store.summarize(review.BookID)
+	return review, nil
+}
+
+// DeleteReview removes a review from the store
+func (store *InMemoryReviewStore) DeleteReview(id int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	review, exists := store.reviews[id]
+	if !exists {
+		return data.NewNotFoundError("Review not found")
+	}
+	delete(store.reviews, id)
+	store.summarize(review.BookID)
+	return nil
+}
+
+// GetRatingSummary returns the rating summary of the approved reviews of a book
+func (store *InMemoryReviewStore) GetRatingSummary(bookID int) data.RatingSummary {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	summary, exists := store.summaries[bookID]
+	if !exists {
+		return newRatingSummary()
+	}
+	histogram := make(map[int]int, len(summary.Histogram))
+	for rating, count := range summary.Histogram {
+		histogram[rating] = count
+	}
+	summary.Histogram = histogram // The caller gets its own copy
+	return summary
+}
+
+// AddReviewDirectly adds a review with a specific ID, ensuring no ID collisions
+func (store *InMemoryReviewStore) AddReviewDirectly(review data.Review) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if review.ID >= store.nextID {
+		store.nextID = review.ID + 1
+	}
+	store.reviews[review.ID] = review
+	store.summarize(review.BookID)
+}
+
+// summarize recomputes the rating summary of a book. The caller must hold the lock.
+func (store *InMemoryReviewStore) summarize(bookID int) {
+	summary := newRatingSummary()
+	total := 0
+	for _, review := range store.reviews {
+		if review.BookID != bookID || review.Status != data.ReviewApproved {
+			continue
+		}
+		summary.ReviewCount++
+		summary.Histogram[review.Rating]++
+		total += review.Rating
+	}
+	if summary.ReviewCount == 0 {
+		delete(store.summaries, bookID)
+		return
+	}
+	summary.AverageRating = math.Round(float64(total)/float64(summary.ReviewCount)*100) / 100
+	store.summaries[bookID] = summary
+}
+
+// newRatingSummary returns the summary of a book without approved reviews, every rating counted as zero
+func newRatingSummary() data.RatingSummary {
+	return data.RatingSummary{Histogram: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+}
+
+// matchRatingCriteria filters books on the rating and review count of their approved reviews. Books without
+// approved reviews have no rating, so they fail any rating bound.
+func matchRatingCriteria(bookID int, criteria data.BookSearchCriteria) bool {
+	if criteria.MinRating == 0 && criteria.MaxRating == 0 && criteria.MinReviewCount == 0 {
+		return true
+	}
+	summary := GetReviewStoreInstance().GetRatingSummary(bookID)
+	if (criteria.MinRating > 0 || criteria.MaxRating > 0) && summary.ReviewCount == 0 {
+		return false
+	}
+	if criteria.MinRating > 0 && summary.AverageRating < criteria.MinRating {
+		return false
+	}
+	if criteria.MaxRating > 0 && summary.AverageRating > criteria.MaxRating {
+		return false
+	}
+	return summary.ReviewCount >= criteria.MinReviewCount
+}
@@ -0,0 +1,247 @@
+package InmemoryStores
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+type InMemorySalesReportStore struct {
+	mu      sync.RWMutex
+	reports map[int]data.SalesReport
+	nextID  int
+}
+
+var (
+	salesReportStoreInstance *InMemorySalesReportStore
+	salesReportOnce          sync.Once
+)
+
+// GetSalesReportStoreInstance returns the singleton instance of InMemorySalesReportStore
+func GetSalesReportStoreInstance() interfaces.SalesReportStore {
+	salesReportOnce.Do(func() {
+		salesReportStoreInstance = &InMemorySalesReportStore{
+			reports: make(map[int]data.SalesReport),
+			nextID:  1,
+		}
+	})
+	return salesReportStoreInstance
+}
+
+// SaveReport adds a report to the sales report history
+func (store *InMemorySalesReportStore) SaveReport(report data.SalesReport) (data.SalesReport, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	report.ID = store.nextID
+	store.nextID++
+	store.reports[report.ID] = report
+	return report, nil
+}
+
+// GetAllReports retrieves all reports sorted by timestamp
+func (store *InMemorySalesReportStore) GetAllReports() []data.SalesReport {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.sortedReports(func(data.SalesReport) bool { return true })
+}
+
+// SearchReports retrieves the reports matching the criteria sorted by timestamp. A report matches the top
+// book criteria when one of its top-selling books does.
+func (store *InMemorySalesReportStore) SearchReports(criteria data.SalesReportSearchCriteria) ([]data.SalesReport, *data.ErrorResponse) {
+	if err := utils.ValidateSalesReportSearchCriteria(criteria); err != nil {
+		return nil, err
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.sortedReports(func(report data.SalesReport) bool {
+		if !criteria.MinTimestamp.IsZero() && report.Timestamp.Before(criteria.MinTimestamp) {
+			return false
+		}
+		if !criteria.MaxTimestamp.IsZero() && report.Timestamp.After(criteria.MaxTimestamp) {
+			return false
+		}
+		if criteria.MinRevenue > 0 && report.TotalRevenue < criteria.MinRevenue {
+			return false
+		}
+		if criteria.MaxRevenue > 0 && report.TotalRevenue > criteria.MaxRevenue {
+			return false
+		}
+		if criteria.MinOrders > 0 && report.TotalOrders < criteria.MinOrders {
+			return false
+		}
+		if criteria.MaxOrders > 0 && report.TotalOrders > criteria.MaxOrders {
+			return false
+		}
+		if hasTopBooksCriteria(criteria.TopBooksCriteria) && !matchTopSellingBooks(report.TopSellingBooks, criteria.TopBooksCriteria) {
+			return false
+		}
+		return true
+	}), nil
+}
+
+// DeleteReportsBefore removes the reports older than the cutoff and returns how many were removed
+func (store *InMemorySalesReportStore) DeleteReportsBefore(cutoff time.Time) (int, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	deleted := 0
+	for id, report := range store.reports {
+		if report.Timestamp.Before(cutoff) {
+			delete(store.reports, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// CompactReportsBefore merges the reports older than the cutoff into one report per month (UTC) and
+// returns how many reports were merged away. A compacted report adds up the revenue, orders and top-selling
+// books of the reports it replaces; its top books are those that were top books on some day, so a book
+// selling steadily below the daily top 5 is missing from it.
+func (store *InMemorySalesReportStore) CompactReportsBefore(cutoff time.Time) (int, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Group the old reports by month
+	months := make(map[string][]data.SalesReport)
+	for _, report := range store.reports {
+		if report.Timestamp.Before(cutoff) {
+			month := report.Timestamp.UTC().Format("2006-01")
+			months[month] = append(months[month], report)
+		}
+	}
+
+	merged := 0
+	for month, reports := range months {
+		if len(reports) < 2 {
+			continue
+		}
+		compacted := compactReports(month, reports)
+		for _, report := range reports {
+			delete(store.reports, report.ID)
+		}
+		store.reports[compacted.ID] = compacted
+		merged += len(reports) - 1
+	}
+	return merged, nil
+}
+
+// AddReportDirectly adds a report with a specific ID, ensuring no ID collisions. Reports without an ID,
+// such as those saved before reports had one, are given the next ID.
+func (store *InMemorySalesReportStore) AddReportDirectly(report data.SalesReport) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if report.ID == 0 {
+		report.ID = store.nextID
+	}
+	if report.ID >= store.nextID {
+		store.nextID = report.ID + 1
+	}
+	store.reports[report.ID] = report
+}
+
+// sortedReports returns the reports kept by the filter sorted by timestamp, then ID
+func (store *InMemorySalesReportStore) sortedReports(keep func(data.SalesReport) bool) []data.SalesReport {
+	reports := []data.SalesReport{}
+	for _, report := range store.reports {
+		if keep(report) {
+			reports = append(reports, report)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].Timestamp.Equal(reports[j].Timestamp) {
+			return reports[i].Timestamp.Before(reports[j].Timestamp)
+		}
+		return reports[i].ID < reports[j].ID
+	})
+	return reports
+}
+
+// compactReports merges the reports of a month into one keeping the lowest ID and the latest timestamp
+func compactReports(month string, reports []data.SalesReport) data.SalesReport {
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Timestamp.Before(reports[j].Timestamp) })
+
+	compacted := data.SalesReport{ID: reports[0].ID, Period: month}
+	quantities := make(map[int]int)
+	books := make(map[int]data.Book)
+	for _, report := range reports {
+		if report.ID < compacted.ID {
+			compacted.ID = report.ID
+		}
+		compacted.Timestamp = report.Timestamp
+		compacted.TotalRevenue += report.TotalRevenue
+		compacted.TotalOrders += report.TotalOrders
+		if report.CompactedFrom > 0 {
+			compacted.CompactedFrom += report.CompactedFrom
+		} else {
+			compacted.CompactedFrom++
+		}
+		for _, topBook := range report.TopSellingBooks {
+			quantities[topBook.Book.ID] += topBook.QuantitySold
+			books[topBook.Book.ID] = topBook.Book // Keep the latest details of the book
+		}
+	}
+	compacted.TotalRevenue = math.Round(compacted.TotalRevenue*100) / 100
+
+	compacted.TopSellingBooks = []data.TopSellingBook{}
+	for id, book := range books {
+		compacted.TopSellingBooks = append(compacted.TopSellingBooks, data.TopSellingBook{Book: book, QuantitySold: quantities[id]})
+	}
+	sort.Slice(compacted.TopSellingBooks, func(i, j int) bool {
+		a, b := compacted.TopSellingBooks[i], compacted.TopSellingBooks[j]
+		if a.QuantitySold != b.QuantitySold {
+			return a.QuantitySold > b.QuantitySold
+		}
+		return a.Book.ID < b.Book.ID
+	})
+	return compacted
+}
+
+// hasTopBooksCriteria reports whether any top book criterion is set
+func hasTopBooksCriteria(criteria data.BookSalesSearchCriteria) bool {
+	book := criteria.BookCriteria
+	author := book.AuthorCriteria
+	return criteria.MinQuantity > 0 || criteria.MaxQuantity > 0 ||
+		len(book.IDs) > 0 || len(book.Titles) > 0 || len(book.Genres) > 0 ||
+		!book.MinPublishedAt.IsZero() || !book.MaxPublishedAt.IsZero() ||
+		book.MinPrice > 0 || book.MaxPrice > 0 || book.MinStock > 0 || book.MaxStock > 0 ||
+		len(author.IDs) > 0 || len(author.FirstNames) > 0 || len(author.LastNames) > 0 || len(author.Keywords) > 0
+}
+
+// matchTopSellingBooks reports whether one of the top-selling books of a report matches the criteria. Books
+// are matched as they were when the report was generated, their author as it is now.
+func matchTopSellingBooks(topBooks []data.TopSellingBook, criteria data.BookSalesSearchCriteria) bool {
+	for _, topBook := range topBooks {
+		if criteria.MinQuantity > 0 && topBook.QuantitySold < criteria.MinQuantity {
+			continue
+		}
+		if criteria.MaxQuantity > 0 && topBook.QuantitySold > criteria.MaxQuantity {
+			continue
+		}
+		book := topBook.Book
+		if book.AuthorID == 0 && book.Author != nil {
+			book.AuthorID = book.Author.ID // Older reports embed the author
+		}
+		if criteria.BookCriteria.MinStock > 0 && book.Stock < criteria.BookCriteria.MinStock {
+			continue
+		}
+		if criteria.BookCriteria.MaxStock > 0 && book.Stock > criteria.BookCriteria.MaxStock {
+			continue
+		}
+		if !matchBookCriteria(book, criteria.BookCriteria) {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package InmemoryStores
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// maxRunsPerSchedule is how many runs of each schedule the run history keeps
+const maxRunsPerSchedule = 100
+
+// InMemoryScheduleStore keeps the report schedules and the history of their runs
+type InMemoryScheduleStore struct {
+	mu        sync.RWMutex
+	schedules map[int]data.ReportSchedule
+	runs      []data.ScheduleRun // Oldest first
+	nextID    int
+	nextRunID int
+}
+
+var (
+	scheduleStoreInstance *InMemoryScheduleStore
+	scheduleOnce          sync.Once
+)
+
+// GetScheduleStoreInstance returns the singleton instance of InMemoryScheduleStore
+func GetScheduleStoreInstance() interfaces.ScheduleStore {
+	scheduleOnce.Do(func() {
+		scheduleStoreInstance = &InMemoryScheduleStore{
+			schedules: make(map[int]data.ReportSchedule),
+			nextID:    1,
+			nextRunID: 1,
+		}
+	})
+	return scheduleStoreInstance
+}
+
+// CreateSchedule adds a new schedule to the store. Names are unique, ignoring case.
+func (store *InMemoryScheduleStore) CreateSchedule(schedule data.ReportSchedule) (data.ReportSchedule, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if errResp := store.checkName(0, schedule.Name); errResp != nil {
+		return data.ReportSchedule{}, errResp
+	}
+	schedule.ID = store.nextID
+	schedule.CreatedAt = time.Now()
+	schedule.LastRunAt = nil
+	store.nextID++
+	store.schedules[schedule.ID] = schedule
+	return schedule, nil
+}
+
+// GetSchedule retrieves a schedule by its ID
+func (store *InMemoryScheduleStore) GetSchedule(id int) (data.ReportSchedule, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	schedule, exists := store.schedules[id]
+	if !exists {
+		return data.ReportSchedule{}, data.NewNotFoundError("Schedule not found")
+	}
+	return schedule, nil
+}
+
+// GetAllSchedules retrieves all schedules sorted by ID
+func (store *InMemoryScheduleStore) GetAllSchedules() []data.ReportSchedule {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	schedules := []data.ReportSchedule{}
+	for _, schedule := range store.schedules {
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+	return schedules
+}
+
+// UpdateSchedule replaces a schedule, keeping its ID, creation time and last run
+func (store *InMemoryScheduleStore) UpdateSchedule(id int, schedule data.ReportSchedule) (data.ReportSchedule, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, exists := store.schedules[id]
+	if !exists {
+		return data.ReportSchedule{}, data.NewNotFoundError("Schedule not found")
+	}
+	if errResp := store.checkName(id, schedule.Name); errResp != nil {
+		return data.ReportSchedule{}, errResp
+	}
+	schedule.ID = id
+	schedule.CreatedAt = existing.CreatedAt
+	schedule.LastRunAt = existing.LastRunAt
+	store.schedules[id] = schedule
+	return schedule, nil
+}
+
+// DeleteSchedule removes a schedule by its ID. Its runs stay in the history.
+func (store *InMemoryScheduleStore) DeleteSchedule(id int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.schedules[id]; !exists {
+		return data.NewNotFoundError("Schedule not found")
+	}
+	delete(store.schedules, id)
+	return nil
+}
+
+// SetRunTimes records the occurrence a schedule last ran for and when it runs next
+func (store *InMemoryScheduleStore) SetRunTimes(id int, lastRunAt, nextRunAt *time.Time) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	schedule, exists := store.schedules[id]
+	if !exists {
+		return data.NewNotFoundError("Schedule not found")
+	}
+	if lastRunAt != nil {
+		schedule.LastRunAt = lastRunAt
+	}
+	schedule.NextRunAt = nextRunAt
+	store.schedules[id] = schedule
+	return nil
+}
+
+// RecordRun adds a run to the history, dropping the oldest runs of its schedule beyond maxRunsPerSchedule
+func (store *InMemoryScheduleStore) RecordRun(run data.ScheduleRun) data.ScheduleRun {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	run.ID = store.nextRunID
+	store.nextRunID++
+	store.runs = append(store.runs, run)
+
+	count := 0
+	for _, existing := range store.runs {
+		if existing.ScheduleID == run.ScheduleID {
+			count++
+		}
+	}
+	if count > maxRunsPerSchedule {
+		kept := store.runs[:0]
+		for _, existing := range store.runs {
+			if existing.ScheduleID == run.ScheduleID && count > maxRunsPerSchedule {
+				count--
+				continue
+			}
+			kept = append(kept, existing)
+		}
+		store.runs = kept
+	}
+	return run
+}
+
+// GetRuns retrieves the runs of a schedule, newest first
+func (store *InMemoryScheduleStore) GetRuns(scheduleID int) []data.ScheduleRun {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	runs := []data.ScheduleRun{}
+	for i := len(store.runs) - 1; i >= 0; i-- {
+		if store.runs[i].ScheduleID == scheduleID {
+			runs = append(runs, store.runs[i])
+		}
+	}
+	return runs
+}
+
+// GetAllRuns retrieves every run, oldest first
+func (store *InMemoryScheduleStore) GetAllRuns() []data.ScheduleRun {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	runs := make([]data.ScheduleRun, len(store.runs))
+	copy(runs, store.runs)
+	return runs
+}
+
+// AddScheduleDirectly adds a schedule with a specific ID, ensuring no ID collisions
+func (store *InMemoryScheduleStore) AddScheduleDirectly(schedule data.ReportSchedule) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if schedule.ID >= store.nextID {
+		store.nextID = schedule.ID + 1
+	}
+	store.schedules[schedule.ID] = schedule
+}
+
+// AddRunDirectly adds a loaded run, keeping its ID. Runs must be added oldest first.
+func (store *InMemoryScheduleStore) AddRunDirectly(run data.ScheduleRun) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if run.ID >= store.nextRunID {
+		store.nextRunID = run.ID + 1
+	}
+	store.runs = append(store.runs, run)
+}
+
+// checkName refuses a name already used by another schedule
+func (store *InMemoryScheduleStore) checkName(id int, name string) *data.ErrorResponse {
+	for _, existing := range store.schedules {
+		if existing.ID != id && strings.EqualFold(existing.Name, name) {
+			return data.NewConflictError(fmt.Sprintf("A schedule named %q already exists", existing.Name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package InmemoryStores
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+type InMemoryStockAlertStore struct {
+	mu     sync.RWMutex
+	alerts map[int]data.StockAlert
+	nextID int
+}
+
+var (
+	stockAlertStoreInstance *InMemoryStockAlertStore
+	stockAlertOnce          sync.Once
+)
+
+// GetStockAlertStoreInstance returns the singleton instance of InMemoryStockAlertStore
+func GetStockAlertStoreInstance() interfaces.StockAlertStore {
+	stockAlertOnce.Do(func() {
+		stockAlertStoreInstance = &InMemoryStockAlertStore{
+			alerts: make(map[int]data.StockAlert),
+			nextID: 1,
+		}
+	})
+	return stockAlertStoreInstance
+}
+
+// CreateAlert adds a new open alert to the store
+func (store *InMemoryStockAlertStore) CreateAlert(alert data.StockAlert) data.StockAlert {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	alert.ID = store.nextID
+	alert.CreatedAt = time.Now()
+	alert.ResolvedAt = nil
+	store.nextID++
+	store.alerts[alert.ID] = alert
+	return alert
+}
+
+// GetOpenAlert retrieves the unresolved alert of a book, if any
+func (store *InMemoryStockAlertStore) GetOpenAlert(bookID int) (data.StockAlert, bool) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, alert := range store.alerts {
+		if alert.BookID == bookID && alert.IsOpen() {
+			return alert, true
+		}
+	}
+	return data.StockAlert{}, false
+}
+
+// ResolveAlert marks an alert as resolved
+func (store *InMemoryStockAlertStore) ResolveAlert(id int) (data.StockAlert, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	alert, exists := store.alerts[id]
+	if !exists {
+		return data.StockAlert{}, data.NewNotFoundError("Stock alert not found")
+	}
+	now := time.Now()
+	alert.ResolvedAt = &now
+	store.alerts[id] = alert
+	return alert, nil
+}
+
+// GetAllAlerts retrieves all alerts, newest first
+func (store *InMemoryStockAlertStore) GetAllAlerts() []data.StockAlert {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	alerts := []data.StockAlert{}
+	for _, alert := range store.alerts {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID > alerts[j].ID })
+	return alerts
+}
+
+// AddAlertDirectly adds an alert with a specific ID, ensuring no ID collisions
+func (store *InMemoryStockAlertStore) AddAlertDirectly(alert data.StockAlert) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if alert.ID >= store.nextID {
+		store.nextID = alert.ID + 1
+	}
+	store.alerts[alert.ID] = alert
+}
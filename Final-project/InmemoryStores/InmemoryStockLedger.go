@@ -0,0 +1,97 @@
+package InmemoryStores
+
+import (
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// InMemoryStockLedger keeps the stock movements in the order they were recorded. Movements are never changed or removed.
+type InMemoryStockLedger struct {
+	mu        sync.RWMutex
+	movements []data.StockMovement
+	byBook    map[int][]int // Indexes into movements, per book
+	nextID    int
+}
+
+var (
+	stockLedgerInstance *InMemoryStockLedger
+	stockLedgerOnce     sync.Once
+)
+
+// GetStockLedgerInstance returns the singleton instance of InMemoryStockLedger
+func GetStockLedgerInstance() interfaces.StockLedger {
+	stockLedgerOnce.Do(func() {
+		stockLedgerInstance = &InMemoryStockLedger{
+			byBook: make(map[int][]int),
+			nextID: 1,
+		}
+	})
+	return stockLedgerInstance
+}
+
+// RecordMovement appends a movement to the ledger
+func (ledger *InMemoryStockLedger) RecordMovement(movement data.StockMovement) data.StockMovement {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	movement.ID = ledger.nextID
+	if movement.CreatedAt.IsZero() {
+		movement.CreatedAt = time.Now()
+	}
+	ledger.nextID++
+	ledger.append(movement)
+	return movement
+}
+
+// GetMovements retrieves the movements of a book, oldest first
+func (ledger *InMemoryStockLedger) GetMovements(bookID int) []data.StockMovement {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	movements := []data.StockMovement{}
+	for _, index := range ledger.byBook[bookID] {
+		movements = append(movements, ledger.movements[index])
+	}
+	return movements
+}
+
+// GetAllMovements retrieves every movement, oldest first
+func (ledger *InMemoryStockLedger) GetAllMovements() []data.StockMovement {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	movements := make([]data.StockMovement, len(ledger.movements))
+	copy(movements, ledger.movements)
+	return movements
+}
+
+// Balance returns the sum of a book's movements, and whether the book has any
+func (ledger *InMemoryStockLedger) Balance(bookID int) (int, bool) {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	balance := 0
+	for _, index := range ledger.byBook[bookID] {
+		balance += ledger.movements[index].Quantity
+	}
+	return balance, len(ledger.byBook[bookID]) > 0
+}
+
+// AddMovementDirectly adds a loaded movement, keeping its ID
+func (ledger *InMemoryStockLedger) AddMovementDirectly(movement data.StockMovement) {
+	ledger.mu.Lock()
+	defer ledger.mu.Unlock()
+
+	if movement.ID >= ledger.nextID {
+		ledger.nextID = movement.ID + 1
+	}
+	ledger.append(movement)
+}
+
+func (ledger *InMemoryStockLedger) append(movement data.StockMovement) {
+	ledger.movements = append(ledger.movements, movement)
+	ledger.byBook[movement.BookID] = append(ledger.byBook[movement.BookID], len(ledger.movements)-1)
+}
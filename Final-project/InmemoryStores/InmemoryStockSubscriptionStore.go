@@ -0,0 +1,191 @@
+package InmemoryStores
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+type InMemoryStockSubscriptionStore struct {
+	mu                 sync.RWMutex
+	subscriptions      map[int]data.StockSubscription
+	notifications      map[int]data.StockNotification
+	nextID             int
+	nextNotificationID int
+}
+
+var (
+	stockSubscriptionStoreInstance *InMemoryStockSubscriptionStore
+	stockSubscriptionOnce          sync.Once
+)
+
+// GetStockSubscriptionStoreInstance returns the singleton instance of InMemoryStockSubscriptionStore
+func GetStockSubscriptionStoreInstance() interfaces.StockSubscriptionStore {
+	stockSubscriptionOnce.Do(func() {
+		stockSubscriptionStoreInstance = &InMemoryStockSubscriptionStore{
+			subscriptions:      make(map[int]data.StockSubscription),
+			notifications:      make(map[int]data.StockNotification),
+			nextID:             1,
+			nextNotificationID: 1,
+		}
+	})
+	return stockSubscriptionStoreInstance
+}
+
+// Subscribe creates an active subscription of a customer to a book. It reports false, returning the
+// existing subscription, if the customer is already subscribed to the book.
+func (store *InMemoryStockSubscriptionStore) Subscribe(customerID, bookID int) (data.StockSubscription, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if subscription, exists := store.active(customerID, bookID); exists {
+		return subscription, false
+	}
+	subscription := data.StockSubscription{
+		ID:         store.nextID,
+		CustomerID: customerID,
+		BookID:     bookID,
+		Status:     data.SubscriptionActive,
+		CreatedAt:  time.Now(),
+	}
+	store.nextID++
+	store.subscriptions[subscription.ID] = subscription
+	return subscription, true
+}
+
+// GetSubscriptionsByCustomer retrieves the subscriptions of a customer, newest first
+func (store *InMemoryStockSubscriptionStore) GetSubscriptionsByCustomer(customerID int) []data.StockSubscription {
+	return store.filter(func(subscription data.StockSubscription) bool { return subscription.CustomerID == customerID })
+}
+
+// GetSubscriptionsByBook retrieves the subscriptions to a book, newest first
+func (store *InMemoryStockSubscriptionStore) GetSubscriptionsByBook(bookID int) []data.StockSubscription {
+	return store.filter(func(subscription data.StockSubscription) bool { return subscription.BookID == bookID })
+}
+
+// GetAllSubscriptions retrieves all subscriptions, newest first
+func (store *InMemoryStockSubscriptionStore) GetAllSubscriptions() []data.StockSubscription {
+	return store.filter(func(data.StockSubscription) bool { return true })
+}
+
+// Unsubscribe cancels the active subscription of a customer to a book
+func (store *InMemoryStockSubscriptionStore) Unsubscribe(customerID, bookID int) (data.StockSubscription, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	subscription, exists := store.active(customerID, bookID)
+	if !exists {
+		return data.StockSubscription{}, data.NewNotFoundError("Subscription not found")
+	}
+	now := time.Now()
+	subscription.Status = data.SubscriptionCancelled
+	subscription.CancelledAt = &now
+	store.subscriptions[subscription.ID] = subscription
+	return subscription, nil
+}
+
+// ClaimSubscriptions marks the active subscriptions to a book as notified and returns them, so each
+// subscription is notified once even if the book is restocked again meanwhile
+func (store *InMemoryStockSubscriptionStore) ClaimSubscriptions(bookID int) []data.StockSubscription {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	claimed := []data.StockSubscription{}
+	for id, subscription := range store.subscriptions {
+		if subscription.BookID != bookID || subscription.Status != data.SubscriptionActive {
+			continue
+		}
+		subscription.Status = data.SubscriptionNotified
+		subscription.NotifiedAt = &now
+		store.subscriptions[id] = subscription
+		claimed = append(claimed, subscription)
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	return claimed
+}
+
+// AddSubscriptionDirectly adds a subscription with a specific ID, ensuring no ID collisions
+func (store *InMemoryStockSubscriptionStore) AddSubscriptionDirectly(subscription data.StockSubscription) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if subscription.ID >= store.nextID {
+		store.nextID = subscription.ID + 1
+	}
+	store.subscriptions[subscription.ID] = subscription
+}
+
+// RecordNotification adds a sent notification to the store
+func (store *InMemoryStockSubscriptionStore) RecordNotification(notification data.StockNotification) data.StockNotification {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	notification.ID = store.nextNotificationID
+	store.nextNotificationID++
+	store.notifications[notification.ID] = notification
+	return notification
+}
+
+// GetNotificationsByCustomer retrieves the notifications sent to a customer, newest first
+func (store *InMemoryStockSubscriptionStore) GetNotificationsByCustomer(customerID int) []data.StockNotification {
+	notifications := []data.StockNotification{}
+	for _, notification := range store.GetAllNotifications() {
+		if notification.CustomerID == customerID {
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications
+}
+
+// GetAllNotifications retrieves all notifications, newest first
+func (store *InMemoryStockSubscriptionStore) GetAllNotifications() []data.StockNotification {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	notifications := []data.StockNotification{}
+	for _, notification := range store.notifications {
+		notifications = append(notifications, notification)
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID > notifications[j].ID })
+	return notifications
+}
+
+// AddNotificationDirectly adds a notification with a specific ID, ensuring no ID collisions
+func (store *InMemoryStockSubscriptionStore) AddNotificationDirectly(notification data.StockNotification) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if notification.ID >= store.nextNotificationID {
+		store.nextNotificationID = notification.ID + 1
+	}
+	store.notifications[notification.ID] = notification
+}
+
+// active finds the active subscription of a customer to a book. The caller must hold the lock.
+func (store *InMemoryStockSubscriptionStore) active(customerID, bookID int) (data.StockSubscription, bool) {
+	for _, subscription := range store.subscriptions {
+		if subscription.CustomerID == customerID && subscription.BookID == bookID && subscription.Status == data.SubscriptionActive {
+			return subscription, true
+		}
+	}
+	return data.StockSubscription{}, false
+}
+
+// filter returns the subscriptions matching keep, newest first
+func (store *InMemoryStockSubscriptionStore) filter(keep func(data.StockSubscription) bool) []data.StockSubscription {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	subscriptions := []data.StockSubscription{}
+	for _, subscription := range store.subscriptions {
+		if keep(subscription) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].ID > subscriptions[j].ID })
+	return subscriptions
+}
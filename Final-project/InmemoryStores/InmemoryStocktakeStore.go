@@ -0,0 +1,153 @@
+package InmemoryStores
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+type InMemoryStocktakeStore struct {
+	mu         sync.RWMutex
+	stocktakes map[int]data.Stocktake
+	nextID     int
+}
+
+var (
+	stocktakeStoreInstance *InMemoryStocktakeStore
+	stocktakeOnce          sync.Once
+)
+
+// GetStocktakeStoreInstance returns the singleton instance of InMemoryStocktakeStore
+func GetStocktakeStoreInstance() interfaces.StocktakeStore {
+	stocktakeOnce.Do(func() {
+		stocktakeStoreInstance = &InMemoryStocktakeStore{
+			stocktakes: make(map[int]data.Stocktake),
+			nextID:     1,
+		}
+	})
+	return stocktakeStoreInstance
+}
+
+// CreateStocktake opens a stocktake. A book cannot be counted by two open stocktakes of the same warehouse.
+func (store *InMemoryStocktakeStore) CreateStocktake(stocktake data.Stocktake) (data.Stocktake, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	counting := map[int]bool{}
+	for _, line := range stocktake.Lines {
+		counting[line.BookID] = true
+	}
+	for _, other := range store.stocktakes {
+		if other.Status != data.StocktakeOpen || other.WarehouseID != stocktake.WarehouseID {
+			continue
+		}
+		for _, line := range other.Lines {
+			if counting[line.BookID] {
+				return data.Stocktake{}, data.NewConflictError(fmt.Sprintf("Book %d is already being counted by stocktake %d", line.BookID, other.ID))
+			}
+		}
+	}
+
+	stocktake.ID = store.nextID
+	stocktake.Status = data.StocktakeOpen
+	stocktake.Counts = nil
+	stocktake.OpenedAt = time.Now()
+	stocktake.ClosedAt, stocktake.ClosedBy = nil, ""
+	store.nextID++
+	store.stocktakes[stocktake.ID] = stocktake
+	return stocktake, nil
+}
+
+// GetStocktake retrieves a stocktake by its ID
+func (store *InMemoryStocktakeStore) GetStocktake(id int) (data.Stocktake, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	stocktake, exists := store.stocktakes[id]
+	if !exists {
+		return data.Stocktake{}, data.NewNotFoundError("Stocktake not found")
+	}
+	return stocktake, nil
+}
+
+// GetAllStocktakes retrieves all stocktakes sorted by ID
+func (store *InMemoryStocktakeStore) GetAllStocktakes() []data.Stocktake {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	stocktakes := []data.Stocktake{}
+	for _, stocktake := range store.stocktakes {
+		stocktakes = append(stocktakes, stocktake)
+	}
+	sort.Slice(stocktakes, func(i, j int) bool { return stocktakes[i].ID < stocktakes[j].ID })
+	return stocktakes
+}
+
+// RecordCounts adds counts to an open stocktake. Every counted book must be part of the stocktake.
+func (store *InMemoryStocktakeStore) RecordCounts(id int, counts []data.StocktakeCount) (data.Stocktake, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	stocktake, exists := store.stocktakes[id]
+	if !exists {
+		return data.Stocktake{}, data.NewNotFoundError("Stocktake not found")
+	}
+	if stocktake.Status != data.StocktakeOpen {
+		return data.Stocktake{}, data.NewConflictError(fmt.Sprintf("Stocktake %d is %s", id, stocktake.Status))
+	}
+
+	counting := map[int]bool{}
+	for _, line := range stocktake.Lines {
+		counting[line.BookID] = true
+	}
+	now := time.Now()
+	for i, count := range counts {
+		if !counting[count.BookID] {
+			return data.Stocktake{}, data.NewValidationError(fmt.Sprintf("Book %d is not part of stocktake %d", count.BookID, id))
+		}
+		if count.Quantity < 0 {
+			return data.Stocktake{}, data.NewValidationError("Counted quantities cannot be negative")
+		}
+		counts[i].CountedAt = now
+	}
+
+	stocktake.Counts = append(stocktake.Counts, counts...)
+	store.stocktakes[id] = stocktake
+	return stocktake, nil
+}
+
+// CloseStocktake approves or cancels an open stocktake
+func (store *InMemoryStocktakeStore) CloseStocktake(id int, status data.StocktakeStatus, actor string) (data.Stocktake, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	stocktake, exists := store.stocktakes[id]
+	if !exists {
+		return data.Stocktake{}, data.NewNotFoundError("Stocktake not found")
+	}
+	if stocktake.Status != data.StocktakeOpen {
+		return data.Stocktake{}, data.NewConflictError(fmt.Sprintf("Stocktake %d is already %s", id, stocktake.Status))
+	}
+
+	now := time.Now()
+	stocktake.Status = status
+	stocktake.ClosedAt = &now
+	stocktake.ClosedBy = actor
+	store.stocktakes[id] = stocktake
+	return stocktake, nil
+}
+
+// AddStocktakeDirectly adds a stocktake with a specific ID, ensuring no ID collisions
+func (store *InMemoryStocktakeStore) AddStocktakeDirectly(stocktake data.Stocktake) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if stocktake.ID >= store.nextID {
+		store.nextID = stocktake.ID + 1
+	}
+	store.stocktakes[stocktake.ID] = stocktake
+}
@@ -0,0 +1,107 @@
+package InmemoryStores
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+type InMemorySupplierStore struct {
+	mu        sync.RWMutex
+	suppliers map[int]data.Supplier
+	nextID    int
+}
+
+var (
+	supplierStoreInstance *InMemorySupplierStore
+	supplierOnce          sync.Once
+)
+
+// GetSupplierStoreInstance returns the singleton instance of InMemorySupplierStore
+func GetSupplierStoreInstance() interfaces.SupplierStore {
+	supplierOnce.Do(func() {
+		supplierStoreInstance = &InMemorySupplierStore{
+			suppliers: make(map[int]data.Supplier),
+			nextID:    1,
+		}
+	})
+	return supplierStoreInstance
+}
+
+// CreateSupplier adds a new supplier to the store
+func (store *InMemorySupplierStore) CreateSupplier(supplier data.Supplier) (data.Supplier, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	supplier.ID = store.nextID
+	supplier.CreatedAt = time.Now()
+	store.nextID++
+	store.suppliers[supplier.ID] = supplier
+	return supplier, nil
+}
+
+// GetSupplier retrieves a supplier by its ID
+func (store *InMemorySupplierStore) GetSupplier(id int) (data.Supplier, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	supplier, exists := store.suppliers[id]
+	if !exists {
+		return data.Supplier{}, data.NewNotFoundError("Supplier not found")
+	}
+	return supplier, nil
+}
+
+// GetAllSuppliers retrieves all suppliers sorted by ID
+func (store *InMemorySupplierStore) GetAllSuppliers() []data.Supplier {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	suppliers := []data.Supplier{}
+	for _, supplier := range store.suppliers {
+		suppliers = append(suppliers, supplier)
+	}
+	sort.Slice(suppliers, func(i, j int) bool { return suppliers[i].ID < suppliers[j].ID })
+	return suppliers
+}
+
+// UpdateSupplier updates an existing supplier, keeping its creation date
+func (store *InMemorySupplierStore) UpdateSupplier(id int, supplier data.Supplier) (data.Supplier, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, exists := store.suppliers[id]
+	if !exists {
+		return data.Supplier{}, data.NewNotFoundError("Supplier not found")
+	}
+	supplier.ID = id
+	supplier.CreatedAt = existing.CreatedAt
+	store.suppliers[id] = supplier
+	return supplier, nil
+}
+
+// DeleteSupplier removes a supplier from the store
+func (store *InMemorySupplierStore) DeleteSupplier(id int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.suppliers[id]; !exists {
+		return data.NewNotFoundError("Supplier not found")
+	}
+	delete(store.suppliers, id)
+	return nil
+}
+
+// AddSupplierDirectly adds a supplier with a specific ID, ensuring no ID collisions
+func (store *InMemorySupplierStore) AddSupplierDirectly(supplier data.Supplier) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if supplier.ID >= store.nextID {
+		store.nextID = supplier.ID + 1
+	}
+	store.suppliers[supplier.ID] = supplier
+}
@@ -0,0 +1,510 @@
+package InmemoryStores
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// InMemoryWarehouseStore keeps the warehouses, the stock of every book at every warehouse and the
+// transfers between them. Stock only changes under its lock, so allocations never oversell a warehouse.
+type InMemoryWarehouseStore struct {
+	mu             sync.RWMutex
+	warehouses     map[int]data.Warehouse
+	levels         map[int]map[int]int  // Quantity per book, then per warehouse
+	frozen         map[int]map[int]bool // Books frozen against sales at a warehouse, per book then per warehouse
+	transfers      []data.StockTransfer
+	nextID         int
+	nextTransferID int
+}
+
+var (
+	warehouseStoreInstance *InMemoryWarehouseStore
+	warehouseOnce          sync.Once
+)
+
+// GetWarehouseStoreInstance returns the singleton instance of InMemoryWarehouseStore
+func GetWarehouseStoreInstance() interfaces.WarehouseStore {
+	warehouseOnce.Do(func() {
+		warehouseStoreInstance = &InMemoryWarehouseStore{
+			warehouses:     make(map[int]data.Warehouse),
+			levels:         make(map[int]map[int]int),
+			frozen:         make(map[int]map[int]bool),
+			nextID:         1,
+			nextTransferID: 1,
+		}
+	})
+	return warehouseStoreInstance
+}
+
+// CreateWarehouse adds a new warehouse to the store. Codes are unique, ignoring case.
+func (store *InMemoryWarehouseStore) CreateWarehouse(warehouse data.Warehouse) (data.Warehouse, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if errResp := store.checkCode(0, warehouse.Code); errResp != nil {
+		return data.Warehouse{}, errResp
+	}
+	warehouse.ID = store.nextID
+	warehouse.CreatedAt = time.Now()
+	store.nextID++
+	store.warehouses[warehouse.ID] = warehouse
+	return warehouse, nil
+}
+
+// GetWarehouse retrieves a warehouse by its ID
+func (store *InMemoryWarehouseStore) GetWarehouse(id int) (data.Warehouse, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	warehouse, exists := store.warehouses[id]
+	if !exists {
+		return data.Warehouse{}, data.NewNotFoundError("Warehouse not found")
+	}
+	return warehouse, nil
+}
+
+// GetAllWarehouses retrieves all warehouses sorted by ID
+func (store *InMemoryWarehouseStore) GetAllWarehouses() []data.Warehouse {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.sortedWarehouses()
+}
+
+// UpdateWarehouse updates an existing warehouse, keeping its creation date
+func (store *InMemoryWarehouseStore) UpdateWarehouse(id int, warehouse data.Warehouse) (data.Warehouse, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, exists := store.warehouses[id]
+	if !exists {
+		return data.Warehouse{}, data.NewNotFoundError("Warehouse not found")
+	}
+	if errResp := store.checkCode(id, warehouse.Code); errResp != nil {
+		return data.Warehouse{}, errResp
+	}
+	warehouse.ID = id
+	warehouse.CreatedAt = existing.CreatedAt
+	store.warehouses[id] = warehouse
+	return warehouse, nil
+}
+
+// DeleteWarehouse removes an empty warehouse. The last warehouse cannot be deleted.
+func (store *InMemoryWarehouseStore) DeleteWarehouse(id int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.warehouses[id]; !exists {
+		return data.NewNotFoundError("Warehouse not found")
+	}
+	if len(store.warehouses) == 1 {
+		return data.NewConflictError("The last warehouse cannot be deleted")
+	}
+	for bookID, levels := range store.levels {
+		if levels[id] > 0 {
+			return data.NewConflictError(fmt.Sprintf("Warehouse %d still holds stock of book %d; transfer it first", id, bookID))
+		}
+	}
+	delete(store.warehouses, id)
+	for _, levels := range store.levels {
+		delete(levels, id)
+	}
+	return nil
+}
+
+// DefaultWarehouse returns the warehouse with the lowest ID, which receives stock that has no other location
+func (store *InMemoryWarehouseStore) DefaultWarehouse() (data.Warehouse, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.defaultWarehouse()
+}
+
+// GetStockLevels retrieves the stock of a book at every warehouse holding some, sorted by warehouse
+func (store *InMemoryWarehouseStore) GetStockLevels(bookID int) []data.StockLevel {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	levels := []data.StockLevel{}
+	for warehouseID, quantity := range store.levels[bookID] {
+		levels = append(levels, data.StockLevel{BookID: bookID, WarehouseID: warehouseID, Quantity: quantity})
+	}
+	sortStockLevels(levels)
+	return levels
+}
+
+// GetWarehouseStock retrieves the stock of every book held at a warehouse, sorted by book
+func (store *InMemoryWarehouseStore) GetWarehouseStock(warehouseID int) []data.StockLevel {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	levels := []data.StockLevel{}
+	for bookID, quantities := range store.levels {
+		if quantity, ok := quantities[warehouseID]; ok {
+			levels = append(levels, data.StockLevel{BookID: bookID, WarehouseID: warehouseID, Quantity: quantity})
+		}
+	}
+	sortStockLevels(levels)
+	return levels
+}
+
+// GetAllStockLevels retrieves every stock level, sorted by book then warehouse
+func (store *InMemoryWarehouseStore) GetAllStockLevels() []data.StockLevel {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	levels := []data.StockLevel{}
+	for bookID, quantities := range store.levels {
+		for warehouseID, quantity := range quantities {
+			levels = append(levels, data.StockLevel{BookID: bookID, WarehouseID: warehouseID, Quantity: quantity})
+		}
+	}
+	sortStockLevels(levels)
+	return levels
+}
+
+// AdjustStock adds delta to the stock of a book at a warehouse, refusing to go below zero. It returns the new level.
+func (store *InMemoryWarehouseStore) AdjustStock(bookID, warehouseID, delta int) (int, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.warehouses[warehouseID]; !exists {
+		return 0, data.NewNotFoundError("Warehouse not found")
+	}
+	level := store.levels[bookID][warehouseID] + delta
+	if level < 0 {
+		return 0, data.NewInsufficientStockError(fmt.Sprintf("Warehouse %d has only %d of book %d", warehouseID, level-delta, bookID))
+	}
+	store.set(bookID, warehouseID, level)
+	return level, nil
+}
+
+// Allocate takes the quantity of a book from the warehouses chosen by the strategy, nearest first being
+// the warehouses in the customer's country. Nothing is taken if the quantity cannot be allocated.
+func (store *InMemoryWarehouseStore) Allocate(bookID, quantity int, strategy data.AllocationStrategy, country string) ([]data.StockAllocation, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return store.allocate(bookID, quantity, strategy, country)
+}
+
+// Reallocate puts back the stock allocated to the released items and allocates the requested ones in a
+// single step, so that no other allocation can take the released stock in between. Requested items that
+// cannot be allocated are skipped, and nothing changes if none can be. It returns the released items with
+// the warehouses their stock went back to, and the requested items that were allocated.
+func (store *InMemoryWarehouseStore) Reallocate(released, requested []data.OrderItem, strategy data.AllocationStrategy, country string) ([]data.OrderItem, []data.OrderItem, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Keep the levels of the books involved, to put them back if nothing can be allocated
+	saved := map[int]map[int]int{}
+	for _, item := range append(append([]data.OrderItem{}, released...), requested...) {
+		if _, ok := saved[item.BookID]; ok {
+			continue
+		}
+		saved[item.BookID] = map[int]int{}
+		for warehouseID, quantity := range store.levels[item.BookID] {
+			saved[item.BookID][warehouseID] = quantity
+		}
+	}
+
+	var releasedItems []data.OrderItem
+	for _, item := range released {
+		item.Allocations = store.release(item.BookID, item.Quantity, item.Allocations)
+		releasedItems = append(releasedItems, item)
+	}
+	var allocated []data.OrderItem
+	for _, item := range requested {
+		allocations, errResp := store.allocate(item.BookID, item.Quantity, strategy, country)
+		if errResp != nil {
+			continue
+		}
+		item.Allocations = allocations
+		allocated = append(allocated, item)
+	}
+	if len(allocated) == 0 {
+		for bookID, levels := range saved {
+			store.levels[bookID] = levels
+		}
+		return nil, nil, data.NewInsufficientStockError("Insufficient stock for the requested books")
+	}
+	return releasedItems, allocated, nil
+}
+
+func (store *InMemoryWarehouseStore) allocate(bookID, quantity int, strategy data.AllocationStrategy, country string) ([]data.StockAllocation, *data.ErrorResponse) {
+	// Rank the warehouses holding the book, nearest first
+	var candidates []data.Warehouse
+	for _, warehouse := range store.sortedWarehouses() {
+		if store.levels[bookID][warehouse.ID] > 0 && !store.frozen[bookID][warehouse.ID] {
+			candidates = append(candidates, warehouse)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return sameCountry(candidates[i], country) && !sameCountry(candidates[j], country)
+	})
+
+	var allocations []data.StockAllocation
+	switch strategy {
+	case data.AllocateMostStock:
+		best := -1
+		for i, warehouse := range candidates {
+			if best < 0 || store.levels[bookID][warehouse.ID] > store.levels[bookID][candidates[best].ID] {
+				best = i
+			}
+		}
+		if best >= 0 && store.levels[bookID][candidates[best].ID] >= quantity {
+			allocations = []data.StockAllocation{{WarehouseID: candidates[best].ID, Quantity: quantity}}
+		}
+	case data.AllocateSplit:
+		remaining := quantity
+		for _, warehouse := range candidates {
+			if remaining == 0 {
+				break
+			}
+			taken := store.levels[bookID][warehouse.ID]
+			if taken > remaining {
+				taken = remaining
+			}
+			allocations = append(allocations, data.StockAllocation{WarehouseID: warehouse.ID, Quantity: taken})
+			remaining -= taken
+		}
+		if remaining > 0 {
+			allocations = nil
+		}
+	default:
+		for _, warehouse := range candidates {
+			if store.levels[bookID][warehouse.ID] >= quantity {
+				allocations = []data.StockAllocation{{WarehouseID: warehouse.ID, Quantity: quantity}}
+				break
+			}
+		}
+	}
+	if allocations == nil {
+		return nil, data.NewInsufficientStockError(fmt.Sprintf("Cannot allocate %d of book %d with the %s strategy", quantity, bookID, strategy))
+	}
+
+	for _, allocation := range allocations {
+		store.set(bookID, allocation.WarehouseID, store.levels[bookID][allocation.WarehouseID]-allocation.Quantity)
+	}
+	return allocations, nil
+}
+
+// SetFrozen freezes or unfreezes a book at a warehouse. Allocate skips the warehouses where a book is
+// frozen and Take refuses them, as both sell it. Receipts, returns and corrections still change its stock
+// through AdjustStock; a stocktake finds them in the stock ledger.
+func (store *InMemoryWarehouseStore) SetFrozen(bookID, warehouseID int, frozen bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if !frozen {
+		delete(store.frozen[bookID], warehouseID)
+		return
+	}
+	if store.frozen[bookID] == nil {
+		store.frozen[bookID] = map[int]bool{}
+	}
+	store.frozen[bookID][warehouseID] = true
+}
+
+// Take removes the quantity of a book from the given allocations, or from the default warehouse when there
+// are none, for example to restore an order. Nothing is taken if a warehouse is short or the book is frozen
+// there. It returns the allocations the stock was taken from.
+func (store *InMemoryWarehouseStore) Take(bookID, quantity int, allocations []data.StockAllocation) ([]data.StockAllocation, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	allocations = store.located(quantity, allocations)
+	needed := map[int]int{}
+	for _, allocation := range allocations {
+		needed[allocation.WarehouseID] += allocation.Quantity
+	}
+	for warehouseID, quantity := range needed {
+		if _, exists := store.warehouses[warehouseID]; !exists {
+			return nil, data.NewConflictError(fmt.Sprintf("Warehouse %d no longer exists", warehouseID))
+		}
+		if store.frozen[bookID][warehouseID] {
+			return nil, data.NewConflictError(fmt.Sprintf("Book %d is frozen at warehouse %d for a stocktake", bookID, warehouseID))
+		}
+		if store.levels[bookID][warehouseID] < quantity {
+			return nil, data.NewInsufficientStockError(fmt.Sprintf("Warehouse %d has only %d of book %d", warehouseID, store.levels[bookID][warehouseID], bookID))
+		}
+	}
+	for warehouseID, quantity := range needed {
+		store.set(bookID, warehouseID, store.levels[bookID][warehouseID]-quantity)
+	}
+	return allocations, nil
+}
+
+// Release puts the quantity of a book back to the warehouses it was allocated from. Stock allocated from
+// a warehouse that no longer exists, or never allocated, goes to the default warehouse. It returns the
+// quantity put back per warehouse.
+func (store *InMemoryWarehouseStore) Release(bookID, quantity int, allocations []data.StockAllocation) []data.StockAllocation {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return store.release(bookID, quantity, allocations)
+}
+
+// Transfer moves stock of a book between two warehouses and records the transfer
+func (store *InMemoryWarehouseStore) Transfer(transfer data.StockTransfer) (data.StockTransfer, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if transfer.Quantity < 1 {
+		return data.StockTransfer{}, data.NewValidationError("quantity must be at least 1")
+	}
+	if transfer.FromWarehouseID == transfer.ToWarehouseID {
+		return data.StockTransfer{}, data.NewValidationError("from_warehouse_id and to_warehouse_id must differ")
+	}
+	for _, id := range []int{transfer.FromWarehouseID, transfer.ToWarehouseID} {
+		if _, exists := store.warehouses[id]; !exists {
+			return data.StockTransfer{}, data.NewValidationError(fmt.Sprintf("Warehouse %d does not exist", id))
+		}
+	}
+	available := store.levels[transfer.BookID][transfer.FromWarehouseID]
+	if available < transfer.Quantity {
+		return data.StockTransfer{}, data.NewInsufficientStockError(fmt.Sprintf("Warehouse %d has only %d of book %d", transfer.FromWarehouseID, available, transfer.BookID))
+	}
+
+	store.set(transfer.BookID, transfer.FromWarehouseID, available-transfer.Quantity)
+	store.set(transfer.BookID, transfer.ToWarehouseID, store.levels[transfer.BookID][transfer.ToWarehouseID]+transfer.Quantity)
+	transfer.ID = store.nextTransferID
+	transfer.CreatedAt = time.Now()
+	store.nextTransferID++
+	store.transfers = append(store.transfers, transfer)
+	return transfer, nil
+}
+
+// SearchTransfers retrieves the transfers matching the criteria, newest first
+func (store *InMemoryWarehouseStore) SearchTransfers(criteria data.StockTransferSearchCriteria) []data.StockTransfer {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	transfers := []data.StockTransfer{}
+	for i := len(store.transfers) - 1; i >= 0; i-- {
+		transfer := store.transfers[i]
+		if len(criteria.BookIDs) > 0 && !utils.ContainsInt(criteria.BookIDs, transfer.BookID) {
+			continue
+		}
+		if len(criteria.WarehouseIDs) > 0 &&
+			!utils.ContainsInt(criteria.WarehouseIDs, transfer.FromWarehouseID) &&
+			!utils.ContainsInt(criteria.WarehouseIDs, transfer.ToWarehouseID) {
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers
+}
+
+// AddWarehouseDirectly adds a warehouse with a specific ID, ensuring no ID collisions
+func (store *InMemoryWarehouseStore) AddWarehouseDirectly(warehouse data.Warehouse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if warehouse.ID >= store.nextID {
+		store.nextID = warehouse.ID + 1
+	}
+	store.warehouses[warehouse.ID] = warehouse
+}
+
+// SetStockDirectly sets a loaded stock level
+func (store *InMemoryWarehouseStore) SetStockDirectly(level data.StockLevel) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.set(level.BookID, level.WarehouseID, level.Quantity)
+}
+
+// AddTransferDirectly adds a loaded transfer, keeping its ID
+func (store *InMemoryWarehouseStore) AddTransferDirectly(transfer data.StockTransfer) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if transfer.ID >= store.nextTransferID {
+		store.nextTransferID = transfer.ID + 1
+	}
+	store.transfers = append(store.transfers, transfer)
+	sort.Slice(store.transfers, func(i, j int) bool { return store.transfers[i].ID < store.transfers[j].ID })
+}
+
+func (store *InMemoryWarehouseStore) release(bookID, quantity int, allocations []data.StockAllocation) []data.StockAllocation {
+	var released []data.StockAllocation
+	for _, allocation := range store.located(quantity, allocations) {
+		warehouseID := allocation.WarehouseID
+		if _, exists := store.warehouses[warehouseID]; !exists {
+			fallback, errResp := store.defaultWarehouse()
+			if errResp != nil {
+				continue
+			}
+			warehouseID = fallback.ID
+		}
+		store.set(bookID, warehouseID, store.levels[bookID][warehouseID]+allocation.Quantity)
+		released = append(released, data.StockAllocation{WarehouseID: warehouseID, Quantity: allocation.Quantity})
+	}
+	return released
+}
+
+func (store *InMemoryWarehouseStore) set(bookID, warehouseID, quantity int) {
+	if store.levels[bookID] == nil {
+		store.levels[bookID] = make(map[int]int)
+	}
+	store.levels[bookID][warehouseID] = quantity
+}
+
+func (store *InMemoryWarehouseStore) sortedWarehouses() []data.Warehouse {
+	warehouses := []data.Warehouse{}
+	for _, warehouse := range store.warehouses {
+		warehouses = append(warehouses, warehouse)
+	}
+	sort.Slice(warehouses, func(i, j int) bool { return warehouses[i].ID < warehouses[j].ID })
+	return warehouses
+}
+
+func (store *InMemoryWarehouseStore) defaultWarehouse() (data.Warehouse, *data.ErrorResponse) {
+	warehouses := store.sortedWarehouses()
+	if len(warehouses) == 0 {
+		return data.Warehouse{}, data.NewNotFoundError("No warehouse exists")
+	}
+	return warehouses[0], nil
+}
+
+// located returns the allocations, or the whole quantity at the default warehouse when there are none
+func (store *InMemoryWarehouseStore) located(quantity int, allocations []data.StockAllocation) []data.StockAllocation {
+	if len(allocations) > 0 {
+		return allocations
+	}
+	fallback, errResp := store.defaultWarehouse()
+	if errResp != nil {
+		return nil
+	}
+	return []data.StockAllocation{{WarehouseID: fallback.ID, Quantity: quantity}}
+}
+
+func (store *InMemoryWarehouseStore) checkCode(id int, code string) *data.ErrorResponse {
+	for _, warehouse := range store.warehouses {
+		if warehouse.ID != id && strings.EqualFold(warehouse.Code, code) {
+			return data.NewConflictError(fmt.Sprintf("Warehouse code %s is already used by warehouse %d", code, warehouse.ID))
+		}
+	}
+	return nil
+}
+
+func sameCountry(warehouse data.Warehouse, country string) bool {
+	return country != "" && utils.SameCountry(warehouse.Address.Country, country)
+}
+
+func sortStockLevels(levels []data.StockLevel) {
+	sort.Slice(levels, func(i, j int) bool {
+		if levels[i].BookID != levels[j].BookID {
+			return levels[i].BookID < levels[j].BookID
+		}
+		return levels[i].WarehouseID < levels[j].WarehouseID
+	})
+}
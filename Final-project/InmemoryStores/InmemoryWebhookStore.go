@@ -0,0 +1,205 @@
+package InmemoryStores
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+type InMemoryWebhookStore struct {
+	mu                 sync.RWMutex
+	subscriptions      map[int]data.WebhookSubscription
+	deliveries         map[int]data.WebhookDelivery
+	nextSubscriptionID int
+	nextDeliveryID     int
+}
+
+var (
+	webhookStoreInstance *InMemoryWebhookStore
+	webhookOnce          sync.Once
+)
+
+// GetWebhookStoreInstance returns the singleton instance of InMemoryWebhookStore
+func GetWebhookStoreInstance() interfaces.WebhookStore {
+	webhookOnce.Do(func() {
+		webhookStoreInstance = &InMemoryWebhookStore{
+			subscriptions:      make(map[int]data.WebhookSubscription),
+			deliveries:         make(map[int]data.WebhookDelivery),
+			nextSubscriptionID: 1,
+			nextDeliveryID:     1,
+		}
+	})
+	return webhookStoreInstance
+}
+
+// CreateSubscription adds a new webhook subscription to the store
+func (store *InMemoryWebhookStore) CreateSubscription(subscription data.WebhookSubscription) (data.WebhookSubscription, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	subscription.ID = store.nextSubscriptionID
+	subscription.CreatedAt = time.Now()
+	store.nextSubscriptionID++
+	store.subscriptions[subscription.ID] = subscription
+	return subscription, nil
+}
+
+// GetSubscription retrieves a webhook subscription by its ID
+func (store *InMemoryWebhookStore) GetSubscription(id int) (data.WebhookSubscription, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	subscription, exists := store.subscriptions[id]
+	if !exists {
+		return data.WebhookSubscription{}, data.NewNotFoundError("Webhook subscription not found")
+	}
+	return subscription, nil
+}
+
+// GetAllSubscriptions retrieves all webhook subscriptions sorted by ID
+func (store *InMemoryWebhookStore) GetAllSubscriptions() []data.WebhookSubscription {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var subscriptions []data.WebhookSubscription
+	for _, subscription := range store.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool { return subscriptions[i].ID < subscriptions[j].ID })
+	return subscriptions
+}
+
+// UpdateSubscription updates an existing webhook subscription, keeping its creation date
+func (store *InMemoryWebhookStore) UpdateSubscription(id int, subscription data.WebhookSubscription) (data.WebhookSubscription, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, exists := store.subscriptions[id]
+	if !exists {
+		return data.WebhookSubscription{}, data.NewNotFoundError("Webhook subscription not found")
+	}
+	subscription.ID = id
+	subscription.CreatedAt = existing.CreatedAt
+	store.subscriptions[id] = subscription
+	return subscription, nil
+}
+
+// DeleteSubscription removes a webhook subscription from the store. Its past deliveries are kept.
+func (store *InMemoryWebhookStore) DeleteSubscription(id int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.subscriptions[id]; !exists {
+		return data.NewNotFoundError("Webhook subscription not found")
+	}
+	delete(store.subscriptions, id)
+	return nil
+}
+
+// AddSubscriptionDirectly adds a webhook subscription with a specific ID, ensuring no ID collisions
+func (store *InMemoryWebhookStore) AddSubscriptionDirectly(subscription data.WebhookSubscription) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if subscription.ID >= store.nextSubscriptionID {
+		store.nextSubscriptionID = subscription.ID + 1
+	}
+	store.subscriptions[subscription.ID] = subscription
+}
+
+// CreateDelivery adds a new webhook delivery to the store
+func (store *InMemoryWebhookStore) CreateDelivery(delivery data.WebhookDelivery) data.WebhookDelivery {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delivery.ID = store.nextDeliveryID
+	delivery.CreatedAt = time.Now()
+	store.nextDeliveryID++
+	store.deliveries[delivery.ID] = delivery
+	return delivery
+}
+
+// GetDelivery retrieves a webhook delivery by its ID
+func (store *InMemoryWebhookStore) GetDelivery(id int) (data.WebhookDelivery, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	delivery, exists := store.deliveries[id]
+	if !exists {
+		return data.WebhookDelivery{}, data.NewNotFoundError("Webhook delivery not found")
+	}
+	return delivery, nil
+}
+
+// UpdateDelivery replaces the state of an existing webhook delivery
+func (store *InMemoryWebhookStore) UpdateDelivery(id int, delivery data.WebhookDelivery) (data.WebhookDelivery, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.deliveries[id]; !exists {
+		return data.WebhookDelivery{}, data.NewNotFoundError("Webhook delivery not found")
+	}
+	delivery.ID = id
+	store.deliveries[id] = delivery
+	return delivery, nil
+}
+
+// GetAllDeliveries retrieves all webhook deliveries sorted by ID
+func (store *InMemoryWebhookStore) GetAllDeliveries() []data.WebhookDelivery {
+	return store.SearchDeliveries(data.WebhookDeliverySearchCriteria{})
+}
+
+// SearchDeliveries retrieves the webhook deliveries matching the criteria, sorted by ID
+func (store *InMemoryWebhookStore) SearchDeliveries(criteria data.WebhookDeliverySearchCriteria) []data.WebhookDelivery {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var result []data.WebhookDelivery
+	for _, delivery := range store.deliveries {
+		if len(criteria.SubscriptionIDs) > 0 && !utils.ContainsInt(criteria.SubscriptionIDs, delivery.SubscriptionID) {
+			continue
+		}
+		if len(criteria.Statuses) > 0 && !containsDeliveryStatus(criteria.Statuses, delivery.Status) {
+			continue
+		}
+		if len(criteria.EventTypes) > 0 && !containsEventType(criteria.EventTypes, delivery.Event.Type) {
+			continue
+		}
+		result = append(result, delivery)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// AddDeliveryDirectly adds a webhook delivery with a specific ID, ensuring no ID collisions
+func (store *InMemoryWebhookStore) AddDeliveryDirectly(delivery data.WebhookDelivery) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if delivery.ID >= store.nextDeliveryID {
+		store.nextDeliveryID = delivery.ID + 1
+	}
+	store.deliveries[delivery.ID] = delivery
+}
+
+func containsDeliveryStatus(statuses []data.DeliveryStatus, status data.DeliveryStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func containsEventType(eventTypes []data.EventType, eventType data.EventType) bool {
+	for _, t := range eventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
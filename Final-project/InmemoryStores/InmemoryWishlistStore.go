@@ -0,0 +1,112 @@
+package InmemoryStores
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+type InMemoryWishlistStore struct {
+	mu        sync.RWMutex
+	wishlists map[int]map[int]data.WishlistItem // Items of every customer's wishlist, by book ID
+}
+
+var (
+	wishlistStoreInstance *InMemoryWishlistStore
+	wishlistOnce          sync.Once
+)
+
+// GetWishlistStoreInstance returns the singleton instance of InMemoryWishlistStore
+func GetWishlistStoreInstance() interfaces.WishlistStore {
+	wishlistOnce.Do(func() {
+		wishlistStoreInstance = &InMemoryWishlistStore{
+			wishlists: make(map[int]map[int]data.WishlistItem),
+		}
+	})
+	return wishlistStoreInstance
+}
+
+// GetWishlist retrieves the wishlist of a customer, most recently added books first. A customer who saved
+// no books has an empty wishlist.
+func (store *InMemoryWishlistStore) GetWishlist(customerID int) data.Wishlist {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return store.wishlist(customerID)
+}
+
+// GetAllWishlists retrieves every non-empty wishlist sorted by customer ID
+func (store *InMemoryWishlistStore) GetAllWishlists() []data.Wishlist {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	wishlists := []data.Wishlist{}
+	for customerID, items := range store.wishlists {
+		if len(items) > 0 {
+			wishlists = append(wishlists, store.wishlist(customerID))
+		}
+	}
+	sort.Slice(wishlists, func(i, j int) bool { return wishlists[i].CustomerID < wishlists[j].CustomerID })
+	return wishlists
+}
+
+// AddItem saves a book in a customer's wishlist. It reports false, returning the saved item, if the book
+// was already there.
+func (store *InMemoryWishlistStore) AddItem(customerID, bookID int) (data.WishlistItem, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if item, exists := store.wishlists[customerID][bookID]; exists {
+		return item, false
+	}
+	if store.wishlists[customerID] == nil {
+		store.wishlists[customerID] = make(map[int]data.WishlistItem)
+	}
+	item := data.WishlistItem{BookID: bookID, AddedAt: time.Now()}
+	store.wishlists[customerID][bookID] = item
+	return item, true
+}
+
+// RemoveItem removes a book from a customer's wishlist
+func (store *InMemoryWishlistStore) RemoveItem(customerID, bookID int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.wishlists[customerID][bookID]; !exists {
+		return data.NewNotFoundError("Book not found in the wishlist")
+	}
+	delete(store.wishlists[customerID], bookID)
+	return nil
+}
+
+// AddWishlistDirectly adds a loaded wishlist, keeping the dates its books were added
+func (store *InMemoryWishlistStore) AddWishlistDirectly(wishlist data.Wishlist) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.wishlists[wishlist.CustomerID] == nil {
+		store.wishlists[wishlist.CustomerID] = make(map[int]data.WishlistItem)
+	}
+	for _, item := range wishlist.Items {
+		store.wishlists[wishlist.CustomerID][item.BookID] = data.WishlistItem{BookID: item.BookID, AddedAt: item.AddedAt}
+	}
+}
+
+// wishlist builds the wishlist of a customer. The caller must hold the lock.
+func (store *InMemoryWishlistStore) wishlist(customerID int) data.Wishlist {
+	wishlist := data.Wishlist{CustomerID: customerID, Items: []data.WishlistItem{}}
+	for _, item := range store.wishlists[customerID] {
+		wishlist.Items = append(wishlist.Items, item)
+	}
+	sort.Slice(wishlist.Items, func(i, j int) bool {
+		a, b := wishlist.Items[i], wishlist.Items[j]
+		if !a.AddedAt.Equal(b.AddedAt) {
+			return a.AddedAt.After(b.AddedAt)
+		}
+		return a.BookID < b.BookID
+	})
+	return wishlist
+}
@@ -0,0 +1,99 @@
+package InmemoryStores
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// InventoryMonitor watches book events and raises a low-stock alert when a book's stock falls to its
+// reorder threshold. Books without a threshold are alerted when they run out.
+type InventoryMonitor struct {
+	mu      sync.Mutex
+	books   interfaces.BookStore
+	alerts  interfaces.StockAlertStore
+	bus     interfaces.EventBus
+	OnAlert func() // Called after an alert is raised or resolved, e.g. to persist the alerts
+}
+
+var (
+	inventoryMonitorInstance *InventoryMonitor
+	inventoryMonitorOnce     sync.Once
+)
+
+// GetInventoryMonitorInstance returns the singleton instance of InventoryMonitor
+func GetInventoryMonitorInstance() *InventoryMonitor {
+	inventoryMonitorOnce.Do(func() {
+		inventoryMonitorInstance = &InventoryMonitor{
+			books:  GetBookStoreInstance(),
+			alerts: GetStockAlertStoreInstance(),
+			bus:    GetEventBusInstance(),
+		}
+	})
+	return inventoryMonitorInstance
+}
+
+// Start checks the current stock of every book, then follows book changes on the event bus
+func (m *InventoryMonitor) Start() {
+	for _, book := range m.books.GetAllBooks() {
+		m.Check(book)
+	}
+	m.bus.Subscribe(m.handleEvent)
+}
+
+func (m *InventoryMonitor) handleEvent(event data.DomainEvent) {
+	switch event.Type {
+	case data.EventBookCreated, data.EventBookUpdated, data.EventBookStockChanged, data.EventBookRestored:
+	default:
+		return
+	}
+
+	var book data.Book
+	if err := json.Unmarshal(event.Data, &book); err != nil {
+		log.Printf("Inventory monitor: cannot decode book from event %d: %v", event.ID, err)
+		return
+	}
+	m.Check(book)
+}
+
+// Check raises an alert if the book is at or below its threshold without an open alert, and resolves
+// the open alert once the book is back above it
+func (m *InventoryMonitor) Check(book data.Book) {
+	m.mu.Lock()
+	alert, open := m.alerts.GetOpenAlert(book.ID)
+	low := book.Stock <= book.ReorderThreshold
+
+	switch {
+	case low && !open:
+		alert = m.alerts.CreateAlert(data.StockAlert{
+			BookID:    book.ID,
+			Title:     book.Title,
+			Stock:     book.Stock,
+			Threshold: book.ReorderThreshold,
+		})
+		m.mu.Unlock()
+		log.Printf("Low stock: book %d (%s) has %d left, threshold %d", book.ID, book.Title, book.Stock, book.ReorderThreshold)
+
+		payload, _ := json.Marshal(alert)
+		m.bus.Publish(data.DomainEvent{
+			Type:       data.EventBookLowStock,
+			Resource:   data.ResourceBooks,
+			ResourceID: book.ID,
+			Actor:      "system",
+			Data:       payload,
+		})
+	case !low && open:
+		m.alerts.ResolveAlert(alert.ID)
+		m.mu.Unlock()
+	default:
+		m.mu.Unlock()
+		return
+	}
+
+	if m.OnAlert != nil {
+		m.OnAlert()
+	}
+}
@@ -0,0 +1,515 @@
+package InmemoryStores
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// Invoicer follows order events and keeps the accounting documents in step with the orders: an order is
+// invoiced when it is placed or restored, an edited order's invoice is credited and a new one issued, and
+// a deleted order's invoice is credited. Refunds are credited on request.
+type Invoicer struct {
+	mu       sync.Mutex
+	invoices interfaces.InvoiceStore
+	bus      interfaces.EventBus
+	settings data.InvoiceSettings
+	OnIssue  func() // Called after documents are issued, e.g. to persist the invoices
+}
+
+var (
+	invoicerInstance *Invoicer
+	invoicerOnce     sync.Once
+)
+
+// GetInvoicerInstance returns the singleton instance of Invoicer
+func GetInvoicerInstance() *Invoicer {
+	invoicerOnce.Do(func() {
+		invoicerInstance = &Invoicer{
+			invoices: GetInvoiceStoreInstance(),
+			bus:      GetEventBusInstance(),
+			settings: DefaultInvoiceSettings(),
+		}
+	})
+	return invoicerInstance
+}
+
+// DefaultInvoiceSettings returns the settings used until others are saved: prices in US dollars with no tax
+// on them, and fiscal years following the calendar years in UTC
+func DefaultInvoiceSettings() data.InvoiceSettings {
+	return data.InvoiceSettings{
+		Seller:               data.InvoiceParty{Name: "Bookstore"},
+		Currency:             "USD",
+		PricesIncludeTax:     true,
+		FiscalYearStartMonth: 1,
+		TimeZone:             "UTC",
+		InvoicePrefix:        "INV",
+		CreditNotePrefix:     "CN",
+	}
+}
+
+// Settings returns the current invoice settings
+func (i *Invoicer) Settings() data.InvoiceSettings {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.settings
+}
+
+// SetSettings validates and normalizes invoice settings and applies them to the documents issued afterwards
+func (i *Invoicer) SetSettings(settings data.InvoiceSettings) (data.InvoiceSettings, *data.ErrorResponse) {
+	settings.Seller.Name = strings.TrimSpace(settings.Seller.Name)
+	settings.Seller.Email = strings.TrimSpace(settings.Seller.Email)
+	settings.Seller.TaxID = strings.TrimSpace(settings.Seller.TaxID)
+	if settings.Seller.Name == "" {
+		return data.InvoiceSettings{}, data.NewValidationError("seller.name is required")
+	}
+	if !settings.Seller.Address.IsEmpty() {
+		address, errResp := utils.NormalizeAddress(settings.Seller.Address)
+		if errResp != nil {
+			return data.InvoiceSettings{}, errResp
+		}
+		settings.Seller.Address = address
+	}
+
+	settings.Currency = strings.ToUpper(strings.TrimSpace(settings.Currency))
+	if len(settings.Currency) != 3 || strings.Trim(settings.Currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return data.InvoiceSettings{}, data.NewValidationError("currency must be an ISO 4217 code such as USD")
+	}
+	if settings.TaxRate < 0 || settings.TaxRate >= 1 {
+		return data.InvoiceSettings{}, data.NewValidationError("tax_rate must be between 0 and 1, for example 0.2 for 20%")
+	}
+	taxRates := map[string]float64{}
+	for country, rate := range settings.TaxRates {
+		code, ok := utils.CountryCode(country)
+		if !ok {
+			return data.InvoiceSettings{}, data.NewValidationError("Unknown country in tax_rates: " + country)
+		}
+		if rate < 0 || rate >= 1 {
+			return data.InvoiceSettings{}, data.NewValidationError("The tax rate of " + code + " must be between 0 and 1")
+		}
+		taxRates[code] = rate
+	}
+	settings.TaxRates = taxRates
+
+	if settings.FiscalYearStartMonth == 0 {
+		settings.FiscalYearStartMonth = 1
+	}
+	if settings.FiscalYearStartMonth < 1 || settings.FiscalYearStartMonth > 12 {
+		return data.InvoiceSettings{}, data.NewValidationError("fiscal_year_start_month must be between 1 and 12")
+	}
+	if settings.TimeZone == "" {
+		settings.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(settings.TimeZone); err != nil {
+		return data.InvoiceSettings{}, data.NewValidationError("Unknown time_zone: " + settings.TimeZone)
+	}
+
+	settings.InvoicePrefix = strings.TrimSpace(settings.InvoicePrefix)
+	settings.CreditNotePrefix = strings.TrimSpace(settings.CreditNotePrefix)
+	if settings.InvoicePrefix == "" {
+		settings.InvoicePrefix = "INV"
+	}
+	if settings.CreditNotePrefix == "" {
+		settings.CreditNotePrefix = "CN"
+	}
+	if strings.EqualFold(settings.InvoicePrefix, settings.CreditNotePrefix) {
+		return data.InvoiceSettings{}, data.NewValidationError("invoice_prefix and credit_note_prefix must differ")
+	}
+
+	i.mu.Lock()
+	i.settings = settings
+	i.mu.Unlock()
+	return settings, nil
+}
+
+// Start follows order changes on the event bus. Orders placed before invoicing started are only invoiced
+// on request.
+func (i *Invoicer) Start() {
+	i.bus.Subscribe(i.handleEvent)
+}
+
+func (i *Invoicer) handleEvent(event data.DomainEvent) {
+	switch event.Type {
+	case data.EventOrderCreated, data.EventOrderUpdated, data.EventOrderDeleted, data.EventOrderRestored:
+	default:
+		return
+	}
+
+	var order data.Order
+	if err := json.Unmarshal(event.Data, &order); err != nil {
+		log.Printf("Invoicer: cannot decode order from event %d: %v", event.ID, err)
+		return
+	}
+	context := data.AuditContext{Actor: event.Actor, RequestID: event.RequestID}
+
+	i.mu.Lock()
+	var issued []data.Invoice
+	open, hasOpen := i.openInvoice(order.ID)
+	switch event.Type {
+	case data.EventOrderCreated, data.EventOrderRestored:
+		if !hasOpen {
+			issued = append(issued, i.issueInvoice(order))
+		}
+	case data.EventOrderUpdated:
+		// Orders detached from a deleted customer keep their invoice
+		if hasOpen && order.CustomerID != 0 && !invoiceMatchesOrder(open, order) {
+			creditNote, errResp := i.creditInvoice(open, data.CreditNoteRequest{Reason: "Order edited"})
+			if errResp != nil {
+				log.Printf("Invoicer: cannot credit invoice %s of edited order %d: %s", open.Number, order.ID, errResp.Message)
+				break
+			}
+			issued = append(issued, creditNote, i.issueInvoice(order))
+		}
+	case data.EventOrderDeleted:
+		if hasOpen {
+			creditNote, errResp := i.creditInvoice(open, data.CreditNoteRequest{Reason: "Order deleted"})
+			if errResp != nil {
+				log.Printf("Invoicer: cannot credit invoice %s of deleted order %d: %s", open.Number, order.ID, errResp.Message)
+				break
+			}
+			issued = append(issued, creditNote)
+		}
+	}
+	i.mu.Unlock()
+
+	i.published(issued, context)
+}
+
+// InvoiceOrder issues an invoice for an order, typically one placed before invoicing started. It fails if
+// the order already has an invoice that is not fully credited.
+func (i *Invoicer) InvoiceOrder(order data.Order, context data.AuditContext) (data.Invoice, *data.ErrorResponse) {
+	i.mu.Lock()
+	if open, hasOpen := i.openInvoice(order.ID); hasOpen {
+		i.mu.Unlock()
+		return data.Invoice{}, data.NewConflictError(fmt.Sprintf("Order %d is already invoiced by %s", order.ID, open.Number))
+	}
+	invoice := i.issueInvoice(order)
+	i.mu.Unlock()
+
+	i.published([]data.Invoice{invoice}, context)
+	return invoice, nil
+}
+
+// CreditInvoice issues a credit note refunding the quantities requested of an invoice, or everything not
+// credited yet when no lines are given
+func (i *Invoicer) CreditInvoice(invoiceID int, request data.CreditNoteRequest, context data.AuditContext) (data.Invoice, *data.ErrorResponse) {
+	i.mu.Lock()
+	invoice, errResp := i.invoices.GetInvoice(invoiceID)
+	if errResp != nil {
+		i.mu.Unlock()
+		return data.Invoice{}, errResp
+	}
+	creditNote, errResp := i.creditInvoice(invoice, request)
+	i.mu.Unlock()
+	if errResp != nil {
+		return data.Invoice{}, errResp
+	}
+
+	i.published([]data.Invoice{creditNote}, context)
+	return creditNote, nil
+}
+
+// CreditOrder issues a credit note on the open invoice of an order for the quantities requested, capped at
+// what can still be credited of every book. It reports false when the order has no open invoice or none
+// of the books is left to credit.
+func (i *Invoicer) CreditOrder(orderID int, request data.CreditNoteRequest, context data.AuditContext) (data.Invoice, bool, *data.ErrorResponse) {
+	i.mu.Lock()
+	open, hasOpen := i.openInvoice(orderID)
+	if !hasOpen {
+		i.mu.Unlock()
+		return data.Invoice{}, false, nil
+	}
+
+	creditable := map[int]int{}
+	for _, line := range open.Lines {
+		creditable[line.BookID] += line.Quantity - line.CreditedQuantity
+	}
+	capped := data.CreditNoteRequest{Reason: request.Reason}
+	for _, line := range request.Lines {
+		if quantity := min(line.Quantity, creditable[line.BookID]); quantity > 0 {
+			capped.Lines = append(capped.Lines, data.CreditNoteLine{BookID: line.BookID, Quantity: quantity})
+			creditable[line.BookID] -= quantity
+		}
+	}
+	if len(capped.Lines) == 0 {
+		i.mu.Unlock()
+		return data.Invoice{}, false, nil
+	}
+
+	creditNote, errResp := i.creditInvoice(open, capped)
+	i.mu.Unlock()
+	if errResp != nil {
+		return data.Invoice{}, false, errResp
+	}
+
+	i.published([]data.Invoice{creditNote}, context)
+	return creditNote, true, nil
+}
+
+// openInvoice finds the invoice of an order that is not fully credited. The caller must hold the lock.
+func (i *Invoicer) openInvoice(orderID int) (data.Invoice, bool) {
+	documents := i.invoices.GetInvoicesByOrder(orderID)
+	for j := len(documents) - 1; j >= 0; j-- {
+		if documents[j].Type == data.InvoiceTypeInvoice && documents[j].Status != data.InvoiceCredited {
+			return documents[j], true
+		}
+	}
+	return data.Invoice{}, false
+}
+
+// issueInvoice issues an invoice for an order with the current settings. The caller must hold the lock.
+func (i *Invoicer) issueInvoice(order data.Order) data.Invoice {
+	settings := i.settings
+	customer := orderInvoiceParty(order)
+	country := customer.Address.Country
+	if code, ok := utils.CountryCode(country); ok {
+		country = code // Orders placed before addresses were normalized may name the country
+	}
+	rate := settings.TaxRate
+	if countryRate, ok := settings.TaxRates[country]; ok {
+		rate = countryRate
+	}
+
+	now := time.Now()
+	invoice := data.Invoice{
+		Type:             data.InvoiceTypeInvoice,
+		Status:           data.InvoiceIssued,
+		FiscalYear:       fiscalYear(now, settings),
+		OrderID:          order.ID,
+		CustomerID:       order.CustomerID,
+		Seller:           settings.Seller,
+		Customer:         customer,
+		Lines:            []data.InvoiceLine{},
+		Currency:         settings.Currency,
+		PricesIncludeTax: settings.PricesIncludeTax,
+		IssuedAt:         now,
+	}
+	for _, item := range order.Items {
+		invoice.Lines = append(invoice.Lines, invoiceLine(item, rate, settings.PricesIncludeTax))
+	}
+	sumInvoiceLines(&invoice)
+	return i.invoices.IssueInvoice(invoice, settings.InvoicePrefix)
+}
+
+// creditInvoice issues a credit note for an invoice and records what it credits on the invoice. The caller
+// must hold the lock.
+func (i *Invoicer) creditInvoice(invoice data.Invoice, request data.CreditNoteRequest) (data.Invoice, *data.ErrorResponse) {
+	if invoice.Type != data.InvoiceTypeInvoice {
+		return data.Invoice{}, data.NewValidationError("Only invoices can be credited")
+	}
+	if invoice.Status == data.InvoiceCredited {
+		return data.Invoice{}, data.NewConflictError(fmt.Sprintf("Invoice %s is already fully credited", invoice.Number))
+	}
+
+	// Quantity to credit of every line of the invoice
+	credit := make([]int, len(invoice.Lines))
+	if len(request.Lines) == 0 {
+		for j, line := range invoice.Lines {
+			credit[j] = line.Quantity - line.CreditedQuantity
+		}
+	}
+	for _, requested := range request.Lines {
+		if requested.Quantity <= 0 {
+			return data.Invoice{}, data.NewValidationError("Quantities to credit must be positive")
+		}
+		remaining := requested.Quantity
+		for j, line := range invoice.Lines {
+			if line.BookID != requested.BookID || remaining == 0 {
+				continue
+			}
+			quantity := min(remaining, line.Quantity-line.CreditedQuantity-credit[j])
+			credit[j] += quantity
+			remaining -= quantity
+		}
+		if remaining > 0 {
+			return data.Invoice{}, data.NewValidationError(fmt.Sprintf("Only %d of book %d can still be credited on invoice %s",
+				requested.Quantity-remaining, requested.BookID, invoice.Number))
+		}
+	}
+
+	settings := i.settings
+	now := time.Now()
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		reason = "Refund"
+	}
+	creditNote := data.Invoice{
+		Type:             data.InvoiceTypeCreditNote,
+		Status:           data.InvoiceIssued,
+		FiscalYear:       fiscalYear(now, settings),
+		OrderID:          invoice.OrderID,
+		CustomerID:       invoice.CustomerID,
+		InvoiceID:        invoice.ID,
+		InvoiceNumber:    invoice.Number,
+		Reason:           reason,
+		Seller:           invoice.Seller,
+		Customer:         invoice.Customer,
+		Lines:            []data.InvoiceLine{},
+		Currency:         invoice.Currency,
+		PricesIncludeTax: invoice.PricesIncludeTax,
+		IssuedAt:         now,
+	}
+	invoice.Lines = append([]data.InvoiceLine(nil), invoice.Lines...) // Leave the stored invoice alone until it is updated
+	fullyCredited := true
+	for j, line := range invoice.Lines {
+		if credit[j] > 0 {
+			creditNote.Lines = append(creditNote.Lines, creditedLine(line, credit[j]))
+			invoice.Lines[j].CreditedQuantity += credit[j]
+		}
+		if invoice.Lines[j].CreditedQuantity < line.Quantity {
+			fullyCredited = false
+		}
+	}
+	if len(creditNote.Lines) == 0 {
+		return data.Invoice{}, data.NewValidationError("Nothing to credit")
+	}
+	sumInvoiceLines(&creditNote)
+	creditNote = i.invoices.IssueInvoice(creditNote, settings.CreditNotePrefix)
+
+	invoice.CreditedTotal = roundAmount(invoice.CreditedTotal + creditNote.Total)
+	invoice.Status = data.InvoicePartiallyCredited
+	if fullyCredited {
+		invoice.Status = data.InvoiceCredited
+	}
+	if errResp := i.invoices.UpdateInvoice(invoice); errResp != nil {
+		return data.Invoice{}, errResp
+	}
+	return creditNote, nil
+}
+
+// invoiceMatchesOrder reports whether an invoice still bills an order as it is: the same customer and lines
+func invoiceMatchesOrder(invoice data.Invoice, order data.Order) bool {
+	if invoice.CustomerID != order.CustomerID || invoice.Customer != orderInvoiceParty(order) || len(invoice.Lines) != len(order.Items) {
+		return false
+	}
+	for j, item := range order.Items {
+		line := invoice.Lines[j]
+		if line.BookID != item.BookID || line.Quantity != item.Quantity || line.UnitPrice != item.UnitPrice {
+			return false
+		}
+	}
+	return true
+}
+
+// published announces issued documents on the event bus and calls OnIssue
+func (i *Invoicer) published(documents []data.Invoice, context data.AuditContext) {
+	if len(documents) == 0 {
+		return
+	}
+	for _, document := range documents {
+		eventType := data.EventInvoiceIssued
+		if document.Type == data.InvoiceTypeCreditNote {
+			eventType = data.EventCreditNoteIssued
+		}
+		payload, _ := json.Marshal(document)
+		i.bus.Publish(data.DomainEvent{
+			Type:       eventType,
+			Resource:   data.ResourceInvoices,
+			ResourceID: document.ID,
+			Actor:      context.Actor,
+			RequestID:  context.RequestID,
+			Data:       payload,
+		})
+	}
+
+	if i.OnIssue != nil {
+		i.OnIssue()
+	}
+}
+
+// orderInvoiceParty returns the customer as billed for an order: the customer's details when the order was
+// placed, at the bill-to address
+func orderInvoiceParty(order data.Order) data.InvoiceParty {
+	party := data.InvoiceParty{Name: order.CustomerSnapshot.Name, Email: order.CustomerSnapshot.Email, Address: order.CustomerSnapshot.Address}
+	if order.BillTo != nil {
+		party.Address = order.BillTo.Address
+	}
+	return party
+}
+
+// fiscalYear returns the calendar year the fiscal year of t starts in
+func fiscalYear(t time.Time, settings data.InvoiceSettings) int {
+	location, err := time.LoadLocation(settings.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	t = t.In(location)
+	if int(t.Month()) < settings.FiscalYearStartMonth {
+		return t.Year() - 1
+	}
+	return t.Year()
+}
+
+// invoiceLine bills an order item, splitting its amount into net amount and tax
+func invoiceLine(item data.OrderItem, rate float64, pricesIncludeTax bool) data.InvoiceLine {
+	line := data.InvoiceLine{
+		BookID:      item.BookID,
+		Description: item.Snapshot.Title,
+		Quantity:    item.Quantity,
+		UnitPrice:   item.UnitPrice,
+		TaxRate:     rate,
+	}
+	if line.Description == "" {
+		line.Description = fmt.Sprintf("Book %d", item.BookID)
+	}
+
+	amount := roundAmount(item.UnitPrice * float64(item.Quantity))
+	if pricesIncludeTax {
+		line.NetAmount = roundAmount(amount / (1 + rate))
+		line.TaxAmount = roundAmount(amount - line.NetAmount)
+	} else {
+		line.NetAmount = amount
+		line.TaxAmount = roundAmount(amount * rate)
+	}
+	line.Total = roundAmount(line.NetAmount + line.TaxAmount)
+	return line
+}
+
+// creditedLine is the part of an invoice line refunded by crediting quantity more units. The amounts are
+// the difference between what is credited after and before, so crediting a line in several credit notes
+// adds up to its amounts exactly.
+func creditedLine(line data.InvoiceLine, quantity int) data.InvoiceLine {
+	part := func(amount float64) float64 {
+		after := roundAmount(amount * float64(line.CreditedQuantity+quantity) / float64(line.Quantity))
+		before := roundAmount(amount * float64(line.CreditedQuantity) / float64(line.Quantity))
+		return roundAmount(after - before)
+	}
+	credited := data.InvoiceLine{
+		BookID:      line.BookID,
+		Description: line.Description,
+		Quantity:    quantity,
+		UnitPrice:   line.UnitPrice,
+		TaxRate:     line.TaxRate,
+		NetAmount:   part(line.NetAmount),
+		TaxAmount:   part(line.TaxAmount),
+	}
+	credited.Total = roundAmount(credited.NetAmount + credited.TaxAmount)
+	return credited
+}
+
+// sumInvoiceLines sets the totals of a document from its lines
+func sumInvoiceLines(invoice *data.Invoice) {
+	invoice.NetTotal, invoice.TaxTotal, invoice.Total = 0, 0, 0
+	for _, line := range invoice.Lines {
+		invoice.NetTotal += line.NetAmount
+		invoice.TaxTotal += line.TaxAmount
+		invoice.Total += line.Total
+	}
+	invoice.NetTotal = roundAmount(invoice.NetTotal)
+	invoice.TaxTotal = roundAmount(invoice.TaxTotal)
+	invoice.Total = roundAmount(invoice.Total)
+}
+
+// roundAmount rounds an amount to cents
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
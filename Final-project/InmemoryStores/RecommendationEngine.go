@@ -0,0 +1,359 @@
+package InmemoryStores
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"sort"
+	"sync"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// RecommendationEngine counts how often books are bought in the same orders. It is built from the order
+// history on start and follows order events afterwards, adding, replacing or removing the books of the
+// changed order only.
+type RecommendationEngine struct {
+	mu            sync.RWMutex
+	orders        interfaces.OrderStore
+	books         interfaces.BookStore
+	bus           interfaces.EventBus
+	baskets       map[int]orderBasket // Books of every order, by order ID
+	bookOrders    map[int]int         // Number of orders containing each book
+	pairs         map[int]map[int]int // Number of orders containing both books, in both directions
+	customerBooks map[int]map[int]int // Number of orders of each customer containing each book
+}
+
+// orderBasket is the distinct books of an order and the customer who placed it
+type orderBasket struct {
+	customerID int
+	books      []int
+}
+
+var (
+	recommendationEngineInstance *RecommendationEngine
+	recommendationEngineOnce     sync.Once
+)
+
+// GetRecommendationEngineInstance returns the singleton instance of RecommendationEngine
+func GetRecommendationEngineInstance() *RecommendationEngine {
+	recommendationEngineOnce.Do(func() {
+		recommendationEngineInstance = &RecommendationEngine{
+			orders:        GetOrderStoreInstance(),
+			books:         GetBookStoreInstance(),
+			bus:           GetEventBusInstance(),
+			baskets:       make(map[int]orderBasket),
+			bookOrders:    make(map[int]int),
+			pairs:         make(map[int]map[int]int),
+			customerBooks: make(map[int]map[int]int),
+		}
+	})
+	return recommendationEngineInstance
+}
+
+// Start counts the books of every order, then follows order changes on the event bus
+func (e *RecommendationEngine) Start() {
+	for _, order := range e.orders.GetAllOrders() {
+		e.SetOrder(order)
+	}
+	e.bus.Subscribe(e.handleEvent)
+}
+
+func (e *RecommendationEngine) handleEvent(event data.DomainEvent) {
+	switch event.Type {
+	case data.EventOrderCreated, data.EventOrderUpdated, data.EventOrderRestored:
+		var order data.Order
+		if err := json.Unmarshal(event.Data, &order); err != nil {
+			log.Printf("Recommendation engine: cannot decode order from event %d: %v", event.ID, err)
+			return
+		}
+		e.SetOrder(order)
+	case data.EventOrderDeleted, data.EventOrderPurged:
+		e.RemoveOrder(event.ResourceID)
+	}
+}
+
+// SetOrder counts the books of an order, replacing what was counted for it before
+func (e *RecommendationEngine) SetOrder(order data.Order) {
+	seen := map[int]bool{}
+	basket := orderBasket{customerID: order.CustomerID}
+	for _, item := range order.Items {
+		if !seen[item.BookID] {
+			seen[item.BookID] = true
+			basket.books = append(basket.books, item.BookID)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if previous, exists := e.baskets[order.ID]; exists {
+		e.count(previous, -1)
+	}
+	e.baskets[order.ID] = basket
+	e.count(basket, 1)
+}
+
+// RemoveOrder stops counting the books of an order
+func (e *RecommendationEngine) RemoveOrder(id int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if previous, exists := e.baskets[id]; exists {
+		e.count(previous, -1)
+		delete(e.baskets, id)
+	}
+}
+
+// count adds the books of a basket to the counts, or subtracts them with a delta of -1
+func (e *RecommendationEngine) count(basket orderBasket, delta int) {
+	if e.customerBooks[basket.customerID] == nil {
+		e.customerBooks[basket.customerID] = make(map[int]int)
+	}
+	for i, book := range basket.books {
+		addCount(e.bookOrders, book, delta)
+		addCount(e.customerBooks[basket.customerID], book, delta)
+		for _, other := range basket.books[i+1:] {
+			if e.pairs[book] == nil {
+				e.pairs[book] = make(map[int]int)
+			}
+			if e.pairs[other] == nil {
+				e.pairs[other] = make(map[int]int)
+			}
+			addCount(e.pairs[book], other, delta)
+			addCount(e.pairs[other], book, delta)
+		}
+	}
+}
+
+// addCount changes a count, dropping it once it reaches zero
+func addCount(counts map[int]int, key, delta int) {
+	counts[key] += delta
+	if counts[key] <= 0 {
+		delete(counts, key)
+	}
+}
+
+// similarity is the cosine similarity of the orders of two books. The caller must hold the lock.
+func (e *RecommendationEngine) similarity(a, b int) float64 {
+	together := e.pairs[a][b]
+	if together == 0 {
+		return 0
+	}
+	return float64(together) / math.Sqrt(float64(e.bookOrders[a]*e.bookOrders[b]))
+}
+
+// Related returns the books most often bought with a book, most similar first, completed with the books
+// sharing its author or genres when fewer than limit were bought with it. Deleted books are left out.
+func (e *RecommendationEngine) Related(book data.Book, limit int) []data.RelatedBook {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	related := []data.RelatedBook{}
+	included := map[int]bool{book.ID: true}
+	for other, together := range e.pairs[book.ID] {
+		candidate, errResp := e.books.GetBook(other)
+		if errResp != nil {
+			continue
+		}
+		included[other] = true
+		related = append(related, data.RelatedBook{
+			BookID:              other,
+			Title:               candidate.Title,
+			Stock:               candidate.Stock,
+			Score:               roundScore(e.similarity(book.ID, other)),
+			TimesBoughtTogether: together,
+			Reason:              data.ReasonBoughtTogether,
+		})
+	}
+	sort.Slice(related, func(i, j int) bool {
+		a, b := related[i], related[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.TimesBoughtTogether != b.TimesBoughtTogether {
+			return a.TimesBoughtTogether > b.TimesBoughtTogether
+		}
+		return a.BookID < b.BookID
+	})
+	if len(related) >= limit {
+		return related[:limit]
+	}
+
+	// Cold start: books by the same author or of the same genres
+	affinity := newBookAffinity()
+	affinity.add(book, 1)
+	for _, candidate := range e.rankByAffinity(affinity, included, false) {
+		if len(related) == limit {
+			break
+		}
+		related = append(related, data.RelatedBook{
+			BookID: candidate.book.ID,
+			Title:  candidate.book.Title,
+			Stock:  candidate.book.Stock,
+			Score:  candidate.score,
+			Reason: data.ReasonAffinity,
+		})
+	}
+	return related
+}
+
+// Recommend returns the books a customer is most likely to buy, leaving out those they bought and those
+// out of stock. Books bought with the customer's books come first, scored by the sum of their similarity
+// to each; when fewer than limit are found, books sharing the authors and genres of the customer's books
+// follow, or the most popular books for customers without orders.
+func (e *RecommendationEngine) Recommend(customerID, limit int) []data.BookRecommendation {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	purchased := e.customerBooks[customerID]
+	scores := map[int]float64{}
+	becauseOf := map[int][]int{}
+	for book := range purchased {
+		for other := range e.pairs[book] {
+			if purchased[other] > 0 {
+				continue
+			}
+			scores[other] += e.similarity(book, other)
+			becauseOf[other] = append(becauseOf[other], book)
+		}
+	}
+
+	recommendations := []data.BookRecommendation{}
+	included := map[int]bool{}
+	for book := range purchased {
+		included[book] = true
+	}
+	for other, score := range scores {
+		candidate, errResp := e.books.GetBook(other)
+		if errResp != nil || candidate.Stock <= 0 {
+			continue
+		}
+		included[other] = true
+		sort.Ints(becauseOf[other])
+		recommendations = append(recommendations, data.BookRecommendation{
+			BookID:    other,
+			Title:     candidate.Title,
+			Stock:     candidate.Stock,
+			Score:     roundScore(score),
+			Reason:    data.ReasonBoughtTogether,
+			BecauseOf: becauseOf[other],
+		})
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.BookID < b.BookID
+	})
+	if len(recommendations) >= limit {
+		return recommendations[:limit]
+	}
+
+	// Cold start: the authors and genres of the customer's books, or the most popular books
+	affinity := newBookAffinity()
+	for bookID, orders := range purchased {
+		if book, errResp := e.books.GetBook(bookID); errResp == nil {
+			affinity.add(book, orders)
+		}
+	}
+	reason := data.ReasonAffinity
+	if affinity.total == 0 {
+		reason = data.ReasonPopular
+	}
+	for _, candidate := range e.rankByAffinity(affinity, included, true) {
+		if len(recommendations) == limit {
+			break
+		}
+		recommendations = append(recommendations, data.BookRecommendation{
+			BookID: candidate.book.ID,
+			Title:  candidate.book.Title,
+			Stock:  candidate.book.Stock,
+			Score:  candidate.score,
+			Reason: reason,
+		})
+	}
+	return recommendations
+}
+
+// bookAffinity weighs the authors and genres of a set of books
+type bookAffinity struct {
+	authors map[int]float64
+	genres  map[string]float64
+	total   float64
+}
+
+func newBookAffinity() bookAffinity {
+	return bookAffinity{authors: make(map[int]float64), genres: make(map[string]float64)}
+}
+
+// add weighs the author and genres of a book
+func (a *bookAffinity) add(book data.Book, weight int) {
+	a.authors[book.AuthorID] += float64(weight)
+	for _, genre := range book.Genres {
+		a.genres[genre] += float64(weight)
+	}
+	a.total += float64(weight)
+}
+
+// score rates a book from 0 to 1: half for the weight of its author, half for the average weight of its genres
+func (a bookAffinity) score(book data.Book) float64 {
+	if a.total == 0 {
+		return 0
+	}
+	score := a.authors[book.AuthorID] / a.total
+	if len(book.Genres) > 0 {
+		genres := 0.0
+		for _, genre := range book.Genres {
+			genres += a.genres[genre] / a.total
+		}
+		score += genres / float64(len(book.Genres))
+	}
+	return score / 2
+}
+
+// scoredBook is a candidate of a cold-start ranking
+type scoredBook struct {
+	book  data.Book
+	score float64
+}
+
+// rankByAffinity ranks the books not yet included by affinity, then by the number of orders they are in.
+// Without affinity weights all books are ranked by their number of orders, scored against the most ordered.
+// The caller must hold the lock.
+func (e *RecommendationEngine) rankByAffinity(affinity bookAffinity, included map[int]bool, inStockOnly bool) []scoredBook {
+	mostOrdered := 0
+	for _, orders := range e.bookOrders {
+		mostOrdered = max(mostOrdered, orders)
+	}
+
+	var candidates []scoredBook
+	for _, book := range e.books.GetAllBooks() {
+		if included[book.ID] || (inStockOnly && book.Stock <= 0) {
+			continue
+		}
+		score := affinity.score(book)
+		if affinity.total == 0 && mostOrdered > 0 {
+			score = float64(e.bookOrders[book.ID]) / float64(mostOrdered)
+		}
+		if score > 0 {
+			candidates = append(candidates, scoredBook{book: book, score: roundScore(score)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if e.bookOrders[a.book.ID] != e.bookOrders[b.book.ID] {
+			return e.bookOrders[a.book.ID] > e.bookOrders[b.book.ID]
+		}
+		return a.book.ID < b.book.ID
+	})
+	return candidates
+}
+
+// roundScore rounds a score to four decimals
+func roundScore(score float64) float64 {
+	return math.Round(score*10000) / 10000
+}
@@ -0,0 +1,642 @@
+package InmemoryStores
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// IntegrityEnforcer deletes records while applying the policies of the declared relations.
+// The stores record every change it makes in the audit log; stock movements are recorded here.
+type IntegrityEnforcer struct {
+	mu         sync.Mutex
+	authors    interfaces.AuthorStore
+	books      interfaces.BookStore
+	customers  interfaces.CustomerStore
+	orders     interfaces.OrderStore
+	relations  interfaces.RelationStore
+	ledger     interfaces.StockLedger
+	warehouses interfaces.WarehouseStore
+	returns    interfaces.ReturnStore
+}
+
+// integrityStep is a single change planned by the enforcer: a delete, or a detach when relation is set
+type integrityStep struct {
+	relation *data.Relation
+	resource string
+	id       int
+	parentID int
+}
+
+var (
+	integrityEnforcerInstance *IntegrityEnforcer
+	integrityOnce             sync.Once
+)
+
+// GetIntegrityEnforcerInstance returns the singleton instance of IntegrityEnforcer
+func GetIntegrityEnforcerInstance() *IntegrityEnforcer {
+	integrityOnce.Do(func() {
+		integrityEnforcerInstance = &IntegrityEnforcer{
+			authors:    GetAuthorStoreInstance(),
+			books:      GetBookStoreInstance(),
+			customers:  GetCustomerStoreInstance(),
+			orders:     GetOrderStoreInstance(),
+			relations:  GetRelationStoreInstance(),
+			ledger:     GetStockLedgerInstance(),
+			warehouses: GetWarehouseStoreInstance(),
+			returns:    GetReturnStoreInstance(),
+		}
+	})
+	return integrityEnforcerInstance
+}
+
+// Delete moves a record to the trash and applies the relation policies to everything referencing it.
+// Nothing is changed if a restrict policy blocks the delete anywhere in the cascade, and the steps already
+// applied are undone, newest first, if one of them fails.
+// It returns the resources that were modified and need to be persisted.
+func (e *IntegrityEnforcer) Delete(resource string, id int, ctx data.AuditContext) ([]string, *data.ErrorResponse) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.exists(resource, id) {
+		return nil, data.NewNotFoundError(fmt.Sprintf("%s not found", singular(resource)))
+	}
+
+	var steps []integrityStep
+	if errResp := e.plan(resource, id, map[string]bool{}, &steps); errResp != nil {
+		return nil, errResp
+	}
+
+	affected := map[string]bool{}
+	var undo []func()
+	for _, step := range steps {
+		var errResp *data.ErrorResponse
+		if step.relation != nil {
+			before := e.find(step.resource, step.id)
+			errResp = e.detach(*step.relation, step.id, step.parentID, ctx)
+			if errResp == nil {
+				undo = append(undo, func() { e.reattach(before, ctx) })
+			}
+		} else {
+			errResp = e.deleteRecord(step.resource, step.id, ctx, affected)
+			if errResp == nil {
+				undo = append(undo, func() {
+					if _, errResp := e.restore(step.resource, step.id, ctx); errResp != nil {
+						log.Printf("Failed to restore %s %d while undoing a delete: %s", step.resource, step.id, errResp.Message)
+					}
+				})
+			}
+		}
+		if errResp != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+			return sortedResources(affected), errResp
+		}
+		affected[step.resource] = true
+	}
+	return sortedResources(affected), nil
+}
+
+// Restore takes a record out of the trash. The records it references must not be deleted, and
+// restoring an order takes its items out of stock again.
+// It returns the resources that were modified and need to be persisted.
+func (e *IntegrityEnforcer) Restore(resource string, id int, ctx data.AuditContext) ([]string, *data.ErrorResponse) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.restore(resource, id, ctx)
+}
+
+func (e *IntegrityEnforcer) restore(resource string, id int, ctx data.AuditContext) ([]string, *data.ErrorResponse) {
+	switch resource {
+	case data.ResourceAuthors:
+		if _, errResp := e.authors.RestoreAuthor(id, ctx); errResp != nil {
+			return nil, errResp
+		}
+	case data.ResourceBooks:
+		for _, book := range e.books.GetDeletedBooks() {
+			if book.ID == id && book.AuthorID != 0 && !e.exists(data.ResourceAuthors, book.AuthorID) {
+				return nil, data.NewConflictError(fmt.Sprintf("Author %d is deleted; restore it first", book.AuthorID))
+			}
+		}
+		if _, errResp := e.books.RestoreBook(id, ctx); errResp != nil {
+			return nil, errResp
+		}
+	case data.ResourceCustomers:
+		for _, customer := range e.customers.GetDeletedCustomers() {
+			if customer.ID != id {
+				continue
+			}
+			for _, existing := range e.customers.GetAllCustomers() {
+				if existing.Email == customer.Email {
+					return nil, data.NewConflictError("Customer with this email already exists")
+				}
+			}
+		}
+		if _, errResp := e.customers.RestoreCustomer(id, ctx); errResp != nil {
+			return nil, errResp
+		}
+	case data.ResourceOrders:
+		return e.restoreOrder(id, ctx)
+	default:
+		return nil, data.NewNotFoundError("Unknown resource " + resource)
+	}
+	return []string{resource}, nil
+}
+
+// restoreOrder takes an order out of the trash once its customer and books are available again
+func (e *IntegrityEnforcer) restoreOrder(id int, ctx data.AuditContext) ([]string, *data.ErrorResponse) {
+	var order data.Order
+	found := false
+	for _, deleted := range e.orders.GetDeletedOrders() {
+		if deleted.ID == id {
+			order, found = deleted, true
+			break
+		}
+	}
+	if !found {
+		return nil, data.NewNotFoundError("Order not found in trash")
+	}
+
+	if order.CustomerID != 0 && !e.exists(data.ResourceCustomers, order.CustomerID) {
+		return nil, data.NewConflictError(fmt.Sprintf("Customer %d is deleted; restore it first", order.CustomerID))
+	}
+
+	// Check every book before touching any stock
+	needed := map[int]int{}
+	allocations := map[int][]data.StockAllocation{}
+	var bookIDs []int
+	for _, item := range order.Items {
+		if item.BookID == 0 {
+			continue
+		}
+		if _, seen := needed[item.BookID]; !seen {
+			bookIDs = append(bookIDs, item.BookID)
+		}
+		needed[item.BookID] += item.Quantity
+		allocations[item.BookID] = append(allocations[item.BookID], e.locate(item.Quantity, item.Allocations)...)
+	}
+	sort.Ints(bookIDs)
+	for _, bookID := range bookIDs {
+		book, errResp := e.books.GetBook(bookID)
+		if errResp != nil {
+			return nil, data.NewConflictError(fmt.Sprintf("Book %d is deleted; restore it first", bookID))
+		}
+		if book.Stock < needed[bookID] {
+			return nil, data.NewInsufficientStockError(fmt.Sprintf("Insufficient stock to restore order %d: book %d has %d left", id, bookID, book.Stock))
+		}
+	}
+
+	// Take the stock again from the warehouses the order was shipped from, putting back what was taken if
+	// a book is short or the order cannot be restored
+	taken := map[int][]data.StockAllocation{}
+	changed := map[int][2]data.Book{}
+	putBack := func() {
+		for bookID, allocs := range taken {
+			e.warehouses.Release(bookID, needed[bookID], allocs)
+		}
+		for bookID := range changed {
+			e.books.AdjustStock(bookID, needed[bookID])
+		}
+	}
+	for _, bookID := range bookIDs {
+		allocs, errResp := e.warehouses.Take(bookID, needed[bookID], allocations[bookID])
+		if errResp != nil {
+			putBack()
+			return nil, errResp
+		}
+		taken[bookID] = allocs
+		before, after, errResp := e.books.AdjustStock(bookID, -needed[bookID])
+		if errResp != nil {
+			putBack()
+			return nil, errResp
+		}
+		changed[bookID] = [2]data.Book{before, after}
+	}
+	restored, errResp := e.orders.RestoreOrder(id, ctx)
+	if errResp != nil {
+		putBack()
+		return nil, errResp
+	}
+
+	for _, bookID := range bookIDs {
+		recordChange(data.ResourceBooks, bookID, data.AuditStockChange, ctx, changed[bookID][0], changed[bookID][1])
+		e.move(changed[bookID][0], data.StockMovementSale, -1, taken[bookID], id, "Order restored", ctx)
+	}
+	if restored.AmountPaid > order.AmountPaid {
+		return []string{data.ResourceBooks, data.ResourceOrders, data.ResourceStoreCredit}, nil // The refund was charged back
+	}
+	return []string{data.ResourceBooks, data.ResourceOrders}, nil
+}
+
+// Purge permanently removes a record from the trash
+func (e *IntegrityEnforcer) Purge(resource string, id int, ctx data.AuditContext) *data.ErrorResponse {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.purgeRecord(resource, id, ctx)
+}
+
+// PurgeExpired permanently removes every record that was moved to the trash before cutoff. Children are
+// purged before their parents, and a parent is kept while a child in the trash still references it, so
+// that the child can still be restored.
+// It returns the resources that were modified and the number of records purged.
+func (e *IntegrityEnforcer) PurgeExpired(cutoff time.Time, ctx data.AuditContext) ([]string, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	expired := func(s data.SoftDelete) bool {
+		return s.IsDeleted() && s.DeletedAt.Before(cutoff)
+	}
+	var ids = map[string][]int{}
+	for _, author := range e.authors.GetDeletedAuthors() {
+		if expired(author.SoftDelete) {
+			ids[data.ResourceAuthors] = append(ids[data.ResourceAuthors], author.ID)
+		}
+	}
+	for _, book := range e.books.GetDeletedBooks() {
+		if expired(book.SoftDelete) {
+			ids[data.ResourceBooks] = append(ids[data.ResourceBooks], book.ID)
+		}
+	}
+	for _, customer := range e.customers.GetDeletedCustomers() {
+		if expired(customer.SoftDelete) {
+			ids[data.ResourceCustomers] = append(ids[data.ResourceCustomers], customer.ID)
+		}
+	}
+	for _, order := range e.orders.GetDeletedOrders() {
+		if expired(order.SoftDelete) {
+			ids[data.ResourceOrders] = append(ids[data.ResourceOrders], order.ID)
+		}
+	}
+
+	affected := map[string]bool{}
+	purged := 0
+	for _, resource := range e.childrenFirst([]string{data.ResourceAuthors, data.ResourceBooks, data.ResourceCustomers, data.ResourceOrders}) {
+		resourceIDs := ids[resource]
+		sort.Ints(resourceIDs)
+
+		// The children purged so far are gone, the ones left in the trash keep their parent
+		trashedBooks, trashedOrders := e.books.GetDeletedBooks(), e.orders.GetDeletedOrders()
+		for _, id := range resourceIDs {
+			if e.hasTrashedChildren(resource, id, trashedBooks, trashedOrders) {
+				continue
+			}
+			if errResp := e.purgeRecord(resource, id, ctx); errResp != nil {
+				log.Printf("Failed to purge %s %d: %s", resource, id, errResp.Message)
+				continue
+			}
+			affected[resource] = true
+			purged++
+		}
+	}
+	return sortedResources(affected), purged
+}
+
+// childrenFirst orders resources so that the child of every declared relation comes before its parent
+func (e *IntegrityEnforcer) childrenFirst(resources []string) []string {
+	relations := e.relations.GetAllRelations()
+	var ordered []string
+	remaining := resources
+	for len(remaining) > 0 {
+		var blocked []string
+		for _, resource := range remaining {
+			waiting := false
+			for _, relation := range relations {
+				if relation.Parent == resource && relation.Child != resource && utils.ContainsString(remaining, relation.Child) {
+					waiting = true
+					break
+				}
+			}
+			if waiting {
+				blocked = append(blocked, resource)
+			} else {
+				ordered = append(ordered, resource)
+			}
+		}
+		if len(blocked) == len(remaining) {
+			return append(ordered, blocked...) // The relations form a cycle
+		}
+		remaining = blocked
+	}
+	return ordered
+}
+
+// hasTrashedChildren reports whether a record in the trash still references a parent through a relation
+func (e *IntegrityEnforcer) hasTrashedChildren(resource string, id int, trashedBooks []data.Book, trashedOrders []data.Order) bool {
+	for _, relation := range e.relations.GetRelationsByParent(resource) {
+		if len(childIDsIn(relation.Name, id, trashedBooks, trashedOrders)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *IntegrityEnforcer) purgeRecord(resource string, id int, ctx data.AuditContext) *data.ErrorResponse {
+	switch resource {
+	case data.ResourceAuthors:
+		return e.authors.PurgeAuthor(id, ctx)
+	case data.ResourceBooks:
+		return e.books.PurgeBook(id, ctx)
+	case data.ResourceCustomers:
+		return e.customers.PurgeCustomer(id, ctx)
+	case data.ResourceOrders:
+		return e.orders.PurgeOrder(id, ctx)
+	}
+	return data.NewNotFoundError("Unknown resource " + resource)
+}
+
+// ValidateBookReferences checks that the author a book points to exists
+func (e *IntegrityEnforcer) ValidateBookReferences(book data.Book) *data.ErrorResponse {
+	if book.AuthorID == 0 {
+		return nil
+	}
+	if _, errResp := e.authors.GetAuthor(book.AuthorID); errResp != nil {
+		return data.NewValidationError(fmt.Sprintf("Author %d does not exist", book.AuthorID))
+	}
+	return nil
+}
+
+// plan walks the relations below a record and records the steps needed to delete it. Orders with returns
+// that were not rejected cannot be deleted.
+func (e *IntegrityEnforcer) plan(resource string, id int, visited map[string]bool, steps *[]integrityStep) *data.ErrorResponse {
+	key := resource + ":" + strconv.Itoa(id)
+	if visited[key] {
+		return nil
+	}
+	visited[key] = true
+
+	// Returned goods were put back in stock, which deleting the order would do again
+	if resource == data.ResourceOrders {
+		for _, ret := range e.returns.GetReturnsByOrder(id) {
+			if ret.Status != data.ReturnRejected {
+				return data.NewConflictError(fmt.Sprintf("Order %d has return %s; orders with returns cannot be deleted", id, ret.Number))
+			}
+		}
+	}
+
+	for _, relation := range e.relations.GetRelationsByParent(resource) {
+		children := e.childIDs(relation.Name, id)
+		if len(children) == 0 {
+			continue
+		}
+
+		switch relation.Policy {
+		case data.PolicyRestrict:
+			return data.NewConflictError(fmt.Sprintf("%s %d is referenced by %d %s (relation %s is restrict)",
+				singular(resource), id, len(children), relation.Child, relation.Name))
+		case data.PolicyCascade:
+			for _, childID := range children {
+				if errResp := e.plan(relation.Child, childID, visited, steps); errResp != nil {
+					return errResp
+				}
+			}
+		case data.PolicySetNull, data.PolicySoftDetach:
+			rel := relation
+			for _, childID := range children {
+				*steps = append(*steps, integrityStep{relation: &rel, resource: relation.Child, id: childID, parentID: id})
+			}
+		}
+	}
+
+	*steps = append(*steps, integrityStep{resource: resource, id: id})
+	return nil
+}
+
+// childIDs returns the IDs of the records referencing parentID through a relation
+func (e *IntegrityEnforcer) childIDs(relationName string, parentID int) []int {
+	return childIDsIn(relationName, parentID, e.books.GetAllBooks(), e.orders.GetAllOrders())
+}
+
+// childIDsIn returns the IDs of the books or orders given that reference parentID through a relation
+func childIDsIn(relationName string, parentID int, books []data.Book, orders []data.Order) []int {
+	var ids []int
+	switch relationName {
+	case RelationAuthorBooks:
+		for _, book := range books {
+			if book.AuthorID == parentID {
+				ids = append(ids, book.ID)
+			}
+		}
+	case RelationBookOrderItems:
+		for _, order := range orders {
+			for _, item := range order.Items {
+				if item.BookID == parentID {
+					ids = append(ids, order.ID)
+					break
+				}
+			}
+		}
+	case RelationCustomerOrders:
+		for _, order := range orders {
+			if order.CustomerID == parentID {
+				ids = append(ids, order.ID)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// detach clears a child's reference to its parent. set-null also drops the purchase-time snapshot,
+// soft-detach keeps it. Books hold no author snapshot, so both policies only clear the author ID.
+func (e *IntegrityEnforcer) detach(relation data.Relation, childID, parentID int, ctx data.AuditContext) *data.ErrorResponse {
+	clearSnapshot := relation.Policy == data.PolicySetNull
+
+	switch relation.Name {
+	case RelationAuthorBooks:
+		book, errResp := e.books.GetBook(childID)
+		if errResp != nil {
+			return errResp
+		}
+		book.AuthorID = 0
+		_, errResp = e.books.UpdateBook(childID, book, ctx)
+		return errResp
+	case RelationBookOrderItems:
+		_, errResp := e.orders.DetachOrderItems(childID, parentID, clearSnapshot, ctx)
+		return errResp
+	case RelationCustomerOrders:
+		_, errResp := e.orders.DetachOrderCustomer(childID, clearSnapshot, ctx)
+		return errResp
+	}
+	return nil
+}
+
+// reattach puts back the references of a record detached by a delete that is being undone
+func (e *IntegrityEnforcer) reattach(before interface{}, ctx data.AuditContext) {
+	switch record := before.(type) {
+	case data.Book:
+		if _, errResp := e.books.UpdateBook(record.ID, record, ctx); errResp != nil {
+			log.Printf("Failed to reattach book %d while undoing a delete: %s", record.ID, errResp.Message)
+		}
+	case data.Order:
+		if _, errResp := e.orders.ReattachOrder(record, ctx); errResp != nil {
+			log.Printf("Failed to reattach order %d while undoing a delete: %s", record.ID, errResp.Message)
+		}
+	}
+}
+
+// deleteRecord moves a single record to the trash. Deleting an order puts its items back in stock.
+func (e *IntegrityEnforcer) deleteRecord(resource string, id int, ctx data.AuditContext, affected map[string]bool) *data.ErrorResponse {
+	switch resource {
+	case data.ResourceAuthors:
+		return e.authors.DeleteAuthor(id, ctx)
+	case data.ResourceBooks:
+		return e.books.DeleteBook(id, ctx)
+	case data.ResourceCustomers:
+		return e.customers.DeleteCustomer(id, ctx)
+	case data.ResourceOrders:
+		order, errResp := e.orders.GetOrder(id)
+		if errResp != nil {
+			return errResp
+		}
+		if errResp := e.orders.DeleteOrder(id, ctx); errResp != nil {
+			return errResp
+		}
+		if order.AmountPaid > 0 {
+			affected[data.ResourceStoreCredit] = true // Deleting the order refunds what was paid to store credit
+		}
+
+		// Put the items back in stock
+		for _, item := range order.Items {
+			before, after, errResp := e.books.AdjustStock(item.BookID, item.Quantity)
+			if errResp != nil {
+				log.Printf("Warning: stock of book %d not restored while deleting order %d: %s", item.BookID, id, errResp.Message)
+				continue
+			}
+			released := e.warehouses.Release(item.BookID, item.Quantity, item.Allocations)
+			recordChange(data.ResourceBooks, item.BookID, data.AuditStockChange, ctx, before, after)
+			e.move(before, data.StockMovementCancellation, 1, released, id, "", ctx)
+			affected[data.ResourceBooks] = true
+		}
+		return nil
+	}
+	return data.NewInternalError("Unknown resource "+resource, nil)
+}
+
+// move records the stock an order took from or put back to its warehouses in the stock ledger, one movement
+// per warehouse. sign is -1 when the stock goes out and 1 when it comes back.
+func (e *IntegrityEnforcer) move(before data.Book, movementType data.StockMovementType, sign int, allocations []data.StockAllocation, orderID int, note string, ctx data.AuditContext) {
+	stock := before.Stock
+	for _, allocation := range allocations {
+		stock += sign * allocation.Quantity
+		e.ledger.RecordMovement(data.StockMovement{
+			BookID:      before.ID,
+			Type:        movementType,
+			Quantity:    sign * allocation.Quantity,
+			StockAfter:  stock,
+			WarehouseID: allocation.WarehouseID,
+			SourceType:  data.ResourceOrders,
+			SourceID:    orderID,
+			Note:        note,
+			Actor:       ctx.Actor,
+			RequestID:   ctx.RequestID,
+		})
+	}
+}
+
+// locate returns the warehouses an order item was allocated from. Items ordered before stock was kept
+// per warehouse are treated as allocated from the default warehouse.
+func (e *IntegrityEnforcer) locate(quantity int, allocations []data.StockAllocation) []data.StockAllocation {
+	if len(allocations) > 0 {
+		return allocations
+	}
+	warehouse, errResp := e.warehouses.DefaultWarehouse()
+	if errResp != nil {
+		return []data.StockAllocation{{Quantity: quantity}}
+	}
+	return []data.StockAllocation{{WarehouseID: warehouse.ID, Quantity: quantity}}
+}
+
+// find returns a record whether it is active or in the trash, or nil if it does not exist
+func (e *IntegrityEnforcer) find(resource string, id int) interface{} {
+	switch resource {
+	case data.ResourceAuthors:
+		if author, errResp := e.authors.GetAuthor(id); errResp == nil {
+			return author
+		}
+		for _, author := range e.authors.GetDeletedAuthors() {
+			if author.ID == id {
+				return author
+			}
+		}
+	case data.ResourceBooks:
+		if book, errResp := e.books.GetBook(id); errResp == nil {
+			return book
+		}
+		for _, book := range e.books.GetDeletedBooks() {
+			if book.ID == id {
+				return book
+			}
+		}
+	case data.ResourceCustomers:
+		if customer, errResp := e.customers.GetCustomer(id); errResp == nil {
+			return customer
+		}
+		for _, customer := range e.customers.GetDeletedCustomers() {
+			if customer.ID == id {
+				return customer
+			}
+		}
+	case data.ResourceOrders:
+		if order, errResp := e.orders.GetOrder(id); errResp == nil {
+			return order
+		}
+		for _, order := range e.orders.GetDeletedOrders() {
+			if order.ID == id {
+				return order
+			}
+		}
+	}
+	return nil
+}
+
+// exists reports whether a record is present in its store
+func (e *IntegrityEnforcer) exists(resource string, id int) bool {
+	var errResp *data.ErrorResponse
+	switch resource {
+	case data.ResourceAuthors:
+		_, errResp = e.authors.GetAuthor(id)
+	case data.ResourceBooks:
+		_, errResp = e.books.GetBook(id)
+	case data.ResourceCustomers:
+		_, errResp = e.customers.GetCustomer(id)
+	case data.ResourceOrders:
+		_, errResp = e.orders.GetOrder(id)
+	default:
+		return false
+	}
+	return errResp == nil
+}
+
+// singular turns a resource name into the capitalised entity name used in messages
+func singular(resource string) string {
+	switch resource {
+	case data.ResourceAuthors:
+		return "Author"
+	case data.ResourceBooks:
+		return "Book"
+	case data.ResourceCustomers:
+		return "Customer"
+	case data.ResourceOrders:
+		return "Order"
+	}
+	return resource
+}
+
+func sortedResources(set map[string]bool) []string {
+	resources := make([]string, 0, len(set))
+	for resource := range set {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	return resources
+}
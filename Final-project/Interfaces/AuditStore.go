@@ -0,0 +1,14 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type AuditStore interface {
+	Record(resource string, resourceID int, action data.AuditAction, ctx data.AuditContext, before, after interface{}) data.AuditEvent
+	GetHistory(resource string, resourceID int) []data.AuditEvent
+	GetAllAuditEvents() []data.AuditEvent
+	SearchAuditEvents(criteria data.AuditSearchCriteria) ([]data.AuditEvent, *data.ErrorResponse)
+	SetOnRecord(onRecord func())
+	AddAuditEventDirectly(event data.AuditEvent)
+}
@@ -5,10 +5,14 @@ import (
 )
 
 type AuthorStore interface {
-	CreateAuthor(author data.Author) (data.Author, *data.ErrorResponse)
+	CreateAuthor(author data.Author, ctx data.AuditContext) (data.Author, *data.ErrorResponse)
 	GetAuthor(id int) (data.Author, *data.ErrorResponse)
-	UpdateAuthor(id int, author data.Author) (data.Author, *data.ErrorResponse)
-	DeleteAuthor(id int) *data.ErrorResponse
+	UpdateAuthor(id int, author data.Author, ctx data.AuditContext) (data.Author, *data.ErrorResponse)
+	DeleteAuthor(id int, ctx data.AuditContext) *data.ErrorResponse
+	GetDeletedAuthors() []data.Author
+	RestoreAuthor(id int, ctx data.AuditContext) (data.Author, *data.ErrorResponse)
+	PurgeAuthor(id int, ctx data.AuditContext) *data.ErrorResponse
 	SearchAuthors(criteria data.AuthorSearchCriteria) ([]data.Author, *data.ErrorResponse)
 	GetAllAuthors() []data.Author 
+	AddAuthorDirectly(author data.Author)
 }
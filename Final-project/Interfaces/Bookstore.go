@@ -5,10 +5,15 @@ import (
 )
 
 type BookStore interface {
-	CreateBook(book data.Book) (data.Book, *data.ErrorResponse)
+	CreateBook(book data.Book, ctx data.AuditContext) (data.Book, *data.ErrorResponse)
 	GetBook(id int) (data.Book, *data.ErrorResponse)
-	UpdateBook(id int, book data.Book) (data.Book, *data.ErrorResponse)
-	DeleteBook(id int) *data.ErrorResponse
+	UpdateBook(id int, book data.Book, ctx data.AuditContext) (data.Book, *data.ErrorResponse)
+	AdjustStock(id int, delta int) (data.Book, data.Book, *data.ErrorResponse)
+	SetCostPrice(id int, costPrice float64, ctx data.AuditContext) (data.Book, *data.ErrorResponse)
+	DeleteBook(id int, ctx data.AuditContext) *data.ErrorResponse
+	GetDeletedBooks() []data.Book
+	RestoreBook(id int, ctx data.AuditContext) (data.Book, *data.ErrorResponse)
+	PurgeBook(id int, ctx data.AuditContext) *data.ErrorResponse
 	GetAllBooks() []data.Book
 	AddBookDirectly(book data.Book)
 	SearchBooks(criteria data.BookSearchCriteria) ([]data.Book, *data.ErrorResponse)
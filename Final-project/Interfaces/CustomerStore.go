@@ -5,10 +5,17 @@ import (
 )
 
 type CustomerStore interface {
-	CreateCustomer(customer data.Customer) (data.Customer, *data.ErrorResponse)
+	CreateCustomer(customer data.Customer, ctx data.AuditContext) (data.Customer, *data.ErrorResponse)
 	GetCustomer(id int) (data.Customer, *data.ErrorResponse)
 	GetAllCustomers() []data.Customer
-	UpdateCustomer(id int, customer data.Customer) (data.Customer, *data.ErrorResponse)
-	DeleteCustomer(id int) *data.ErrorResponse
+	UpdateCustomer(id int, customer data.Customer, ctx data.AuditContext) (data.Customer, *data.ErrorResponse)
+	AddAddress(customerID int, address data.CustomerAddress, ctx data.AuditContext) (data.CustomerAddress, *data.ErrorResponse)
+	UpdateAddress(customerID, addressID int, address data.CustomerAddress, ctx data.AuditContext) (data.CustomerAddress, *data.ErrorResponse)
+	DeleteAddress(customerID, addressID int, ctx data.AuditContext) *data.ErrorResponse
+	DeleteCustomer(id int, ctx data.AuditContext) *data.ErrorResponse
+	GetDeletedCustomers() []data.Customer
+	RestoreCustomer(id int, ctx data.AuditContext) (data.Customer, *data.ErrorResponse)
+	PurgeCustomer(id int, ctx data.AuditContext) *data.ErrorResponse
+	AddCustomerDirectly(customer data.Customer)
 	SearchCustomers(criteria data.CustomerSearchCriteria) ([]data.Customer, *data.ErrorResponse)
 }
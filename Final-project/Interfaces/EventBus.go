@@ -0,0 +1,11 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type EventBus interface {
+	Publish(event data.DomainEvent) data.DomainEvent
+	PublishChange(change data.AuditEvent, record interface{})
+	Subscribe(handler func(event data.DomainEvent))
+}
@@ -0,0 +1,23 @@
+package Interfaces
+
+import (
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+type GiftCardStore interface {
+	IssueGiftCard(request data.GiftCardIssueRequest, actor string) (data.GiftCard, *data.ErrorResponse)
+	GetGiftCard(id int) (data.GiftCard, *data.ErrorResponse)
+	GetGiftCardByCode(code string) (data.GiftCard, *data.ErrorResponse)
+	GetAllGiftCards() []data.GiftCard
+	SearchGiftCards(criteria data.GiftCardSearchCriteria) []data.GiftCard
+	Redeem(customerID int, codes []string, useStoreCredit bool, amount float64, orderID int, actor string) ([]data.OrderPayment, *data.ErrorResponse)
+	TransferToStoreCredit(code string, customerID int, actor string) (data.StoreCreditEntry, *data.ErrorResponse)
+	AddStoreCredit(entry data.StoreCreditEntry) (data.StoreCreditEntry, *data.ErrorResponse)
+	GetStoreCredit(customerID int) data.StoreCreditAccount
+	GetAllStoreCreditEntries() []data.StoreCreditEntry
+	ExpireGiftCards(now time.Time, ctx data.AuditContext) []data.GiftCard
+	AddGiftCardDirectly(card data.GiftCard)
+	AddStoreCreditEntryDirectly(entry data.StoreCreditEntry)
+}
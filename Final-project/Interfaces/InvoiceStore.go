@@ -0,0 +1,17 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+// InvoiceStore keeps invoices and credit notes. Issuing a document gives it the next number of its type
+// and fiscal year, so the numbers have no gaps.
+type InvoiceStore interface {
+	IssueInvoice(invoice data.Invoice, prefix string) data.Invoice
+	GetInvoice(id int) (data.Invoice, *data.ErrorResponse)
+	GetAllInvoices() []data.Invoice
+	GetInvoicesByOrder(orderID int) []data.Invoice
+	UpdateInvoice(invoice data.Invoice) *data.ErrorResponse
+	SearchInvoices(criteria data.InvoiceSearchCriteria) []data.Invoice
+	AddInvoiceDirectly(invoice data.Invoice)
+}
@@ -0,0 +1,13 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type NamedReportStore interface {
+	SaveReport(report data.SalesReportResult) (data.SalesReportResult, *data.ErrorResponse)
+	GetReport(id int) (data.SalesReportResult, *data.ErrorResponse)
+	GetAllReports() []data.SalesReportResult
+	DeleteReport(id int) *data.ErrorResponse
+	AddReportDirectly(report data.SalesReportResult)
+}
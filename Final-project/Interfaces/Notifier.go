@@ -0,0 +1,10 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+// Notifier delivers back-in-stock notifications to customers, e.g. by email
+type Notifier interface {
+	Notify(notification data.StockNotification) error
+}
@@ -5,10 +5,17 @@ import (
 )
 
 type OrderStore interface {
-	CreateOrder(order data.Order) (data.Order, *data.ErrorResponse)
+	CreateOrder(order data.Order, ctx data.AuditContext) (data.Order, *data.ErrorResponse)
 	GetOrder(id int) (data.Order, *data.ErrorResponse)
-	UpdateOrder(id int, order data.Order) (data.Order, *data.ErrorResponse)
-	DeleteOrder(id int) *data.ErrorResponse
+	UpdateOrder(id int, order data.Order, ctx data.AuditContext) (data.Order, *data.ErrorResponse)
+	DeleteOrder(id int, ctx data.AuditContext) *data.ErrorResponse
+	DetachOrderItems(orderID, bookID int, clearSnapshot bool, ctx data.AuditContext) (data.Order, *data.ErrorResponse)
+	DetachOrderCustomer(orderID int, clearSnapshot bool, ctx data.AuditContext) (data.Order, *data.ErrorResponse)
+	ReattachOrder(before data.Order, ctx data.AuditContext) (data.Order, *data.ErrorResponse)
+	GetDeletedOrders() []data.Order
+	RestoreOrder(id int, ctx data.AuditContext) (data.Order, *data.ErrorResponse)
+	PurgeOrder(id int, ctx data.AuditContext) *data.ErrorResponse
 	GetAllOrders() []data.Order
+	AddOrderDirectly(order data.Order)
 	SearchOrders(criteria data.OrderSearchCriteria) ([]data.Order, *data.ErrorResponse)
 }
@@ -0,0 +1,17 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type PurchaseOrderStore interface {
+	CreatePurchaseOrder(order data.PurchaseOrder) (data.PurchaseOrder, *data.ErrorResponse)
+	GetPurchaseOrder(id int) (data.PurchaseOrder, *data.ErrorResponse)
+	GetAllPurchaseOrders() []data.PurchaseOrder
+	UpdatePurchaseOrder(id int, order data.PurchaseOrder) (data.PurchaseOrder, *data.ErrorResponse)
+	DeletePurchaseOrder(id int) *data.ErrorResponse
+	SendPurchaseOrder(id int) (data.PurchaseOrder, *data.ErrorResponse)
+	ReceiveGoods(id int, receipt data.GoodsReceipt) (data.PurchaseOrder, data.GoodsReceipt, *data.ErrorResponse)
+	SearchPurchaseOrders(criteria data.PurchaseOrderSearchCriteria) []data.PurchaseOrder
+	AddPurchaseOrderDirectly(order data.PurchaseOrder)
+}
@@ -0,0 +1,12 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type RelationStore interface {
+	GetAllRelations() []data.Relation
+	GetRelation(name string) (data.Relation, *data.ErrorResponse)
+	GetRelationsByParent(parent string) []data.Relation
+	SetPolicy(name string, policy data.RelationPolicy) (data.Relation, *data.ErrorResponse)
+}
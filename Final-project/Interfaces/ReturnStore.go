@@ -0,0 +1,19 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type ReturnStore interface {
+	CreateReturn(request data.Return, order data.Order) (data.Return, *data.ErrorResponse)
+	GetReturn(id int) (data.Return, *data.ErrorResponse)
+	GetAllReturns() []data.Return
+	GetReturnsByOrder(orderID int) []data.Return
+	ApproveReturn(id int, actor string) (data.Return, *data.ErrorResponse)
+	RejectReturn(id int, reason string, actor string) (data.Return, *data.ErrorResponse)
+	ReceiveReturn(id int, receipt data.ReturnReceipt) (data.Return, data.ReturnReceipt, *data.ErrorResponse)
+	RecordRefund(id int, receipt data.ReturnReceipt) (data.Return, *data.ErrorResponse)
+	RemoveReceipt(id, receiptID int) (data.Return, *data.ErrorResponse)
+	SearchReturns(criteria data.ReturnSearchCriteria) []data.Return
+	AddReturnDirectly(ret data.Return)
+}
@@ -0,0 +1,17 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type ReviewStore interface {
+	CreateReview(review data.Review) (data.Review, *data.ErrorResponse)
+	GetReview(id int) (data.Review, *data.ErrorResponse)
+	GetAllReviews() []data.Review
+	GetReviewsByBook(bookID int) []data.Review
+	UpdateReview(id int, review data.Review) (data.Review, *data.ErrorResponse)
+	ModerateReview(id int, moderation data.ReviewModeration) (data.Review, *data.ErrorResponse)
+	DeleteReview(id int) *data.ErrorResponse
+	GetRatingSummary(bookID int) data.RatingSummary
+	AddReviewDirectly(review data.Review)
+}
@@ -0,0 +1,16 @@
+package Interfaces
+
+import (
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+type SalesReportStore interface {
+	SaveReport(report data.SalesReport) (data.SalesReport, *data.ErrorResponse)
+	GetAllReports() []data.SalesReport
+	SearchReports(criteria data.SalesReportSearchCriteria) ([]data.SalesReport, *data.ErrorResponse)
+	DeleteReportsBefore(cutoff time.Time) (int, *data.ErrorResponse)
+	CompactReportsBefore(cutoff time.Time) (int, *data.ErrorResponse)
+	AddReportDirectly(report data.SalesReport)
+}
@@ -0,0 +1,23 @@
+package Interfaces
+
+import (
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+type ScheduleStore interface {
+	CreateSchedule(schedule data.ReportSchedule) (data.ReportSchedule, *data.ErrorResponse)
+	GetSchedule(id int) (data.ReportSchedule, *data.ErrorResponse)
+	GetAllSchedules() []data.ReportSchedule
+	UpdateSchedule(id int, schedule data.ReportSchedule) (data.ReportSchedule, *data.ErrorResponse)
+	DeleteSchedule(id int) *data.ErrorResponse
+	SetRunTimes(id int, lastRunAt, nextRunAt *time.Time) *data.ErrorResponse
+
+	RecordRun(run data.ScheduleRun) data.ScheduleRun
+	GetRuns(scheduleID int) []data.ScheduleRun
+	GetAllRuns() []data.ScheduleRun
+
+	AddScheduleDirectly(schedule data.ReportSchedule)
+	AddRunDirectly(run data.ScheduleRun)
+}
@@ -0,0 +1,13 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type StockAlertStore interface {
+	CreateAlert(alert data.StockAlert) data.StockAlert
+	GetOpenAlert(bookID int) (data.StockAlert, bool)
+	ResolveAlert(id int) (data.StockAlert, *data.ErrorResponse)
+	GetAllAlerts() []data.StockAlert
+	AddAlertDirectly(alert data.StockAlert)
+}
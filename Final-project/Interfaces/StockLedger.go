@@ -0,0 +1,13 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type StockLedger interface {
+	RecordMovement(movement data.StockMovement) data.StockMovement
+	GetMovements(bookID int) []data.StockMovement
+	GetAllMovements() []data.StockMovement
+	Balance(bookID int) (int, bool)
+	AddMovementDirectly(movement data.StockMovement)
+}
@@ -0,0 +1,20 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type StockSubscriptionStore interface {
+	Subscribe(customerID, bookID int) (data.StockSubscription, bool)
+	GetSubscriptionsByCustomer(customerID int) []data.StockSubscription
+	GetSubscriptionsByBook(bookID int) []data.StockSubscription
+	GetAllSubscriptions() []data.StockSubscription
+	Unsubscribe(customerID, bookID int) (data.StockSubscription, *data.ErrorResponse)
+	ClaimSubscriptions(bookID int) []data.StockSubscription
+	AddSubscriptionDirectly(subscription data.StockSubscription)
+
+	RecordNotification(notification data.StockNotification) data.StockNotification
+	GetNotificationsByCustomer(customerID int) []data.StockNotification
+	GetAllNotifications() []data.StockNotification
+	AddNotificationDirectly(notification data.StockNotification)
+}
@@ -0,0 +1,14 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type StocktakeStore interface {
+	CreateStocktake(stocktake data.Stocktake) (data.Stocktake, *data.ErrorResponse)
+	GetStocktake(id int) (data.Stocktake, *data.ErrorResponse)
+	GetAllStocktakes() []data.Stocktake
+	RecordCounts(id int, counts []data.StocktakeCount) (data.Stocktake, *data.ErrorResponse)
+	CloseStocktake(id int, status data.StocktakeStatus, actor string) (data.Stocktake, *data.ErrorResponse)
+	AddStocktakeDirectly(stocktake data.Stocktake)
+}
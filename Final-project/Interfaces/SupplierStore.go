@@ -0,0 +1,14 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type SupplierStore interface {
+	CreateSupplier(supplier data.Supplier) (data.Supplier, *data.ErrorResponse)
+	GetSupplier(id int) (data.Supplier, *data.ErrorResponse)
+	GetAllSuppliers() []data.Supplier
+	UpdateSupplier(id int, supplier data.Supplier) (data.Supplier, *data.ErrorResponse)
+	DeleteSupplier(id int) *data.ErrorResponse
+	AddSupplierDirectly(supplier data.Supplier)
+}
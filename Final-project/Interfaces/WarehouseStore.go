@@ -0,0 +1,31 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type WarehouseStore interface {
+	CreateWarehouse(warehouse data.Warehouse) (data.Warehouse, *data.ErrorResponse)
+	GetWarehouse(id int) (data.Warehouse, *data.ErrorResponse)
+	GetAllWarehouses() []data.Warehouse
+	UpdateWarehouse(id int, warehouse data.Warehouse) (data.Warehouse, *data.ErrorResponse)
+	DeleteWarehouse(id int) *data.ErrorResponse
+	DefaultWarehouse() (data.Warehouse, *data.ErrorResponse)
+
+	GetStockLevels(bookID int) []data.StockLevel
+	GetWarehouseStock(warehouseID int) []data.StockLevel
+	GetAllStockLevels() []data.StockLevel
+	AdjustStock(bookID, warehouseID, delta int) (int, *data.ErrorResponse)
+	Allocate(bookID, quantity int, strategy data.AllocationStrategy, country string) ([]data.StockAllocation, *data.ErrorResponse)
+	Reallocate(released, requested []data.OrderItem, strategy data.AllocationStrategy, country string) ([]data.OrderItem, []data.OrderItem, *data.ErrorResponse)
+	Take(bookID, quantity int, allocations []data.StockAllocation) ([]data.StockAllocation, *data.ErrorResponse)
+	Release(bookID, quantity int, allocations []data.StockAllocation) []data.StockAllocation
+	SetFrozen(bookID, warehouseID int, frozen bool)
+
+	Transfer(transfer data.StockTransfer) (data.StockTransfer, *data.ErrorResponse)
+	SearchTransfers(criteria data.StockTransferSearchCriteria) []data.StockTransfer
+
+	AddWarehouseDirectly(warehouse data.Warehouse)
+	SetStockDirectly(level data.StockLevel)
+	AddTransferDirectly(transfer data.StockTransfer)
+}
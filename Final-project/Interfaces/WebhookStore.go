@@ -0,0 +1,21 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type WebhookStore interface {
+	CreateSubscription(subscription data.WebhookSubscription) (data.WebhookSubscription, *data.ErrorResponse)
+	GetSubscription(id int) (data.WebhookSubscription, *data.ErrorResponse)
+	GetAllSubscriptions() []data.WebhookSubscription
+	UpdateSubscription(id int, subscription data.WebhookSubscription) (data.WebhookSubscription, *data.ErrorResponse)
+	DeleteSubscription(id int) *data.ErrorResponse
+	AddSubscriptionDirectly(subscription data.WebhookSubscription)
+
+	CreateDelivery(delivery data.WebhookDelivery) data.WebhookDelivery
+	GetDelivery(id int) (data.WebhookDelivery, *data.ErrorResponse)
+	UpdateDelivery(id int, delivery data.WebhookDelivery) (data.WebhookDelivery, *data.ErrorResponse)
+	GetAllDeliveries() []data.WebhookDelivery
+	SearchDeliveries(criteria data.WebhookDeliverySearchCriteria) []data.WebhookDelivery
+	AddDeliveryDirectly(delivery data.WebhookDelivery)
+}
@@ -0,0 +1,13 @@
+package Interfaces
+
+import (
+	data "finalProject/StructureData"
+)
+
+type WishlistStore interface {
+	GetWishlist(customerID int) data.Wishlist
+	GetAllWishlists() []data.Wishlist
+	AddItem(customerID, bookID int) (data.WishlistItem, bool)
+	RemoveItem(customerID, bookID int) *data.ErrorResponse
+	AddWishlistDirectly(wishlist data.Wishlist)
+}
@@ -5,13 +5,65 @@ type Address struct {
 	City       string `json:"city"`
 	State      string `json:"state"`
 	PostalCode string `json:"postal_code"`
-	Country    string `json:"country"`
+	Country    string `json:"country"` // ISO 3166-1 alpha-2 code once normalized
+}
+
+// IsEmpty reports whether no field of the address is set
+func (a Address) IsEmpty() bool {
+	return a == Address{}
+}
+
+// AddressUse tells what an address book entry can be used for
+type AddressUse string
+
+const (
+	AddressUseAny      AddressUse = "any" // Shipping and billing
+	AddressUseShipping AddressUse = "shipping"
+	AddressUseBilling  AddressUse = "billing"
+)
+
+// IsValidAddressUse reports whether use is a known address use
+func IsValidAddressUse(use AddressUse) bool {
+	switch use {
+	case AddressUseAny, AddressUseShipping, AddressUseBilling:
+		return true
+	}
+	return false
+}
+
+// CustomerAddress is an entry of a customer's address book. The fields of the address are inlined.
+type CustomerAddress struct {
+	ID    int        `json:"id"`
+	Label string     `json:"label,omitempty"` // For example Home or Office
+	Use   AddressUse `json:"use"`
+	Address
+	DefaultShipping bool `json:"default_shipping"`
+	DefaultBilling  bool `json:"default_billing"`
+}
+
+// CanShip reports whether orders can be shipped to the address
+func (a CustomerAddress) CanShip() bool {
+	return a.Use != AddressUseBilling
+}
+
+// CanBill reports whether orders can be billed to the address
+func (a CustomerAddress) CanBill() bool {
+	return a.Use != AddressUseShipping
+}
+
+// OrderAddress is the ship-to or bill-to address of an order as it was when the order was placed
+type OrderAddress struct {
+	AddressID int    `json:"address_id,omitempty"` // The address book entry it was taken from; unset for a one-off address
+	Label     string `json:"label,omitempty"`
+	Address
 }
 
 type AddressSearchCriteria struct {
-	Streets     []string `json:"streets,omitempty"`       // Filter by street names
-	Cities      []string `json:"cities,omitempty"`        // Filter by city names
-	States      []string `json:"states,omitempty"`        // Filter by states
-	PostalCodes []string `json:"postal_codes,omitempty"`  // Filter by postal codes
-	Countries   []string `json:"countries,omitempty"`     // Filter by countries
+	Streets     []string     `json:"streets,omitempty"`      // Filter by street names
+	Cities      []string     `json:"cities,omitempty"`       // Filter by city names
+	States      []string     `json:"states,omitempty"`       // Filter by states
+	PostalCodes []string     `json:"postal_codes,omitempty"` // Filter by postal codes
+	Countries   []string     `json:"countries,omitempty"`    // Filter by countries
+	Labels      []string     `json:"labels,omitempty"`       // Filter by address book labels
+	Uses        []AddressUse `json:"uses,omitempty"`         // Filter by what the addresses can be used for
 }
@@ -0,0 +1,70 @@
+package StructureData
+
+import "time"
+
+// AnalyticsInterval is the size of the buckets of a time series
+type AnalyticsInterval string
+
+const (
+	IntervalDay   AnalyticsInterval = "day"
+	IntervalWeek  AnalyticsInterval = "week" // ISO week, starting on Monday
+	IntervalMonth AnalyticsInterval = "month"
+)
+
+// RevenueFigures are the sales of a bucket or a range. Orders are counted once per bucket, or once per
+// series and bucket when the series are split.
+type RevenueFigures struct {
+	Revenue float64 `json:"revenue"`
+	Units   int     `json:"units"`
+	Orders  int     `json:"orders"`
+}
+
+// RevenueAverages are the figures averaged over the buckets of the moving average window
+type RevenueAverages struct {
+	Revenue float64 `json:"revenue"`
+	Units   float64 `json:"units"`
+	Orders  float64 `json:"orders"`
+}
+
+// MetricDelta compares a figure with its value in an earlier bucket
+type MetricDelta struct {
+	Previous float64  `json:"previous"`
+	Change   float64  `json:"change"`
+	Percent  *float64 `json:"percent"` // Null when the previous value is zero
+}
+
+// RevenueComparison compares the figures of a bucket with those of an earlier bucket
+type RevenueComparison struct {
+	Period  string      `json:"period"` // The earlier bucket
+	Revenue MetricDelta `json:"revenue"`
+	Units   MetricDelta `json:"units"`
+	Orders  MetricDelta `json:"orders"`
+}
+
+// RevenuePoint is one bucket of a time series. The moving average covers the bucket and the ones before it.
+type RevenuePoint struct {
+	Period string    `json:"period"` // 2025-01-13, 2025-W02 or 2025-01
+	Start  time.Time `json:"start"`
+	RevenueFigures
+	MovingAverage  RevenueAverages   `json:"moving_average"`
+	PreviousPeriod RevenueComparison `json:"previous_period"`
+	YearOverYear   RevenueComparison `json:"year_over_year"`
+}
+
+// RevenueSeries is the time series of all sales, or of a genre or author when the analytics are split
+type RevenueSeries struct {
+	Split  *ReportGroupKey `json:"split,omitempty"`
+	Points []RevenuePoint  `json:"points"`
+	Totals RevenueFigures  `json:"totals"`
+}
+
+// RevenueAnalytics are the revenue, units and orders of a time range in buckets, with no gaps
+type RevenueAnalytics struct {
+	Interval            AnalyticsInterval `json:"interval"`
+	From                time.Time         `json:"from"` // Start of the first bucket
+	To                  time.Time         `json:"to"`
+	TimeZone            string            `json:"time_zone"`
+	SplitBy             ReportDimension   `json:"split_by,omitempty"` // genre or author
+	MovingAverageWindow int               `json:"moving_average_window"`
+	Series              []RevenueSeries   `json:"series"`
+}
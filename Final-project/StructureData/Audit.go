@@ -0,0 +1,55 @@
+package StructureData
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction is the kind of change recorded by an audit event
+type AuditAction string
+
+const (
+	AuditCreate      AuditAction = "create"
+	AuditUpdate      AuditAction = "update"
+	AuditDelete      AuditAction = "delete"
+	AuditRestore     AuditAction = "restore"
+	AuditPurge       AuditAction = "purge"
+	AuditStockChange AuditAction = "stock_change" // Stock moved at a warehouse, recorded apart from the book update
+)
+
+// AuditContext identifies who made a change and the request it came from
+type AuditContext struct {
+	Actor     string `json:"actor"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// AuditEvent is an immutable record of a single change to an entity
+type AuditEvent struct {
+	ID         int           `json:"id"`
+	Resource   string        `json:"resource"`
+	ResourceID int           `json:"resource_id"`
+	Action     AuditAction   `json:"action"`
+	Actor      string        `json:"actor"`
+	RequestID  string        `json:"request_id,omitempty"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Changes    []FieldChange `json:"changes"`
+}
+
+// FieldChange holds the JSON value of a field before and after a change. A missing value means
+// the field did not exist, as for every field of a created record.
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+type AuditSearchCriteria struct {
+	Resources    []string      `json:"resources,omitempty"`
+	ResourceIDs  []int         `json:"resource_ids,omitempty"`
+	Actions      []AuditAction `json:"actions,omitempty"`
+	Actors       []string      `json:"actors,omitempty"`
+	RequestIDs   []string      `json:"request_ids,omitempty"`
+	Fields       []string      `json:"fields,omitempty"` // Only events changing one of these fields
+	MinTimestamp time.Time     `json:"min_timestamp,omitempty"`
+	MaxTimestamp time.Time     `json:"max_timestamp,omitempty"`
+}
@@ -5,6 +5,7 @@ type Author struct {
 	FirstName string `json:"first_name"`
 	LastName string `json:"last_name"`
 	Bio string `json:"bio"`
+	SoftDelete
    }
    
    type AuthorSearchCriteria struct {
@@ -5,13 +5,19 @@ import ("time")
 
 
 type Book struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Author      Author    `json:"author"`
-	Genres      []string  `json:"genres"`
-	PublishedAt time.Time `json:"published_at"`
-	Price       float64   `json:"price"`
-	Stock       int       `json:"stock"`
+	ID               int            `json:"id"`
+	Title            string         `json:"title"`
+	AuthorID         int            `json:"author_id"`
+	Genres           []string       `json:"genres"`
+	PublishedAt      time.Time      `json:"published_at"`
+	Price            float64        `json:"price"`
+	Stock            int            `json:"stock"`
+	ReorderThreshold int            `json:"reorder_threshold,omitempty"` // A low-stock alert is raised when stock falls to this level
+	TargetStock      int            `json:"target_stock,omitempty"`      // Level replenishment suggestions restock up to
+	CostPrice        float64        `json:"cost_price,omitempty"`        // Unit cost of the last goods receipt
+	Author           *Author        `json:"author,omitempty"`            // Only set when expanded with ?expand=author
+	Rating           *RatingSummary `json:"rating,omitempty"`            // Only set when expanded with ?expand=rating
+	SoftDelete
 }
 type BookSearchCriteria struct {
 	IDs            []int       `json:"ids,omitempty"`
@@ -24,4 +30,32 @@ type BookSearchCriteria struct {
 	MinStock       int         `json:"min_stock,omitempty"`
 	MaxStock       int         `json:"max_stock,omitempty"`
 	AuthorCriteria AuthorSearchCriteria `json:"author_criteria,omitempty"`
-}
\ No newline at end of file
+
+	// Average rating and number of the approved reviews
+	MinRating      float64       `json:"min_rating,omitempty"`
+	MaxRating      float64       `json:"max_rating,omitempty"`
+	MinReviewCount int           `json:"min_review_count,omitempty"`
+	SortBy         BookSortField `json:"sort_by,omitempty"`    // Only used by the book search
+	SortOrder      string        `json:"sort_order,omitempty"` // asc or desc; rating and review_count default to desc
+}
+
+// BookSortField is the field the book search sorts its results by
+type BookSortField string
+
+const (
+	SortByTitle       BookSortField = "title"
+	SortByPrice       BookSortField = "price"
+	SortByPublishedAt BookSortField = "published_at"
+	SortByStock       BookSortField = "stock"
+	SortByRating      BookSortField = "rating"
+	SortByReviewCount BookSortField = "review_count"
+)
+
+// IsValidBookSortField reports whether field is one of the sortable book fields
+func IsValidBookSortField(field BookSortField) bool {
+	switch field {
+	case SortByTitle, SortByPrice, SortByPublishedAt, SortByStock, SortByRating, SortByReviewCount:
+		return true
+	}
+	return false
+}
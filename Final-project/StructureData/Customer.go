@@ -3,11 +3,33 @@ package StructureData
 import "time"
 
 type Customer struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Address   Address   `json:"address"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	Email     string            `json:"email"`
+	Address   Address           `json:"address"`   // The default shipping address, kept for clients reading a single address
+	Addresses []CustomerAddress `json:"addresses"` // The address book
+	CreatedAt time.Time         `json:"created_at"`
+	SoftDelete
+}
+
+// DefaultAddress returns the default shipping or billing address of the address book, if any
+func (c Customer) DefaultAddress(use AddressUse) (CustomerAddress, bool) {
+	for _, address := range c.Addresses {
+		if use == AddressUseShipping && address.DefaultShipping || use == AddressUseBilling && address.DefaultBilling {
+			return address, true
+		}
+	}
+	return CustomerAddress{}, false
+}
+
+// FindAddress returns the address book entry with the given ID
+func (c Customer) FindAddress(id int) (CustomerAddress, bool) {
+	for _, address := range c.Addresses {
+		if address.ID == id {
+			return address, true
+		}
+	}
+	return CustomerAddress{}, false
 }
 
 type CustomerSearchCriteria struct {
@@ -0,0 +1,98 @@
+package StructureData
+
+import "time"
+
+// GenreSpend is what a customer spent on the books of a genre. A book of several genres counts for each.
+type GenreSpend struct {
+	Genre string  `json:"genre"`
+	Units int     `json:"units"`
+	Spend float64 `json:"spend"`
+}
+
+// CustomerStats sums up the orders of a customer
+type CustomerStats struct {
+	CustomerID         int          `json:"customer_id"`
+	Name               string       `json:"name"`
+	CustomerSince      time.Time    `json:"customer_since"`
+	OrderCount         int          `json:"order_count"`
+	UnitsBought        int          `json:"units_bought"`
+	LifetimeSpend      float64      `json:"lifetime_spend"`
+	AverageBasket      float64      `json:"average_basket"`   // Spend per order
+	FavouriteGenres    []GenreSpend `json:"favourite_genres"` // Highest spend first, three at most
+	FirstOrderAt       *time.Time   `json:"first_order_at,omitempty"`
+	LastOrderAt        *time.Time   `json:"last_order_at,omitempty"`
+	DaysSinceLastOrder *int         `json:"days_since_last_order,omitempty"`
+}
+
+// CustomerSegment is the group a customer falls in by the recency and frequency scores of their orders
+type CustomerSegment string
+
+const (
+	SegmentChampions         CustomerSegment = "champions"          // Bought recently and often
+	SegmentLoyal             CustomerSegment = "loyal"              // Buy often, fairly recently
+	SegmentNew               CustomerSegment = "new"                // Bought recently for the first time
+	SegmentPotentialLoyalist CustomerSegment = "potential_loyalist" // Bought recently, a few times
+	SegmentCantLose          CustomerSegment = "cant_lose"          // Bought very often, but long ago
+	SegmentAtRisk            CustomerSegment = "at_risk"            // Bought often, but not lately
+	SegmentHibernating       CustomerSegment = "hibernating"        // Bought rarely and long ago
+)
+
+// CustomerSegments lists the segments, best first
+var CustomerSegments = []CustomerSegment{
+	SegmentChampions, SegmentLoyal, SegmentNew, SegmentPotentialLoyalist, SegmentCantLose, SegmentAtRisk, SegmentHibernating,
+}
+
+// CustomerRFM scores a customer from 1 to 5 on the recency, frequency and monetary value of their orders,
+// by their rank among the customers with orders
+type CustomerRFM struct {
+	CustomerID     int             `json:"customer_id"`
+	Name           string          `json:"name"`
+	RecencyDays    int             `json:"recency_days"` // Days since the last order
+	Frequency      int             `json:"frequency"`    // Number of orders
+	Monetary       float64         `json:"monetary"`     // Lifetime spend
+	RecencyScore   int             `json:"recency_score"`
+	FrequencyScore int             `json:"frequency_score"`
+	MonetaryScore  int             `json:"monetary_score"`
+	RFMScore       string          `json:"rfm_score"` // The three scores, like 545
+	Segment        CustomerSegment `json:"segment"`
+}
+
+// SegmentSummary counts the customers of a segment and their spend
+type SegmentSummary struct {
+	Segment   CustomerSegment `json:"segment"`
+	Customers int             `json:"customers"`
+	Share     float64         `json:"share"` // Percent of the customers with orders
+	Revenue   float64         `json:"revenue"`
+}
+
+// CustomerSegmentation is the RFM score and segment of every customer with orders
+type CustomerSegmentation struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Segments    []SegmentSummary `json:"segments"`
+	Customers   []CustomerRFM    `json:"customers"` // Highest scores first
+}
+
+// CohortPeriod is how many customers of a cohort ordered in a month, Offset months after they signed up
+type CohortPeriod struct {
+	Offset    int     `json:"offset"`
+	Period    string  `json:"period"`
+	Customers int     `json:"customers"`
+	Rate      float64 `json:"rate"` // Percent of the cohort
+}
+
+// Cohort is the customers who signed up in a month, with their retention in every month since
+type Cohort struct {
+	Cohort    string         `json:"cohort"` // Signup month, like 2025-01
+	Start     time.Time      `json:"start"`
+	Customers int            `json:"customers"`
+	Periods   []CohortPeriod `json:"periods"`
+}
+
+// CohortRetention is the retention matrix of the customers by signup month
+type CohortRetention struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	TimeZone    string    `json:"time_zone"`
+	From        string    `json:"from"` // First signup month
+	To          string    `json:"to"`   // Last signup month
+	Cohorts     []Cohort  `json:"cohorts"`
+}
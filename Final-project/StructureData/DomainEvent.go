@@ -0,0 +1,99 @@
+package StructureData
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventType names a domain event published on the event bus
+type EventType string
+
+const (
+	EventAuthorCreated  EventType = "AuthorCreated"
+	EventAuthorUpdated  EventType = "AuthorUpdated"
+	EventAuthorDeleted  EventType = "AuthorDeleted"
+	EventAuthorRestored EventType = "AuthorRestored"
+	EventAuthorPurged   EventType = "AuthorPurged"
+
+	EventBookCreated      EventType = "BookCreated"
+	EventBookUpdated      EventType = "BookUpdated"
+	EventBookDeleted      EventType = "BookDeleted"
+	EventBookRestored     EventType = "BookRestored"
+	EventBookPurged       EventType = "BookPurged"
+	EventBookStockChanged EventType = "BookStockChanged"
+
+	EventCustomerCreated  EventType = "CustomerCreated"
+	EventCustomerUpdated  EventType = "CustomerUpdated"
+	EventCustomerDeleted  EventType = "CustomerDeleted"
+	EventCustomerRestored EventType = "CustomerRestored"
+	EventCustomerPurged   EventType = "CustomerPurged"
+
+	EventOrderCreated  EventType = "OrderCreated"
+	EventOrderUpdated  EventType = "OrderUpdated"
+	EventOrderDeleted  EventType = "OrderDeleted"
+	EventOrderRestored EventType = "OrderRestored"
+	EventOrderPurged   EventType = "OrderPurged"
+
+	EventBookLowStock    EventType = "BookLowStock"    // Published by the inventory monitor, not for an audited change
+	EventBookBackInStock EventType = "BookBackInStock" // Published by the back-in-stock monitor when subscribers are notified
+
+	EventInvoiceIssued    EventType = "InvoiceIssued"    // Published by the invoicer with the invoice as payload
+	EventCreditNoteIssued EventType = "CreditNoteIssued" // Published by the invoicer with the credit note as payload
+)
+
+// standaloneEventTypes are published directly rather than for an audited change
+var standaloneEventTypes = []EventType{EventBookLowStock, EventBookBackInStock, EventInvoiceIssued, EventCreditNoteIssued}
+
+// domainEventTypes maps every audited change to the event published for it
+var domainEventTypes = map[string]map[AuditAction]EventType{
+	ResourceAuthors: {
+		AuditCreate: EventAuthorCreated, AuditUpdate: EventAuthorUpdated, AuditDelete: EventAuthorDeleted,
+		AuditRestore: EventAuthorRestored, AuditPurge: EventAuthorPurged,
+	},
+	ResourceBooks: {
+		AuditCreate: EventBookCreated, AuditUpdate: EventBookUpdated, AuditDelete: EventBookDeleted,
+		AuditRestore: EventBookRestored, AuditPurge: EventBookPurged, AuditStockChange: EventBookStockChanged,
+	},
+	ResourceCustomers: {
+		AuditCreate: EventCustomerCreated, AuditUpdate: EventCustomerUpdated, AuditDelete: EventCustomerDeleted,
+		AuditRestore: EventCustomerRestored, AuditPurge: EventCustomerPurged,
+	},
+	ResourceOrders: {
+		AuditCreate: EventOrderCreated, AuditUpdate: EventOrderUpdated, AuditDelete: EventOrderDeleted,
+		AuditRestore: EventOrderRestored, AuditPurge: EventOrderPurged,
+	},
+}
+
+// DomainEvent is published on the event bus whenever an entity changes
+type DomainEvent struct {
+	ID         int             `json:"id"`
+	Type       EventType       `json:"type"`
+	Resource   string          `json:"resource"`
+	ResourceID int             `json:"resource_id"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data,omitempty"` // The record after the change, or before it when purged
+}
+
+// DomainEventType returns the event published for an audited change, or an empty type if there is none
+func DomainEventType(resource string, action AuditAction) EventType {
+	return domainEventTypes[resource][action]
+}
+
+// IsValidEventType reports whether eventType is published by the system
+func IsValidEventType(eventType EventType) bool {
+	for _, t := range standaloneEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	for _, types := range domainEventTypes {
+		for _, t := range types {
+			if t == eventType {
+				return true
+			}
+		}
+	}
+	return false
+}
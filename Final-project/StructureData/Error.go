@@ -1,8 +1,105 @@
 package StructureData
 
+// ErrorCode classifies an ErrorResponse so callers can react to the kind of
+// failure instead of parsing its message.
+type ErrorCode string
+
+const (
+	ErrorCodeNotFound          ErrorCode = "not_found"
+	ErrorCodeConflict          ErrorCode = "conflict"
+	ErrorCodeValidation        ErrorCode = "validation"
+	ErrorCodeInsufficientStock ErrorCode = "insufficient_stock"
+	ErrorCodeNotAcceptable     ErrorCode = "not_acceptable"
+	ErrorCodeCanceled          ErrorCode = "canceled"
+	ErrorCodeInternal          ErrorCode = "internal"
+)
+
 type ErrorResponse struct {
-	Message string `json:"error"`
+	Code    ErrorCode `json:"code,omitempty"`
+	Message string    `json:"error"`
+	Err     error     `json:"-"` // Underlying cause, if any
 }
+
+// Error returns the message followed by the underlying cause, if any. Clients are only sent the message.
 func (e *ErrorResponse) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
 	return e.Message
-}
\ No newline at end of file
+}
+
+// Unwrap exposes the underlying cause to errors.Is and errors.As
+func (e *ErrorResponse) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel for the same error code, so that
+// errors.Is(err, ErrNotFound) matches any not-found error regardless of its message
+func (e *ErrorResponse) Is(target error) bool {
+	sentinel, ok := target.(*ErrorResponse)
+	if !ok || sentinel.Message != "" {
+		return false
+	}
+	return e.ErrorCode() == sentinel.Code
+}
+
+// ErrorCode returns the error's code, treating uncoded errors as internal
+func (e *ErrorResponse) ErrorCode() ErrorCode {
+	if e.Code == "" {
+		return ErrorCodeInternal
+	}
+	return e.Code
+}
+
+// Sentinel errors to be used with errors.Is
+var (
+	ErrNotFound          = &ErrorResponse{Code: ErrorCodeNotFound}
+	ErrConflict          = &ErrorResponse{Code: ErrorCodeConflict}
+	ErrValidation        = &ErrorResponse{Code: ErrorCodeValidation}
+	ErrInsufficientStock = &ErrorResponse{Code: ErrorCodeInsufficientStock}
+	ErrNotAcceptable     = &ErrorResponse{Code: ErrorCodeNotAcceptable}
+	ErrCanceled          = &ErrorResponse{Code: ErrorCodeCanceled}
+	ErrInternal          = &ErrorResponse{Code: ErrorCodeInternal}
+)
+
+func NewNotFoundError(message string) *ErrorResponse {
+	return &ErrorResponse{Code: ErrorCodeNotFound, Message: message}
+}
+
+func NewConflictError(message string) *ErrorResponse {
+	return &ErrorResponse{Code: ErrorCodeConflict, Message: message}
+}
+
+func NewValidationError(message string) *ErrorResponse {
+	return &ErrorResponse{Code: ErrorCodeValidation, Message: message}
+}
+
+func NewInsufficientStockError(message string) *ErrorResponse {
+	return &ErrorResponse{Code: ErrorCodeInsufficientStock, Message: message}
+}
+
+// NewNotAcceptableError reports that none of the formats a client accepts can be produced
+func NewNotAcceptableError(message string) *ErrorResponse {
+	return &ErrorResponse{Code: ErrorCodeNotAcceptable, Message: message}
+}
+
+// NewCanceledError reports that a request was canceled before it completed; err may be nil
+func NewCanceledError(message string, err error) *ErrorResponse {
+	return &ErrorResponse{Code: ErrorCodeCanceled, Message: message, Err: err}
+}
+
+// NewInternalError wraps an unexpected failure; err may be nil. The message is what clients see, so it
+// should stay generic: the cause is kept in Err for the logs.
+func NewInternalError(message string, err error) *ErrorResponse {
+	return &ErrorResponse{Code: ErrorCodeInternal, Message: message, Err: err}
+}
+
+// ProblemDetails is the RFC 7807 "application/problem+json" body returned by the API on errors
+type ProblemDetails struct {
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Status   int       `json:"status"`
+	Detail   string    `json:"detail,omitempty"`
+	Instance string    `json:"instance,omitempty"`
+	Code     ErrorCode `json:"code"`
+}
@@ -0,0 +1,67 @@
+package StructureData
+
+import "time"
+
+// ForecastMethod is the model predicting the weekly demand of a book
+type ForecastMethod string
+
+const (
+	ForecastAuto                 ForecastMethod = "auto" // Exponential smoothing, or a moving average for short histories
+	ForecastMovingAverage        ForecastMethod = "moving_average"
+	ForecastExponentialSmoothing ForecastMethod = "exponential_smoothing" // Damped Holt-Winters, seasonal once two seasons of history exist
+)
+
+// ForecastPoint is the predicted units of one week, with the bounds of the confidence band
+type ForecastPoint struct {
+	Period string    `json:"period"` // ISO week, like 2025-W02
+	Start  time.Time `json:"start"`
+	Units  float64   `json:"units"`
+	Lower  float64   `json:"lower"`
+	Upper  float64   `json:"upper"`
+}
+
+// WeeklyUnits is the units of a book sold in one week of its history
+type WeeklyUnits struct {
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+	Units  int       `json:"units"`
+}
+
+// ForecastParameters are the smoothing parameters fitted to the history of a book
+type ForecastParameters struct {
+	Alpha float64 `json:"alpha"`           // Level
+	Beta  float64 `json:"beta"`            // Trend
+	Gamma float64 `json:"gamma,omitempty"` // Seasonality
+}
+
+// BookForecast is the predicted demand of a book for the coming weeks. The history starts at the week of the
+// book's first sale in the history range and ends with the last complete week; the forecast starts with the
+// current week.
+type BookForecast struct {
+	BookID        int                 `json:"book_id"`
+	Title         string              `json:"title"`
+	Method        ForecastMethod      `json:"method"` // The method used, never auto
+	Seasonal      bool                `json:"seasonal"`
+	SeasonWeeks   int                 `json:"season_weeks,omitempty"`
+	Window        int                 `json:"window,omitempty"` // Weeks averaged by the moving average
+	Parameters    *ForecastParameters `json:"parameters,omitempty"`
+	Confidence    float64             `json:"confidence"`
+	MeanAbsError  float64             `json:"mean_absolute_error"` // Of the one-step-ahead predictions over the history
+	HistoryWeeks  int                 `json:"history_weeks"`
+	UnitsSold     int                 `json:"units_sold"` // Over the history
+	History       []WeeklyUnits       `json:"history,omitempty"`
+	Forecast      []ForecastPoint     `json:"forecast"`
+	ForecastUnits float64             `json:"forecast_units"` // Total of the forecast weeks
+	ForecastLower float64             `json:"forecast_lower"`
+	ForecastUpper float64             `json:"forecast_upper"`
+}
+
+// DemandForecast is the forecast of every book sold in the history range, highest demand first
+type DemandForecast struct {
+	GeneratedAt  time.Time      `json:"generated_at"`
+	Weeks        int            `json:"weeks"`
+	HistoryWeeks int            `json:"history_weeks"`
+	Method       ForecastMethod `json:"method"`
+	Confidence   float64        `json:"confidence"`
+	Books        []BookForecast `json:"books"`
+}
@@ -0,0 +1,130 @@
+package StructureData
+
+import "time"
+
+// GiftCardStatus tells whether a gift card can still be spent
+type GiftCardStatus string
+
+const (
+	GiftCardActive  GiftCardStatus = "active"
+	GiftCardExpired GiftCardStatus = "expired" // Past its expiry date; the balance left was forfeited
+)
+
+// GiftCardTransactionType is the reason a gift card's balance changed
+type GiftCardTransactionType string
+
+const (
+	GiftCardIssue          GiftCardTransactionType = "issue"           // The card was sold with its initial balance
+	GiftCardRedemption     GiftCardTransactionType = "redemption"      // Spent on an order
+	GiftCardCreditTransfer GiftCardTransactionType = "credit_transfer" // Moved to a customer's store credit
+	GiftCardExpiry         GiftCardTransactionType = "expiry"          // Forfeited when the card expired
+)
+
+// GiftCard is a prepaid card identified by its code. It can be spent on any number of orders until its
+// balance runs out or it expires.
+type GiftCard struct {
+	ID             int                   `json:"id"`
+	Code           string                `json:"code"` // For example 7KQ2-M9XD-4HTP-WB3C
+	Status         GiftCardStatus        `json:"status"`
+	InitialBalance float64               `json:"initial_balance"`
+	Balance        float64               `json:"balance"`
+	CustomerID     int                   `json:"customer_id,omitempty"` // The customer the card was sold to, if known
+	Note           string                `json:"note,omitempty"`
+	IssuedAt       time.Time             `json:"issued_at"`
+	IssuedBy       string                `json:"issued_by"`
+	ExpiresAt      time.Time             `json:"expires_at"`
+	Transactions   []GiftCardTransaction `json:"transactions"`
+}
+
+// Expired reports whether the card can no longer be spent at a time
+func (c GiftCard) Expired(at time.Time) bool {
+	return c.Status == GiftCardExpired || !at.Before(c.ExpiresAt)
+}
+
+// GiftCardTransaction is one change of a gift card's balance, negative when money is taken off the card
+type GiftCardTransaction struct {
+	Type         GiftCardTransactionType `json:"type"`
+	Amount       float64                 `json:"amount"`
+	BalanceAfter float64                 `json:"balance_after"`
+	OrderID      int                     `json:"order_id,omitempty"`
+	CustomerID   int                     `json:"customer_id,omitempty"` // The customer credited by a transfer
+	Actor        string                  `json:"actor"`
+	CreatedAt    time.Time               `json:"created_at"`
+}
+
+// GiftCardIssueRequest asks to sell a gift card. Cards expire a year after they are issued unless
+// expires_at is given.
+type GiftCardIssueRequest struct {
+	Amount     float64    `json:"amount"`
+	CustomerID int        `json:"customer_id,omitempty"`
+	Note       string     `json:"note,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+type GiftCardSearchCriteria struct {
+	IDs         []int            `json:"ids,omitempty"`
+	Codes       []string         `json:"codes,omitempty"`
+	Statuses    []GiftCardStatus `json:"statuses,omitempty"`
+	CustomerIDs []int            `json:"customer_ids,omitempty"`
+}
+
+// StoreCreditEntryType is the reason a customer's store credit changed
+type StoreCreditEntryType string
+
+const (
+	StoreCreditGiftCard   StoreCreditEntryType = "gift_card"  // The balance of a gift card moved to the credit
+	StoreCreditRefund     StoreCreditEntryType = "refund"     // An order or return refunded to the credit
+	StoreCreditPayment    StoreCreditEntryType = "payment"    // Spent on an order
+	StoreCreditAdjustment StoreCreditEntryType = "adjustment" // Changed by hand by staff
+)
+
+// StoreCreditEntry is one entry of a customer's append-only store-credit ledger. The entries of a customer
+// sum to the balance, which never goes below zero. Store credit does not expire.
+type StoreCreditEntry struct {
+	ID           int                  `json:"id"`
+	CustomerID   int                  `json:"customer_id"`
+	Type         StoreCreditEntryType `json:"type"`
+	Amount       float64              `json:"amount"` // Positive when credit is added, negative when it is spent
+	BalanceAfter float64              `json:"balance_after"`
+	OrderID      int                  `json:"order_id,omitempty"`
+	GiftCardID   int                  `json:"gift_card_id,omitempty"`
+	ReturnID     int                  `json:"return_id,omitempty"`
+	Note         string               `json:"note,omitempty"`
+	Actor        string               `json:"actor"`
+	CreatedAt    time.Time            `json:"created_at"`
+}
+
+// StoreCreditAccount is a customer's store-credit balance and ledger
+type StoreCreditAccount struct {
+	CustomerID int                `json:"customer_id"`
+	Balance    float64            `json:"balance"`
+	Entries    []StoreCreditEntry `json:"entries"`
+}
+
+// StoreCreditRequest changes a customer's store credit by hand, or moves a gift card's balance to it
+// when gift_card_code is given instead of an amount
+type StoreCreditRequest struct {
+	Amount       float64 `json:"amount,omitempty"`
+	Note         string  `json:"note,omitempty"`
+	GiftCardCode string  `json:"gift_card_code,omitempty"`
+}
+
+// PaymentMethod is how part of an order was paid before checkout completed
+type PaymentMethod string
+
+const (
+	PaymentGiftCard     PaymentMethod = "gift_card"
+	PaymentStoreCredit  PaymentMethod = "store_credit"
+	PaymentCreditRefund PaymentMethod = "store_credit_refund" // Paid back to the customer's store credit
+)
+
+// OrderPayment is an amount paid towards an order with a gift card or store credit, or refunded from it to
+// the customer's store credit with a negative amount
+type OrderPayment struct {
+	Method       PaymentMethod `json:"method"`
+	Amount       float64       `json:"amount"`
+	GiftCardID   int           `json:"gift_card_id,omitempty"`
+	GiftCardCode string        `json:"gift_card_code,omitempty"` // Masked except for the last group
+	Reason       string        `json:"reason,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
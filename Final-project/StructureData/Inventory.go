@@ -0,0 +1,46 @@
+package StructureData
+
+import "time"
+
+// StockAlert is raised when a book's stock falls to its reorder threshold, and resolved once it is restocked above it
+type StockAlert struct {
+	ID         int        `json:"id"`
+	BookID     int        `json:"book_id"`
+	Title      string     `json:"title"`
+	Stock      int        `json:"stock"`
+	Threshold  int        `json:"threshold"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// IsOpen reports whether the alert has not been resolved yet
+func (a StockAlert) IsOpen() bool {
+	return a.ResolvedAt == nil
+}
+
+// ReplenishmentSuggestion is the reorder quantity suggested for one book
+type ReplenishmentSuggestion struct {
+	BookID            int      `json:"book_id"`
+	Title             string   `json:"title"`
+	Stock             int      `json:"stock"`
+	ReorderThreshold  int      `json:"reorder_threshold"`
+	TargetStock       int      `json:"target_stock"`
+	UnitsSold         int      `json:"units_sold"`              // Over the report window
+	DailyVelocity     float64  `json:"daily_velocity"`          // Average units sold per day over the window, or forecast after the lead time
+	DaysOfCover       *float64 `json:"days_of_cover,omitempty"` // How long the stock lasts at the current velocity; unset if nothing sold
+	ReorderLevel      int      `json:"reorder_level"`           // Threshold plus the expected sales during the lead time, and the safety stock
+	SuggestedQuantity int      `json:"suggested_quantity"`
+
+	// Set when the demand is forecast
+	ForecastMethod ForecastMethod `json:"forecast_method,omitempty"`
+	LeadTimeDemand *float64       `json:"lead_time_demand,omitempty"` // Forecast units during the lead time
+	SafetyStock    *int           `json:"safety_stock,omitempty"`     // Up to the upper bound of the lead time demand
+}
+
+type ReplenishmentReport struct {
+	GeneratedAt  time.Time                 `json:"generated_at"`
+	WindowDays   int                       `json:"window_days"`
+	LeadTimeDays int                       `json:"lead_time_days"`
+	Demand       string                    `json:"demand"` // history or forecast
+	Suggestions  []ReplenishmentSuggestion `json:"suggestions"`
+}
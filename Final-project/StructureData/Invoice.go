@@ -0,0 +1,108 @@
+package StructureData
+
+import "time"
+
+// InvoiceType tells an invoice from a credit note. Both are numbered in their own gap-free sequence per
+// fiscal year.
+type InvoiceType string
+
+const (
+	InvoiceTypeInvoice    InvoiceType = "invoice"
+	InvoiceTypeCreditNote InvoiceType = "credit_note"
+)
+
+// InvoiceStatus tells how much of an invoice was credited. Credit notes are always issued.
+type InvoiceStatus string
+
+const (
+	InvoiceIssued            InvoiceStatus = "issued"
+	InvoicePartiallyCredited InvoiceStatus = "partially_credited"
+	InvoiceCredited          InvoiceStatus = "credited" // Nothing is left to credit
+)
+
+// Invoice is an accounting document for an order: an invoice, or a credit note refunding part or all of an
+// invoice. Documents are never changed once issued, except for what was credited of an invoice. Amounts of
+// credit notes are positive.
+type Invoice struct {
+	ID               int           `json:"id"`
+	Number           string        `json:"number"` // For example INV-2025-00001
+	Type             InvoiceType   `json:"type"`
+	Status           InvoiceStatus `json:"status"`
+	FiscalYear       int           `json:"fiscal_year"` // The calendar year the fiscal year starts in
+	Sequence         int           `json:"sequence"`    // Position in the sequence of the type and fiscal year
+	OrderID          int           `json:"order_id"`
+	CustomerID       int           `json:"customer_id"`
+	InvoiceID        int           `json:"invoice_id,omitempty"`     // The invoice a credit note refunds
+	InvoiceNumber    string        `json:"invoice_number,omitempty"` // The number of that invoice
+	Reason           string        `json:"reason,omitempty"`         // Why a credit note was issued
+	Seller           InvoiceParty  `json:"seller"`
+	Customer         InvoiceParty  `json:"customer"`
+	Lines            []InvoiceLine `json:"lines"`
+	Currency         string        `json:"currency"`
+	PricesIncludeTax bool          `json:"prices_include_tax"`
+	NetTotal         float64       `json:"net_total"`
+	TaxTotal         float64       `json:"tax_total"`
+	Total            float64       `json:"total"`
+	CreditedTotal    float64       `json:"credited_total,omitempty"` // Invoices only
+	IssuedAt         time.Time     `json:"issued_at"`
+}
+
+// InvoiceParty is the seller or the customer as printed on an invoice
+type InvoiceParty struct {
+	Name    string  `json:"name"`
+	Email   string  `json:"email,omitempty"`
+	TaxID   string  `json:"tax_id,omitempty"`
+	Address Address `json:"address"`
+}
+
+// InvoiceLine is one order item on an invoice, or the part of it refunded by a credit note
+type InvoiceLine struct {
+	BookID           int     `json:"book_id"`
+	Description      string  `json:"description"`
+	Quantity         int     `json:"quantity"`
+	UnitPrice        float64 `json:"unit_price"` // As on the order, including tax if the prices do
+	TaxRate          float64 `json:"tax_rate"`   // For example 0.2 for 20%
+	NetAmount        float64 `json:"net_amount"`
+	TaxAmount        float64 `json:"tax_amount"`
+	Total            float64 `json:"total"`
+	CreditedQuantity int     `json:"credited_quantity,omitempty"` // Invoices only
+}
+
+// InvoiceSettings are the seller's details and the tax rules applied to the documents issued afterwards
+type InvoiceSettings struct {
+	Seller               InvoiceParty       `json:"seller"`
+	Currency             string             `json:"currency"`                // ISO 4217 code
+	TaxRate              float64            `json:"tax_rate"`                // For example 0.2 for 20%
+	TaxRates             map[string]float64 `json:"tax_rates,omitempty"`     // By country of the customer's billing address, overriding TaxRate
+	PricesIncludeTax     bool               `json:"prices_include_tax"`      // Whether book prices include the tax
+	FiscalYearStartMonth int                `json:"fiscal_year_start_month"` // 1 for January
+	TimeZone             string             `json:"time_zone,omitempty"`     // Time zone of the fiscal years, UTC by default
+	InvoicePrefix        string             `json:"invoice_prefix"`          // INV by default
+	CreditNotePrefix     string             `json:"credit_note_prefix"`      // CN by default
+}
+
+// CreditNoteRequest asks to refund part of an invoice. Without lines, everything not credited yet is refunded.
+type CreditNoteRequest struct {
+	Reason string           `json:"reason"`
+	Lines  []CreditNoteLine `json:"lines,omitempty"`
+}
+
+// CreditNoteLine is the quantity of a book to refund
+type CreditNoteLine struct {
+	BookID   int `json:"book_id"`
+	Quantity int `json:"quantity"`
+}
+
+type InvoiceSearchCriteria struct {
+	IDs         []int           `json:"ids,omitempty"`
+	Numbers     []string        `json:"numbers,omitempty"`
+	Types       []InvoiceType   `json:"types,omitempty"`
+	Statuses    []InvoiceStatus `json:"statuses,omitempty"`
+	OrderIDs    []int           `json:"order_ids,omitempty"`
+	CustomerIDs []int           `json:"customer_ids,omitempty"`
+	FiscalYears []int           `json:"fiscal_years,omitempty"`
+	MinTotal    float64         `json:"min_total,omitempty"`
+	MaxTotal    float64         `json:"max_total,omitempty"`
+	MinIssuedAt time.Time       `json:"min_issued_at,omitempty"`
+	MaxIssuedAt time.Time       `json:"max_issued_at,omitempty"`
+}
@@ -3,12 +3,31 @@ package StructureData
 import "time"
 
 type Order struct {
-	ID         int         `json:"id"`
-	Customer   Customer    `json:"customer"`
-	Items      []OrderItem `json:"items"`
-	TotalPrice float64     `json:"total_price"`
-	CreatedAt  time.Time   `json:"created_at"`
+	ID                 int                `json:"id"`
+	CustomerID         int                `json:"customer_id"`
+	CustomerSnapshot   CustomerSnapshot   `json:"customer_snapshot"`
+	Items              []OrderItem        `json:"items"`
+	TotalPrice         float64            `json:"total_price"`
+	AllocationStrategy AllocationStrategy `json:"allocation_strategy,omitempty"` // How the items were allocated to warehouses
+	NotifyOnRestock    bool               `json:"notify_on_restock,omitempty"`   // Subscribes the customer to the books skipped for lack of stock
+	ShipTo             *OrderAddress      `json:"ship_to,omitempty"`             // Where the order is shipped; the default shipping address unless chosen
+	BillTo             *OrderAddress      `json:"bill_to,omitempty"`             // Who the order is billed to; the default billing address unless chosen
+	GiftCardCodes      []string           `json:"gift_card_codes,omitempty"`     // Gift cards to pay with when the order is placed; not stored
+	UseStoreCredit     bool               `json:"use_store_credit,omitempty"`    // Pays what the gift cards leave from the customer's store credit; not stored
+	Payments           []OrderPayment     `json:"payments,omitempty"`            // Paid with gift cards and store credit, and refunded to store credit
+	AmountPaid         float64            `json:"amount_paid"`                   // Sum of the payments
+	AmountDue          float64            `json:"amount_due"`                    // What is left to pay at checkout
+	CreatedAt          time.Time          `json:"created_at"`
+	Customer           *Customer          `json:"customer,omitempty"` // Only set when expanded with ?expand=customer
+	SoftDelete
+}
 
+// CustomerSnapshot records the customer's details as they were when the order was placed. The address is the
+// ship-to address of the order.
+type CustomerSnapshot struct {
+	Name    string  `json:"name"`
+	Email   string  `json:"email"`
+	Address Address `json:"address"`
 }
 
 type OrderSearchCriteria struct {
@@ -1,9 +1,20 @@
 package StructureData
 
 type OrderItem struct {
-	Book Book `json:"book"`
-	Quantity int `json:"quantity"`
+	BookID      int               `json:"book_id"`
+	Quantity    int               `json:"quantity"`
+	UnitPrice   float64           `json:"unit_price"`
+	Snapshot    BookSnapshot      `json:"snapshot"`
+	Allocations []StockAllocation `json:"allocations,omitempty"` // Warehouses the quantity is shipped from
+	Book        *Book             `json:"book,omitempty"`        // Only set when expanded with ?expand=items.book
    }
+
+// BookSnapshot records the book as it was when it was ordered
+type BookSnapshot struct {
+	Title    string   `json:"title"`
+	AuthorID int      `json:"author_id"`
+	Genres   []string `json:"genres,omitempty"`
+}
    
 type OrderItemSearchCriteria struct {
 	BookCriteria BookSearchCriteria `json:"book_criteria,omitempty"`
@@ -0,0 +1,61 @@
+package StructureData
+
+import "time"
+
+// PurchaseOrderStatus is the stage of a purchase order
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "draft"              // Being prepared, can still be edited or deleted
+	PurchaseOrderSent              PurchaseOrderStatus = "sent"               // Sent to the supplier, waiting for goods
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "partially_received" // Some lines are not fully received yet
+	PurchaseOrderReceived          PurchaseOrderStatus = "received"           // Every line is fully received
+)
+
+type PurchaseOrder struct {
+	ID          int                 `json:"id"`
+	SupplierID  int                 `json:"supplier_id"`
+	WarehouseID int                 `json:"warehouse_id"` // Where the goods are delivered, the default warehouse unless set
+	Status      PurchaseOrderStatus `json:"status"`
+	Lines       []PurchaseOrderLine `json:"lines"`
+	ExpectedAt  *time.Time          `json:"expected_at,omitempty"`
+	Notes       string              `json:"notes,omitempty"`
+	Receipts    []GoodsReceipt      `json:"receipts,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	SentAt      *time.Time          `json:"sent_at,omitempty"`
+	ReceivedAt  *time.Time          `json:"received_at,omitempty"`
+}
+
+// PurchaseOrderLine is the quantity of one book ordered from the supplier at the agreed unit cost
+type PurchaseOrderLine struct {
+	BookID           int     `json:"book_id"`
+	QuantityOrdered  int     `json:"quantity_ordered"`
+	QuantityReceived int     `json:"quantity_received"`
+	UnitCost         float64 `json:"unit_cost"`
+}
+
+// Outstanding returns the quantity still expected for the line
+func (l PurchaseOrderLine) Outstanding() int {
+	return l.QuantityOrdered - l.QuantityReceived
+}
+
+// GoodsReceipt records a delivery of goods against a purchase order
+type GoodsReceipt struct {
+	ID         int           `json:"id"`
+	ReceivedAt time.Time     `json:"received_at"`
+	ReceivedBy string        `json:"received_by"`
+	Lines      []ReceiptLine `json:"lines"`
+}
+
+// ReceiptLine is the quantity of one book actually delivered, at the cost actually invoiced
+type ReceiptLine struct {
+	BookID   int     `json:"book_id"`
+	Quantity int     `json:"quantity"`
+	UnitCost float64 `json:"unit_cost,omitempty"` // Defaults to the unit cost of the purchase order line
+}
+
+type PurchaseOrderSearchCriteria struct {
+	SupplierIDs []int                 `json:"supplier_ids,omitempty"`
+	Statuses    []PurchaseOrderStatus `json:"statuses,omitempty"`
+	BookIDs     []int                 `json:"book_ids,omitempty"`
+}
@@ -0,0 +1,31 @@
+package StructureData
+
+// RecommendationReason tells why a book is recommended
+type RecommendationReason string
+
+const (
+	ReasonBoughtTogether RecommendationReason = "bought_together" // Bought in the same orders as the customer's books
+	ReasonAffinity       RecommendationReason = "affinity"        // Shares an author or genres with the customer's books
+	ReasonPopular        RecommendationReason = "popular"         // Among the books in the most orders, for customers without any
+)
+
+// RelatedBook is a book bought in the same orders as another, or sharing its author or genres when it has
+// not been bought with it. Score is the cosine similarity of the two books' orders, or the affinity score.
+type RelatedBook struct {
+	BookID              int                  `json:"book_id"`
+	Title               string               `json:"title"`
+	Stock               int                  `json:"stock"`
+	Score               float64              `json:"score"`
+	TimesBoughtTogether int                  `json:"times_bought_together"`
+	Reason              RecommendationReason `json:"reason"`
+}
+
+// BookRecommendation is a book recommended to a customer, with the purchased books it is recommended for
+type BookRecommendation struct {
+	BookID    int                  `json:"book_id"`
+	Title     string               `json:"title"`
+	Stock     int                  `json:"stock"`
+	Score     float64              `json:"score"`
+	Reason    RecommendationReason `json:"reason"`
+	BecauseOf []int                `json:"because_of,omitempty"` // IDs of the customer's books it was bought with
+}
@@ -0,0 +1,47 @@
+package StructureData
+
+// Resource names used to declare relationships between entities
+const (
+	ResourceAuthors   = "authors"
+	ResourceBooks     = "books"
+	ResourceCustomers = "customers"
+	ResourceOrders    = "orders"
+
+	ResourceSuppliers      = "suppliers"
+	ResourcePurchaseOrders = "purchase_orders"
+	ResourceWarehouses     = "warehouses"
+	ResourceStockTransfers = "stock_transfers"
+	ResourceStocktakes     = "stocktakes"
+	ResourceReviews        = "reviews"
+	ResourceInvoices       = "invoices"
+	ResourceReturns        = "returns"
+	ResourceGiftCards      = "gift_cards"
+	ResourceStoreCredit    = "store_credit"
+)
+
+// RelationPolicy decides what happens to children when their parent is deleted
+type RelationPolicy string
+
+const (
+	PolicyRestrict   RelationPolicy = "restrict"    // Refuse the delete while children exist
+	PolicyCascade    RelationPolicy = "cascade"     // Delete the children as well
+	PolicySetNull    RelationPolicy = "set-null"    // Clear the children's reference and embedded copy
+	PolicySoftDetach RelationPolicy = "soft-detach" // Clear the reference but keep the embedded copy as a snapshot
+)
+
+// Relation declares that Child records reference a Parent record
+type Relation struct {
+	Name   string         `json:"name"`
+	Parent string         `json:"parent"`
+	Child  string         `json:"child"`
+	Policy RelationPolicy `json:"policy"`
+}
+
+// IsValidRelationPolicy reports whether policy is one of the supported policies
+func IsValidRelationPolicy(policy RelationPolicy) bool {
+	switch policy {
+	case PolicyRestrict, PolicyCascade, PolicySetNull, PolicySoftDetach:
+		return true
+	}
+	return false
+}
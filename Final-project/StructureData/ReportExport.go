@@ -0,0 +1,15 @@
+package StructureData
+
+// ExportFormat is a format reports can be downloaded in, chosen with ?format= or the Accept header
+type ExportFormat string
+
+const (
+	ExportJSON ExportFormat = "json"
+	ExportCSV  ExportFormat = "csv"  // One row per top-selling book of a sales report
+	ExportXLSX ExportFormat = "xlsx" // Spreadsheet with the rows of the CSV export
+	ExportHTML ExportFormat = "html" // Printable statement with totals
+	ExportPDF  ExportFormat = "pdf"  // The statement as a PDF document
+)
+
+// ExportFormats lists every format, in the order they are documented
+var ExportFormats = []ExportFormat{ExportJSON, ExportCSV, ExportXLSX, ExportHTML, ExportPDF}
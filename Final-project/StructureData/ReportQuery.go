@@ -0,0 +1,90 @@
+package StructureData
+
+import "time"
+
+// ReportDimension is a field sales can be grouped by
+type ReportDimension string
+
+const (
+	ReportByBook     ReportDimension = "book"
+	ReportByAuthor   ReportDimension = "author"
+	ReportByGenre    ReportDimension = "genre" // A book with several genres counts in each of them
+	ReportByCustomer ReportDimension = "customer"
+	ReportByCountry  ReportDimension = "country" // Country of the customer's address on the order
+	ReportByDay      ReportDimension = "day"
+	ReportByWeek     ReportDimension = "week" // ISO week, such as 2025-W02
+	ReportByMonth    ReportDimension = "month"
+)
+
+// ReportMetric is a figure computed for every group of a report
+type ReportMetric string
+
+const (
+	MetricRevenue           ReportMetric = "revenue"
+	MetricUnits             ReportMetric = "units"
+	MetricOrders            ReportMetric = "orders"
+	MetricAverageOrderValue ReportMetric = "average_order_value" // Revenue divided by orders
+)
+
+// ReportDimensions lists every dimension, in the order they are documented
+var ReportDimensions = []ReportDimension{ReportByBook, ReportByAuthor, ReportByGenre, ReportByCustomer, ReportByCountry, ReportByDay, ReportByWeek, ReportByMonth}
+
+// ReportMetrics lists every metric, in the order they are documented
+var ReportMetrics = []ReportMetric{MetricRevenue, MetricUnits, MetricOrders, MetricAverageOrderValue}
+
+// SalesReportQuery asks for the sales of a time range, grouped by dimensions. Without dimensions the
+// report has a single row; without metrics it has all of them.
+type SalesReportQuery struct {
+	Name       string            `json:"name,omitempty"`      // Save the report under this name
+	From       time.Time         `json:"from"`                // 24 hours before To by default
+	To         time.Time         `json:"to"`                  // Now by default
+	TimeZone   string            `json:"time_zone,omitempty"` // Time zone of the day, week and month buckets, UTC by default
+	Dimensions []ReportDimension `json:"dimensions,omitempty"`
+	Metrics    []ReportMetric    `json:"metrics,omitempty"`
+	SortBy     ReportMetric      `json:"sort_by,omitempty"` // Highest first; rows are sorted by group otherwise
+	Limit      int               `json:"limit,omitempty"`
+}
+
+// ReportGroupKey is the value of one dimension for a row
+type ReportGroupKey struct {
+	Dimension ReportDimension `json:"dimension"`
+	Value     string          `json:"value"` // The ID of a book, author or customer; the label otherwise
+	Label     string          `json:"label"`
+}
+
+// SalesReportRow is one group of a report and its metrics
+type SalesReportRow struct {
+	Group   []ReportGroupKey         `json:"group"`
+	Metrics map[ReportMetric]float64 `json:"metrics"`
+}
+
+// SalesReportResult is the outcome of a query. Named results are saved and get an ID.
+type SalesReportResult struct {
+	ID          int                      `json:"id,omitempty"`
+	Name        string                   `json:"name,omitempty"`
+	Query       SalesReportQuery         `json:"query"`
+	GeneratedAt time.Time                `json:"generated_at"`
+	GeneratedBy string                   `json:"generated_by,omitempty"`
+	Rows        []SalesReportRow         `json:"rows"`
+	Totals      map[ReportMetric]float64 `json:"totals"`
+}
+
+// IsValidReportDimension reports whether a dimension is known
+func IsValidReportDimension(dimension ReportDimension) bool {
+	for _, known := range ReportDimensions {
+		if dimension == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidReportMetric reports whether a metric is known
+func IsValidReportMetric(metric ReportMetric) bool {
+	for _, known := range ReportMetrics {
+		if metric == known {
+			return true
+		}
+	}
+	return false
+}
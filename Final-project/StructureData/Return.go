@@ -0,0 +1,116 @@
+package StructureData
+
+import "time"
+
+// ReturnStatus is the stage of a return
+type ReturnStatus string
+
+const (
+	ReturnRequested         ReturnStatus = "requested"          // Waiting for staff to approve or reject it
+	ReturnApproved          ReturnStatus = "approved"           // The customer can send the goods back
+	ReturnRejected          ReturnStatus = "rejected"           // Nothing is taken back
+	ReturnPartiallyReceived ReturnStatus = "partially_received" // Some lines are not fully received yet
+	ReturnReceived          ReturnStatus = "received"           // Every line is back and refunded
+)
+
+// ReturnCondition grades the goods received back. Only new and good copies are sellable and restocked.
+type ReturnCondition string
+
+const (
+	ReturnConditionNew       ReturnCondition = "new"       // As sold
+	ReturnConditionGood      ReturnCondition = "good"      // Read, but sellable
+	ReturnConditionDamaged   ReturnCondition = "damaged"   // Damaged in transit or by the customer
+	ReturnConditionDefective ReturnCondition = "defective" // Printing or binding fault
+)
+
+// ReturnConditions lists every condition, in the order they are documented
+var ReturnConditions = []ReturnCondition{ReturnConditionNew, ReturnConditionGood, ReturnConditionDamaged, ReturnConditionDefective}
+
+// IsValidReturnCondition reports whether a condition is known
+func IsValidReturnCondition(condition ReturnCondition) bool {
+	for _, known := range ReturnConditions {
+		if condition == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Sellable reports whether goods in this condition go back in stock
+func (c ReturnCondition) Sellable() bool {
+	return c == ReturnConditionNew || c == ReturnConditionGood
+}
+
+// Return is a return merchandise authorization (RMA): the books of an order a customer sends back, and the
+// receipts and refunds of the goods once they arrive
+type Return struct {
+	ID              int             `json:"id"`
+	Number          string          `json:"number"` // For example RMA-00001
+	OrderID         int             `json:"order_id"`
+	CustomerID      int             `json:"customer_id"`
+	Status          ReturnStatus    `json:"status"`
+	Reason          string          `json:"reason"`
+	Lines           []ReturnLine    `json:"lines"`
+	Receipts        []ReturnReceipt `json:"receipts,omitempty"`
+	RejectionReason string          `json:"rejection_reason,omitempty"`
+	RefundTotal     float64         `json:"refund_total"` // Sum of the refunds of the receipts
+	RequestedBy     string          `json:"requested_by"`
+	RequestedAt     time.Time       `json:"requested_at"`
+	DecidedBy       string          `json:"decided_by,omitempty"` // Who approved or rejected the return
+	DecidedAt       *time.Time      `json:"decided_at,omitempty"`
+	ReceivedAt      *time.Time      `json:"received_at,omitempty"`
+}
+
+// ReturnLine is the quantity of one book of the order sent back, with its price and details as ordered
+type ReturnLine struct {
+	BookID           int          `json:"book_id"`
+	Quantity         int          `json:"quantity"`
+	QuantityReceived int          `json:"quantity_received"`
+	UnitPrice        float64      `json:"unit_price"`
+	Snapshot         BookSnapshot `json:"snapshot"`
+}
+
+// Outstanding returns the quantity still expected back for the line
+func (l ReturnLine) Outstanding() int {
+	return l.Quantity - l.QuantityReceived
+}
+
+// ReturnReceipt records goods received back against a return and how they were refunded. The refund is a
+// credit note on the order's invoice, or an amount at the ordered prices for orders that were not invoiced.
+// It is paid to the customer's store credit when refund_to_store_credit is set.
+type ReturnReceipt struct {
+	ID                  int                 `json:"id"`
+	ReceivedAt          time.Time           `json:"received_at"`
+	ReceivedBy          string              `json:"received_by"`
+	Lines               []ReturnReceiptLine `json:"lines"`
+	Refund              float64             `json:"refund"`
+	CreditNoteID        int                 `json:"credit_note_id,omitempty"`
+	CreditNoteNumber    string              `json:"credit_note_number,omitempty"`
+	RefundToStoreCredit bool                `json:"refund_to_store_credit,omitempty"`
+	StoreCreditEntryID  int                 `json:"store_credit_entry_id,omitempty"` // The ledger entry the refund was paid with
+}
+
+// ReturnReceiptLine is the quantity of one book received back in a condition. Sellable copies are restocked
+// at the warehouse given, by default the one the book was shipped from.
+type ReturnReceiptLine struct {
+	BookID      int             `json:"book_id"`
+	Quantity    int             `json:"quantity"`
+	Condition   ReturnCondition `json:"condition"`
+	WarehouseID int             `json:"warehouse_id,omitempty"`
+	Restocked   bool            `json:"restocked"`
+}
+
+// ReturnDecision approves or rejects a return; a rejection gives its reason
+type ReturnDecision struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type ReturnSearchCriteria struct {
+	IDs            []int          `json:"ids,omitempty"`
+	OrderIDs       []int          `json:"order_ids,omitempty"`
+	CustomerIDs    []int          `json:"customer_ids,omitempty"`
+	Statuses       []ReturnStatus `json:"statuses,omitempty"`
+	BookIDs        []int          `json:"book_ids,omitempty"`
+	MinRequestedAt time.Time      `json:"min_requested_at,omitempty"`
+	MaxRequestedAt time.Time      `json:"max_requested_at,omitempty"`
+}
@@ -0,0 +1,61 @@
+package StructureData
+
+import "time"
+
+// ReviewStatus is the moderation state of a review. Only approved reviews are shown and rated by default.
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// IsValidReviewStatus reports whether status is one of the moderation states
+func IsValidReviewStatus(status ReviewStatus) bool {
+	switch status {
+	case ReviewPending, ReviewApproved, ReviewRejected:
+		return true
+	}
+	return false
+}
+
+// Review is a customer's rating of a book, from 1 to 5. It is a verified purchase when the customer has an
+// order containing the book.
+type Review struct {
+	ID               int          `json:"id"`
+	BookID           int          `json:"book_id"`
+	CustomerID       int          `json:"customer_id"`
+	Rating           int          `json:"rating"`
+	Title            string       `json:"title"`
+	Body             string       `json:"body,omitempty"`
+	Status           ReviewStatus `json:"status"`
+	ModerationNote   string       `json:"moderation_note,omitempty"`
+	VerifiedPurchase bool         `json:"verified_purchase"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// ReviewModeration is the decision of a moderator on a review
+type ReviewModeration struct {
+	Status ReviewStatus `json:"status"`
+	Note   string       `json:"note,omitempty"`
+}
+
+// RatingSummary aggregates the approved reviews of a book. The histogram counts the reviews of every
+// rating from 1 to 5.
+type RatingSummary struct {
+	AverageRating float64     `json:"average_rating"`
+	ReviewCount   int         `json:"review_count"`
+	Histogram     map[int]int `json:"histogram"`
+}
+
+// ReviewPage is one page of the reviews of a book, with the rating summary of all its approved reviews
+type ReviewPage struct {
+	Reviews    []Review      `json:"reviews"`
+	Page       int           `json:"page"`
+	PerPage    int           `json:"per_page"`
+	Total      int           `json:"total"` // Reviews matching the filters
+	TotalPages int           `json:"total_pages"`
+	Summary    RatingSummary `json:"summary"`
+}
@@ -3,10 +3,13 @@ package StructureData
 import "time"
 
 type SalesReport struct {
+	ID              int              `json:"id"`
 	Timestamp       time.Time        `json:"timestamp"`
 	TotalRevenue    float64          `json:"total_revenue"`
 	TotalOrders     int              `json:"total_orders"`
 	TopSellingBooks []TopSellingBook `json:"top_selling_books"`
+	Period          string           `json:"period,omitempty"`         // Month of a compacted report, e.g. 2025-01
+	CompactedFrom   int              `json:"compacted_from,omitempty"` // Number of reports merged into a compacted report
 }
 type TopSellingBook struct {
 	Book         Book `json:"book"`
@@ -0,0 +1,41 @@
+package StructureData
+
+import "time"
+
+// ReportSchedule runs a report at the times given by a cron expression
+type ReportSchedule struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	Cron      string            `json:"cron"`                // minute hour day-of-month month day-of-week, or @hourly, @daily, @weekly, @monthly, @yearly
+	TimeZone  string            `json:"time_zone,omitempty"` // Time zone the expression is read in, UTC by default
+	Query     *SalesReportQuery `json:"query,omitempty"`     // Saved as a named report on every run; the daily sales report when missing
+	Window    string            `json:"window,omitempty"`    // Duration the query covers up to each run, 24h by default
+	CatchUp   bool              `json:"catch_up"`            // Run the occurrences missed while the server was down
+	Paused    bool              `json:"paused"`
+	LastRunAt *time.Time        `json:"last_run_at,omitempty"` // Occurrence of the last run
+	NextRunAt *time.Time        `json:"next_run_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// ScheduleRunStatus is the outcome of a schedule run
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunSucceeded ScheduleRunStatus = "succeeded"
+	ScheduleRunFailed    ScheduleRunStatus = "failed"
+	ScheduleRunCancelled ScheduleRunStatus = "cancelled" // Interrupted by the server shutting down
+)
+
+// ScheduleRun records one run of a schedule
+type ScheduleRun struct {
+	ID           int               `json:"id"`
+	ScheduleID   int               `json:"schedule_id"`
+	ScheduledFor time.Time         `json:"scheduled_for"` // Occurrence the run covers; the report ends at this time
+	StartedAt    time.Time         `json:"started_at"`
+	FinishedAt   time.Time         `json:"finished_at"`
+	Status       ScheduleRunStatus `json:"status"`
+	CatchUp      bool              `json:"catch_up,omitempty"` // Run late for an occurrence missed while the server was down
+	Manual       bool              `json:"manual,omitempty"`
+	ReportID     int               `json:"report_id,omitempty"` // Named report saved by the run
+	Error        string            `json:"error,omitempty"`
+}
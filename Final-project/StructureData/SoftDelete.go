@@ -0,0 +1,15 @@
+package StructureData
+
+import "time"
+
+// SoftDelete marks a record as moved to the trash. Trashed records are hidden from normal reads
+// until they are restored or purged.
+type SoftDelete struct {
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
+}
+
+// IsDeleted reports whether the record is in the trash
+func (s SoftDelete) IsDeleted() bool {
+	return s.DeletedAt != nil
+}
@@ -0,0 +1,42 @@
+package StructureData
+
+import "time"
+
+// StockMovementType is the reason a book's stock changed
+type StockMovementType string
+
+const (
+	StockMovementSale         StockMovementType = "sale"                 // Stock taken by a new or restored order
+	StockMovementOrderEdit    StockMovementType = "order_edit"           // Net change made by editing an order
+	StockMovementCancellation StockMovementType = "cancellation"         // Stock put back by deleting an order
+	StockMovementReceipt      StockMovementType = "receipt"              // Goods received against a purchase order
+	StockMovementAdjustment   StockMovementType = "manual_adjustment"    // Stock set by hand on the book, including its opening balance
+	StockMovementStocktake    StockMovementType = "stocktake_correction" // Difference found by counting the shelves
+	StockMovementTransfer     StockMovementType = "transfer"             // Stock moved between warehouses, recorded at both ends
+	StockMovementReturn       StockMovementType = "return"               // Sellable goods received back from a customer
+)
+
+// StockMovement is one entry of the append-only stock ledger. The ledger of a book sums to its stock.
+type StockMovement struct {
+	ID          int               `json:"id"`
+	BookID      int               `json:"book_id"`
+	Type        StockMovementType `json:"type"`
+	Quantity    int               `json:"quantity"`     // Positive when stock comes in, negative when it goes out
+	StockAfter  int               `json:"stock_after"`  // The book's stock once the movement is applied
+	WarehouseID int               `json:"warehouse_id"` // Zero for movements recorded before stock was kept per warehouse
+	SourceType  string            `json:"source_type"`  // Resource of the source document, e.g. orders or purchase_orders
+	SourceID    int               `json:"source_id"`
+	Note        string            `json:"note,omitempty"`
+	Actor       string            `json:"actor"`
+	RequestID   string            `json:"request_id,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// StockLedgerView is a book's ledger together with the check of its balance against the current stock
+type StockLedgerView struct {
+	BookID        int             `json:"book_id"`
+	Stock         int             `json:"stock"`
+	LedgerBalance int             `json:"ledger_balance"`
+	Consistent    bool            `json:"consistent"`
+	Movements     []StockMovement `json:"movements"`
+}
@@ -0,0 +1,75 @@
+package StructureData
+
+import "time"
+
+// StocktakeStatus is the stage of a stocktake
+type StocktakeStatus string
+
+const (
+	StocktakeOpen      StocktakeStatus = "open"      // Counts are being submitted
+	StocktakeApproved  StocktakeStatus = "approved"  // The variances were posted as stock corrections
+	StocktakeCancelled StocktakeStatus = "cancelled" // Closed without changing any stock
+)
+
+// Stocktake is a count of the shelves of one warehouse for a set of books
+type Stocktake struct {
+	ID          int              `json:"id"`
+	WarehouseID int              `json:"warehouse_id"`
+	BookIDs     []int            `json:"book_ids,omitempty"` // Books to count, together with the books of the genres
+	Genres      []string         `json:"genres,omitempty"`
+	FreezeSales bool             `json:"freeze_sales"` // Keep orders from being allocated the counted books at the warehouse
+	Note        string           `json:"note,omitempty"`
+	Status      StocktakeStatus  `json:"status"`
+	Lines       []StocktakeLine  `json:"lines"`
+	LedgerMark  int              `json:"ledger_mark"` // ID of the last stock movement when the stocktake was opened
+	Counts      []StocktakeCount `json:"counts,omitempty"`
+	OpenedAt    time.Time        `json:"opened_at"`
+	OpenedBy    string           `json:"opened_by"`
+	ClosedAt    *time.Time       `json:"closed_at,omitempty"`
+	ClosedBy    string           `json:"closed_by,omitempty"`
+}
+
+// StocktakeLine is a book being counted, with its stock at the warehouse when the stocktake was opened
+type StocktakeLine struct {
+	BookID   int `json:"book_id"`
+	Snapshot int `json:"snapshot"`
+}
+
+// StocktakeCount is the quantity of a book found by one counter. A counter's later count of the same
+// book replaces the earlier one, and the counts of different counters add up.
+type StocktakeCount struct {
+	BookID    int       `json:"book_id"`
+	Quantity  int       `json:"quantity"`
+	Counter   string    `json:"counter"`
+	CountedAt time.Time `json:"counted_at"`
+}
+
+// StocktakeSubmission is a batch of counts from one counter, the request's actor by default
+type StocktakeSubmission struct {
+	Counter string           `json:"counter,omitempty"`
+	Counts  []StocktakeCount `json:"counts"`
+}
+
+// StocktakeVariance compares the counted quantity of a book with the quantity expected at the warehouse:
+// the snapshot plus the stock movements recorded there since the stocktake was opened
+type StocktakeVariance struct {
+	BookID    int      `json:"book_id"`
+	Title     string   `json:"title"`
+	Snapshot  int      `json:"snapshot"`
+	Movements int      `json:"movements"`
+	Expected  int      `json:"expected"`
+	Counted   *int     `json:"counted,omitempty"`  // Missing until a counter submits the book
+	Variance  *int     `json:"variance,omitempty"` // Counted minus expected
+	Counters  []string `json:"counters,omitempty"`
+}
+
+// StocktakeReview is the variance report of a stocktake
+type StocktakeReview struct {
+	StocktakeID int                 `json:"stocktake_id"`
+	WarehouseID int                 `json:"warehouse_id"`
+	Status      StocktakeStatus     `json:"status"`
+	Lines       []StocktakeVariance `json:"lines"`
+	Counted     int                 `json:"counted"`
+	Uncounted   int                 `json:"uncounted"`
+	NetVariance int                 `json:"net_variance"`
+}
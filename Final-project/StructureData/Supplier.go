@@ -0,0 +1,13 @@
+package StructureData
+
+import "time"
+
+type Supplier struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	Phone        string    `json:"phone,omitempty"`
+	Address      Address   `json:"address"`
+	LeadTimeDays int       `json:"lead_time_days,omitempty"` // Usual days between sending a purchase order and receiving it
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,59 @@
+package StructureData
+
+import "time"
+
+type Warehouse struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Address   Address   `json:"address"` // The country decides which customers the warehouse is nearest to
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StockLevel is the stock of one book held at one warehouse. The levels of a book sum to its stock.
+type StockLevel struct {
+	BookID      int `json:"book_id"`
+	WarehouseID int `json:"warehouse_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// StockTransfer moves stock of a book from one warehouse to another
+type StockTransfer struct {
+	ID              int       `json:"id"`
+	BookID          int       `json:"book_id"`
+	FromWarehouseID int       `json:"from_warehouse_id"`
+	ToWarehouseID   int       `json:"to_warehouse_id"`
+	Quantity        int       `json:"quantity"`
+	Note            string    `json:"note,omitempty"`
+	Actor           string    `json:"actor"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// AllocationStrategy decides which warehouses an order line is shipped from
+type AllocationStrategy string
+
+const (
+	AllocateNearest   AllocationStrategy = "nearest"    // A single warehouse, preferring the customer's country
+	AllocateMostStock AllocationStrategy = "most_stock" // The single warehouse holding the most stock of the book
+	AllocateSplit     AllocationStrategy = "split"      // As many warehouses as needed, nearest first
+)
+
+// IsValidAllocationStrategy reports whether strategy is a known strategy
+func IsValidAllocationStrategy(strategy AllocationStrategy) bool {
+	switch strategy {
+	case AllocateNearest, AllocateMostStock, AllocateSplit:
+		return true
+	}
+	return false
+}
+
+// StockAllocation is the quantity of an order line taken from one warehouse
+type StockAllocation struct {
+	WarehouseID int `json:"warehouse_id"`
+	Quantity    int `json:"quantity"`
+}
+
+type StockTransferSearchCriteria struct {
+	BookIDs      []int `json:"book_ids,omitempty"`
+	WarehouseIDs []int `json:"warehouse_ids,omitempty"` // Matches transfers from or to the warehouses
+}
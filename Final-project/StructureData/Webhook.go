@@ -0,0 +1,58 @@
+package StructureData
+
+import "time"
+
+// WebhookSubscription registers a partner URL to receive domain events
+type WebhookSubscription struct {
+	ID         int         `json:"id"`
+	URL        string      `json:"url"`
+	Secret     string      `json:"secret,omitempty"`      // HMAC key, only returned when the subscription is created
+	EventTypes []EventType `json:"event_types,omitempty"` // Empty means every event
+	Active     bool        `json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Matches reports whether the subscription wants events of the given type
+func (s WebhookSubscription) Matches(eventType EventType) bool {
+	if !s.Active {
+		return false
+	}
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus is the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"   // Waiting for its next attempt
+	DeliveryDelivered DeliveryStatus = "delivered" // Acknowledged with a 2xx response
+	DeliveryDead      DeliveryStatus = "dead"      // Gave up after the last retry; can be redelivered by hand
+)
+
+// WebhookDelivery tracks sending one event to one subscription
+type WebhookDelivery struct {
+	ID             int            `json:"id"`
+	SubscriptionID int            `json:"subscription_id"`
+	Event          DomainEvent    `json:"event"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time     `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type WebhookDeliverySearchCriteria struct {
+	SubscriptionIDs []int            `json:"subscription_ids,omitempty"`
+	Statuses        []DeliveryStatus `json:"statuses,omitempty"`
+	EventTypes      []EventType      `json:"event_types,omitempty"`
+}
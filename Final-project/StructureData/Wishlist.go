@@ -0,0 +1,71 @@
+package StructureData
+
+import "time"
+
+// Wishlist is the list of books a customer saved for later
+type Wishlist struct {
+	CustomerID int            `json:"customer_id"`
+	Items      []WishlistItem `json:"items"`
+}
+
+// WishlistItem is a book saved in a wishlist. Title, Stock and Notify are filled when the wishlist is read.
+type WishlistItem struct {
+	BookID  int       `json:"book_id"`
+	AddedAt time.Time `json:"added_at"`
+	Title   string    `json:"title,omitempty"`
+	Stock   int       `json:"stock"`
+	Notify  bool      `json:"notify"` // Whether the customer is notified when the book is back in stock
+}
+
+// StockSubscriptionStatus is the state of a back-in-stock subscription
+type StockSubscriptionStatus string
+
+const (
+	SubscriptionActive    StockSubscriptionStatus = "active"    // Waiting for the book to be back in stock
+	SubscriptionNotified  StockSubscriptionStatus = "notified"  // The customer was notified, once
+	SubscriptionCancelled StockSubscriptionStatus = "cancelled" // The customer unsubscribed
+)
+
+// IsValidSubscriptionStatus reports whether status is a known subscription status
+func IsValidSubscriptionStatus(status StockSubscriptionStatus) bool {
+	switch status {
+	case SubscriptionActive, SubscriptionNotified, SubscriptionCancelled:
+		return true
+	}
+	return false
+}
+
+// StockSubscription asks for a customer to be notified the next time a book goes from out of stock to in
+// stock. A customer has at most one active subscription per book.
+type StockSubscription struct {
+	ID          int                     `json:"id"`
+	CustomerID  int                     `json:"customer_id"`
+	BookID      int                     `json:"book_id"`
+	Status      StockSubscriptionStatus `json:"status"`
+	CreatedAt   time.Time               `json:"created_at"`
+	NotifiedAt  *time.Time              `json:"notified_at,omitempty"`
+	CancelledAt *time.Time              `json:"cancelled_at,omitempty"`
+}
+
+// StockNotification is a back-in-stock notification sent to a customer through the notifier
+type StockNotification struct {
+	ID             int       `json:"id"`
+	SubscriptionID int       `json:"subscription_id"`
+	CustomerID     int       `json:"customer_id"`
+	Name           string    `json:"name"`
+	Email          string    `json:"email"`
+	BookID         int       `json:"book_id"`
+	Title          string    `json:"title"`
+	Stock          int       `json:"stock"`
+	Delivered      bool      `json:"delivered"`
+	Error          string    `json:"error,omitempty"` // Why the notifier failed to deliver it
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// BackInStock is the payload of the BookBackInStock event, published once per restock of a book
+type BackInStock struct {
+	BookID      int    `json:"book_id"`
+	Title       string `json:"title"`
+	Stock       int    `json:"stock"`
+	Subscribers []int  `json:"subscribers"` // IDs of the customers notified
+}
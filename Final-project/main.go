@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -15,32 +16,94 @@ import (
 )
 
 func main() {
+	// Migrate JSON files written with embedded authors and customers, or single customer addresses
+	if err := controllers.MigrateDataFiles(); err != nil {
+		log.Fatalf("Data migration failed: %v", err)
+	}
+
 	// Initialize JSON files for persistence
+	controllers.InitializeRelationFile()
+	controllers.InitializeAuditFile()
 	controllers.InitializeCustomerFile()
 	controllers.InitializeAuthorFile()
 	controllers.InitializeBookFile()
 	controllers.InitializeOrderFile()
+	controllers.InitializeWarehouseFiles()
+	controllers.InitializeStockMovementFile()
+	controllers.InitializeStocktakeFile()
+	controllers.InitializeWebhookFiles()
+	controllers.InitializeSupplierFile()
+	controllers.InitializeReviewFile()
+	controllers.InitializeWishlistFiles()
+	controllers.InitializePurchaseOrderFile()
+	controllers.InitializeSalesReportFile()
+	controllers.InitializeNamedReportFile()
+	controllers.InitializeInvoiceFiles()
+	controllers.InitializeReturnFile()
+	controllers.InitializeGiftCardFiles()
+
+	// Deliver domain events to the registered webhooks
+	controllers.StartWebhookDispatcher(controllers.DefaultRetryPolicy)
+
+	// Raise low-stock alerts
+	controllers.InitializeStockAlertFile()
+	controllers.StartInventoryMonitor()
+
+	// Count the books bought together for recommendations
+	controllers.StartRecommendationEngine()
+
+	// Notify the customers waiting for books that are back in stock
+	controllers.StartBackInStockMonitor()
+
+	// Invoice orders when they are placed, crediting the invoices of edited and deleted orders
+	controllers.StartInvoicer()
 	
 
-	// Start periodic sales report generation
-	go func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+	// Run the report schedules, catching up on the runs missed while the server was down
+	controllers.InitializeScheduleFiles()
+	controllers.StartReportScheduler()
 
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
+	// Periodic jobs run until jobsCtx is cancelled at shutdown, which then waits for them on jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
 
-		for {
-			select {
-			case <-ticker.C:
-				log.Println("Generating periodic sales report...")
-				controllers.GenerateSalesReport(ctx)
-			case <-ctx.Done():
-				log.Println("Stopped periodic sales report generation.")
-				return
-			}
+	// Start periodic purge of expired trash
+	trashRetention := 30 * 24 * time.Hour
+	if value := os.Getenv("TRASH_RETENTION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid TRASH_RETENTION %q: %v", value, err)
 		}
-	}()
+		trashRetention = parsed
+	}
+	runPeriodically(jobsCtx, &jobs, time.Hour, true, func() {
+		controllers.PurgeExpiredTrash(trashRetention)
+	})
+
+	// Start periodic expiry of gift cards past their expiry date
+	runPeriodically(jobsCtx, &jobs, time.Hour, true, controllers.ExpireGiftCards)
+
+	// Start periodic compaction of old sales reports into monthly reports, deleting them after the retention.
+	// The first run is an hour after startup, so starting the server does not rewrite the report file.
+	compactAfter := 90 * 24 * time.Hour
+	if value := os.Getenv("SALES_REPORT_COMPACT_AFTER"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid SALES_REPORT_COMPACT_AFTER %q: %v", value, err)
+		}
+		compactAfter = parsed
+	}
+	var reportRetention time.Duration // Kept forever unless set
+	if value := os.Getenv("SALES_REPORT_RETENTION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid SALES_REPORT_RETENTION %q: %v", value, err)
+		}
+		reportRetention = parsed
+	}
+	runPeriodically(jobsCtx, &jobs, time.Hour, false, func() {
+		controllers.MaintainSalesReports(compactAfter, reportRetention)
+	})
 
 	// Create a new router
 	router := httprouter.New()
@@ -53,6 +116,70 @@ func main() {
 		r.URL.Path = "/customers/" + ps.ByName("id")
 		controllers.GetCustomerByID(w, r)
 	})
+	router.GET("/customers/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "customers")
+	})
+	router.GET("/customers/:id/recommendations", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.GetCustomerRecommendations(w, r)
+	})
+	router.GET("/customers/:id/stats", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.GetCustomerStats(w, r)
+	})
+	router.GET("/customers/:id/addresses", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.GetCustomerAddresses(w, r)
+	})
+	router.POST("/customers/:id/addresses", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.CreateCustomerAddress(w, r)
+	})
+	router.PUT("/customers/:id/addresses/:address_id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id") + "/" + ps.ByName("address_id")
+		controllers.UpdateCustomerAddress(w, r)
+	})
+	router.DELETE("/customers/:id/addresses/:address_id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id") + "/" + ps.ByName("address_id")
+		controllers.DeleteCustomerAddress(w, r)
+	})
+	router.GET("/customers/:id/wishlist", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.GetWishlist(w, r)
+	})
+	router.POST("/customers/:id/wishlist", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.AddToWishlist(w, r)
+	})
+	router.DELETE("/customers/:id/wishlist/:book_id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id") + "/" + ps.ByName("book_id")
+		controllers.RemoveFromWishlist(w, r)
+	})
+	router.GET("/customers/:id/subscriptions", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.GetStockSubscriptions(w, r)
+	})
+	router.POST("/customers/:id/subscriptions", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.SubscribeToBook(w, r)
+	})
+	router.DELETE("/customers/:id/subscriptions/:book_id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id") + "/" + ps.ByName("book_id")
+		controllers.UnsubscribeFromBook(w, r)
+	})
+	router.GET("/customers/:id/notifications", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.GetStockNotifications(w, r)
+	})
+	router.GET("/customers/:id/store-credit", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.GetStoreCredit(w, r)
+	})
+	router.POST("/customers/:id/store-credit", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.AddStoreCredit(w, r)
+	})
 	router.POST("/customers", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		controllers.CreateCustomer(w, r)
 	})
@@ -64,9 +191,18 @@ func main() {
 		r.URL.Path = "/customers/" + ps.ByName("id")
 		controllers.DeleteCustomer(w, r)
 	})
-	router.POST("/customers/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// httprouter cannot mix a static segment with the :id of the restore route, so search matches on :id
+	router.POST("/customers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("id") != "search" {
+			http.NotFound(w, r)
+			return
+		}
 		controllers.SearchCustomers(w, r)
 	})
+	router.POST("/customers/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		controllers.RestoreRecord(w, r, "customers")
+	})
 
 	// Author Routes
 	router.GET("/authors", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -76,6 +212,10 @@ func main() {
 		r.URL.Path = "/authors/" + ps.ByName("id")
 		controllers.GetAuthorByID(w, r)
 	})
+	router.GET("/authors/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/authors/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "authors")
+	})
 	router.POST("/authors", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		controllers.CreateAuthor(w, r)
 	})
@@ -87,9 +227,18 @@ func main() {
 		r.URL.Path = "/authors/" + ps.ByName("id")
 		controllers.DeleteAuthor(w, r)
 	})
-	router.POST("/authors/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// httprouter cannot mix a static segment with the :id of the restore route, so search matches on :id
+	router.POST("/authors/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("id") != "search" {
+			http.NotFound(w, r)
+			return
+		}
 		controllers.SearchAuthors(w, r)
 	})
+	router.POST("/authors/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/authors/" + ps.ByName("id")
+		controllers.RestoreRecord(w, r, "authors")
+	})
 
 	// Book Routes
 	router.GET("/books", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -99,6 +248,34 @@ func main() {
 		r.URL.Path = "/books/" + ps.ByName("id")
 		controllers.GetBookByID(w, r)
 	})
+	router.GET("/books/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "books")
+	})
+	router.GET("/books/:id/reviews", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		controllers.GetBookReviews(w, r)
+	})
+	router.POST("/books/:id/reviews", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		controllers.CreateBookReview(w, r)
+	})
+	router.GET("/books/:id/related", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		controllers.GetRelatedBooks(w, r)
+	})
+	router.GET("/books/:id/subscriptions", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		controllers.GetBookSubscriptions(w, r)
+	})
+	router.GET("/books/:id/stock-levels", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		controllers.GetBookStockLevels(w, r)
+	})
+	router.GET("/books/:id/stock-movements", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		controllers.GetStockMovements(w, r)
+	})
 	router.POST("/books", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		controllers.CreateBook(w, r)
 	})
@@ -110,9 +287,18 @@ func main() {
 		r.URL.Path = "/books/" + ps.ByName("id")
 		controllers.DeleteBook(w, r)
 	})
-	router.POST("/books/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// httprouter cannot mix a static segment with the :id of the restore route, so search matches on :id
+	router.POST("/books/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("id") != "search" {
+			http.NotFound(w, r)
+			return
+		}
 		controllers.SearchBooks(w, r)
 	})
+	router.POST("/books/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		controllers.RestoreRecord(w, r, "books")
+	})
 
 	// Order Routes
 	router.GET("/orders", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -122,6 +308,10 @@ func main() {
 		r.URL.Path = "/orders/" + ps.ByName("id")
 		controllers.GetOrderByID(w, r)
 	})
+	router.GET("/orders/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "orders")
+	})
 	router.POST("/orders", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		controllers.CreateOrder(w, r)
 	})
@@ -133,18 +323,376 @@ func main() {
 		r.URL.Path = "/orders/" + ps.ByName("id")
 		controllers.DeleteOrder(w, r)
 	})
-	router.POST("/orders/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	// httprouter cannot mix a static segment with the :id of the restore route, so search matches on :id
+	router.POST("/orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("id") != "search" {
+			http.NotFound(w, r)
+			return
+		}
 		controllers.SearchOrders(w, r)
 	})
+	router.POST("/orders/:id/restore", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		controllers.RestoreRecord(w, r, "orders")
+	})
+	router.GET("/orders/:id/invoices", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		controllers.GetOrderInvoices(w, r)
+	})
+	router.POST("/orders/:id/invoice", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		controllers.InvoiceOrder(w, r)
+	})
+	router.GET("/orders/:id/returns", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		controllers.GetOrderReturns(w, r)
+	})
+
+	// Invoice Routes
+	router.GET("/invoices", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllInvoices(w, r)
+	})
+	router.GET("/invoices/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/invoices/" + ps.ByName("id")
+		controllers.GetInvoiceByID(w, r)
+	})
+	// httprouter cannot mix a static segment with the :id of the credit note route, so search matches on :id
+	router.POST("/invoices/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("id") != "search" {
+			http.NotFound(w, r)
+			return
+		}
+		controllers.SearchInvoices(w, r)
+	})
+	router.POST("/invoices/:id/credit-notes", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/invoices/" + ps.ByName("id")
+		controllers.CreateCreditNote(w, r)
+	})
+	router.GET("/invoice-settings", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetInvoiceSettings(w, r)
+	})
+	router.PUT("/invoice-settings", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.UpdateInvoiceSettings(w, r)
+	})
+
+	// Relation Routes
+	router.GET("/relations", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllRelations(w, r)
+	})
+	router.PUT("/relations/:name", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/relations/" + ps.ByName("name")
+		controllers.UpdateRelation(w, r)
+	})
+
+	// Trash Routes
+	router.GET("/trash/:resource", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/trash/" + ps.ByName("resource")
+		controllers.GetTrash(w, r)
+	})
+	router.DELETE("/trash/:resource/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/trash/" + ps.ByName("resource") + "/" + ps.ByName("id")
+		controllers.PurgeRecord(w, r)
+	})
+
+	// Audit Routes
+	router.GET("/audit", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAuditEvents(w, r)
+	})
+
+	// Webhook Routes
+	router.GET("/webhooks", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllWebhooks(w, r)
+	})
+	router.GET("/webhooks/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/webhooks/" + ps.ByName("id")
+		controllers.GetWebhookByID(w, r)
+	})
+	router.POST("/webhooks", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.CreateWebhook(w, r)
+	})
+	router.PUT("/webhooks/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/webhooks/" + ps.ByName("id")
+		controllers.UpdateWebhook(w, r)
+	})
+	router.DELETE("/webhooks/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/webhooks/" + ps.ByName("id")
+		controllers.DeleteWebhook(w, r)
+	})
+	router.GET("/webhook-deliveries", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetWebhookDeliveries(w, r)
+	})
+	router.POST("/webhook-deliveries/:id/redeliver", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/webhook-deliveries/" + ps.ByName("id")
+		controllers.RedeliverWebhook(w, r)
+	})
+
+	// Inventory Routes
+	router.GET("/inventory/alerts", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetStockAlerts(w, r)
+	})
+	router.GET("/inventory/replenishment", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetReplenishmentReport(w, r)
+	})
+
+	// Supplier Routes
+	router.GET("/suppliers", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllSuppliers(w, r)
+	})
+	router.GET("/suppliers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/suppliers/" + ps.ByName("id")
+		controllers.GetSupplierByID(w, r)
+	})
+	router.GET("/suppliers/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/suppliers/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "suppliers")
+	})
+	router.POST("/suppliers", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.CreateSupplier(w, r)
+	})
+	router.PUT("/suppliers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/suppliers/" + ps.ByName("id")
+		controllers.UpdateSupplier(w, r)
+	})
+	router.DELETE("/suppliers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/suppliers/" + ps.ByName("id")
+		controllers.DeleteSupplier(w, r)
+	})
+
+	// Review Routes
+	router.GET("/reviews", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllReviews(w, r)
+	})
+	router.GET("/reviews/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/reviews/" + ps.ByName("id")
+		controllers.GetReviewByID(w, r)
+	})
+	router.GET("/reviews/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/reviews/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "reviews")
+	})
+	router.PUT("/reviews/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/reviews/" + ps.ByName("id")
+		controllers.UpdateReview(w, r)
+	})
+	router.POST("/reviews/:id/moderate", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/reviews/" + ps.ByName("id")
+		controllers.ModerateReview(w, r)
+	})
+	router.DELETE("/reviews/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/reviews/" + ps.ByName("id")
+		controllers.DeleteReview(w, r)
+	})
+
+	// Purchase Order Routes
+	router.GET("/purchase-orders", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllPurchaseOrders(w, r)
+	})
+	router.GET("/purchase-orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/purchase-orders/" + ps.ByName("id")
+		controllers.GetPurchaseOrderByID(w, r)
+	})
+	router.GET("/purchase-orders/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/purchase_orders/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "purchase_orders")
+	})
+	router.POST("/purchase-orders", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.CreatePurchaseOrder(w, r)
+	})
+	router.PUT("/purchase-orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/purchase-orders/" + ps.ByName("id")
+		controllers.UpdatePurchaseOrder(w, r)
+	})
+	router.DELETE("/purchase-orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/purchase-orders/" + ps.ByName("id")
+		controllers.DeletePurchaseOrder(w, r)
+	})
+	router.POST("/purchase-orders/:id/send", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/purchase-orders/" + ps.ByName("id")
+		controllers.SendPurchaseOrder(w, r)
+	})
+	router.POST("/purchase-orders/:id/receive", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/purchase-orders/" + ps.ByName("id")
+		controllers.ReceiveGoods(w, r)
+	})
+
+	// Return Routes
+	router.GET("/returns", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllReturns(w, r)
+	})
+	router.GET("/returns/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/returns/" + ps.ByName("id")
+		controllers.GetReturnByID(w, r)
+	})
+	router.GET("/returns/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/returns/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "returns")
+	})
+	router.POST("/returns", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.CreateReturn(w, r)
+	})
+	router.POST("/returns/:id/approve", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/returns/" + ps.ByName("id")
+		controllers.ApproveReturn(w, r)
+	})
+	router.POST("/returns/:id/reject", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/returns/" + ps.ByName("id")
+		controllers.RejectReturn(w, r)
+	})
+	router.POST("/returns/:id/receive", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/returns/" + ps.ByName("id")
+		controllers.ReceiveReturn(w, r)
+	})
+
+	// Gift Card Routes
+	router.GET("/gift-cards", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllGiftCards(w, r)
+	})
+	router.GET("/gift-cards/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/gift-cards/" + ps.ByName("id")
+		controllers.GetGiftCardByID(w, r)
+	})
+	router.GET("/gift-cards/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/gift_cards/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "gift_cards")
+	})
+	router.POST("/gift-cards", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.IssueGiftCard(w, r)
+	})
+
+	// Warehouse Routes
+	router.GET("/warehouses", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllWarehouses(w, r)
+	})
+	router.GET("/warehouses/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/warehouses/" + ps.ByName("id")
+		controllers.GetWarehouseByID(w, r)
+	})
+	router.GET("/warehouses/:id/stock", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/warehouses/" + ps.ByName("id")
+		controllers.GetWarehouseStock(w, r)
+	})
+	router.GET("/warehouses/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/warehouses/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "warehouses")
+	})
+	router.POST("/warehouses", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.CreateWarehouse(w, r)
+	})
+	router.PUT("/warehouses/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/warehouses/" + ps.ByName("id")
+		controllers.UpdateWarehouse(w, r)
+	})
+	router.DELETE("/warehouses/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/warehouses/" + ps.ByName("id")
+		controllers.DeleteWarehouse(w, r)
+	})
+	router.GET("/stock-transfers", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetStockTransfers(w, r)
+	})
+	router.POST("/stock-transfers", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.CreateStockTransfer(w, r)
+	})
+
+	// Stocktake Routes
+	router.GET("/stocktakes", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllStocktakes(w, r)
+	})
+	router.GET("/stocktakes/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/stocktakes/" + ps.ByName("id")
+		controllers.GetStocktakeByID(w, r)
+	})
+	router.GET("/stocktakes/:id/variance", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/stocktakes/" + ps.ByName("id")
+		controllers.GetStocktakeVariance(w, r)
+	})
+	router.GET("/stocktakes/:id/history", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/stocktakes/" + ps.ByName("id")
+		controllers.GetHistory(w, r, "stocktakes")
+	})
+	router.POST("/stocktakes", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.CreateStocktake(w, r)
+	})
+	router.POST("/stocktakes/:id/counts", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/stocktakes/" + ps.ByName("id")
+		controllers.SubmitStocktakeCounts(w, r)
+	})
+	router.POST("/stocktakes/:id/approve", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/stocktakes/" + ps.ByName("id")
+		controllers.ApproveStocktake(w, r)
+	})
+	router.POST("/stocktakes/:id/cancel", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/stocktakes/" + ps.ByName("id")
+		controllers.CancelStocktake(w, r)
+	})
+
+	// Schedule Routes
+	router.GET("/schedules", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetAllSchedules(w, r)
+	})
+	router.GET("/schedules/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/schedules/" + ps.ByName("id")
+		controllers.GetScheduleByID(w, r)
+	})
+	router.GET("/schedules/:id/runs", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/schedules/" + ps.ByName("id")
+		controllers.GetScheduleRuns(w, r)
+	})
+	router.POST("/schedules", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.CreateSchedule(w, r)
+	})
+	router.POST("/schedules/:id/run", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/schedules/" + ps.ByName("id")
+		controllers.RunSchedule(w, r)
+	})
+	router.PUT("/schedules/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/schedules/" + ps.ByName("id")
+		controllers.UpdateSchedule(w, r)
+	})
+	router.DELETE("/schedules/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/schedules/" + ps.ByName("id")
+		controllers.DeleteSchedule(w, r)
+	})
+
+	// Analytics Routes
+	router.GET("/analytics/revenue", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetRevenueAnalytics(w, r)
+	})
+	router.GET("/analytics/customers/segments", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetCustomerSegments(w, r)
+	})
+	router.GET("/analytics/customers/cohorts", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetCohortRetention(w, r)
+	})
+	router.GET("/analytics/forecast", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetDemandForecast(w, r)
+	})
+	router.GET("/analytics/forecast/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/analytics/forecast/" + ps.ByName("id")
+		controllers.GetBookForecast(w, r)
+	})
 
 	// Reports Routes
 	router.GET("/reports/sales", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		ctx := r.Context()
 		controllers.GetSalesReport(ctx, w, r)
 	})
+	router.POST("/reports/sales/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.SearchSalesReports(w, r)
+	})
+	router.GET("/reports/named", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		controllers.GetNamedReports(w, r)
+	})
+	router.GET("/reports/named/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/reports/named/" + ps.ByName("id")
+		controllers.GetNamedReportByID(w, r)
+	})
+	router.DELETE("/reports/named/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/reports/named/" + ps.ByName("id")
+		controllers.DeleteNamedReport(w, r)
+	})
 
 	// Gracefully handle server shutdown
-	server := &http.Server{Addr: ":8080", Handler: router}
+	server := &http.Server{Addr: ":8080", Handler: controllers.WithRequestID(router)}
 	go func() {
 		log.Println("Starting server on :8080...")
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -152,10 +700,7 @@ func main() {
 		}
 	}()
 	router.POST("/reports/sales/generate", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		ctx := r.Context()
-		controllers.GenerateSalesReport(ctx)
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Sales report generated successfully"))
+		controllers.GenerateSalesReportOnDemand(w, r)
 	})
 	
 	// Wait for termination signal to gracefully shut down
@@ -169,5 +714,51 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server shutdown failed: %v", err)
 	}
+	stopJobs()
+	if err := waitForJobs(ctx, &jobs); err != nil {
+		log.Printf("Periodic jobs did not stop: %v", err)
+	}
+	if err := controllers.StopReportScheduler(ctx); err != nil {
+		log.Printf("Report scheduler did not stop: %v", err)
+	}
 	log.Println("Server exited gracefully.")
 }
+
+// runPeriodically runs job every interval, and once right away if runNow is set, until the context is
+// cancelled. A run in progress is finished before it returns.
+func runPeriodically(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, runNow bool, job func()) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		if runNow {
+			job()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				job()
+			}
+		}
+	}()
+}
+
+// waitForJobs waits for the periodic jobs to return or the context to expire
+func waitForJobs(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	data "finalProject/StructureData"
+)
+
+// postalFormat is the postal code format of a country. The parts captured by the pattern are joined with
+// the separator to give the canonical code.
+type postalFormat struct {
+	pattern   *regexp.Regexp
+	separator string
+}
+
+func newPostalFormat(pattern, separator string) *postalFormat {
+	return &postalFormat{pattern: regexp.MustCompile("^(?:" + pattern + ")$"), separator: separator}
+}
+
+var (
+	threeDigits    = newPostalFormat(`(\d{3})`, "")
+	fourDigits     = newPostalFormat(`(\d{4})`, "")
+	fiveDigits     = newPostalFormat(`(\d{5})`, "")
+	sixDigits      = newPostalFormat(`(\d{6})`, "")
+	sevenDigits    = newPostalFormat(`(\d{7})`, "")
+	threeTwoDigits = newPostalFormat(`(\d{3}) ?(\d{2})`, " ")
+)
+
+// country is an ISO 3166-1 country with the names it is known by
+type country struct {
+	alpha2 string
+	alpha3 string
+	names  []string
+	postal *postalFormat // Nil for countries without postal codes
+}
+
+var countries = []country{
+	{"AE", "ARE", []string{"United Arab Emirates", "UAE"}, nil},
+	{"AR", "ARG", []string{"Argentina"}, newPostalFormat(`([A-Z]\d{4}[A-Z]{3}|\d{4})`, "")},
+	{"AT", "AUT", []string{"Austria", "Österreich"}, fourDigits},
+	{"AU", "AUS", []string{"Australia"}, fourDigits},
+	{"BE", "BEL", []string{"Belgium", "België", "Belgique"}, fourDigits},
+	{"BG", "BGR", []string{"Bulgaria"}, fourDigits},
+	{"BR", "BRA", []string{"Brazil", "Brasil"}, newPostalFormat(`(\d{5})-?(\d{3})`, "-")},
+	{"CA", "CAN", []string{"Canada"}, newPostalFormat(`([A-Z]\d[A-Z]) ?(\d[A-Z]\d)`, " ")},
+	{"CH", "CHE", []string{"Switzerland", "Schweiz", "Suisse", "Svizzera"}, fourDigits},
+	{"CL", "CHL", []string{"Chile"}, sevenDigits},
+	{"CN", "CHN", []string{"China"}, sixDigits},
+	{"CO", "COL", []string{"Colombia"}, sixDigits},
+	{"CZ", "CZE", []string{"Czechia", "Czech Republic"}, threeTwoDigits},
+	{"DE", "DEU", []string{"Germany", "Deutschland"}, fiveDigits},
+	{"DK", "DNK", []string{"Denmark", "Danmark"}, fourDigits},
+	{"EG", "EGY", []string{"Egypt"}, fiveDigits},
+	{"ES", "ESP", []string{"Spain", "España"}, fiveDigits},
+	{"FI", "FIN", []string{"Finland", "Suomi"}, fiveDigits},
+	{"FR", "FRA", []string{"France"}, fiveDigits},
+	{"GB", "GBR", []string{"United Kingdom", "UK", "Great Britain", "England", "Scotland", "Wales", "Northern Ireland"},
+		newPostalFormat(`([A-Z]{1,2}\d[A-Z\d]?) ?(\d[A-Z]{2})`, " ")},
+	{"GR", "GRC", []string{"Greece"}, threeTwoDigits},
+	{"HK", "HKG", []string{"Hong Kong"}, nil},
+	{"HU", "HUN", []string{"Hungary"}, fourDigits},
+	{"ID", "IDN", []string{"Indonesia"}, fiveDigits},
+	{"IE", "IRL", []string{"Ireland"}, newPostalFormat(`([AC-FHKNPRTV-Y]\d{2}|D6W) ?([0-9AC-FHKNPRTV-Y]{4})`, " ")},
+	{"IL", "ISR", []string{"Israel"}, sevenDigits},
+	{"IN", "IND", []string{"India"}, sixDigits},
+	{"IS", "ISL", []string{"Iceland"}, threeDigits},
+	{"IT", "ITA", []string{"Italy", "Italia"}, fiveDigits},
+	{"JP", "JPN", []string{"Japan", "Nippon"}, newPostalFormat(`(\d{3})-?(\d{4})`, "-")},
+	{"KE", "KEN", []string{"Kenya"}, fiveDigits},
+	{"KR", "KOR", []string{"South Korea", "Korea"}, fiveDigits},
+	{"LU", "LUX", []string{"Luxembourg"}, newPostalFormat(`(?:L-?)?(\d{4})`, "")},
+	{"MA", "MAR", []string{"Morocco"}, fiveDigits},
+	{"MX", "MEX", []string{"Mexico", "México"}, fiveDigits},
+	{"MY", "MYS", []string{"Malaysia"}, fiveDigits},
+	{"NG", "NGA", []string{"Nigeria"}, sixDigits},
+	{"NL", "NLD", []string{"Netherlands", "The Netherlands", "Holland", "Nederland"}, newPostalFormat(`(\d{4}) ?([A-Z]{2})`, " ")},
+	{"NO", "NOR", []string{"Norway", "Norge"}, fourDigits},
+	{"NZ", "NZL", []string{"New Zealand"}, fourDigits},
+	{"PH", "PHL", []string{"Philippines"}, fourDigits},
+	{"PK", "PAK", []string{"Pakistan"}, fiveDigits},
+	{"PL", "POL", []string{"Poland", "Polska"}, newPostalFormat(`(\d{2})-?(\d{3})`, "-")},
+	{"PT", "PRT", []string{"Portugal"}, newPostalFormat(`(\d{4})-?(\d{3})`, "-")},
+	{"QA", "QAT", []string{"Qatar"}, nil},
+	{"RO", "ROU", []string{"Romania"}, sixDigits},
+	{"RU", "RUS", []string{"Russia", "Russian Federation"}, sixDigits},
+	{"SA", "SAU", []string{"Saudi Arabia"}, fiveDigits},
+	{"SE", "SWE", []string{"Sweden", "Sverige"}, threeTwoDigits},
+	{"SG", "SGP", []string{"Singapore"}, sixDigits},
+	{"SK", "SVK", []string{"Slovakia"}, threeTwoDigits},
+	{"TH", "THA", []string{"Thailand"}, fiveDigits},
+	{"TR", "TUR", []string{"Turkey", "Türkiye"}, fiveDigits},
+	{"UA", "UKR", []string{"Ukraine"}, fiveDigits},
+	{"US", "USA", []string{"United States", "United States of America", "America"}, newPostalFormat(`(\d{5})(?:[- ]?(\d{4}))?`, "-")},
+	{"VN", "VNM", []string{"Vietnam", "Viet Nam"}, sixDigits},
+	{"ZA", "ZAF", []string{"South Africa"}, fourDigits},
+}
+
+// countriesByKey finds a country by its alpha-2 code, alpha-3 code or any of its names, in upper case
+var countriesByKey = func() map[string]*country {
+	byKey := map[string]*country{}
+	for i := range countries {
+		c := &countries[i]
+		byKey[c.alpha2] = c
+		byKey[c.alpha3] = c
+		for _, name := range c.names {
+			byKey[countryKey(name)] = c
+		}
+	}
+	return byKey
+}()
+
+// countryKey is the lookup key of a country name: upper case, without dots and with single spaces
+func countryKey(name string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(strings.ReplaceAll(name, ".", "")), " "))
+}
+
+// CountryCode returns the ISO 3166-1 alpha-2 code of a country given by code or by name
+func CountryCode(name string) (string, bool) {
+	c, ok := countriesByKey[countryKey(name)]
+	if !ok {
+		return "", false
+	}
+	return c.alpha2, true
+}
+
+// SameCountry reports whether two country names or codes designate the same country. Unknown countries
+// are compared ignoring case.
+func SameCountry(a, b string) bool {
+	codeA, okA := CountryCode(a)
+	codeB, okB := CountryCode(b)
+	if okA && okB {
+		return codeA == codeB
+	}
+	return a != "" && strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// NormalizeAddress trims the fields of an address and collapses their spaces, capitalizes the words of
+// fields written all in upper or lower case, replaces the country by its ISO 3166-1 alpha-2 code and checks
+// the postal code against the format of the country, returning it in its canonical form
+func NormalizeAddress(address data.Address) (data.Address, *data.ErrorResponse) {
+	address.Street = capitalizeWords(collapseSpaces(address.Street))
+	address.City = capitalizeWords(collapseSpaces(address.City))
+	address.State = collapseSpaces(address.State)
+	if len(address.State) <= 3 {
+		address.State = strings.ToUpper(address.State) // A state or province code
+	} else {
+		address.State = capitalizeWords(address.State)
+	}
+	address.PostalCode = strings.ToUpper(collapseSpaces(address.PostalCode))
+
+	if address.Street == "" || address.City == "" || strings.TrimSpace(address.Country) == "" {
+		return data.Address{}, data.NewValidationError("street, city and country are required")
+	}
+	c, ok := countriesByKey[countryKey(address.Country)]
+	if !ok {
+		return data.Address{}, data.NewValidationError("Unknown country: " + strings.TrimSpace(address.Country))
+	}
+	address.Country = c.alpha2
+
+	switch {
+	case c.postal == nil:
+		address.PostalCode = "" // The country has no postal codes
+	case address.PostalCode == "" && c.alpha2 == "IE":
+		// Not every Irish address has an Eircode
+	case address.PostalCode == "":
+		return data.Address{}, data.NewValidationError("postal_code is required for " + c.alpha2)
+	default:
+		parts := c.postal.pattern.FindStringSubmatch(address.PostalCode)
+		if parts == nil {
+			return data.Address{}, data.NewValidationError("Invalid postal_code for " + c.alpha2 + ": " + address.PostalCode)
+		}
+		var canonical []string
+		for _, part := range parts[1:] {
+			if part != "" {
+				canonical = append(canonical, part)
+			}
+		}
+		address.PostalCode = strings.Join(canonical, c.postal.separator)
+	}
+	return address, nil
+}
+
+// collapseSpaces trims a field and replaces every run of white space with a single space
+func collapseSpaces(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// capitalizeWords capitalizes every word of a field written all in upper or lower case, leaving fields in
+// mixed case, such as "McAllen", as they are
+func capitalizeWords(value string) string {
+	if value != strings.ToUpper(value) && value != strings.ToLower(value) {
+		return value
+	}
+	runes := []rune(strings.ToLower(value))
+	startOfWord := true
+	for i, r := range runes {
+		if startOfWord && unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+		}
+		startOfWord = unicode.IsSpace(r) || r == '-' || r == '/'
+	}
+	return string(runes)
+}
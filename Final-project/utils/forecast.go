@@ -0,0 +1,164 @@
+package utils
+
+import "math"
+
+// Forecast is the prediction of a series for the periods following it, with a confidence band. The band
+// widens with the square root of the distance, from the spread of the one-step-ahead errors over the
+// history. Negative values are raised to zero, as the series are counts.
+type Forecast struct {
+	Values   []float64
+	Lower    []float64
+	Upper    []float64
+	Error    float64 // Standard deviation of the one-step-ahead errors over the history
+	MAE      float64 // Mean absolute one-step-ahead error over the history
+	Seasonal bool
+	Alpha    float64 // Smoothing of the level
+	Beta     float64 // Smoothing of the trend
+	Gamma    float64 // Smoothing of the seasonality
+}
+
+// smoothingGrid is the values tried for every smoothing parameter, keeping those fitting the history best
+var smoothingGrid = []float64{0.1, 0.3, 0.5, 0.7, 0.9}
+
+// trendDamping shrinks the trend at every step ahead, so a short rise is not extrapolated forever
+const trendDamping = 0.9
+
+// ZScore returns the number of standard deviations covering a two-sided confidence level, 1.96 for 0.95
+func ZScore(confidence float64) float64 {
+	return math.Sqrt2 * math.Erfinv(confidence)
+}
+
+// MovingAverageForecast predicts every following period as the mean of the last window periods
+func MovingAverageForecast(history []float64, window, horizon int, z float64) Forecast {
+	if window > len(history) {
+		window = len(history)
+	}
+
+	// One-step-ahead errors over the history
+	var errors []float64
+	for t := window; t < len(history) && window > 0; t++ {
+		errors = append(errors, history[t]-mean(history[t-window:t]))
+	}
+
+	value := 0.0
+	if window > 0 {
+		value = mean(history[len(history)-window:])
+	}
+	values := make([]float64, horizon)
+	for h := range values {
+		values[h] = value
+	}
+	return newForecast(values, errors, z)
+}
+
+// ExponentialSmoothingForecast predicts the following periods with damped Holt-Winters exponential smoothing:
+// additive seasonality of the given length when the history covers two seasons, Holt's linear trend
+// otherwise. The smoothing parameters are those of a grid fitting the history best.
+func ExponentialSmoothingForecast(history []float64, season, horizon int, z float64) Forecast {
+	if len(history) < 2 {
+		return MovingAverageForecast(history, len(history), horizon, z)
+	}
+	seasonal := season > 1 && len(history) >= 2*season
+
+	best := Forecast{Error: math.Inf(1)}
+	gammas := []float64{0}
+	if seasonal {
+		gammas = smoothingGrid
+	}
+	for _, alpha := range smoothingGrid {
+		for _, beta := range smoothingGrid {
+			for _, gamma := range gammas {
+				values, errors := holtWinters(history, season, seasonal, alpha, beta, gamma, horizon)
+				forecast := newForecast(values, errors, z)
+				if forecast.Error < best.Error {
+					best = forecast
+					best.Alpha, best.Beta, best.Gamma = alpha, beta, gamma
+				}
+			}
+		}
+	}
+	best.Seasonal = seasonal
+	return best
+}
+
+// holtWinters runs damped exponential smoothing over the history and returns the forecast and the
+// one-step-ahead errors
+func holtWinters(history []float64, season int, seasonal bool, alpha, beta, gamma float64, horizon int) ([]float64, []float64) {
+	var level, trend float64
+	var seasons []float64
+	start := 1
+	if seasonal {
+		// Start from the first season's mean, the change to the second and the deviations of the first
+		level = mean(history[:season])
+		trend = (mean(history[season:2*season]) - level) / float64(season)
+		seasons = make([]float64, season)
+		for i := range seasons {
+			seasons[i] = history[i] - level
+		}
+		start = season
+	} else {
+		level = history[0]
+		trend = history[1] - history[0]
+	}
+
+	var errors []float64
+	for t := start; t < len(history); t++ {
+		seasonality := 0.0
+		if seasonal {
+			seasonality = seasons[t%season]
+		}
+		errors = append(errors, history[t]-(level+trendDamping*trend+seasonality))
+
+		previousLevel := level
+		level = alpha*(history[t]-seasonality) + (1-alpha)*(level+trendDamping*trend)
+		trend = beta*(level-previousLevel) + (1-beta)*trendDamping*trend
+		if seasonal {
+			seasons[t%season] = gamma*(history[t]-level) + (1-gamma)*seasonality
+		}
+	}
+
+	values := make([]float64, horizon)
+	damping := 0.0
+	for h := range values {
+		damping += math.Pow(trendDamping, float64(h+1))
+		values[h] = level + damping*trend
+		if seasonal {
+			values[h] += seasons[(len(history)+h)%season]
+		}
+	}
+	return values, errors
+}
+
+// newForecast adds the error measures and the confidence band to predicted values
+func newForecast(values, errors []float64, z float64) Forecast {
+	forecast := Forecast{Values: values, Lower: make([]float64, len(values)), Upper: make([]float64, len(values))}
+	var squares, absolutes float64
+	for _, e := range errors {
+		squares += e * e
+		absolutes += math.Abs(e)
+	}
+	if len(errors) > 0 {
+		forecast.Error = math.Sqrt(squares / float64(len(errors)))
+		forecast.MAE = absolutes / float64(len(errors))
+	}
+
+	for h, value := range values {
+		spread := z * forecast.Error * math.Sqrt(float64(h+1))
+		forecast.Values[h] = math.Max(value, 0)
+		forecast.Lower[h] = math.Max(value-spread, 0)
+		forecast.Upper[h] = math.Max(value+spread, 0)
+	}
+	return forecast
+}
+
+// mean returns the average of values, zero for none
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
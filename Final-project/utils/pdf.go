@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Layout of the PDF pages: A4 landscape in points, Courier so columns line up
+const (
+	pdfPageWidth  = 842
+	pdfPageHeight = 595
+	pdfMargin     = 36
+	pdfFontSize   = 9
+	pdfLeading    = 11
+	pdfLinesPage  = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+)
+
+// PDFLineWidth is the number of characters that fit on a line of a PDF page
+const PDFLineWidth = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6) // Courier glyphs are 0.6 em wide
+
+// PDFWriter writes a document of monospaced lines to a PDF file. Each page is written as soon as it is
+// full, so long documents are never held in memory.
+type PDFWriter struct {
+	out     *countingWriter
+	offsets []int64 // Offset of every object, by object number
+	pages   []int   // Object numbers of the pages
+	content bytes.Buffer
+	lines   int
+	Header  []string // Lines repeated in bold at the top of every page after the first
+}
+
+// countingWriter counts the bytes written, as the cross-reference table needs the offset of every object
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Object numbers reserved for the catalog, the page tree and the fonts
+const (
+	pdfCatalog = 1 + iota
+	pdfPageTree
+	pdfRegularFont
+	pdfBoldFont
+)
+
+// NewPDFWriter starts a PDF document
+func NewPDFWriter(w io.Writer) (*PDFWriter, error) {
+	p := &PDFWriter{out: &countingWriter{w: w}, offsets: make([]int64, pdfBoldFont+1)}
+	if _, err := io.WriteString(p.out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"); err != nil {
+		return nil, err
+	}
+	objects := map[int]string{
+		pdfCatalog:     fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPageTree),
+		pdfRegularFont: "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		pdfBoldFont:    "<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	for _, number := range []int{pdfCatalog, pdfRegularFont, pdfBoldFont} {
+		if err := p.writeObject(number, objects[number]); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// WriteLine adds a line to the document, in bold if asked, starting a new page when the current one is full.
+// Lines longer than PDFLineWidth are cut.
+func (p *PDFWriter) WriteLine(text string, bold bool) error {
+	if p.lines == pdfLinesPage {
+		if err := p.flushPage(); err != nil {
+			return err
+		}
+		for _, header := range p.Header {
+			p.addLine(header, true)
+		}
+	}
+	p.addLine(text, bold)
+	return nil
+}
+
+// Close writes the last page, the page tree and the cross-reference table. It does not close the
+// underlying writer.
+func (p *PDFWriter) Close() error {
+	if p.lines > 0 || len(p.pages) == 0 {
+		if err := p.flushPage(); err != nil {
+			return err
+		}
+	}
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	if err := p.writeObject(pdfPageTree, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages))); err != nil {
+		return err
+	}
+
+	// Cross-reference table and trailer
+	xrefOffset := p.out.n
+	var xref strings.Builder
+	fmt.Fprintf(&xref, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets))
+	for _, offset := range p.offsets[1:] {
+		fmt.Fprintf(&xref, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&xref, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets), pdfCatalog, xrefOffset)
+	_, err := io.WriteString(p.out, xref.String())
+	return err
+}
+
+// addLine adds a line to the content of the current page
+func (p *PDFWriter) addLine(text string, bold bool) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	if runes := []rune(text); len(runes) > PDFLineWidth {
+		text = string(runes[:PDFLineWidth])
+	}
+	y := pdfPageHeight - pdfMargin - pdfFontSize - p.lines*pdfLeading
+	fmt.Fprintf(&p.content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, pdfFontSize, pdfMargin, y, pdfString(text))
+	p.lines++
+}
+
+// flushPage writes the current page with its number at the bottom and starts a new one
+func (p *PDFWriter) flushPage() error {
+	pageNumber := fmt.Sprintf("Page %d", len(p.pages)+1)
+	x := pdfPageWidth - pdfMargin - len(pageNumber)*pdfFontSize*6/10
+	fmt.Fprintf(&p.content, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n", pdfFontSize, x, pdfMargin/2, pageNumber)
+
+	contentNumber := len(p.offsets)
+	p.offsets = append(p.offsets, 0)
+	if err := p.writeObject(contentNumber, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.content.Len(), p.content.String())); err != nil {
+		return err
+	}
+
+	pageObject := len(p.offsets)
+	p.offsets = append(p.offsets, 0)
+	page := fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Contents %d 0 R /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> >>",
+		pdfPageTree, pdfPageWidth, pdfPageHeight, contentNumber, pdfRegularFont, pdfBoldFont)
+	if err := p.writeObject(pageObject, page); err != nil {
+		return err
+	}
+	p.pages = append(p.pages, pageObject)
+
+	p.content.Reset()
+	p.lines = 0
+	return nil
+}
+
+// writeObject writes an object and records its offset
+func (p *PDFWriter) writeObject(number int, body string) error {
+	p.offsets[number] = p.out.n
+	_, err := fmt.Fprintf(p.out, "%d 0 obj\n%s\nendobj\n", number, body)
+	return err
+}
+
+// pdfString escapes text for a PDF string in the WinAnsi encoding, replacing the characters it lacks by ?
+func pdfString(text string) string {
+	var escaped strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			escaped.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&escaped, "\\%03o", r) // Latin-1 letters have the same code in WinAnsi
+		case r == '€':
+			escaped.WriteString("\\200")
+		default:
+			escaped.WriteByte('?')
+		}
+	}
+	return escaped.String()
+}
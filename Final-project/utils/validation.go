@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+// ValidateBookSearchCriteria rejects negative bounds, inverted ranges, ratings outside 0 to 5 and unknown sort fields
+func ValidateBookSearchCriteria(criteria data.BookSearchCriteria) *data.ErrorResponse {
+	if criteria.MinPrice < 0 || criteria.MaxPrice < 0 {
+		return data.NewValidationError("Price bounds cannot be negative")
+	}
+	if criteria.MaxPrice > 0 && criteria.MinPrice > criteria.MaxPrice {
+		return data.NewValidationError("min_price cannot be greater than max_price")
+	}
+	if criteria.MinStock < 0 || criteria.MaxStock < 0 {
+		return data.NewValidationError("Stock bounds cannot be negative")
+	}
+	if criteria.MaxStock > 0 && criteria.MinStock > criteria.MaxStock {
+		return data.NewValidationError("min_stock cannot be greater than max_stock")
+	}
+	if invertedTimeRange(criteria.MinPublishedAt, criteria.MaxPublishedAt) {
+		return data.NewValidationError("min_published_at cannot be after max_published_at")
+	}
+	if criteria.MinRating < 0 || criteria.MaxRating < 0 || criteria.MinRating > 5 || criteria.MaxRating > 5 {
+		return data.NewValidationError("Rating bounds must be between 0 and 5")
+	}
+	if criteria.MaxRating > 0 && criteria.MinRating > criteria.MaxRating {
+		return data.NewValidationError("min_rating cannot be greater than max_rating")
+	}
+	if criteria.MinReviewCount < 0 {
+		return data.NewValidationError("min_review_count cannot be negative")
+	}
+	if criteria.SortBy != "" && !data.IsValidBookSortField(criteria.SortBy) {
+		return data.NewValidationError("sort_by must be title, price, published_at, stock, rating or review_count")
+	}
+	if criteria.SortOrder != "" && criteria.SortOrder != "asc" && criteria.SortOrder != "desc" {
+		return data.NewValidationError("sort_order must be asc or desc")
+	}
+	return nil
+}
+
+// ValidateCustomerSearchCriteria rejects an inverted creation date range
+func ValidateCustomerSearchCriteria(criteria data.CustomerSearchCriteria) *data.ErrorResponse {
+	if invertedTimeRange(criteria.MinCreatedAt, criteria.MaxCreatedAt) {
+		return data.NewValidationError("min_created_at cannot be after max_created_at")
+	}
+	return nil
+}
+
+// ValidateOrderSearchCriteria rejects negative bounds and inverted ranges, including item criteria
+func ValidateOrderSearchCriteria(criteria data.OrderSearchCriteria) *data.ErrorResponse {
+	if criteria.MinTotalPrice < 0 || criteria.MaxTotalPrice < 0 {
+		return data.NewValidationError("Total price bounds cannot be negative")
+	}
+	if criteria.MaxTotalPrice > 0 && criteria.MinTotalPrice > criteria.MaxTotalPrice {
+		return data.NewValidationError("min_total_price cannot be greater than max_total_price")
+	}
+	if invertedTimeRange(criteria.MinCreatedAt, criteria.MaxCreatedAt) {
+		return data.NewValidationError("min_created_at cannot be after max_created_at")
+	}
+	if criteria.ItemCriteria.MinQuantity < 0 || criteria.ItemCriteria.MaxQuantity < 0 {
+		return data.NewValidationError("Quantity bounds cannot be negative")
+	}
+	if criteria.ItemCriteria.MaxQuantity > 0 && criteria.ItemCriteria.MinQuantity > criteria.ItemCriteria.MaxQuantity {
+		return data.NewValidationError("min_quantity cannot be greater than max_quantity")
+	}
+	return ValidateBookSearchCriteria(criteria.ItemCriteria.BookCriteria)
+}
+
+func invertedTimeRange(min, max time.Time) bool {
+	return !min.IsZero() && !max.IsZero() && min.After(max)
+}
+
+// ValidateAuditSearchCriteria rejects an inverted timestamp range
+func ValidateAuditSearchCriteria(criteria data.AuditSearchCriteria) *data.ErrorResponse {
+	if invertedTimeRange(criteria.MinTimestamp, criteria.MaxTimestamp) {
+		return data.NewValidationError("min_timestamp cannot be after max_timestamp")
+	}
+	return nil
+}
+
+// ValidateSalesReportSearchCriteria rejects negative bounds and inverted ranges, including top book criteria
+func ValidateSalesReportSearchCriteria(criteria data.SalesReportSearchCriteria) *data.ErrorResponse {
+	if invertedTimeRange(criteria.MinTimestamp, criteria.MaxTimestamp) {
+		return data.NewValidationError("min_timestamp cannot be after max_timestamp")
+	}
+	if criteria.MinRevenue < 0 || criteria.MaxRevenue < 0 {
+		return data.NewValidationError("Revenue bounds cannot be negative")
+	}
+	if criteria.MaxRevenue > 0 && criteria.MinRevenue > criteria.MaxRevenue {
+		return data.NewValidationError("min_revenue cannot be greater than max_revenue")
+	}
+	if criteria.MinOrders < 0 || criteria.MaxOrders < 0 {
+		return data.NewValidationError("Order count bounds cannot be negative")
+	}
+	if criteria.MaxOrders > 0 && criteria.MinOrders > criteria.MaxOrders {
+		return data.NewValidationError("min_orders cannot be greater than max_orders")
+	}
+	if criteria.TopBooksCriteria.MinQuantity < 0 || criteria.TopBooksCriteria.MaxQuantity < 0 {
+		return data.NewValidationError("Quantity bounds cannot be negative")
+	}
+	if criteria.TopBooksCriteria.MaxQuantity > 0 && criteria.TopBooksCriteria.MinQuantity > criteria.TopBooksCriteria.MaxQuantity {
+		return data.NewValidationError("min_quantity cannot be greater than max_quantity")
+	}
+	return ValidateBookSearchCriteria(criteria.TopBooksCriteria.BookCriteria)
+}